@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/plugins/pkg/ipam"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/config"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/sriov"
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
+)
+
+// cmdAddBond configures the member VFs of the bond netconf in the pod netns and bonds them. Each member is cached as
+// <containerID>-<memberIfName> and the bond as the container interface, whose result is returned by a retried cmdAdd.
+func cmdAddBond(args *skel.CmdArgs) error {
+	cached, _, err := config.LoadConfFromCache(args)
+	if err == nil && cached.Bond != nil && cached.AddResult != nil {
+		logging.Info("Bond already configured by a previous cmdAdd, returning its result",
+			"func", "cmdAddBond",
+			"members", cached.Bond.Members)
+		return types.PrintResult(cached.AddResult, cached.CNIVersion)
+	}
+
+	bondConf, err := config.LoadBondConf(args.StdinData)
+	if err != nil {
+		return fmt.Errorf("SRIOV-CNI failed to load netconf: %w", err)
+	}
+	memberConfs, err := config.LoadBondMemberConfs(args.StdinData)
+	if err != nil {
+		return fmt.Errorf("SRIOV-CNI failed to load netconf: %w", err)
+	}
+	bondIfName := config.BondIfName(bondConf, args.IfName)
+
+	if bondConf.VerifyAllocation {
+		for _, memberConf := range memberConfs {
+			if err = verifyDeviceAllocation(memberConf, args); err != nil {
+				return err
+			}
+		}
+	}
+
+	vfs := make([]sriov.BatchVF, len(memberConfs))
+	memberIfNames := make([]string, len(memberConfs))
+	for i, memberConf := range memberConfs {
+		if memberIfNames[i], err = config.BondMemberIfName(bondIfName, i); err != nil {
+			return err
+		}
+		vfs[i] = sriov.BatchVF{Conf: memberConf, PodIfName: memberIfNames[i]}
+	}
+
+	netns, err := utils.GetNSWithRetry(args.Netns)
+	if err != nil {
+		return fmt.Errorf("failed to open netns %q: %w", args.Netns, err)
+	}
+	defer netns.Close()
+
+	sm := sriov.NewSriovManagerForConf(bondConf)
+	// SetupVFs rolls the members back on its own failure
+	if err = sm.SetupVFs(vfs, netns, 0); err != nil {
+		return fmt.Errorf("failed to set up the members of bond %q: %w", bondIfName, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = sm.ReleaseBond(bondIfName, netns)
+			sm.RollbackVFs(vfs, netns)
+		}
+	}()
+
+	if err = sm.SetupBond(bondConf, bondIfName, memberIfNames, netns); err != nil {
+		return fmt.Errorf("failed to set up bond %q: %w", bondIfName, err)
+	}
+
+	result := &current.Result{}
+	result.Interfaces = []*current.Interface{{
+		Name:    bondIfName,
+		Sandbox: netns.Path(),
+	}}
+	for i, memberConf := range memberConfs {
+		result.Interfaces = append(result.Interfaces, &current.Interface{
+			Name:    memberIfNames[i],
+			Mac:     config.GetMacAddressForResult(memberConf),
+			Sandbox: netns.Path(),
+			PciID:   memberConf.DeviceID,
+		})
+	}
+
+	// run the IPAM plugin, the addresses are configured on the bond
+	if bondConf.IPAM.Type != "" {
+		var ipamStdinData []byte
+		ipamStdinData, err = config.IPAMStdinData(bondConf, args.StdinData)
+		if err != nil {
+			return err
+		}
+
+		var r types.Result
+		r, err = ipam.ExecAdd(bondConf.IPAM.Type, ipamStdinData)
+		if err != nil {
+			return fmt.Errorf("failed to set up IPAM plugin type %q for bond %q: %w", bondConf.IPAM.Type, bondIfName, err)
+		}
+		defer func() {
+			if err != nil {
+				_ = ipam.ExecDel(bondConf.IPAM.Type, ipamStdinData)
+			}
+		}()
+
+		var newResult *current.Result
+		newResult, err = current.NewResultFromResult(r)
+		if err != nil {
+			return err
+		}
+		if len(newResult.IPs) == 0 {
+			err = errors.New("IPAM plugin returned missing IP config")
+			return err
+		}
+
+		newResult.Interfaces = result.Interfaces
+		for _, ipc := range newResult.IPs {
+			ipc.Interface = current.Int(0)
+		}
+		if err = sm.ConfigureIPAMResult(bondConf, bondIfName, netns, newResult); err != nil {
+			return err
+		}
+		result = newResult
+	}
+
+	// Cache the members and the bond for CmdDel
+	cache := config.GetCache(bondConf)
+	allocator := utils.NewPCIAllocator(config.CacheDir(bondConf))
+	for i, memberConf := range memberConfs {
+		if err = cache.Save(args.ContainerID, memberIfNames[i], memberConf); err != nil {
+			return fmt.Errorf("error saving NetConf of bond member %s: %w", memberConf.DeviceID, err)
+		}
+		if err = allocator.SaveAllocatedPCI(memberConf.DeviceID, args.Netns); err != nil {
+			return fmt.Errorf("error saving the pci allocation for vf pci address %s: %w", memberConf.DeviceID, err)
+		}
+	}
+	bondConf.AddResult = result
+	if err = cache.Save(args.ContainerID, args.IfName, bondConf); err != nil {
+		return fmt.Errorf("error saving NetConf: %w", err)
+	}
+
+	return types.PrintResult(result, bondConf.CNIVersion)
+}
+
+// cmdDelBond deletes the bond of the cached bond netconf and returns each member VF to the host like cmdDel. The
+// cache of the bond itself is removed by cmdDel.
+func cmdDelBond(args *skel.CmdArgs, bondConf *sriovtypes.NetConf, cache config.Cache) error {
+	if bondConf.IPAM.Type != "" {
+		ipamStdinData, err := config.IPAMStdinData(bondConf, args.StdinData)
+		if err != nil {
+			return err
+		}
+		if err = ipam.ExecDel(bondConf.IPAM.Type, ipamStdinData); err != nil {
+			return err
+		}
+	}
+
+	// https://github.com/kubernetes/kubernetes/pull/35240
+	if args.Netns == "" {
+		return nil
+	}
+
+	bondIfName := config.BondIfName(bondConf, args.IfName)
+	sm := sriov.NewSriovManagerForConf(bondConf)
+
+	// a netns that no longer exists took the bond and the members with it, they are only released on the host
+	netns, err := ns.GetNS(args.Netns)
+	if err != nil {
+		if _, ok := err.(ns.NSPathNotExistErr); !ok {
+			return fmt.Errorf("failed to open netns %s: %w", args.Netns, err)
+		}
+		netns = nil
+	} else {
+		defer netns.Close()
+		if err = sm.ReleaseBond(bondIfName, netns); err != nil {
+			return err
+		}
+	}
+
+	for i := range bondConf.Bond.Members {
+		memberIfName, err := config.BondMemberIfName(bondIfName, i)
+		if err != nil {
+			return err
+		}
+		memberConf, err := cache.Load(args.ContainerID, memberIfName)
+		if err != nil {
+			// released by a previous cmdDel
+			logging.Debug("Bond member not cached, skipping it",
+				"func", "cmdDelBond",
+				"memberIfName", memberIfName,
+				"err", err)
+			continue
+		}
+		if err = sm.ResetVFConfig(memberConf); err != nil {
+			return fmt.Errorf("cmdDel() error reseting bond member %s: %w", memberConf.DeviceID, err)
+		}
+		if netns != nil {
+			if err = sm.ReleaseVF(memberConf, memberIfName, netns); err != nil {
+				return err
+			}
+		}
+		if err = releaseVFAllocation(memberConf); err != nil {
+			return err
+		}
+		if err = cache.Remove(args.ContainerID, memberIfName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkBond runs the cmdCheck comparison on each cached member VF of the bond netconf
+func checkBond(args *skel.CmdArgs, bondConf *sriovtypes.NetConf) error {
+	bondIfName := config.BondIfName(bondConf, args.IfName)
+	cache := config.GetCache(bondConf)
+	sm := sriov.NewSriovManagerForConf(bondConf)
+	for i := range bondConf.Bond.Members {
+		memberIfName, err := config.BondMemberIfName(bondIfName, i)
+		if err != nil {
+			return err
+		}
+		memberConf, err := cache.Load(args.ContainerID, memberIfName)
+		if err != nil {
+			return fmt.Errorf("cmdCheck() failed to load cached netconf of bond member %s: %w", memberIfName, err)
+		}
+		if err = sm.CheckVFConfig(memberConf); err != nil {
+			logVFConfigDiff("cmdCheck", memberConf, err)
+			return fmt.Errorf("cmdCheck() bond member %s configuration check failed: %w", memberConf.DeviceID, err)
+		}
+		// the members are bound to a kernel driver
+		if err = sm.CheckVFDriver(memberConf); err != nil {
+			return fmt.Errorf("cmdCheck() bond member %s driver check failed: %w", memberConf.DeviceID, err)
+		}
+	}
+	return nil
+}
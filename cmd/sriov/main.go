@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"os"
 	"runtime"
 	"strings"
 	"time"
@@ -16,13 +17,18 @@ import (
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/config"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/sriov"
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
 	"github.com/vishvananda/netlink"
 )
 
+// unsetValue is logged for the VF attributes that are not configured
+const unsetValue = "unset"
+
 type envArgs struct {
 	types.CommonArgs
-	MAC types.UnmarshallableString `json:"mac,omitempty"`
+	MAC         types.UnmarshallableString `json:"mac,omitempty"`
+	K8S_POD_UID types.UnmarshallableString //nolint:revive // the field is named after the CNI arg
 }
 
 func init() {
@@ -52,94 +58,155 @@ func cmdAdd(args *skel.CmdArgs) error {
 		"func", "cmdAdd",
 		"args.Path", args.Path, "args.StdinData", string(args.StdinData), "args.Args", args.Args)
 
+	unlockNode, err := config.LockNode(args.StdinData)
+	if err != nil {
+		return err
+	}
+	defer unlockNode()
+
+	if config.IsBondConf(args.StdinData) {
+		return cmdAddBond(args)
+	}
+
+	// A retried sandbox setup finds the VF already configured by the previous cmdAdd
+	prevResult, err := retriedAddResult(args)
+	if err != nil {
+		return err
+	}
+	if prevResult != nil {
+		return prevResult.Print()
+	}
+
 	netConf, err := config.LoadConf(args.StdinData)
 	if err != nil {
 		return fmt.Errorf("SRIOV-CNI failed to load netconf: %v", err)
 	}
+	podIfName := config.PodIfName(netConf, args.IfName)
 
-	envArgs, err := getEnvArgs(args.Args)
-	if err != nil {
-		return fmt.Errorf("SRIOV-CNI failed to parse args: %v", err)
+	if err = setRequestedMAC(netConf, args); err != nil {
+		return err
 	}
 
-	if envArgs != nil {
-		MAC := string(envArgs.MAC)
-		if MAC != "" {
-			netConf.MAC = MAC
+	if netConf.VerifyAllocation {
+		if err = verifyDeviceAllocation(netConf, args); err != nil {
+			return err
 		}
 	}
 
-	// RuntimeConfig takes preference than envArgs.
-	// This maintains compatibility of using envArgs
-	// for MAC config.
-	if netConf.RuntimeConfig.Mac != "" {
-		netConf.MAC = netConf.RuntimeConfig.Mac
+	if netConf.MACRegistry && netConf.MAC != "" {
+		macRegistry := utils.NewMACRegistry(config.CacheDir(netConf))
+		if err = macRegistry.Reserve(netConf.MAC, netConf.DeviceID); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				_ = macRegistry.Release(netConf.MAC, netConf.DeviceID)
+			}
+		}()
 	}
 
-	// Always use lower case for mac address
-	netConf.MAC = strings.ToLower(netConf.MAC)
+	if netConf.EnforceSchedulerCapacity != "" && netConf.MinTxRate != nil {
+		if netConf.SiblingMinTxRates, err = config.LoadSiblingMinTxRates(netConf); err != nil {
+			return fmt.Errorf("failed to load the min_tx_rate of the other VFs of PF %s: %v", netConf.Master, err)
+		}
+	}
 
-	netns, err := ns.GetNS(args.Netns)
+	netns, err := vfNetNS(netConf, args.Netns, utils.GetNSWithRetry)
 	if err != nil {
-		return fmt.Errorf("failed to open netns %q: %v", netns, err)
+		return fmt.Errorf("failed to open netns %q: %v", args.Netns, err)
 	}
 	defer netns.Close()
 
-	sm := sriov.NewSriovManager()
+	sm := sriov.NewSriovManagerForConf(netConf)
 	err = sm.FillOriginalVfInfo(netConf)
 	if err != nil {
 		return fmt.Errorf("failed to get original vf information: %v", err)
 	}
 	defer func() {
 		if err != nil {
-			err := netns.Do(func(_ ns.NetNS) error {
-				_, err := netlink.LinkByName(args.IfName)
+			// The reset scope only applies to cmdDel, a failed cmdAdd reverts everything
+			netConf.ResetScope = sriovtypes.ResetScopeAll
+			linkErr := netns.Do(func(_ ns.NetNS) error {
+				_, err := netlink.LinkByName(podIfName)
 				return err
 			})
-			if err == nil {
-				_ = sm.ReleaseVF(netConf, args.IfName, netns)
+			if linkErr == nil {
+				// Sample the traffic of the VF for diagnostics before it is released
+				sm.CaptureOnFailure(netConf, podIfName, netns, err)
+				_ = sm.ReleaseVF(netConf, podIfName, netns)
 			}
 			// Reset the VF if failure occurs before the netconf is cached
 			_ = sm.ResetVFConfig(netConf)
+			if netConf.DriverOverride != "" {
+				_ = sm.RestoreVFDriver(netConf)
+			}
 		}
 	}()
 	if err := sm.ApplyVFConfig(netConf); err != nil {
 		return fmt.Errorf("SRIOV-CNI failed to configure VF %q", err)
 	}
 
+	if netConf.DriverOverride != "" {
+		if err = sm.BindVFDriver(netConf); err != nil {
+			return fmt.Errorf("SRIOV-CNI failed to bind VF driver %q", err)
+		}
+	}
+
 	result := &current.Result{}
 	result.Interfaces = []*current.Interface{{
-		Name:    args.IfName,
+		Name:    podIfName,
 		Sandbox: netns.Path(),
+		PciID:   netConf.DeviceID,
 	}}
+	if netConf.KeepsInHostNetns() {
+		// an empty sandbox is a host interface
+		result.Interfaces[0].Sandbox = ""
+	}
 
 	if !netConf.DPDKMode {
-		err = sm.SetupVF(netConf, args.IfName, netns)
+		err = sm.SetupVF(netConf, podIfName, netns)
 
 		if err != nil {
-			return fmt.Errorf("failed to set up pod interface %q from the device %q: %v", args.IfName, netConf.Master, err)
+			return fmt.Errorf("failed to set up pod interface %q from the device %q: %v", podIfName, netConf.Master, err)
 		}
 	}
 
 	result.Interfaces[0].Mac = config.GetMacAddressForResult(netConf)
+	logAppliedVFConfig(netConf, result.Interfaces[0].Mac, getVFDrvInfo(netConf, podIfName, netns))
 	// check if we are able to find MTU for the virtual function
 	if netConf.MTU != nil {
 		result.Interfaces[0].Mtu = *netConf.MTU
 	}
 
+	// Report the VF representor as a host interface, for the components plugging it into an offloaded switch
+	if repName, err := sm.GetRepresentor(netConf); err == nil {
+		result.Interfaces = append(result.Interfaces, &current.Interface{Name: repName})
+	} else if !errors.Is(err, utils.ErrLegacyMode) {
+		logging.Warning("Failed to find the VF representor, it is not reported in the result",
+			"func", "cmdAdd",
+			"netConf.DeviceID", netConf.DeviceID,
+			"err", err)
+	}
+
 	doAnnounce := false
 
 	// run the IPAM plugin
 	if netConf.IPAM.Type != "" {
+		var ipamStdinData []byte
+		ipamStdinData, err = config.IPAMStdinData(netConf, args.StdinData)
+		if err != nil {
+			return err
+		}
+
 		var r types.Result
-		r, err = ipam.ExecAdd(netConf.IPAM.Type, args.StdinData)
+		r, err = ipam.ExecAdd(netConf.IPAM.Type, ipamStdinData)
 		if err != nil {
 			return fmt.Errorf("failed to set up IPAM plugin type %q from the device %q: %v", netConf.IPAM.Type, netConf.Master, err)
 		}
 
 		defer func() {
 			if err != nil {
-				_ = ipam.ExecDel(netConf.IPAM.Type, args.StdinData)
+				_ = ipam.ExecDel(netConf.IPAM.Type, ipamStdinData)
 			}
 		}()
 
@@ -163,10 +230,7 @@ func cmdAdd(args *skel.CmdArgs) error {
 		}
 
 		if !netConf.DPDKMode {
-			err = netns.Do(func(_ ns.NetNS) error {
-				return ipam.ConfigureIface(args.IfName, newResult)
-			})
-			if err != nil {
+			if err = sm.ConfigureIPAMResult(netConf, podIfName, netns, newResult); err != nil {
 				return err
 			}
 			doAnnounce = true
@@ -174,21 +238,22 @@ func cmdAdd(args *skel.CmdArgs) error {
 		result = newResult
 	}
 
-	// Cache NetConf for CmdDel
+	// Cache NetConf for CmdDel, and the result for a retried cmdAdd
+	netConf.AddResult = result
 	logging.Debug("Cache NetConf for CmdDel",
 		"func", "cmdAdd",
-		"config.DefaultCNIDir", config.DefaultCNIDir,
+		"cacheDir", config.CacheDir(netConf),
 		"netConf", netConf)
-	if err = utils.SaveNetConf(args.ContainerID, config.DefaultCNIDir, args.IfName, netConf); err != nil {
+	if err = config.GetCache(netConf).Save(args.ContainerID, args.IfName, netConf); err != nil {
 		return fmt.Errorf("error saving NetConf %q", err)
 	}
 
 	// Mark the pci address as in use.
 	logging.Debug("Mark the PCI address as in use",
 		"func", "cmdAdd",
-		"config.DefaultCNIDir", config.DefaultCNIDir,
+		"cacheDir", config.CacheDir(netConf),
 		"netConf.DeviceID", netConf.DeviceID)
-	allocator := utils.NewPCIAllocator(config.DefaultCNIDir)
+	allocator := utils.NewPCIAllocator(config.CacheDir(netConf))
 	if err = allocator.SaveAllocatedPCI(netConf.DeviceID, args.Netns); err != nil {
 		return fmt.Errorf("error saving the pci allocation for vf pci address %s: %v", netConf.DeviceID, err)
 	}
@@ -209,10 +274,10 @@ func cmdAdd(args *skel.CmdArgs) error {
 			 */
 
 			/* The interface might not yet have carrier. Wait for it for a short time. */
-			hasCarrier := utils.WaitForCarrier(args.IfName, 200*time.Millisecond)
+			hasCarrier := utils.WaitForCarrier(podIfName, 200*time.Millisecond)
 
 			/* The error is ignored here because enabling this feature is only a performance enhancement. */
-			err := utils.AnnounceIPs(args.IfName, result.IPs)
+			err := utils.AnnounceIPs(podIfName, result.IPs)
 
 			logging.Debug("announcing IPs", "hasCarrier", hasCarrier, "IPs", result.IPs, "announceError", err)
 			return nil
@@ -222,6 +287,201 @@ func cmdAdd(args *skel.CmdArgs) error {
 	return types.PrintResult(result, netConf.CNIVersion)
 }
 
+// setRequestedMAC sets the MAC address requested by the CNI args or the runtime config
+func setRequestedMAC(netConf *sriovtypes.NetConf, args *skel.CmdArgs) error {
+	envArgs, err := getEnvArgs(args.Args)
+	if err != nil {
+		return fmt.Errorf("SRIOV-CNI failed to parse args: %v", err)
+	}
+
+	if envArgs != nil {
+		MAC := string(envArgs.MAC)
+		if MAC != "" {
+			netConf.MAC = MAC
+		}
+	}
+
+	// RuntimeConfig takes preference than envArgs.
+	// This maintains compatibility of using envArgs
+	// for MAC config.
+	if netConf.RuntimeConfig.Mac != "" {
+		netConf.MAC = netConf.RuntimeConfig.Mac
+	}
+
+	// Always use lower case for mac address
+	netConf.MAC = strings.ToLower(netConf.MAC)
+	return nil
+}
+
+// verifyDeviceAllocation checks that the device plugin allocated the VF to the pod, so that a pod cannot use
+// a VF it was not allocated by naming its pci address in the netconf
+func verifyDeviceAllocation(netConf *sriovtypes.NetConf, args *skel.CmdArgs) error {
+	envArgs, err := getEnvArgs(args.Args)
+	if err != nil {
+		return fmt.Errorf("SRIOV-CNI failed to parse args: %v", err)
+	}
+	if envArgs == nil || envArgs.K8S_POD_UID == "" {
+		return fmt.Errorf("cannot verify the allocation of VF %s: K8S_POD_UID is missing from the CNI args", netConf.DeviceID)
+	}
+
+	podUID := string(envArgs.K8S_POD_UID)
+	allocated, err := utils.IsDeviceAllocatedToPod(utils.KubeletCheckpointFile, podUID, netConf.DeviceID)
+	if err != nil {
+		return fmt.Errorf("cannot verify the allocation of VF %s: %v", netConf.DeviceID, err)
+	}
+	if !allocated {
+		return fmt.Errorf("VF %s is not allocated to pod %s by the device plugin", netConf.DeviceID, podUID)
+	}
+	return nil
+}
+
+// retriedAddResult returns the result of the previous cmdAdd of the container interface when the VF it
+// configured is still in the pod netns as requested, so that a retried cmdAdd does not configure it twice.
+// A VF that no longer matches the request is released, to be configured again by this cmdAdd.
+func retriedAddResult(args *skel.CmdArgs) (types.Result, error) {
+	cached, cache, err := config.LoadConfFromCache(args)
+	if err != nil || cached.AddResult == nil {
+		// no previous cmdAdd of the container interface
+		return nil, nil
+	}
+
+	requested, err := config.LoadRequestedConf(args.StdinData, cached)
+	if err != nil {
+		return nil, fmt.Errorf("SRIOV-CNI failed to load netconf: %v", err)
+	}
+	if err = setRequestedMAC(requested, args); err != nil {
+		return nil, err
+	}
+
+	sandbox := args.Netns
+	if len(cached.AddResult.Interfaces) > 0 && !cached.KeepsInHostNetns() {
+		sandbox = cached.AddResult.Interfaces[0].Sandbox
+	}
+	netns, err := vfNetNS(cached, sandbox, utils.GetNSWithRetry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netns %q: %v", sandbox, err)
+	}
+	defer netns.Close()
+
+	sm := sriov.NewSriovManagerForConf(requested)
+	if requested.KeepsInHostNetns() != cached.KeepsInHostNetns() {
+		err = fmt.Errorf("vf %s keepInHostNetns changed to %t", cached.DeviceID, requested.KeepsInHostNetns())
+	} else if sandbox != args.Netns {
+		err = fmt.Errorf("vf %s is in netns %s, not %s", cached.DeviceID, sandbox, args.Netns)
+	} else {
+		err = sm.CompareVFConfig(requested, config.PodIfName(requested, args.IfName), netns)
+	}
+	if err == nil {
+		logging.Info("VF already configured by a previous cmdAdd, returning its result",
+			"func", "retriedAddResult",
+			"netConf.DeviceID", cached.DeviceID)
+		return cached.AddResult.GetAsVersion(requested.CNIVersion)
+	}
+	logging.Info("VF configured by a previous cmdAdd differs from the request, configuring it again",
+		"func", "retriedAddResult",
+		"netConf.DeviceID", cached.DeviceID,
+		"diff", err)
+
+	// Release the VF and its IP allocation like cmdDel
+	cached.ResetScope = sriovtypes.ResetScopeAll
+	cached.DPDKMode = cached.DriverOverride != "" || cached.OrigVfState.HostIFName == ""
+	if cached.IPAM.Type != "" {
+		ipamStdinData, err := config.IPAMStdinData(cached, args.StdinData)
+		if err != nil {
+			return nil, err
+		}
+		if err = ipam.ExecDel(cached.IPAM.Type, ipamStdinData); err != nil {
+			return nil, fmt.Errorf("failed to release the IP allocation of the previous cmdAdd: %v", err)
+		}
+	}
+	if err = sm.ResetVFConfig(cached); err != nil {
+		return nil, fmt.Errorf("failed to reset the VF configured by the previous cmdAdd: %v", err)
+	}
+	if cached.DriverOverride != "" {
+		if err = sm.RestoreVFDriver(cached); err != nil {
+			return nil, fmt.Errorf("failed to restore the driver of the VF configured by the previous cmdAdd: %v", err)
+		}
+	}
+	if !cached.DPDKMode {
+		if err = sm.ReleaseVF(cached, config.PodIfName(cached, args.IfName), netns); err != nil {
+			return nil, fmt.Errorf("failed to release the VF configured by the previous cmdAdd: %v", err)
+		}
+	}
+
+	allocator := utils.NewPCIAllocator(config.CacheDir(cached))
+	if err = allocator.DeleteAllocatedPCI(cached.DeviceID); err != nil {
+		return nil, fmt.Errorf("error cleaning the pci allocation for vf pci address %s: %v", cached.DeviceID, err)
+	}
+	if err = cache.Remove(args.ContainerID, args.IfName); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// getVFDrvInfo returns the driver information of the VF for inventory. Missing information is logged
+// and left empty, it never fails cmdAdd. A VF bound to a userspace driver only reports its driver name.
+func getVFDrvInfo(netConf *sriovtypes.NetConf, podifName string, netns ns.NetNS) *utils.DrvInfo {
+	if netConf.DPDKMode {
+		driver, err := utils.GetVFDriver(netConf.DeviceID)
+		if err != nil {
+			logging.Warning("Failed to get VF driver", "func", "cmdAdd", "deviceID", netConf.DeviceID, "err", err)
+		}
+		return &utils.DrvInfo{Driver: driver, BusInfo: netConf.DeviceID}
+	}
+
+	drvInfo := &utils.DrvInfo{BusInfo: netConf.DeviceID}
+	err := netns.Do(func(_ ns.NetNS) error {
+		info, err := utils.GetDrvInfo(podifName)
+		if err != nil {
+			return err
+		}
+		drvInfo = info
+		return nil
+	})
+	if err != nil {
+		logging.Warning("Failed to get VF driver information", "func", "cmdAdd", "deviceID", netConf.DeviceID, "err", err)
+	}
+	return drvInfo
+}
+
+// logAppliedVFConfig logs the VF configuration applied by cmdAdd in a single line.
+func logAppliedVFConfig(netConf *sriovtypes.NetConf, effectiveMAC string, drvInfo *utils.DrvInfo) {
+	logging.Info("Applied VF configuration",
+		"func", "cmdAdd",
+		"deviceID", netConf.DeviceID,
+		"vfID", netConf.VFID,
+		"driver", stringOrUnset(drvInfo.Driver),
+		"firmware", stringOrUnset(drvInfo.FwVersion),
+		"busInfo", stringOrUnset(drvInfo.BusInfo),
+		"vlan", valueOrUnset(netConf.Vlan),
+		"vlanQoS", valueOrUnset(netConf.VlanQoS),
+		"vlanProto", valueOrUnset(netConf.VlanProto),
+		"spoofchk", stringOrUnset(netConf.SpoofChk),
+		"trust", stringOrUnset(netConf.Trust),
+		"linkState", stringOrUnset(netConf.LinkState),
+		"minTxRate", valueOrUnset(netConf.MinTxRate),
+		"maxTxRate", valueOrUnset(netConf.MaxTxRate),
+		"txQLen", valueOrUnset(netConf.TxQLen),
+		"mac", stringOrUnset(effectiveMAC))
+}
+
+// valueOrUnset returns the value p points to, or "unset" if p is nil.
+func valueOrUnset[T any](p *T) interface{} {
+	if p == nil {
+		return unsetValue
+	}
+	return *p
+}
+
+// stringOrUnset returns s, or "unset" if s is empty.
+func stringOrUnset(s string) string {
+	if s == "" {
+		return unsetValue
+	}
+	return s
+}
+
 func cmdDel(args *skel.CmdArgs) error {
 	if err := config.SetLogging(args.StdinData, args.ContainerID, args.Netns, args.IfName); err != nil {
 		return err
@@ -230,7 +490,13 @@ func cmdDel(args *skel.CmdArgs) error {
 		"func", "cmdDel",
 		"args.Path", args.Path, "args.StdinData", string(args.StdinData), "args.Args", args.Args)
 
-	netConf, cRefPath, err := config.LoadConfFromCache(args)
+	unlockNode, err := config.LockNode(args.StdinData)
+	if err != nil {
+		return err
+	}
+	defer unlockNode()
+
+	netConf, cache, err := config.LoadConfFromCache(args)
 	if err != nil {
 		// If cmdDel() fails, cached netconf is cleaned up by
 		// the followed defer call. However, subsequence calls
@@ -246,30 +512,82 @@ func cmdDel(args *skel.CmdArgs) error {
 	}
 
 	defer func() {
-		if err == nil && cRefPath != "" {
-			_ = utils.CleanCachedNetConf(cRefPath)
+		if err == nil {
+			_ = cache.Remove(args.ContainerID, args.IfName)
 		}
 	}()
 
-	if netConf.IPAM.Type != "" {
-		err = ipam.ExecDel(netConf.IPAM.Type, args.StdinData)
+	if netConf.Bond != nil {
+		err = cmdDelBond(args, netConf, cache)
+		return err
+	}
+
+	sm := sriov.NewSriovManagerForConf(netConf)
+
+	// Signal the loss of the VF to its peers before it is torn down
+	if args.Netns != "" && netConf.SignalDownOnDel {
+		if netns, err := vfNetNS(netConf, args.Netns, ns.GetNS); err == nil {
+			sm.SignalVFDown(netConf, config.PodIfName(netConf, args.IfName), netns)
+			netns.Close()
+		}
+	}
+
+	// Keep the VF and its IP configuration in place while connections are drained
+	if args.Netns != "" {
+		sm.DrainVF(netConf)
+	}
+
+	if netConf.IPAM.Type != "" && netConf.ResetsL3() {
+		var ipamStdinData []byte
+		ipamStdinData, err = config.IPAMStdinData(netConf, args.StdinData)
+		if err != nil {
+			return err
+		}
+		err = ipam.ExecDel(netConf.IPAM.Type, ipamStdinData)
 		if err != nil {
 			return err
 		}
 	}
 
 	// https://github.com/kubernetes/kubernetes/pull/35240
-	if args.Netns == "" {
+	// A VF kept in the host netns is released whether or not the pod netns is gone
+	if args.Netns == "" && !netConf.KeepsInHostNetns() {
 		return nil
 	}
 
+	// A PF whose driver was unloaded no longer has the VF, there is nothing left to clean up at the hardware level
+	if vfErr := sm.CheckVFDevice(netConf); errors.Is(vfErr, sriov.ErrVFNotFound) {
+		logging.Warning("The VF no longer exists, its PF driver may be unloaded, skipping the VF reset",
+			"func", "cmdDel",
+			"deviceID", netConf.DeviceID,
+			"err", vfErr)
+		// a failed release keeps the cached NetConf for the retried cmdDel
+		err = releaseVFAllocation(netConf)
+		return err
+	}
+
+	// A PF whose VFs were recreated with fewer VFs no longer has the VF, its index may now be the one of
+	// another VF so the VF is left untouched
+	if numVFsErr := sm.CheckPFNumVFs(netConf); numVFsErr != nil {
+		if errors.Is(numVFsErr, sriov.ErrVFNotFound) {
+			logging.Warning("The VF no longer exists on its PF, skipping the VF reset",
+				"func", "cmdDel",
+				"deviceID", netConf.DeviceID,
+				"err", numVFsErr)
+			err = releaseVFAllocation(netConf)
+			return err
+		}
+		logging.Warning("Failed to check the number of VFs of the PF",
+			"func", "cmdDel",
+			"pf", netConf.Master,
+			"err", numVFsErr)
+	}
+
 	// Verify VF ID existence.
 	if _, err := utils.GetVfid(netConf.DeviceID, netConf.Master); err != nil {
 		return fmt.Errorf("cmdDel() error obtaining VF ID: %q", err)
 	}
 
-	sm := sriov.NewSriovManager()
-
 	/* ResetVFConfig resets a VF administratively. We must run ResetVFConfig
 	   before ReleaseVF because some drivers will error out if we try to
 	   reset netdev VF with trust off. So, reset VF MAC address via PF first.
@@ -278,8 +596,26 @@ func cmdDel(args *skel.CmdArgs) error {
 		return fmt.Errorf("cmdDel() error reseting VF: %q", err)
 	}
 
+	// Cut the VF off the offloaded datapath until an operator reclaims its representor
+	if netConf.QuarantineHostRepOnDel {
+		var repName string
+		repName, err = sm.QuarantineRepresentor(netConf)
+		if err != nil {
+			return fmt.Errorf("cmdDel() error quarantining VF representor: %q", err)
+		}
+		if err = utils.NewRepresentorQuarantine(config.CacheDir(netConf)).Record(repName, netConf.DeviceID); err != nil {
+			return err
+		}
+	}
+
+	if netConf.DriverOverride != "" {
+		if err = sm.RestoreVFDriver(netConf); err != nil {
+			return fmt.Errorf("cmdDel() error restoring VF driver: %q", err)
+		}
+	}
+
 	if !netConf.DPDKMode {
-		netns, err := ns.GetNS(args.Netns)
+		netns, err := vfNetNS(netConf, args.Netns, ns.GetNS)
 		if err != nil {
 			// according to:
 			// https://github.com/kubernetes/kubernetes/issues/43014#issuecomment-287164444
@@ -295,32 +631,120 @@ func cmdDel(args *skel.CmdArgs) error {
 		}
 		defer netns.Close()
 
-		if err = sm.ReleaseVF(netConf, args.IfName, netns); err != nil {
+		if err = sm.ReleaseVF(netConf, config.PodIfName(netConf, args.IfName), netns); err != nil {
+			return err
+		}
+	}
+
+	err = releaseVFAllocation(netConf)
+	return err
+}
+
+// vfNetNS opens the netns the VF of netConf is configured in: the netns at nspath opened by open, or else the
+// host netns when the VF is kept there
+func vfNetNS(netConf *sriovtypes.NetConf, nspath string, open func(string) (ns.NetNS, error)) (ns.NetNS, error) {
+	if netConf.KeepsInHostNetns() {
+		return ns.GetCurrentNS()
+	}
+	return open(nspath)
+}
+
+// releaseVFAllocation marks the pci address and the MAC address of the VF as released
+func releaseVFAllocation(netConf *sriovtypes.NetConf) error {
+	if netConf.MACRegistry && netConf.MAC != "" {
+		if err := utils.NewMACRegistry(config.CacheDir(netConf)).Release(netConf.MAC, netConf.DeviceID); err != nil {
 			return err
 		}
 	}
 
-	// Mark the pci address as released
 	logging.Debug("Mark the PCI address as released",
 		"func", "cmdDel",
-		"config.DefaultCNIDir", config.DefaultCNIDir,
+		"cacheDir", config.CacheDir(netConf),
 		"netConf.DeviceID", netConf.DeviceID)
-	allocator := utils.NewPCIAllocator(config.DefaultCNIDir)
-	if err = allocator.DeleteAllocatedPCI(netConf.DeviceID); err != nil {
+	allocator := utils.NewPCIAllocator(config.CacheDir(netConf))
+	if err := allocator.DeleteAllocatedPCI(netConf.DeviceID); err != nil {
 		return fmt.Errorf("error cleaning the pci allocation for vf pci address %s: %v", netConf.DeviceID, err)
 	}
-
 	return nil
 }
 
-func cmdCheck(_ *skel.CmdArgs) error {
+func cmdCheck(args *skel.CmdArgs) error {
+	if err := config.SetLogging(args.StdinData, args.ContainerID, args.Netns, args.IfName); err != nil {
+		return err
+	}
+	logging.Debug("function called",
+		"func", "cmdCheck",
+		"args.Path", args.Path, "args.StdinData", string(args.StdinData), "args.Args", args.Args)
+
+	unlockNode, err := config.LockNode(args.StdinData)
+	if err != nil {
+		return err
+	}
+	defer unlockNode()
+
+	netConf, _, err := config.LoadConfFromCache(args)
+	if err != nil {
+		return fmt.Errorf("cmdCheck() failed to load cached netconf: %v", err)
+	}
+
+	if netConf.Bond != nil {
+		return checkBond(args, netConf)
+	}
+
+	sm := sriov.NewSriovManagerForConf(netConf)
+	if err = sm.CheckVFConfig(netConf); err != nil {
+		logVFConfigDiff("cmdCheck", netConf, err)
+		return fmt.Errorf("cmdCheck() VF configuration check failed: %v", err)
+	}
+
+	// the DPDK mode is not cached, a VF without netdev on cmdAdd is bound to a userspace driver
+	netConf.DPDKMode = netConf.DriverOverride != "" || netConf.OrigVfState.HostIFName == ""
+	if err = sm.CheckVFDriver(netConf); err != nil {
+		return fmt.Errorf("cmdCheck() VF driver check failed: %v", err)
+	}
+
 	return nil
 }
 
+// logVFConfigDiff logs the attributes of the VF that drifted from its configuration, one structured field each
+func logVFConfigDiff(funcName string, netConf *sriovtypes.NetConf, err error) {
+	var diffErr *sriov.VFConfigDiffError
+	if !errors.As(err, &diffErr) {
+		return
+	}
+	args := []interface{}{
+		"func", funcName,
+		"deviceID", netConf.DeviceID,
+		"master", netConf.Master,
+		"vfID", netConf.VFID,
+	}
+	logging.Error("VF drifted from its configuration", append(args, diffErr.LogArgs()...)...)
+}
+
 func main() {
+	// Maintenance commands are run by hand with arguments, the container runtime passes none
+	if len(os.Args) > 1 {
+		if err := runMaintenance(os.Args[1:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// VALIDATE is not a command of the CNI spec, skel rejects it
+	if os.Getenv("CNI_COMMAND") == config.ValidateCommand {
+		if e := config.RunValidate(os.Stdin); e != nil {
+			if err := e.Print(); err != nil {
+				fmt.Fprintln(os.Stderr, "Error writing error JSON to stdout:", err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+
 	cniFuncs := skel.CNIFuncs{
-		Add:   cmdAdd,
-		Del:   cmdDel,
+		Add:   withMetrics("ADD", cmdAdd),
+		Del:   withMetrics("DEL", cmdDel),
 		Check: cmdCheck,
 	}
 	skel.PluginMainFuncs(cniFuncs, version.All, "")
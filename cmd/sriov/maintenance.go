@@ -0,0 +1,295 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/config"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/sriov"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
+)
+
+// runMaintenance handles the maintenance commands used for support cases, the plugin is run with arguments
+// by an operator instead of the container runtime.
+func runMaintenance(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("sriov", flag.ContinueOnError)
+	fs.SetOutput(out)
+	exportNAD := fs.String("export-nad", "", "print a NetworkAttachmentDefinition reproducing the cached configuration of the given container ID")
+	redact := fs.Bool("redact", false, "omit MAC addresses, GUIDs and RSS hash keys from the exported configuration")
+	checkAll := fs.Bool("check-all", false, "compare every cached VF configuration with the live VF state and log the drifted VFs, without changing them")
+	reconcile := fs.Bool("reconcile", false, "re-apply the attributes of every cached VF configuration that drifted from it, and log each correction")
+	dryRun := fs.Bool("dry-run", false, "with -reconcile, only log the drifted attributes without re-applying them")
+	reclaim := fs.String("reclaim-representor", "", "set up again a VF representor quarantined on DEL, \"all\" reclaims every quarantined representor")
+	locate := fs.String("locate", "", "blink the port identification LED of the PF of the given PCI address, of the PF or of one of its VFs")
+	seconds := fs.Int("seconds", 10, "with -locate, how long the port identification LED blinks")
+	selfTest := fs.String("self-test", "", "run the offline self-test of the VF of the given PCI address with its loopback feature enabled, the VF must not be allocated to a pod")
+	querySocket := fs.String("query-socket", "", "serve the cached configurations as JSON over HTTP on the unix socket at the given path, until the process is stopped")
+	schema := fs.Bool("schema", false, "print the JSON schema of the network configuration")
+	cacheDir := fs.String("cache-dir", config.DefaultCNIDir, "directory of the cached configurations")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	config.DefaultCNIDir = *cacheDir
+
+	if *schema {
+		data, err := config.NetConfSchema()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(data))
+		return nil
+	}
+
+	if *querySocket != "" {
+		return serveQuerySocket(*querySocket)
+	}
+
+	if *checkAll {
+		return checkAllVFs()
+	}
+
+	if *reconcile {
+		return reconcileVFs(*dryRun)
+	}
+
+	if *reclaim != "" {
+		return reclaimRepresentors(*reclaim)
+	}
+
+	if *locate != "" {
+		return locatePort(*locate, *seconds)
+	}
+
+	if *selfTest != "" {
+		return selfTestVF(*selfTest, out)
+	}
+
+	if *exportNAD == "" {
+		fs.Usage()
+		return fmt.Errorf("no maintenance command given")
+	}
+
+	netConfs, err := config.LoadConfsFromCache(*exportNAD)
+	if err != nil {
+		return err
+	}
+	for _, netConf := range netConfs {
+		nad, err := config.ExportNAD(netConf, *redact)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(nad))
+	}
+
+	return nil
+}
+
+// serveQuerySocket serves the cached configurations on the unix socket at path for live introspection
+func serveQuerySocket(path string) error {
+	logging.Init("info", "", "", "", "")
+
+	l, err := config.ListenQuerySocket(path)
+	if err != nil {
+		return err
+	}
+	logging.Info("Serving the cached configurations",
+		"func", "serveQuerySocket",
+		"socket", path,
+		"cacheDir", config.DefaultCNIDir)
+	return config.ServeQuery(l)
+}
+
+// checkAllVFs runs the cmdCheck comparison on every cached VF configuration and reports the VFs that drifted
+// out of their configuration. Nothing is re-applied.
+func checkAllVFs() error {
+	logging.Init("info", "", "", "", "")
+
+	netConfs, err := config.LoadAllConfsFromCache()
+	if err != nil {
+		return err
+	}
+	cRefs := make([]string, 0, len(netConfs))
+	for cRef, netConf := range netConfs {
+		// the member VFs of a bond are cached on their own
+		if netConf.Bond != nil {
+			continue
+		}
+		cRefs = append(cRefs, cRef)
+	}
+	sort.Strings(cRefs)
+
+	sm := sriov.NewSriovManager()
+	drifted := 0
+	for _, cRef := range cRefs {
+		netConf := netConfs[cRef]
+		// only report, a drifted link state is not re-applied
+		netConf.FixLinkStateOnCheck = false
+		if err := sm.CheckVFConfig(netConf); err != nil {
+			drifted++
+			logging.Warning("VF drifted from its cached configuration",
+				"func", "checkAllVFs",
+				"cache", cRef,
+				"deviceID", netConf.DeviceID,
+				"master", netConf.Master,
+				"vfID", netConf.VFID,
+				"err", err)
+			continue
+		}
+		logging.Info("VF matches its cached configuration",
+			"func", "checkAllVFs",
+			"cache", cRef,
+			"deviceID", netConf.DeviceID)
+	}
+
+	if drifted > 0 {
+		return fmt.Errorf("%d of %d cached VFs drifted from their configuration", drifted, len(cRefs))
+	}
+	return nil
+}
+
+// reconcileVFs re-applies the attributes of every cached VF configuration that drifted from it. With dryRun the
+// drifted attributes are only reported.
+func reconcileVFs(dryRun bool) error {
+	logging.Init("info", "", "", "", "")
+
+	netConfs, err := config.LoadAllConfsFromCache()
+	if err != nil {
+		return err
+	}
+	cRefs := make([]string, 0, len(netConfs))
+	for cRef, netConf := range netConfs {
+		// the member VFs of a bond are cached on their own
+		if netConf.Bond != nil {
+			continue
+		}
+		cRefs = append(cRefs, cRef)
+	}
+	sort.Strings(cRefs)
+
+	sm := sriov.NewSriovManager()
+	failed := 0
+	for _, cRef := range cRefs {
+		netConf := netConfs[cRef]
+		drifts, err := sm.ReconcileVFConfig(netConf, dryRun)
+		for _, drift := range drifts {
+			msg := "Re-applied drifted VF attribute"
+			if dryRun {
+				msg = "VF attribute drifted from its cached configuration"
+			}
+			logging.Info(msg,
+				"func", "reconcileVFs",
+				"cache", cRef,
+				"deviceID", netConf.DeviceID,
+				"drift", drift)
+		}
+		if err != nil {
+			failed++
+			logging.Error("Failed to reconcile VF with its cached configuration",
+				"func", "reconcileVFs",
+				"cache", cRef,
+				"deviceID", netConf.DeviceID,
+				"err", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to reconcile %d of %d cached VFs", failed, len(cRefs))
+	}
+	return nil
+}
+
+// reclaimRepresentors sets up again the representors that cmdDel quarantined, and removes them from the quarantine
+func reclaimRepresentors(repName string) error {
+	logging.Init("info", "", "", "", "")
+
+	quarantine := utils.NewRepresentorQuarantine(config.DefaultCNIDir)
+	reps, err := quarantine.List()
+	if err != nil {
+		return err
+	}
+	if repName != "all" {
+		pciAddress, ok := reps[repName]
+		if !ok {
+			return fmt.Errorf("representor %s is not quarantined", repName)
+		}
+		reps = map[string]string{repName: pciAddress}
+	}
+
+	sm := sriov.NewSriovManager()
+	for rep, pciAddress := range reps {
+		if err := sm.ReclaimRepresentor(rep); err != nil {
+			return err
+		}
+		if err := quarantine.Remove(rep); err != nil {
+			return err
+		}
+		logging.Info("Reclaimed the quarantined VF representor",
+			"func", "reclaimRepresentors",
+			"representor", rep,
+			"deviceID", pciAddress)
+	}
+	return nil
+}
+
+// locatePort blinks the port identification LED of the PF of pciAddr so that a datacenter technician can
+// locate the NIC
+func locatePort(pciAddr string, seconds int) error {
+	logging.Init("info", "", "", "", "")
+
+	if seconds <= 0 {
+		return fmt.Errorf("invalid -seconds %d, must be positive", seconds)
+	}
+
+	logging.Info("Blinking the PF port identification LED",
+		"func", "locatePort",
+		"deviceID", pciAddr,
+		"seconds", seconds)
+	pfName, err := sriov.NewSriovManager().LocatePort(pciAddr, seconds)
+	if err != nil {
+		return err
+	}
+	logging.Info("Blinked the PF port identification LED",
+		"func", "locatePort",
+		"deviceID", pciAddr,
+		"pf", pfName)
+	return nil
+}
+
+// selfTestVF runs the offline self-test of a VF in loopback mode for NIC bring-up validation, and prints the result
+// of each test
+func selfTestVF(pciAddr string, out io.Writer) error {
+	logging.Init("info", "", "", "", "")
+
+	// the offline self-test interrupts the VF traffic
+	allocated, err := utils.NewPCIAllocator(config.DefaultCNIDir).IsAllocated(pciAddr)
+	if err != nil {
+		return err
+	}
+	if allocated {
+		return fmt.Errorf("VF %s is allocated to a pod, release it before running the self-test", pciAddr)
+	}
+
+	result, err := sriov.NewSriovManager().SelfTestVF(pciAddr)
+	if result != nil {
+		names := make([]string, 0, len(result.Results))
+		for name := range result.Results {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(out, "%s: %d\n", name, result.Results[name])
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !result.Passed {
+		fmt.Fprintf(out, "VF %s self-test: FAIL\n", pciAddr)
+		return fmt.Errorf("VF %s failed its self-test", pciAddr)
+	}
+	fmt.Fprintf(out, "VF %s self-test: PASS\n", pciAddr)
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/skel"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
+)
+
+// withMetrics wraps a CNI command to record its duration and outcome in the metrics file of the netconf
+func withMetrics(command string, cmd func(*skel.CmdArgs) error) func(*skel.CmdArgs) error {
+	return func(args *skel.CmdArgs) error {
+		start := time.Now()
+		err := cmd(args)
+		recordMetric(command, args, time.Since(start), err)
+		return err
+	}
+}
+
+// recordMetric writes the metric of a CNI command, failing to do so does not fail the command
+func recordMetric(command string, args *skel.CmdArgs, duration time.Duration, cmdErr error) {
+	n := &sriovtypes.NetConf{}
+	if err := json.Unmarshal(args.StdinData, n); err != nil || n.MetricsFile == "" {
+		return
+	}
+
+	m := &utils.OperationMetric{
+		Command:  command,
+		DeviceID: n.DeviceID,
+		Outcome:  "success",
+		Duration: duration,
+	}
+	if cmdErr != nil {
+		m.Outcome = "failure"
+	}
+	if pfName, err := utils.GetPfName(n.DeviceID); err == nil {
+		if vfID, err := utils.GetVfid(n.DeviceID, pfName); err == nil {
+			m.VFID = strconv.Itoa(vfID)
+		}
+	}
+
+	if err := utils.WriteOperationMetric(n.MetricsFile, m); err != nil {
+		logging.Warning("Failed to write metrics file",
+			"func", "recordMetric",
+			"metricsFile", n.MetricsFile,
+			"err", err)
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+)
+
+// IsBondConf returns true if the stdin netconf bonds several VFs in the pod netns instead of configuring deviceID
+func IsBondConf(bytes []byte) bool {
+	n := struct {
+		Bond *sriovtypes.Bond `json:"bond"`
+	}{}
+	return json.Unmarshal(bytes, &n) == nil && n.Bond != nil
+}
+
+// LoadBondConf parses and validates the stdin netconf of a bond, the member VFs are loaded by LoadBondMemberConfs
+func LoadBondConf(bytes []byte) (*sriovtypes.NetConf, error) {
+	n := &sriovtypes.NetConf{}
+	if err := json.Unmarshal(bytes, n); err != nil {
+		return nil, fmt.Errorf("LoadBondConf(): failed to load netconf: %w", err)
+	}
+	applyRuntimeConfig(n)
+
+	if err := errors.Join(validateFields(n)...); err != nil {
+		return nil, fmt.Errorf("LoadBondConf(): %w", err)
+	}
+	if n.Bond == nil {
+		return nil, fmt.Errorf("LoadBondConf(): netconf has no bond")
+	}
+	// every member would be given the same MAC address
+	if n.MAC != "" || n.RuntimeConfig.Mac != "" {
+		return nil, fmt.Errorf("LoadBondConf(): bond cannot be configured together with mac")
+	}
+
+	setDefaults(n)
+
+	return n, nil
+}
+
+// LoadBondMemberConfs returns the NetConf of each member VF of the stdin netconf of a bond, loaded by LoadConf from
+// the netconf with the member as deviceID, so that every member is configured with the netconf fields
+func LoadBondMemberConfs(bytes []byte) ([]*sriovtypes.NetConf, error) {
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(bytes, &raw); err != nil {
+		return nil, fmt.Errorf("LoadBondMemberConfs(): failed to load netconf: %w", err)
+	}
+	bond := struct {
+		Bond *sriovtypes.Bond `json:"bond"`
+	}{}
+	if err := json.Unmarshal(bytes, &bond); err != nil {
+		return nil, fmt.Errorf("LoadBondMemberConfs(): failed to load netconf: %w", err)
+	}
+	if bond.Bond == nil {
+		return nil, fmt.Errorf("LoadBondMemberConfs(): netconf has no bond")
+	}
+
+	delete(raw, "bond")
+	confs := make([]*sriovtypes.NetConf, 0, len(bond.Bond.Members))
+	for _, member := range bond.Bond.Members {
+		raw["deviceID"] = member
+		memberBytes, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("LoadBondMemberConfs(): failed to build the netconf of member %s: %w", member, err)
+		}
+		conf, err := LoadConf(memberBytes)
+		if err != nil {
+			return nil, fmt.Errorf("LoadBondMemberConfs(): failed to load member %s: %w", member, err)
+		}
+		if conf.DPDKMode {
+			return nil, fmt.Errorf("LoadBondMemberConfs(): member %s is not bound to a kernel driver", member)
+		}
+		confs = append(confs, conf)
+	}
+	return confs, nil
+}
+
+// BondIfName returns the name of the bond in the pod netns: the ifName of the bond, or else ifName, the interface
+// name of the CNI args
+func BondIfName(n *sriovtypes.NetConf, ifName string) string {
+	if n.Bond == nil || n.Bond.IfName == "" {
+		return ifName
+	}
+	return n.Bond.IfName
+}
+
+// BondMemberIfName returns the name in the pod netns of the i-th member VF of the bond bondIfName
+func BondMemberIfName(bondIfName string, i int) (string, error) {
+	ifName := fmt.Sprintf("%s_%d", bondIfName, i)
+	if len(ifName) > maxIfNameLen {
+		return "", fmt.Errorf("bond member interface name %s is longer than %d characters", ifName, maxIfNameLen)
+	}
+	return ifName, nil
+}
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
+)
+
+// stateDir is the subdirectory of the cache directory holding the binary state files of the NetConf
+const stateDir = "state"
+
+// cacheSchemaVersion is the version of the format of the cached NetConf. A change of the format that the NetConf
+// fields do not absorb bumps it, with a migration of the previous version in cacheMigrations.
+var cacheSchemaVersion = 1
+
+// cacheMigrations are the migrations of a cached NetConf, decoded as a JSON object, by the version they upgrade
+// to the next one
+var cacheMigrations = map[int]func(netConf map[string]interface{}) error{}
+
+func init() {
+	// the values of a prevResult decoded from JSON
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// Cache stores the NetConf of the configured container interfaces from cmdAdd to cmdDel
+type Cache interface {
+	Save(containerID, ifName string, netConf *sriovtypes.NetConf) error
+	Load(containerID, ifName string) (*sriovtypes.NetConf, error)
+	Remove(containerID, ifName string) error
+}
+
+// NewCache returns the Cache of the NetConf cached in dir, tests can replace it with another implementation
+var NewCache = func(dir string) Cache {
+	return &fileCache{dir: dir}
+}
+
+// fileCache is the Cache keeping each NetConf as a JSON file named <containerID>-<ifName> in a directory. The NetConf
+// with binaryCache are also kept as a gob encoded state file, which Load prefers when it exists.
+type fileCache struct {
+	dir string
+}
+
+func (c *fileCache) cRefPath(containerID, ifName string) string {
+	return filepath.Join(c.dir, strings.Join([]string{containerID, ifName}, "-"))
+}
+
+func (c *fileCache) statePath(containerID, ifName string) string {
+	return filepath.Join(c.dir, stateDir, strings.Join([]string{containerID, ifName}, "-"))
+}
+
+// Save implements Cache
+func (c *fileCache) Save(containerID, ifName string, netConf *sriovtypes.NetConf) error {
+	netConf.SchemaVersion = cacheSchemaVersion
+	if err := utils.SaveNetConf(containerID, c.dir, ifName, netConf); err != nil {
+		return err
+	}
+	if !netConf.BinaryCache {
+		return nil
+	}
+
+	// the JSON file is loaded if the state file cannot be written
+	if err := saveState(c.statePath(containerID, ifName), netConf); err != nil {
+		logging.Warning("Failed to write the binary state file of the NetConf",
+			"func", "Save",
+			"containerID", containerID,
+			"ifName", ifName,
+			"err", err)
+		_ = os.Remove(c.statePath(containerID, ifName))
+	}
+	return nil
+}
+
+// Load implements Cache
+func (c *fileCache) Load(containerID, ifName string) (*sriovtypes.NetConf, error) {
+	// a state file of another cache format is not migrated, the JSON file is
+	if netConf, err := loadState(c.statePath(containerID, ifName)); err == nil && netConf.SchemaVersion == cacheSchemaVersion {
+		return netConf, nil
+	} else if err != nil && !os.IsNotExist(err) {
+		logging.Warning("Failed to read the binary state file of the NetConf, reading the JSON file",
+			"func", "Load",
+			"containerID", containerID,
+			"ifName", ifName,
+			"err", err)
+	}
+
+	netConfBytes, err := utils.ReadScratchNetConf(c.cRefPath(containerID, ifName))
+	if err != nil {
+		return nil, fmt.Errorf("error reading cached NetConf in %s with name %s-%s", c.dir, containerID, ifName)
+	}
+
+	return parseCachedNetConf(netConfBytes)
+}
+
+// Remove implements Cache
+func (c *fileCache) Remove(containerID, ifName string) error {
+	if err := os.Remove(c.statePath(containerID, ifName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing the binary state file of the NetConf: %v", err)
+	}
+	return utils.CleanCachedNetConf(c.cRefPath(containerID, ifName))
+}
+
+// parseCachedNetConf parses a NetConf cached as JSON, migrated from the cache format it was written with. A NetConf
+// missing its version was cached before the format was versioned, as version 1.
+func parseCachedNetConf(data []byte) (*sriovtypes.NetConf, error) {
+	// numbers are kept as written, e.g. the 64-bit GUIDs
+	raw := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse NetConf: %q", err)
+	}
+
+	version := 1
+	if v, ok := raw["SchemaVersion"].(json.Number); ok {
+		if n, err := v.Int64(); err == nil && n >= 1 {
+			version = int(n)
+		}
+	}
+	if version > cacheSchemaVersion {
+		// cached by a newer plugin before a downgrade, the fields this version knows are still loaded
+		logging.Warning("Cached NetConf has a newer cache format, loading it as is",
+			"func", "parseCachedNetConf",
+			"schemaVersion", version,
+			"supportedSchemaVersion", cacheSchemaVersion)
+	}
+	for ; version < cacheSchemaVersion; version++ {
+		migrate, ok := cacheMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration of the cached NetConf from version %d", version)
+		}
+		if err := migrate(raw); err != nil {
+			return nil, fmt.Errorf("failed to migrate the cached NetConf from version %d: %v", version, err)
+		}
+	}
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate the cached NetConf: %v", err)
+	}
+	netConf := &sriovtypes.NetConf{}
+	if err = json.Unmarshal(migrated, netConf); err != nil {
+		return nil, fmt.Errorf("failed to parse NetConf: %q", err)
+	}
+	if netConf.SchemaVersion < cacheSchemaVersion {
+		netConf.SchemaVersion = cacheSchemaVersion
+	}
+	return netConf, nil
+}
+
+// gobState is the content of a binary state file. Gob does not encode zero values, even through pointers, so the
+// paths of the pointers to a zero value are kept to decode them as in the JSON file, e.g. a vlan set to 0.
+type gobState struct {
+	NetConf      *sriovtypes.NetConf
+	ZeroPointers [][]int
+}
+
+// saveState writes the gob encoding of the NetConf to path. The fields that are not cached in the JSON file are
+// left out, so that both files load the same NetConf.
+func saveState(path string, netConf *sriovtypes.NetConf) error {
+	state := gobState{NetConf: new(sriovtypes.NetConf)}
+	*state.NetConf = *netConf
+	state.NetConf.DPDKMode = false
+	state.NetConf.PrevResult = nil
+	findZeroPointers(reflect.ValueOf(state.NetConf).Elem(), nil, &state.ZeroPointers)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&state); err != nil {
+		return fmt.Errorf("error encoding NetConf: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create the sriov state directory(%q): %v", filepath.Dir(path), err)
+	}
+	return os.WriteFile(path, buf.Bytes(), 0600)
+}
+
+// loadState reads a NetConf written by saveState
+func loadState(path string) (*sriovtypes.NetConf, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	state := gobState{}
+	if err = gob.NewDecoder(f).Decode(&state); err != nil {
+		return nil, fmt.Errorf("error decoding NetConf %s: %v", path, err)
+	}
+	if state.NetConf == nil {
+		state.NetConf = &sriovtypes.NetConf{}
+	}
+	for _, zeroPointer := range state.ZeroPointers {
+		setZeroPointer(reflect.ValueOf(state.NetConf).Elem(), zeroPointer)
+	}
+	return state.NetConf, nil
+}
+
+// findZeroPointers appends the path of the non-nil pointers to a zero value found in v to paths. A path holds the
+// indexes of the struct fields and slice elements leading to the pointer.
+func findZeroPointers(v reflect.Value, path []int, paths *[][]int) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return
+		}
+		if v.Elem().IsZero() {
+			*paths = append(*paths, append([]int{}, path...))
+			return
+		}
+		findZeroPointers(v.Elem(), path, paths)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				findZeroPointers(v.Field(i), append(path, i), paths)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			findZeroPointers(v.Index(i), append(path, i), paths)
+		}
+	default:
+	}
+}
+
+// setZeroPointer sets the pointer found by findZeroPointers at path in v to a new zero value
+func setZeroPointer(v reflect.Value, path []int) {
+	for {
+		switch v.Kind() {
+		case reflect.Ptr:
+			if len(path) == 0 {
+				if v.CanSet() {
+					v.Set(reflect.New(v.Type().Elem()))
+				}
+				return
+			}
+			if v.IsNil() {
+				return
+			}
+			v = v.Elem()
+		case reflect.Struct:
+			if len(path) == 0 || path[0] >= v.NumField() {
+				return
+			}
+			v, path = v.Field(path[0]), path[1:]
+		case reflect.Slice, reflect.Array:
+			if len(path) == 0 || path[0] >= v.Len() {
+				return
+			}
+			v, path = v.Index(path[0]), path[1:]
+		default:
+			return
+		}
+	}
+}
+
+// CacheDir returns the directory of the NetConf cache and of the PCI allocations of a netconf
+func CacheDir(n *sriovtypes.NetConf) string {
+	if n.CacheDir != "" {
+		return n.CacheDir
+	}
+	return DefaultCNIDir
+}
+
+// GetCache returns the Cache of a netconf
+func GetCache(n *sriovtypes.NetConf) Cache {
+	return NewCache(CacheDir(n))
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+)
+
+// cachedNetConfJSON is a NetConf as cached by cmdAdd
+const cachedNetConfJSON = `{
+    "cniVersion": "1.0.0",
+    "name": "mynet",
+    "type": "sriov",
+    "ipam": {"type": "host-local"},
+    "deviceID": "0000:af:06.1",
+    "vlan": 0,
+    "vlanQoS": 0,
+    "vlanProto": "802.1q",
+    "spoofchk": "on",
+    "binaryCache": true,
+    "Master": "enp175s0f1",
+    "VFID": 1,
+    "MAC": "02:00:00:00:00:01",
+    "OrigVfState": {"HostIFName": "enp175s7", "SpoofChk": true, "EffectiveMAC": "ca:fe:00:00:00:01"},
+    "AddResult": {
+        "cniVersion": "1.0.0",
+        "interfaces": [{"name": "net1", "mac": "02:00:00:00:00:01", "sandbox": "/var/run/netns/pod"}],
+        "ips": [{"interface": 0, "address": "10.55.206.2/26", "gateway": "10.55.206.1"}],
+        "routes": [{"dst": "0.0.0.0/0"}]
+    }
+}`
+
+func BenchmarkCacheLoad(b *testing.B) {
+	netconf := &types.NetConf{}
+	if err := json.Unmarshal([]byte(cachedNetConfJSON), netconf); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, binaryCache := range []bool{false, true} {
+		name := "json"
+		if binaryCache {
+			name = "binary"
+		}
+		b.Run(name, func(b *testing.B) {
+			tmpdir, err := os.MkdirTemp("/tmp", "sriovplugin-cache-bench-")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer os.RemoveAll(tmpdir)
+
+			netconf.BinaryCache = binaryCache
+			cache := NewCache(tmpdir)
+			if err = cache.Save("container1", "net1", netconf); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err = cache.Load("container1", "net1"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -2,22 +2,44 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/containernetworking/cni/pkg/skel"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
 	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
+	"golang.org/x/sys/unix"
 )
 
+// maxIngressPoliceRate is the highest ingress policing rate in Mbps
+const maxIngressPoliceRate = math.MaxUint32 / (1000 * 1000 / 8)
+
+// interfaceSysctlRe matches the names of the interface sysctls that can be set on the VF netdev
+var interfaceSysctlRe = regexp.MustCompile(`^net\.(ipv4\.conf|ipv6\.conf|ipv4\.neigh)\.` + utils.SysctlIfName + `\.[a-z0-9_]+$`)
+
+// maxIfNameLen is the longest network interface name, IFNAMSIZ without the terminating NUL
+const maxIfNameLen = 15
+
+// LogLevelEnv is the environment variable overriding the netconf log level
+const LogLevelEnv = "SRIOV_CNI_LOG_LEVEL"
+
 var (
 	// DefaultCNIDir used for caching NetConf
 	DefaultCNIDir = "/var/lib/cni/sriov"
 )
 
-// SetLogging sets global logging parameters.
+// SetLogging sets global logging parameters and logs the environment of the invocation at debug level.
 func SetLogging(stdinData []byte, containerID, netns, ifName string) error {
 	n := &sriovtypes.NetConf{}
 	if err := json.Unmarshal(stdinData, n); err != nil {
@@ -25,27 +47,74 @@ func SetLogging(stdinData []byte, containerID, netns, ifName string) error {
 	}
 
 	logging.Init(n.LogLevel, n.LogFile, containerID, netns, ifName)
+	logging.SetLogFormat(n.LogFormat)
+	logging.SetLogCaller(n.LogCaller)
+	if n.LogToStderr != nil {
+		logging.SetLogStderr(*n.LogToStderr)
+	}
+	logging.SetLogOptions(n.LogOptions)
+	logging.SetLevelFiles(n.LevelFiles)
+
+	// The environment overrides the netconf log level, to raise the verbosity without editing the netconf
+	if l := os.Getenv(LogLevelEnv); l != "" {
+		if !logging.SetLogLevel(l) {
+			logging.Warning("Invalid log level in the environment, ignoring it",
+				"func", "SetLogging",
+				"env", LogLevelEnv,
+				"level", l)
+		}
+	}
+
+	utils.LogEnvironment(n.DeviceID)
 	return nil
 }
 
+// LockNode acquires the node-wide lock serializing the VF operations of the plugin invocations when the netconf
+// sets globalSerialize, it blocks until the lock is released by the other invocations. The returned function
+// releases the lock.
+func LockNode(stdinData []byte) (func(), error) {
+	n := &sriovtypes.NetConf{}
+	if err := json.Unmarshal(stdinData, n); err != nil {
+		return nil, fmt.Errorf("LockNode(): failed to load netconf: %v", err)
+	}
+	if !n.GlobalSerialize {
+		return func() {}, nil
+	}
+
+	lock := utils.NewNodeLock(CacheDir(n))
+	if err := lock.Lock(); err != nil {
+		return nil, err
+	}
+	logging.Debug("Acquired the node lock",
+		"func", "LockNode",
+		"cacheDir", CacheDir(n))
+	return func() { _ = lock.Unlock() }, nil
+}
+
 // LoadConf parses and validates stdin netconf and returns NetConf object
 func LoadConf(bytes []byte) (*sriovtypes.NetConf, error) {
 	n := &sriovtypes.NetConf{}
 	if err := json.Unmarshal(bytes, n); err != nil {
 		return nil, fmt.Errorf("LoadConf(): failed to load netconf: %v", err)
 	}
+	applyRuntimeConfig(n)
 
-	// DeviceID takes precedence; if we are given a VF pciaddr then work from there
-	if n.DeviceID != "" {
-		// Get rest of the VF information
-		pfName, vfID, err := getVfInfo(n.DeviceID)
-		if err != nil {
-			return nil, fmt.Errorf("LoadConf(): failed to get VF information: %q", err)
-		}
-		n.VFID = vfID
-		n.Master = pfName
-	} else {
-		return nil, fmt.Errorf("LoadConf(): VF pci addr is required")
+	if err := errors.Join(validateFields(n)...); err != nil {
+		return nil, fmt.Errorf("LoadConf(): %v", err)
+	}
+
+	// Get rest of the VF information from its pci address
+	pfName, vfID, err := getVfInfo(n.DeviceID)
+	if err != nil {
+		return nil, fmt.Errorf("LoadConf(): failed to get VF information: %q", err)
+	}
+	if err = checkRequestedVFID(bytes, vfID); err != nil {
+		return nil, fmt.Errorf("LoadConf(): %v", err)
+	}
+	n.VFID = vfID
+	n.Master = pfName
+	if err = checkIfNameTemplate(n); err != nil {
+		return nil, fmt.Errorf("LoadConf(): %v", err)
 	}
 
 	// Check if the device is already allocated.
@@ -54,9 +123,9 @@ func LoadConf(bytes []byte) (*sriovtypes.NetConf, error) {
 	// This will block the new pod creation until the cmdDel is done.
 	logging.Debug("Check if the device is already allocated",
 		"func", "LoadConf",
-		"DefaultCNIDir", DefaultCNIDir,
+		"cacheDir", CacheDir(n),
 		"n.DeviceID", n.DeviceID)
-	allocator := utils.NewPCIAllocator(DefaultCNIDir)
+	allocator := utils.NewPCIAllocator(CacheDir(n))
 	isAllocated, err := allocator.IsAllocated(n.DeviceID)
 	if err != nil {
 		return n, err
@@ -81,63 +150,727 @@ func LoadConf(bytes []byte) (*sriovtypes.NetConf, error) {
 		n.OrigVfState.HostIFName = hostIFName
 	}
 
+	// The VF is bound to the userspace driver during cmdAdd, so it is handled in DPDK mode
+	if n.DriverOverride != "" {
+		n.DPDKMode = true
+	}
+
 	if hostIFName == "" && !n.DPDKMode {
 		return nil, fmt.Errorf("LoadConf(): the VF %s does not have a interface name or a dpdk driver", n.DeviceID)
 	}
 
+	setDefaults(n)
+
+	if n.RSSHashKey != "" && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): RSS hash key cannot be set on a VF bound to a userspace driver")
+	}
+
+	if n.RSS != nil && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): RSS cannot be configured on a VF bound to a userspace driver")
+	}
+
+	if n.IRQAffinity != nil && *n.IRQAffinity && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): irqAffinity cannot be set on a VF bound to a userspace driver")
+	}
+
+	if n.EgressQoSMap != "" && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): egressQoSMap cannot be set on a VF bound to a userspace driver")
+	}
+
+	if n.AllMulti != "" && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): allMulti cannot be set on a VF bound to a userspace driver")
+	}
+
+	if n.TxQLen != nil && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): txQLen cannot be set on a VF bound to a userspace driver")
+	}
+
+	if n.VerifyMAC && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): verifyMAC cannot be set on a VF bound to a userspace driver")
+	}
+
+	if n.RouteTable != nil && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): routeTable cannot be set on a VF bound to a userspace driver")
+	}
+
+	if len(n.Routes) > 0 && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): routes cannot be set on a VF bound to a userspace driver")
+	}
+
+	if len(n.Sysctls) > 0 && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): sysctls cannot be set on a VF bound to a userspace driver")
+	}
+
+	if n.Neigh != nil && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): neigh cannot be set on a VF bound to a userspace driver")
+	}
+
+	if n.IngressQoSMap != nil && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): ingressQoSMap cannot be set on a VF bound to a userspace driver")
+	}
+
+	if len(n.ETS) > 0 && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): ets cannot be set on a VF bound to a userspace driver")
+	}
+
+	if n.CaptureOnFailure && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): captureOnFailure cannot be set on a VF bound to a userspace driver")
+	}
+
+	if n.FullResets() && n.DPDKMode {
+		return nil, fmt.Errorf("LoadConf(): fullReset cannot be set on a VF bound to a userspace driver")
+	}
+
+	return n, nil
+}
+
+// LoadRequestedConf parses and validates the netconf of a cmdAdd retried for a VF that the previous cmdAdd,
+// cached as cached, moved to the pod netns. The VF details are taken from the cached NetConf as the VF
+// netdev is no longer found on the host.
+func LoadRequestedConf(bytes []byte, cached *sriovtypes.NetConf) (*sriovtypes.NetConf, error) {
+	n := &sriovtypes.NetConf{}
+	if err := json.Unmarshal(bytes, n); err != nil {
+		return nil, fmt.Errorf("LoadRequestedConf(): failed to load netconf: %v", err)
+	}
+	applyRuntimeConfig(n)
+
+	if err := errors.Join(validateFields(n)...); err != nil {
+		return nil, fmt.Errorf("LoadRequestedConf(): %v", err)
+	}
+
+	if n.DeviceID != cached.DeviceID {
+		return nil, fmt.Errorf("LoadRequestedConf(): requested VF %s differs from the cached VF %s", n.DeviceID, cached.DeviceID)
+	}
+	n.VFID = cached.VFID
+	n.Master = cached.Master
+	if err := checkIfNameTemplate(n); err != nil {
+		return nil, fmt.Errorf("LoadRequestedConf(): %v", err)
+	}
+	n.OrigVfState = cached.OrigVfState
+	n.DPDKMode = n.DriverOverride != "" || cached.OrigVfState.HostIFName == ""
+
+	setDefaults(n)
+
+	return n, nil
+}
+
+// ValidateConf parses stdin netconf and validates its fields without accessing sysfs or netlink.
+// Every problem found is reported in the returned error.
+func ValidateConf(bytes []byte) error {
+	n := &sriovtypes.NetConf{}
+	if err := json.Unmarshal(bytes, n); err != nil {
+		return fmt.Errorf("ValidateConf(): failed to load netconf: %v", err)
+	}
+	applyRuntimeConfig(n)
+
+	return errors.Join(validateFields(n)...)
+}
+
+// ValidateCommand is the custom CNI_COMMAND validating the stdin netconf for pre-admission checks
+const ValidateCommand = "VALIDATE"
+
+// RunValidate runs the VALIDATE command: it reads the netconf from stdin and validates its fields like
+// ValidateConf, without accessing the host devices. It returns a CNI error listing every problem found, or nil.
+func RunValidate(stdin io.Reader) *cnitypes.Error {
+	bytes, err := io.ReadAll(stdin)
+	if err != nil {
+		return cnitypes.NewError(cnitypes.ErrIOFailure, "failed to read the netconf from stdin", err.Error())
+	}
+
+	n := &sriovtypes.NetConf{}
+	if err := json.Unmarshal(bytes, n); err != nil {
+		return cnitypes.NewError(cnitypes.ErrDecodingFailure, "failed to load netconf", err.Error())
+	}
+	applyRuntimeConfig(n)
+
+	if err := errors.Join(validateFields(n)...); err != nil {
+		return cnitypes.NewError(cnitypes.ErrInvalidNetworkConfig, "invalid network configuration", err.Error())
+	}
+	return nil
+}
+
+// applyRuntimeConfig overrides the netconf fields with the ones of the runtime config, which the runtime sets per pod
+// for the capabilities declared by the network configuration
+func applyRuntimeConfig(n *sriovtypes.NetConf) {
+	if n.RuntimeConfig.Vlan != nil {
+		n.Vlan = n.RuntimeConfig.Vlan
+	}
+}
+
+// validateFields checks the user provided netconf fields, it does not access the host devices
+func validateFields(n *sriovtypes.NetConf) []error {
+	var errs []error
+
+	if n.DeviceID == "" && n.Bond == nil {
+		errs = append(errs, fmt.Errorf("VF pci addr is required"))
+	}
+
+	if n.DriverOverride != "" && !utils.IsUserspaceDriver(n.DriverOverride) {
+		errs = append(errs, fmt.Errorf("invalid driverOverride %s: value must be one of %v", n.DriverOverride, utils.UserspaceDrivers))
+	}
+
 	if n.Vlan == nil {
 		// validate non-nil value for vlan qos
 		if n.VlanQoS != nil {
-			return nil, fmt.Errorf("LoadConf(): vlan id must be configured to set vlan QoS to a non-nil value")
+			errs = append(errs, fmt.Errorf("vlan id must be configured to set vlan QoS to a non-nil value"))
 		}
 
 		// validate non-nil value for vlan proto
 		if n.VlanProto != nil {
-			return nil, fmt.Errorf("LoadConf(): vlan id must be configured to set vlan proto to a non-nil value")
+			errs = append(errs, fmt.Errorf("vlan id must be configured to set vlan proto to a non-nil value"))
 		}
 	} else {
 		// validate vlan id range
 		if *n.Vlan < 0 || *n.Vlan > 4094 {
-			return nil, fmt.Errorf("LoadConf(): vlan id %d invalid: value must be in the range 0-4094", *n.Vlan)
+			errs = append(errs, fmt.Errorf("vlan id %d invalid: value must be in the range 0-4094", *n.Vlan))
 		}
 
-		if n.VlanQoS == nil {
-			qos := 0
-			n.VlanQoS = &qos
-		}
+		if n.VlanQoS != nil {
+			// validate that VLAN QoS is in the 0-7 range
+			if *n.VlanQoS < 0 || *n.VlanQoS > 7 {
+				errs = append(errs, fmt.Errorf("vlan QoS PCP %d invalid: value must be in the range 0-7", *n.VlanQoS))
+			}
 
-		// validate that VLAN QoS is in the 0-7 range
-		if *n.VlanQoS < 0 || *n.VlanQoS > 7 {
-			return nil, fmt.Errorf("LoadConf(): vlan QoS PCP %d invalid: value must be in the range 0-7", *n.VlanQoS)
+			// validate non-zero value for vlan id if vlan qos is set to a non-zero value
+			if *n.VlanQoS != 0 && *n.Vlan == 0 {
+				errs = append(errs, fmt.Errorf("non-zero vlan id must be configured to set vlan QoS to a non-zero value"))
+			}
 		}
 
-		// validate non-zero value for vlan id if vlan qos is set to a non-zero value
-		if *n.VlanQoS != 0 && *n.Vlan == 0 {
-			return nil, fmt.Errorf("LoadConf(): non-zero vlan id must be configured to set vlan QoS to a non-zero value")
+		if n.VlanProto != nil {
+			proto := strings.ToLower(*n.VlanProto)
+			if proto != sriovtypes.Proto8021ad && proto != sriovtypes.Proto8021q {
+				errs = append(errs, fmt.Errorf("vlan Proto %s invalid: value must be '802.1Q' or '802.1ad'", proto))
+			}
+
+			// validate non-zero value for vlan id if vlan proto is set to 802.1ad
+			if proto == sriovtypes.Proto8021ad && *n.Vlan == 0 {
+				errs = append(errs, fmt.Errorf("non-zero vlan id must be configured to set vlan proto 802.1ad"))
+			}
 		}
+	}
 
-		if n.VlanProto == nil {
-			proto := sriovtypes.Proto8021q
-			n.VlanProto = &proto
+	// validate that spoofchk and trust are one of supported values
+	if n.SpoofChk != "" && n.SpoofChk != "on" && n.SpoofChk != "off" {
+		errs = append(errs, fmt.Errorf("invalid spoofchk value: %s", n.SpoofChk))
+	}
+	if n.Trust != "" && n.Trust != "on" && n.Trust != "off" {
+		errs = append(errs, fmt.Errorf("invalid trust value: %s", n.Trust))
+	}
+	if n.AllowGuestVlan != nil && *n.AllowGuestVlan && n.Vlan != nil && *n.Vlan != 0 &&
+		(n.VlanProto == nil || strings.ToLower(*n.VlanProto) == sriovtypes.Proto8021q) {
+		errs = append(errs, fmt.Errorf("allowGuestVlan requires an untagged VF or an 802.1ad vlan, the guest vlan tags would be mixed with the 802.1q vlan %d", *n.Vlan))
+	}
+
+	if n.AllMulti != "" && n.AllMulti != "on" && n.AllMulti != "off" {
+		errs = append(errs, fmt.Errorf("invalid allMulti value: %s", n.AllMulti))
+	}
+
+	if n.TxQLen != nil && *n.TxQLen < 0 {
+		errs = append(errs, fmt.Errorf("invalid txQLen %d: value must be non-negative", *n.TxQLen))
+	}
+
+	// validate the mode, the spoofchk and trust values it sets must not be overridden
+	if n.Mode != "" {
+		if n.Mode != sriovtypes.ModeMacvlanHost {
+			errs = append(errs, fmt.Errorf("invalid mode value: %s", n.Mode))
+		} else {
+			if n.SpoofChk == "on" {
+				errs = append(errs, fmt.Errorf("mode %s requires spoofchk to be off", n.Mode))
+			}
+			if n.Trust == "off" {
+				errs = append(errs, fmt.Errorf("mode %s requires trust to be on", n.Mode))
+			}
 		}
+	}
 
-		*n.VlanProto = strings.ToLower(*n.VlanProto)
-		if *n.VlanProto != sriovtypes.Proto8021ad && *n.VlanProto != sriovtypes.Proto8021q {
-			return nil, fmt.Errorf("LoadConf(): vlan Proto %s invalid: value must be '802.1Q' or '802.1ad'", *n.VlanProto)
+	// a passthrough VF is not configured, the VF attributes must not be requested
+	if n.IsPassthrough() {
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{
+			{"vlan", n.Vlan != nil},
+			{"mac", n.MAC != ""},
+			{"min_tx_rate", n.MinTxRate != nil},
+			{"max_tx_rate", n.MaxTxRate != nil},
+			{"spoofchk", n.SpoofChk != ""},
+			{"trust", n.Trust != ""},
+			{"link_state", n.LinkState != ""},
+			{"mode", n.Mode != ""},
+		} {
+			if f.set {
+				errs = append(errs, fmt.Errorf("%s cannot be configured in passthrough mode", f.name))
+			}
 		}
+	}
 
-		// validate non-zero value for vlan id if vlan proto is set to 802.1ad
-		if *n.VlanProto == sriovtypes.Proto8021ad && *n.Vlan == 0 {
-			return nil, fmt.Errorf("LoadConf(): non-zero vlan id must be configured to set vlan proto 802.1ad")
+	// validate MAC change limit, it only applies to trusted VFs
+	if n.MaxMacChanges != nil {
+		if *n.MaxMacChanges < 0 {
+			errs = append(errs, fmt.Errorf("invalid maxMacChanges %d: value must be non-negative", *n.MaxMacChanges))
+		}
+		if n.Trust != "on" && n.Mode != sriovtypes.ModeMacvlanHost {
+			errs = append(errs, fmt.Errorf("maxMacChanges requires trust to be on"))
 		}
 	}
 
 	// validate that link state is one of supported values
 	if n.LinkState != "" && n.LinkState != "auto" && n.LinkState != "enable" && n.LinkState != "disable" {
-		return nil, fmt.Errorf("LoadConf(): invalid link_state value: %s", n.LinkState)
+		errs = append(errs, fmt.Errorf("invalid link_state value: %s", n.LinkState))
 	}
 
-	return n, nil
+	// the runtimeConfig MAC is set as both the VF admin and effective MAC, it must be a unicast address
+	if n.RuntimeConfig.Mac != "" {
+		mac, err := net.ParseMAC(n.RuntimeConfig.Mac)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid runtimeConfig mac %s: %v", n.RuntimeConfig.Mac, err))
+		} else if !utils.IsValidMACAddress(mac) || mac[0]&0x01 != 0 {
+			errs = append(errs, fmt.Errorf("invalid runtimeConfig mac %s: value must be a unicast MAC address", n.RuntimeConfig.Mac))
+		}
+	}
+
+	if n.RuntimeConfig.Vlan != nil && (*n.RuntimeConfig.Vlan < 1 || *n.RuntimeConfig.Vlan > 4094) {
+		errs = append(errs, fmt.Errorf("invalid runtimeConfig vlan %d: value must be in the range 1-4094", *n.RuntimeConfig.Vlan))
+	}
+
+	// validate secondary MAC addresses, they must differ from the primary MAC and from each other
+	if len(n.AltMACs) > 0 {
+		if n.DriverOverride != "" {
+			errs = append(errs, fmt.Errorf("altMACs cannot be configured together with driverOverride"))
+		}
+		seen := make(map[string]bool, len(n.AltMACs)+2)
+		for _, primary := range []string{n.MAC, n.RuntimeConfig.Mac} {
+			if mac, err := net.ParseMAC(primary); err == nil {
+				seen[mac.String()] = true
+			}
+		}
+		for _, altMAC := range n.AltMACs {
+			mac, err := net.ParseMAC(altMAC)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid altMACs mac %s: %v", altMAC, err))
+				continue
+			}
+			if seen[mac.String()] {
+				errs = append(errs, fmt.Errorf("invalid altMACs mac %s: duplicates the primary or another secondary MAC address", altMAC))
+			}
+			seen[mac.String()] = true
+		}
+	}
+
+	if n.GUID != "" {
+		if _, err := utils.ParseGUID(n.GUID); err != nil {
+			errs = append(errs, err)
+		}
+		if n.MAC != "" || n.RuntimeConfig.Mac != "" {
+			errs = append(errs, fmt.Errorf("mac and guid cannot be configured together"))
+		}
+	}
+
+	if n.MetricsFile != "" && !filepath.IsAbs(n.MetricsFile) {
+		errs = append(errs, fmt.Errorf("invalid metricsFile %s: path must be absolute", n.MetricsFile))
+	}
+
+	// validate that reset scope is one of supported values
+	if n.ResetScope != "" && n.ResetScope != sriovtypes.ResetScopeAll &&
+		n.ResetScope != sriovtypes.ResetScopeL3Only && n.ResetScope != sriovtypes.ResetScopeL2Only {
+		errs = append(errs, fmt.Errorf("invalid resetScope value: %s", n.ResetScope))
+	}
+
+	if n.VerifyMAC && n.SkipsMACConfig() {
+		errs = append(errs, fmt.Errorf("verifyMAC cannot be combined with skipMACConfig"))
+	}
+
+	if n.IfNameTemplate != "" {
+		if strings.ContainsAny(n.IfNameTemplate, "/ ") {
+			errs = append(errs, fmt.Errorf("invalid ifNameTemplate %s: value must not contain slashes or spaces", n.IfNameTemplate))
+		}
+		if strings.Count(n.IfNameTemplate, "%") != strings.Count(n.IfNameTemplate, "%d") {
+			errs = append(errs, fmt.Errorf("invalid ifNameTemplate %s: %%d is the only substitution", n.IfNameTemplate))
+		}
+	}
+
+	if n.FullResets() && n.ResetScope == sriovtypes.ResetScopeL3Only {
+		errs = append(errs, fmt.Errorf("fullReset cannot be combined with resetScope %s", n.ResetScope))
+	}
+
+	// validate min/max tx rate limits
+	if n.MinTxRate != nil && *n.MinTxRate < 0 {
+		errs = append(errs, fmt.Errorf("invalid min_tx_rate %d: value must be non-negative", *n.MinTxRate))
+	}
+	if n.MaxTxRate != nil && *n.MaxTxRate < 0 {
+		errs = append(errs, fmt.Errorf("invalid max_tx_rate %d: value must be non-negative", *n.MaxTxRate))
+	}
+	if n.MinTxRate != nil && n.MaxTxRate != nil && *n.MaxTxRate > 0 && *n.MinTxRate > *n.MaxTxRate {
+		errs = append(errs, fmt.Errorf("min_tx_rate %d must be less than or equal to max_tx_rate %d", *n.MinTxRate, *n.MaxTxRate))
+	}
+
+	if n.EnforceSchedulerCapacity != "" && n.EnforceSchedulerCapacity != sriovtypes.SchedulerCapacityWarn &&
+		n.EnforceSchedulerCapacity != sriovtypes.SchedulerCapacityReject {
+		errs = append(errs, fmt.Errorf("invalid enforceSchedulerCapacity value: %s", n.EnforceSchedulerCapacity))
+	}
+
+	if n.EnforceRateCeiling != "" && n.EnforceRateCeiling != sriovtypes.RateCeilingReject && n.EnforceRateCeiling != sriovtypes.RateCeilingClamp {
+		errs = append(errs, fmt.Errorf("invalid enforceRateCeiling value: %s", n.EnforceRateCeiling))
+	}
+
+	if n.DrainDelay < 0 || n.DrainDelay > sriovtypes.MaxDrainDelay {
+		errs = append(errs, fmt.Errorf("invalid drainDelay %d: value must be in the range 0-%d", n.DrainDelay, sriovtypes.MaxDrainDelay))
+	}
+
+	if n.EgressQoSMap != "" {
+		if _, err := utils.ParseEgressQoSMap(n.EgressQoSMap); err != nil {
+			errs = append(errs, err)
+		}
+		if n.DriverOverride != "" {
+			errs = append(errs, fmt.Errorf("egressQoSMap cannot be set on a VF bound to a userspace driver"))
+		}
+	}
+
+	if n.CaptureDir != "" && !filepath.IsAbs(n.CaptureDir) {
+		errs = append(errs, fmt.Errorf("captureDir %q invalid: value must be an absolute path", n.CaptureDir))
+	}
+
+	if n.IPAMDataDir != "" && !filepath.IsAbs(n.IPAMDataDir) {
+		errs = append(errs, fmt.Errorf("ipamDataDir %q invalid: value must be an absolute path", n.IPAMDataDir))
+	}
+
+	if n.CacheDir != "" && !filepath.IsAbs(n.CacheDir) {
+		errs = append(errs, fmt.Errorf("cacheDir %q invalid: value must be an absolute path", n.CacheDir))
+	}
+
+	// the drain delay keeps the VF link up, which contradicts signalling it down
+	if n.SignalDownOnDel && n.DrainDelay > 0 {
+		errs = append(errs, fmt.Errorf("signalDownOnDel cannot be used with a drainDelay"))
+	}
+
+	if n.NetlinkTimeout != nil && *n.NetlinkTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("invalid netlinkTimeout %d: value must be positive", *n.NetlinkTimeout))
+	}
+
+	if n.LinkUpTimeout != nil && *n.LinkUpTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("invalid linkUpTimeout %d: value must be positive", *n.LinkUpTimeout))
+	}
+
+	if n.LogFormat != "" && !logging.IsValidFormat(n.LogFormat) {
+		errs = append(errs, fmt.Errorf("invalid logFormat %s: value must be one of %s, %s", n.LogFormat, logging.FormatText, logging.FormatJSON))
+	}
+
+	levels := make([]string, 0, len(n.LevelFiles))
+	for level := range n.LevelFiles {
+		levels = append(levels, level)
+	}
+	sort.Strings(levels)
+	for _, level := range levels {
+		if !logging.IsValidLevel(level) {
+			errs = append(errs, fmt.Errorf("invalid levelFiles level %s: value must be one of panic, error, warning, info, debug", level))
+		}
+		if n.LevelFiles[level] == "" {
+			errs = append(errs, fmt.Errorf("invalid levelFiles file for level %s: path must not be empty", level))
+		}
+	}
+
+	if n.LogOptions != nil {
+		for _, o := range []struct {
+			name  string
+			value *int
+		}{{"maxSize", n.LogOptions.MaxSize}, {"maxAge", n.LogOptions.MaxAge}, {"maxBackups", n.LogOptions.MaxBackups}} {
+			if o.value != nil && *o.value < 0 {
+				errs = append(errs, fmt.Errorf("invalid logOptions %s %d: value must not be negative", o.name, *o.value))
+			}
+		}
+	}
+
+	// validate the VNI of the FDB entry of the VF MAC
+	if n.FdbVNI != nil && (*n.FdbVNI < 1 || *n.FdbVNI > sriovtypes.MaxVNI) {
+		errs = append(errs, fmt.Errorf("fdbVni %d invalid: value must be in the range 1-%d", *n.FdbVNI, sriovtypes.MaxVNI))
+	}
+
+	if n.RouteTable != nil {
+		if *n.RouteTable < 1 || *n.RouteTable > sriovtypes.MaxRouteTable {
+			errs = append(errs, fmt.Errorf("routeTable %d invalid: value must be in the range 1-%d", *n.RouteTable, sriovtypes.MaxRouteTable))
+		} else if *n.RouteTable >= unix.RT_TABLE_DEFAULT && *n.RouteTable <= unix.RT_TABLE_LOCAL {
+			errs = append(errs, fmt.Errorf("routeTable %d invalid: the default, main and local tables are reserved", *n.RouteTable))
+		}
+		if n.IPAM.Type == "" {
+			errs = append(errs, fmt.Errorf("routeTable requires ipam"))
+		}
+	}
+
+	for _, r := range n.Routes {
+		_, dst, err := net.ParseCIDR(r.Dst)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid route dst %q: %v", r.Dst, err))
+			continue
+		}
+		if r.GW == "" {
+			continue
+		}
+		gw := net.ParseIP(r.GW)
+		if gw == nil {
+			errs = append(errs, fmt.Errorf("invalid gw %q of route %s", r.GW, dst))
+		} else if (gw.To4() == nil) != (dst.IP.To4() == nil) {
+			errs = append(errs, fmt.Errorf("invalid gw %s of route %s: address family differs from the dst one", gw, dst))
+		}
+	}
+	if len(n.Routes) > 0 && n.IPAM.Type == "" {
+		errs = append(errs, fmt.Errorf("routes require ipam"))
+	}
+
+	if n.EnforceVlanExclusivity && (n.Vlan == nil || *n.Vlan == 0) {
+		errs = append(errs, fmt.Errorf("enforceVlanExclusivity requires a non-zero vlan"))
+	}
+
+	if n.RepresentorVlan != nil {
+		if *n.RepresentorVlan < 1 || *n.RepresentorVlan > 4094 {
+			errs = append(errs, fmt.Errorf("representorVlan %d invalid: value must be in the range 1-4094", *n.RepresentorVlan))
+		}
+		if n.Vlan != nil && *n.Vlan != 0 {
+			errs = append(errs, fmt.Errorf("representorVlan cannot be configured together with a non-zero vlan"))
+		}
+	}
+
+	if n.ManagesRepresentor() && n.LinkState != "enable" {
+		errs = append(errs, fmt.Errorf("manageRepresentor requires link_state enable"))
+	}
+
+	if n.RebindOnDel != nil && n.DriverOverride == "" {
+		errs = append(errs, fmt.Errorf("rebindOnDel requires driverOverride"))
+	}
+
+	if len(n.PrivFlags) > 0 && n.DriverOverride != "" {
+		errs = append(errs, fmt.Errorf("privFlags cannot be configured together with driverOverride"))
+	}
+
+	if n.MicroburstProtection && n.DriverOverride != "" {
+		errs = append(errs, fmt.Errorf("microburstProtection cannot be configured together with driverOverride"))
+	}
+
+	if n.KeepsInHostNetns() {
+		// the VF is named by the template in the host netns, a name that cannot clash with the pod interface names
+		if n.IfNameTemplate == "" {
+			errs = append(errs, fmt.Errorf("keepInHostNetns requires ifNameTemplate"))
+		}
+		if n.DriverOverride != "" {
+			errs = append(errs, fmt.Errorf("keepInHostNetns cannot be configured together with driverOverride"))
+		}
+		if n.Neigh != nil {
+			errs = append(errs, fmt.Errorf("keepInHostNetns cannot be configured together with neigh, the thresholds would apply to the host netns"))
+		}
+	}
+
+	if n.Bond != nil {
+		errs = append(errs, validateBond(n)...)
+	}
+	for name := range n.Sysctls {
+		if !interfaceSysctlRe.MatchString(name) {
+			errs = append(errs, fmt.Errorf("invalid sysctl %s: only net.ipv4.conf.%s.*, net.ipv6.conf.%s.* and net.ipv4.neigh.%s.* are allowed",
+				name, utils.SysctlIfName, utils.SysctlIfName, utils.SysctlIfName))
+		}
+	}
+
+	if n.Neigh != nil {
+		errs = append(errs, validateNeigh(n.Neigh)...)
+	}
+
+	if n.IngressQoSMap != nil {
+		errs = append(errs, validateIngressQoSMap(n.IngressQoSMap)...)
+	}
+
+	if len(n.ETS) > 0 {
+		errs = append(errs, validateETS(n.ETS)...)
+	}
+
+	for name := range n.PrivFlags {
+		if name == "" {
+			errs = append(errs, fmt.Errorf("invalid privFlags: flag name must not be empty"))
+		}
+	}
+
+	// validate per-queue tx rate limits
+	for _, qr := range n.QueueRates {
+		if qr.Queue < 0 {
+			errs = append(errs, fmt.Errorf("invalid tx queue index %d: value must be non-negative", qr.Queue))
+		}
+		if qr.MaxRate < 0 {
+			errs = append(errs, fmt.Errorf("invalid max rate %d for tx queue %d: value must be non-negative", qr.MaxRate, qr.Queue))
+		}
+	}
+
+	// validate ingress policing, the rate is passed to the kernel in bytes per second on 32 bits
+	if n.IngressPolice != nil {
+		if n.IngressPolice.Rate <= 0 || n.IngressPolice.Rate > maxIngressPoliceRate {
+			errs = append(errs, fmt.Errorf("invalid ingressPolice rate %d: value must be in the range 1-%d", n.IngressPolice.Rate, maxIngressPoliceRate))
+		}
+		if n.IngressPolice.Burst <= 0 || int64(n.IngressPolice.Burst) > math.MaxUint32 {
+			errs = append(errs, fmt.Errorf("invalid ingressPolice burst %d: value must be in the range 1-%d", n.IngressPolice.Burst, uint32(math.MaxUint32)))
+		}
+		if n.DriverOverride != "" {
+			errs = append(errs, fmt.Errorf("ingressPolice cannot be configured together with driverOverride"))
+		}
+	}
+
+	if n.RSSHashKey != "" {
+		if _, err := utils.ParseRSSHashKey(n.RSSHashKey); err != nil {
+			errs = append(errs, err)
+		}
+		if n.DriverOverride != "" {
+			errs = append(errs, fmt.Errorf("RSS hash key cannot be set on a VF bound to a userspace driver"))
+		}
+	}
+
+	if n.RSS != nil {
+		if n.RSS.HashKey != "" {
+			if _, err := utils.ParseRSSHashKey(n.RSS.HashKey); err != nil {
+				errs = append(errs, err)
+			}
+			if n.RSSHashKey != "" {
+				errs = append(errs, fmt.Errorf("rss hashKey cannot be configured together with rssHashKey"))
+			}
+		}
+		if l := len(n.RSS.IndirTable); l > 0 && l&(l-1) != 0 {
+			errs = append(errs, fmt.Errorf("invalid rss indirTable length %d: value must be a power of two", l))
+		}
+		for _, queue := range n.RSS.IndirTable {
+			if queue < 0 {
+				errs = append(errs, fmt.Errorf("invalid rss indirTable entry %d: value must be non-negative", queue))
+			}
+		}
+		if n.DriverOverride != "" {
+			errs = append(errs, fmt.Errorf("RSS cannot be configured on a VF bound to a userspace driver"))
+		}
+	}
+
+	return errs
+}
+
+// validateBond checks the bond mode and members, the member VFs are configured in the pod netns with the netconf
+// fields, so the fields applying to a single VF or to a userspace driver are rejected
+func validateBond(n *sriovtypes.NetConf) []error {
+	var errs []error
+	if n.Bond.Mode != sriovtypes.BondModeActiveBackup && n.Bond.Mode != sriovtypes.BondMode8023AD {
+		errs = append(errs, fmt.Errorf("invalid bond mode %q: value must be one of %s and %s", n.Bond.Mode,
+			sriovtypes.BondModeActiveBackup, sriovtypes.BondMode8023AD))
+	}
+	if len(n.Bond.Members) < 2 {
+		errs = append(errs, fmt.Errorf("invalid bond: at least two members are required, %d set", len(n.Bond.Members)))
+	}
+	seen := map[string]bool{}
+	for _, member := range n.Bond.Members {
+		if member == "" {
+			errs = append(errs, fmt.Errorf("invalid bond member: VF pci addr is required"))
+		} else if seen[member] {
+			errs = append(errs, fmt.Errorf("invalid bond member %s: VF is set more than once", member))
+		}
+		seen[member] = true
+	}
+	if n.DeviceID != "" {
+		errs = append(errs, fmt.Errorf("bond cannot be configured together with deviceID"))
+	}
+	if n.DriverOverride != "" {
+		errs = append(errs, fmt.Errorf("bond cannot be configured together with driverOverride"))
+	}
+	if n.IfNameTemplate != "" {
+		errs = append(errs, fmt.Errorf("bond cannot be configured together with ifNameTemplate"))
+	}
+	if len(n.Bond.IfName) > maxIfNameLen {
+		errs = append(errs, fmt.Errorf("invalid bond ifName %s: value must not be longer than %d characters", n.Bond.IfName, maxIfNameLen))
+	}
+	return errs
+}
+
+// validateNeigh checks the neighbor table garbage collection thresholds are in range and in increasing order
+func validateNeigh(neigh *sriovtypes.Neigh) []error {
+	var errs []error
+	var prevName string
+	var prev int
+	for i, thresh := range []int{neigh.GcThresh1, neigh.GcThresh2, neigh.GcThresh3} {
+		name := fmt.Sprintf("gcThresh%d", i+1)
+		if thresh == 0 {
+			continue
+		}
+		if thresh < 0 || thresh > sriovtypes.MaxGcThresh {
+			errs = append(errs, fmt.Errorf("invalid neigh %s %d: value must be in the range 1-%d", name, thresh, sriovtypes.MaxGcThresh))
+			continue
+		}
+		if prevName != "" && thresh < prev {
+			errs = append(errs, fmt.Errorf("invalid neigh %s %d: value must not be lower than %s %d", name, thresh, prevName, prev))
+		}
+		prevName, prev = name, thresh
+	}
+	if prevName == "" {
+		errs = append(errs, fmt.Errorf("invalid neigh: at least one of gcThresh1, gcThresh2 and gcThresh3 must be set"))
+	}
+	return errs
+}
+
+// validateIngressQoSMap checks the DSCP to priority and PCP to traffic class mappings, the traffic classes are
+// checked against the number of traffic classes of the VF netdev when the mappings are set
+func validateIngressQoSMap(qosMap *sriovtypes.IngressQoSMap) []error {
+	var errs []error
+	if qosMap.DSCP == "" && qosMap.PCP == "" {
+		errs = append(errs, fmt.Errorf("invalid ingressQoSMap: at least one of dscp and pcp must be set"))
+	}
+	if qosMap.DSCP != "" {
+		if _, err := utils.ParseIngressQoSMap(qosMap.DSCP, "dscp", "priority", 63); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if qosMap.PCP != "" {
+		if _, err := utils.ParseIngressQoSMap(qosMap.PCP, "pcp", "tc", 7); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// validateETS checks the traffic classes are in range and set once and their bandwidth percentages sum to 100, the
+// traffic classes are checked against the number of traffic classes of the VF netdev when the bandwidth is set
+func validateETS(ets []sriovtypes.ETSBandwidth) []error {
+	var errs []error
+	seen := map[int]bool{}
+	total := 0
+	for _, bw := range ets {
+		if bw.TC < 0 || bw.TC >= utils.DCBMaxTCs {
+			errs = append(errs, fmt.Errorf("invalid ets tc %d: value must be in the range 0-%d", bw.TC, utils.DCBMaxTCs-1))
+		} else if seen[bw.TC] {
+			errs = append(errs, fmt.Errorf("invalid ets tc %d: traffic class is set more than once", bw.TC))
+		}
+		seen[bw.TC] = true
+		if bw.BandwidthPercent < 0 || bw.BandwidthPercent > 100 {
+			errs = append(errs, fmt.Errorf("invalid ets bandwidthPercent %d of tc %d: value must be in the range 0-100", bw.BandwidthPercent, bw.TC))
+		}
+		total += bw.BandwidthPercent
+	}
+	if total != 100 {
+		errs = append(errs, fmt.Errorf("invalid ets: bandwidth percentages sum to %d instead of 100", total))
+	}
+	return errs
+}
+
+// setDefaults sets the defaults of the optional netconf fields
+func setDefaults(n *sriovtypes.NetConf) {
+	if n.Vlan != nil {
+		if n.VlanQoS == nil {
+			qos := 0
+			n.VlanQoS = &qos
+		}
+
+		if n.VlanProto == nil {
+			proto := sriovtypes.Proto8021q
+			n.VlanProto = &proto
+		}
+		*n.VlanProto = strings.ToLower(*n.VlanProto)
+	}
+
+	if n.Mode == sriovtypes.ModeMacvlanHost {
+		n.SpoofChk = "off"
+		n.Trust = "on"
+	}
+
+	if n.CaptureOnFailure && n.CaptureDir == "" {
+		n.CaptureDir = filepath.Join(CacheDir(n), "captures")
+	}
 }
 
 func getVfInfo(vfPci string) (string, int, error) {
@@ -156,24 +889,186 @@ func getVfInfo(vfPci string) (string, int, error) {
 	return pf, vfID, nil
 }
 
-// LoadConfFromCache retrieves cached NetConf returns it along with a handle for removal
-func LoadConfFromCache(args *skel.CmdArgs) (*sriovtypes.NetConf, string, error) {
-	netConf := &sriovtypes.NetConf{}
+// IPAMStdinData returns the netconf passed to the IPAM plugin. The ipamDataDir of the netconf is set as the
+// dataDir of the ipam config, unless the ipam config sets one, so that the IPAM plugin does not use its default.
+func IPAMStdinData(n *sriovtypes.NetConf, stdinData []byte) ([]byte, error) {
+	if n.IPAMDataDir == "" {
+		return stdinData, nil
+	}
+
+	conf := map[string]interface{}{}
+	if err := json.Unmarshal(stdinData, &conf); err != nil {
+		return nil, fmt.Errorf("failed to load netconf: %v", err)
+	}
+	ipamConf, ok := conf["ipam"].(map[string]interface{})
+	if !ok {
+		return stdinData, nil
+	}
+	if _, ok := ipamConf["dataDir"]; ok {
+		return stdinData, nil
+	}
+	ipamConf["dataDir"] = n.IPAMDataDir
 
-	s := []string{args.ContainerID, args.IfName}
-	cRef := strings.Join(s, "-")
-	cRefPath := filepath.Join(DefaultCNIDir, cRef)
+	return json.Marshal(conf)
+}
 
-	netConfBytes, err := utils.ReadScratchNetConf(cRefPath)
+// checkRequestedVFID verifies that a VF index given in the netconf is the index of the deviceID VF on its PF,
+// resolved from the virtfn links of the PF, so that a stale device plugin allocation does not configure another VF
+func checkRequestedVFID(bytes []byte, vfID int) error {
+	requested := struct {
+		VFID *int
+	}{}
+	if err := json.Unmarshal(bytes, &requested); err != nil {
+		return fmt.Errorf("failed to load netconf: %v", err)
+	}
+	if requested.VFID != nil && *requested.VFID != vfID {
+		return fmt.Errorf("VFID %d does not match the deviceID VF, which is VF %d of its PF", *requested.VFID, vfID)
+	}
+	return nil
+}
+
+// PodIfName returns the name of the VF netdevice in the pod netns: the ifNameTemplate rendered with the VF
+// index, or else ifName, the interface name of the CNI args
+func PodIfName(n *sriovtypes.NetConf, ifName string) string {
+	if n.IfNameTemplate == "" {
+		return ifName
+	}
+	return strings.ReplaceAll(n.IfNameTemplate, "%d", strconv.Itoa(n.VFID))
+}
+
+// checkIfNameTemplate verifies that the ifNameTemplate rendered with the VF index is a valid interface name
+func checkIfNameTemplate(n *sriovtypes.NetConf) error {
+	if n.IfNameTemplate == "" {
+		return nil
+	}
+	if ifName := PodIfName(n, ""); len(ifName) > maxIfNameLen {
+		return fmt.Errorf("interface name %s rendered from ifNameTemplate %s is longer than %d characters", ifName, n.IfNameTemplate, maxIfNameLen)
+	}
+	return nil
+}
+
+// LoadConfFromCache retrieves cached NetConf returns it along with the cache for removal. The cache is
+// located by the cacheDir of the netconf the runtime passes to cmdDel and cmdCheck.
+func LoadConfFromCache(args *skel.CmdArgs) (*sriovtypes.NetConf, Cache, error) {
+	n := &sriovtypes.NetConf{}
+	// a netconf that cannot be parsed uses the default cache
+	_ = json.Unmarshal(args.StdinData, n)
+
+	cache := GetCache(n)
+	netConf, err := cache.Load(args.ContainerID, args.IfName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return netConf, cache, nil
+}
+
+// LoadAllConfsFromCache retrieves every cached NetConf, by the name of its cache file. The files that cannot
+// be parsed are logged and skipped.
+func LoadAllConfsFromCache() (map[string]*sriovtypes.NetConf, error) {
+	return loadAllConfsFromDir(DefaultCNIDir)
+}
+
+// ManagedVF is a VF configured by the plugin for a container interface, as recorded in the NetConf cache
+type ManagedVF struct {
+	DeviceID    string
+	ContainerID string
+	Netns       string
+	IfName      string
+}
+
+// ListManagedVFs returns the VFs of the NetConf cached in the default cache directory, ordered by container ID and
+// interface name. It only reads the cache, the netns is the one of the cached result or else of the PCI allocation.
+func ListManagedVFs() ([]ManagedVF, error) {
+	return listManagedVFs(DefaultCNIDir)
+}
+
+func listManagedVFs(dir string) ([]ManagedVF, error) {
+	netConfs, err := loadAllConfsFromDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		// nothing was cached yet
+		return []ManagedVF{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vfs := []ManagedVF{}
+	for name, netConf := range netConfs {
+		// the cache files are named <containerID>-<ifName>, the other records have no device ID
+		containerID, ifName, ok := strings.Cut(name, "-")
+		if !ok || netConf.DeviceID == "" {
+			continue
+		}
+		vf := ManagedVF{DeviceID: netConf.DeviceID, ContainerID: containerID, IfName: ifName}
+		if netConf.AddResult != nil && len(netConf.AddResult.Interfaces) > 0 {
+			vf.Netns = netConf.AddResult.Interfaces[0].Sandbox
+		} else if netns, err := os.ReadFile(filepath.Join(dir, "pci", netConf.DeviceID)); err == nil {
+			vf.Netns = string(netns)
+		}
+		vfs = append(vfs, vf)
+	}
+	sort.Slice(vfs, func(i, j int) bool {
+		if vfs[i].ContainerID != vfs[j].ContainerID {
+			return vfs[i].ContainerID < vfs[j].ContainerID
+		}
+		return vfs[i].IfName < vfs[j].IfName
+	})
+	return vfs, nil
+}
+
+// LoadSiblingMinTxRates returns the min_tx_rate of the other VFs of the PF of netConf cached in its cache
+// directory, by VF id
+func LoadSiblingMinTxRates(netConf *sriovtypes.NetConf) (map[int]int, error) {
+	rates := map[int]int{}
+	netConfs, err := loadAllConfsFromDir(CacheDir(netConf))
+	if errors.Is(err, os.ErrNotExist) {
+		// nothing was cached yet
+		return rates, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cached := range netConfs {
+		if cached.Master != netConf.Master || cached.VFID == netConf.VFID || cached.MinTxRate == nil {
+			continue
+		}
+		rates[cached.VFID] = *cached.MinTxRate
+	}
+	return rates, nil
+}
+
+func loadAllConfsFromDir(dir string) (map[string]*sriovtypes.NetConf, error) {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, "", fmt.Errorf("error reading cached NetConf in %s with name %s", DefaultCNIDir, cRef)
+		return nil, fmt.Errorf("failed to list cached NetConf in %s: %w", dir, err)
 	}
 
-	if err = json.Unmarshal(netConfBytes, netConf); err != nil {
-		return nil, "", fmt.Errorf("failed to parse NetConf: %q", err)
+	netConfs := map[string]*sriovtypes.NetConf{}
+	for _, entry := range entries {
+		// the pci allocations and the other records are kept in sub-directories
+		if entry.IsDir() {
+			continue
+		}
+		cRefPath := filepath.Join(dir, entry.Name())
+		netConfBytes, err := utils.ReadScratchNetConf(cRefPath)
+		if err != nil {
+			return nil, err
+		}
+
+		netConf, err := parseCachedNetConf(netConfBytes)
+		if err != nil {
+			logging.Warning("Skipping cached NetConf that cannot be parsed",
+				"func", "loadAllConfsFromDir",
+				"cRefPath", cRefPath,
+				"err", err)
+			continue
+		}
+		netConfs[entry.Name()] = netConf
 	}
 
-	return netConf, cRefPath, nil
+	return netConfs, nil
 }
 
 // GetMacAddressForResult return the mac address we should report to the CNI call return object
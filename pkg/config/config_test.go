@@ -1,10 +1,17 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/containernetworking/cni/pkg/skel"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/testutils"
+	cnilog "github.com/k8snetworkplumbingwg/cni-log"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -12,6 +19,29 @@ import (
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
 )
 
+// memoryCache is a Cache keeping the NetConf in memory
+type memoryCache struct {
+	netConfs map[string]*types.NetConf
+}
+
+func (c *memoryCache) Save(containerID, ifName string, netConf *types.NetConf) error {
+	c.netConfs[containerID+"-"+ifName] = netConf
+	return nil
+}
+
+func (c *memoryCache) Load(containerID, ifName string) (*types.NetConf, error) {
+	netConf, ok := c.netConfs[containerID+"-"+ifName]
+	if !ok {
+		return nil, fmt.Errorf("no cached NetConf for %s-%s", containerID, ifName)
+	}
+	return netConf, nil
+}
+
+func (c *memoryCache) Remove(containerID, ifName string) error {
+	delete(c.netConfs, containerID+"-"+ifName)
+	return nil
+}
+
 var _ = Describe("Config", func() {
 	Context("Checking LoadConf function", func() {
 		It("Assuming correct config file - existing DeviceID", func() {
@@ -163,6 +193,1720 @@ var _ = Describe("Config", func() {
 		})
 
 	})
+	Context("Checking LoadConf function - tx queue rate limits", func() {
+		DescribeTable("Queue rates",
+			func(queueRates string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "queueRates": %s
+                        }`, queueRates))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("valid queue rates", `[{"queue": 0, "maxRate": 100}, {"queue": 1, "maxRate": 0}]`, false),
+			Entry("negative queue index", `[{"queue": -1, "maxRate": 100}]`, true),
+			Entry("negative max rate", `[{"queue": 0, "maxRate": -100}]`, true),
+		)
+	})
+	Context("Checking LoadConf function - driver override", func() {
+		It("Assuming userspace driver override", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "driverOverride": "vfio-pci"
+                        }`)
+			netconf, err := LoadConf(conf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netconf.DPDKMode).To(BeTrue())
+		})
+		It("Assuming kernel driver override", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "driverOverride": "iavf"
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking LoadConf function - RSS hash key", func() {
+		DescribeTable("RSS hash key",
+			func(hashKey string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "rssHashKey": %q
+                        }`, hashKey))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("colon separated key", "6d:5a:56:da:25:5b:0e:c2", false),
+			Entry("plain hex key", "6d5a56da255b0ec2", false),
+			Entry("odd number of digits", "6d5a5", true),
+			Entry("non hex digits", "6d:5a:zz", true),
+		)
+	})
+	Context("Checking LoadConf function - RSS", func() {
+		DescribeTable("RSS hash key and indirection table",
+			func(rss string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "rss": %s
+                        }`, rss))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("hash key and indirection table", `{"hashKey": "6d:5a:56:da:25:5b:0e:c2", "indirTable": [0, 1, 2, 3]}`, false),
+			Entry("indirection table only", `{"indirTable": [0, 1]}`, false),
+			Entry("invalid hash key", `{"hashKey": "6d:5a:zz"}`, true),
+			Entry("indirection table length not a power of two", `{"indirTable": [0, 1, 2]}`, true),
+			Entry("negative queue", `{"indirTable": [0, -1]}`, true),
+		)
+		It("Rejects a hash key configured in both rss and rssHashKey", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "rssHashKey": "6d:5a:56:da:25:5b:0e:c2",
+        "rss": {"hashKey": "6d:5a:56:da:25:5b:0e:c2"}
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking LoadConf function - guest vlan tags", func() {
+		DescribeTable("Guest vlan tags",
+			func(vlan, vlanProto string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "allowGuestVlan": true
+        %s %s
+                        }`, vlan, vlanProto))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("untagged VF", "", "", false),
+			Entry("vlan 0", `, "vlan": 0`, "", false),
+			Entry("802.1ad vlan", `, "vlan": 100`, `, "vlanProto": "802.1AD"`, false),
+			Entry("802.1q vlan", `, "vlan": 100`, "", true),
+		)
+	})
+	Context("Checking LoadConf function - allmulticast", func() {
+		DescribeTable("Allmulticast",
+			func(allMulti string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "allMulti": %q
+                        }`, allMulti))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("allmulticast on", "on", false),
+			Entry("allmulticast off", "off", false),
+			Entry("invalid value", "true", true),
+		)
+	})
+	Context("Checking LoadConf function - reset scope", func() {
+		DescribeTable("Reset scope",
+			func(resetScope string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "resetScope": %q
+                        }`, resetScope))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("reset all", "all", false),
+			Entry("reset l3 only", "l3only", false),
+			Entry("reset l2 only", "l2only", false),
+			Entry("invalid scope", "l4only", true),
+		)
+	})
+	Context("Checking LoadConf function - MAC address read back", func() {
+		It("Rejects verifyMAC together with skipMACConfig", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "mac": "e4:11:22:33:44:55",
+        "verifyMAC": true,
+        "skipMACConfig": true
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(MatchError(ContainSubstring("verifyMAC cannot be combined with skipMACConfig")))
+		})
+	})
+	Context("Checking LoadConf function - interface name template", func() {
+		DescribeTable("Interface name template",
+			func(template, ifName string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "ifNameTemplate": %q
+                        }`, template))
+				netConf, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+					return
+				}
+				Expect(err).ToNot(HaveOccurred())
+				Expect(PodIfName(netConf, "net1")).To(Equal(ifName))
+			},
+			Entry("no template", "", "net1", false),
+			Entry("VF index", "sriov%d", "sriov1", false),
+			Entry("fixed name", "data0", "data0", false),
+			Entry("longest name", "vf-uplink-abc%d", "vf-uplink-abc1", false),
+			Entry("name longer than IFNAMSIZ", "vf-uplink-abcde%d", "", true),
+			Entry("slash", "net/%d", "", true),
+			Entry("other substitution", "net%s", "", true),
+		)
+	})
+	Context("Checking LoadConf function - full reset", func() {
+		DescribeTable("Full reset",
+			func(resetScope string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "fullReset": true,
+        "resetScope": %q
+                        }`, resetScope))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("default scope", "", false),
+			Entry("reset l2 only", "l2only", false),
+			Entry("reset l3 only", "l3only", true),
+		)
+	})
+	Context("Checking LoadConf function - InfiniBand GUID", func() {
+		DescribeTable("GUID",
+			func(guid, mac string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "guid": %q,
+        "mac": %q
+                        }`, guid, mac))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("valid GUID", "00:11:22:33:44:55:66:77", "", false),
+			Entry("GUID with 6 bytes", "00:11:22:33:44:55", "", true),
+			Entry("GUID with dot separators", "0011.2233.4455.6677", "", true),
+			Entry("GUID and MAC", "00:11:22:33:44:55:66:77", "aa:f3:8d:65:1b:d4", true),
+		)
+	})
+	Context("Checking LoadConf function - MAC change limit", func() {
+		DescribeTable("Max MAC changes",
+			func(maxMacChanges int, trust string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "trust": %q,
+        "maxMacChanges": %d
+                        }`, trust, maxMacChanges))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("limit on trusted VF", 3, "on", false),
+			Entry("limit on untrusted VF", 3, "off", true),
+			Entry("negative limit", -1, "on", true),
+		)
+	})
+	Context("Checking LoadConf function - runtimeConfig MAC", func() {
+		DescribeTable("RuntimeConfig MAC",
+			func(mac string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "runtimeConfig": {"mac": %q}
+                        }`, mac))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("unicast MAC", "02:11:22:33:44:55", false),
+			Entry("multicast MAC", "01:00:5e:00:00:01", true),
+			Entry("broadcast MAC", "ff:ff:ff:ff:ff:ff", true),
+			Entry("zero MAC", "00:00:00:00:00:00", true),
+			Entry("malformed MAC", "02:11:22:33:44", true),
+		)
+	})
+	Context("Checking LoadConf function - runtimeConfig vlan", func() {
+		DescribeTable("RuntimeConfig vlan",
+			func(vlan, runtimeVlan string, expectedVlan int, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        %s
+        "runtimeConfig": {"vlan": %s}
+                        }`, vlan, runtimeVlan))
+				n, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+					return
+				}
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n.Vlan).NotTo(BeNil())
+				Expect(*n.Vlan).To(Equal(expectedVlan))
+				Expect(*n.VlanQoS).To(Equal(0))
+			},
+			Entry("overrides the netconf vlan", `"vlan": 100,`, "200", 200, false),
+			Entry("sets the vlan of a netconf without vlan", ``, "300", 300, false),
+			Entry("vlan 0", `"vlan": 100,`, "0", 0, true),
+			Entry("vlan out of range", ``, "4095", 0, true),
+		)
+		It("Keeps the netconf vlan without runtimeConfig vlan", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "vlan": 100,
+        "runtimeConfig": {"mac": "02:11:22:33:44:55"}
+                        }`)
+			n, err := LoadConf(conf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*n.Vlan).To(Equal(100))
+		})
+		It("Rejects an invalid runtimeConfig vlan on VALIDATE", func() {
+			Expect(ValidateConf([]byte(`{"name": "mynet", "type": "sriov", "deviceID": "0000:af:06.1", "runtimeConfig": {"vlan": 5000}}`))).
+				To(MatchError(ContainSubstring("invalid runtimeConfig vlan 5000")))
+		})
+	})
+	Context("Checking LoadConf function - max tx rate ceiling", func() {
+		DescribeTable("Enforce rate ceiling",
+			func(policy string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "max_tx_rate": 1000,
+        "enforceRateCeiling": %q
+                        }`, policy))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("reject policy", "reject", false),
+			Entry("clamp policy", "clamp", false),
+			Entry("invalid policy", "ignore", true),
+		)
+	})
+	Context("Checking LoadConf function - drain delay", func() {
+		DescribeTable("Drain delay",
+			func(delay int, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "drainDelay": %d
+                        }`, delay))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("no delay", 0, false),
+			Entry("valid delay", 5000, false),
+			Entry("maximum delay", 30000, false),
+			Entry("negative delay", -1, true),
+			Entry("delay above the maximum", 30001, true),
+		)
+	})
+	Context("Checking LoadConf function - signal down on DEL", func() {
+		DescribeTable("Signal down on DEL",
+			func(delay int, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "signalDownOnDel": true,
+        "drainDelay": %d
+                        }`, delay))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("without drain delay", 0, false),
+			Entry("with drain delay", 5000, true),
+		)
+	})
+	Context("Checking LoadConf function - VLAN egress QoS map", func() {
+		DescribeTable("VLAN egress QoS map",
+			func(qosMap string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "vlan": 100,
+        "vlanQoS": 2,
+        "egressQoSMap": %q
+                        }`, qosMap))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("valid map", "0:1,2:3", false),
+			Entry("priority out of range", "8:1", true),
+			Entry("pcp out of range", "0:9", true),
+			Entry("malformed entry", "0-1", true),
+		)
+	})
+	Context("Checking LoadConf function - IRQ affinity", func() {
+		It("Assuming irqAffinity on a VF bound to a userspace driver", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "driverOverride": "vfio-pci",
+        "irqAffinity": true
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(MatchError(ContainSubstring("irqAffinity cannot be set")))
+		})
+	})
+	Context("Checking LoadConf function - FDB VNI", func() {
+		DescribeTable("FDB VNI",
+			func(vni int, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "fdbVni": %d
+                        }`, vni))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("valid VNI", 10100, false),
+			Entry("largest VNI", 16777215, false),
+			Entry("zero VNI", 0, true),
+			Entry("VNI above 24 bits", 16777216, true),
+		)
+	})
+	Context("Checking LoadConf function - vlan exclusivity", func() {
+		It("Assuming enforceVlanExclusivity without vlan", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "enforceVlanExclusivity": true
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(MatchError(ContainSubstring("enforceVlanExclusivity requires a non-zero vlan")))
+		})
+	})
+	Context("Checking LoadConf function - log format", func() {
+		DescribeTable("Log format",
+			func(logFormat string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "logFormat": %q
+                        }`, logFormat))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(MatchError(ContainSubstring("invalid logFormat")))
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("text", "text", false),
+			Entry("json", "json", false),
+			Entry("unset", "", false),
+			Entry("invalid", "xml", true),
+		)
+	})
+	Context("Checking LoadConf function - level files", func() {
+		DescribeTable("Level files",
+			func(levelFiles string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "levelFiles": %s
+                        }`, levelFiles))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("valid levels", `{"error": "/tmp/sriov-error.log", "debug": "/tmp/sriov-debug.log"}`, false),
+			Entry("invalid level", `{"verbose": "/tmp/sriov-verbose.log"}`, true),
+			Entry("empty file", `{"error": ""}`, true),
+		)
+	})
+	Context("Checking LoadConf function - log options", func() {
+		DescribeTable("Log options",
+			func(logOptions string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "logOptions": %s
+                        }`, logOptions))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("valid options", `{"maxSize": 10, "maxAge": 1, "maxBackups": 0, "compress": false}`, false),
+			Entry("no options", `{}`, false),
+			Entry("negative maxSize", `{"maxSize": -1}`, true),
+			Entry("negative maxAge", `{"maxAge": -1}`, true),
+			Entry("negative maxBackups", `{"maxBackups": -2}`, true),
+		)
+	})
+	Context("Checking LoadConf function - passthrough", func() {
+		DescribeTable("Passthrough",
+			func(attributes string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "passthrough": true%s
+                        }`, attributes))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("no VF attribute", ``, false),
+			Entry("with ipam", `, "ipam": {"type": "host-local"}`, false),
+			Entry("with vlan", `, "vlan": 100`, true),
+			Entry("with mac", `, "mac": "0a:00:00:00:00:01"`, true),
+			Entry("with max_tx_rate", `, "max_tx_rate": 1000`, true),
+			Entry("with spoofchk", `, "spoofchk": "off"`, true),
+			Entry("with trust", `, "trust": "on"`, true),
+			Entry("with link_state", `, "link_state": "enable"`, true),
+			Entry("with mode", `, "mode": "macvlan-host"`, true),
+		)
+	})
+	Context("Checking LoadConf function - secondary MAC addresses", func() {
+		DescribeTable("Alt MACs",
+			func(mac, altMACs string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "mac": %q,
+        "altMACs": %s
+                        }`, mac, altMACs))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("valid secondary MACs", "02:00:00:00:00:01", `["02:00:00:00:00:02", "02:00:00:00:00:03"]`, false),
+			Entry("invalid secondary MAC", "02:00:00:00:00:01", `["02:00:00:00:00"]`, true),
+			Entry("secondary MAC duplicating the primary MAC", "02:00:00:00:00:01", `["02:00:00:00:00:01"]`, true),
+			Entry("secondary MAC duplicating the primary MAC in another case", "0a:00:00:00:00:01", `["0A:00:00:00:00:01"]`, true),
+			Entry("duplicated secondary MACs", "", `["02:00:00:00:00:02", "02:00:00:00:00:02"]`, true),
+		)
+	})
+	Context("Checking LoadConf function - link up timeout", func() {
+		DescribeTable("Link up timeout",
+			func(timeout int, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "waitForLinkUp": true,
+        "linkUpTimeout": %d
+                        }`, timeout))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("valid timeout", 10, false),
+			Entry("zero timeout", 0, true),
+			Entry("negative timeout", -1, true),
+		)
+	})
+	Context("Checking LoadConf function - netlink timeout", func() {
+		DescribeTable("Netlink timeout",
+			func(timeout int, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "netlinkTimeout": %d
+                        }`, timeout))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("valid timeout", 30, false),
+			Entry("zero timeout", 0, true),
+			Entry("negative timeout", -1, true),
+		)
+	})
+	Context("Checking LoadConf function - ingress policing", func() {
+		DescribeTable("Ingress police",
+			func(police string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "ingressPolice": %s
+                        }`, police))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("valid rate and burst", `{"rate": 1000, "burst": 65536}`, false),
+			Entry("maximum rate", `{"rate": 34359, "burst": 65536}`, false),
+			Entry("rate above the maximum", `{"rate": 34360, "burst": 65536}`, true),
+			Entry("missing rate", `{"burst": 65536}`, true),
+			Entry("missing burst", `{"rate": 1000}`, true),
+			Entry("negative burst", `{"rate": 1000, "burst": -1}`, true),
+		)
+	})
+	Context("Checking LoadConf function - macvlan-host mode", func() {
+		It("Sets spoofchk off and trust on", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "mode": "macvlan-host"
+                        }`)
+			netconf, err := LoadConf(conf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netconf.SpoofChk).To(Equal("off"))
+			Expect(netconf.Trust).To(Equal("on"))
+		})
+		DescribeTable("Conflicting values",
+			func(spoofchk, trust string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "mode": "macvlan-host",
+        "spoofchk": %q,
+        "trust": %q
+                        }`, spoofchk, trust))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("matching values", "off", "on", false),
+			Entry("spoofchk on", "on", "on", true),
+			Entry("trust off", "off", "off", true),
+		)
+		It("Rejects an unknown mode", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "mode": "macvlan"
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking LoadConf function - private flags", func() {
+		DescribeTable("Validates the private flags",
+			func(privFlags string, extra string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "privFlags": %s%s
+                        }`, privFlags, extra))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("valid flags", `{"vf-true-promisc-support": true, "legacy-rx": false}`, "", false),
+			Entry("empty flag name", `{"": true}`, "", true),
+			Entry("with driverOverride", `{"legacy-rx": true}`, `,
+        "driverOverride": "vfio-pci"`, true),
+		)
+	})
+	Context("Checking LoadConf function - microburst protection", func() {
+		It("Rejects microburstProtection with driverOverride", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "driverOverride": "vfio-pci",
+        "microburstProtection": true
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking LoadConf function - representor vlan", func() {
+		DescribeTable("Validates the representor vlan",
+			func(repVlan int, vlan int, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "representorVlan": %d,
+        "vlan": %d
+                        }`, repVlan, vlan))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("valid vlan", 100, 0, false),
+			Entry("vlan 0", 0, 0, true),
+			Entry("vlan out of range", 4095, 0, true),
+			Entry("with a VF vlan", 100, 200, true),
+		)
+	})
+	Context("Checking LoadConf function - managed representor", func() {
+		DescribeTable("Validates the link state of a managed representor",
+			func(linkState string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "manageRepresentor": true,
+        "link_state": "%s"
+                        }`, linkState))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("link state enable", "enable", false),
+			Entry("link state auto", "auto", true),
+			Entry("link state disable", "disable", true),
+		)
+	})
+	Context("Checking LoadConf function - scheduler capacity", func() {
+		DescribeTable("Validates the scheduler capacity policy",
+			func(policy string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "min_tx_rate": 1000,
+        "enforceSchedulerCapacity": %q
+                        }`, policy))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("warn", "warn", false),
+			Entry("reject", "reject", false),
+			Entry("unknown policy", "clamp", true),
+		)
+	})
+	Context("Checking LoadConf function - interface sysctls", func() {
+		DescribeTable("Validates the sysctl names",
+			func(name string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "sysctls": {%q: "1"}
+                        }`, name))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("IPv4 conf", "net.ipv4.conf.IFNAME.arp_ignore", false),
+			Entry("IPv6 conf", "net.ipv6.conf.IFNAME.accept_ra", false),
+			Entry("IPv4 neigh", "net.ipv4.neigh.IFNAME.gc_stale_time", false),
+			Entry("IPv6 neigh", "net.ipv6.neigh.IFNAME.gc_stale_time", true),
+			Entry("literal interface name", "net.ipv6.conf.net1.accept_ra", true),
+			Entry("all interfaces", "net.ipv4.conf.all.forwarding", true),
+			Entry("global sysctl", "net.ipv4.ip_forward", true),
+			Entry("path traversal", "net.ipv4.conf.IFNAME.../../kernel", true),
+		)
+	})
+	Context("Checking LoadConf function - neighbor table thresholds", func() {
+		DescribeTable("Validates the thresholds",
+			func(neigh string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "neigh": %s
+                        }`, neigh))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(MatchError(ContainSubstring("invalid neigh")))
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("all thresholds", `{"gcThresh1": 1024, "gcThresh2": 4096, "gcThresh3": 8192}`, false),
+			Entry("equal thresholds", `{"gcThresh1": 4096, "gcThresh2": 4096}`, false),
+			Entry("only gcThresh3", `{"gcThresh3": 8192}`, false),
+			Entry("no threshold", `{}`, true),
+			Entry("decreasing thresholds", `{"gcThresh2": 4096, "gcThresh3": 1024}`, true),
+			Entry("decreasing thresholds around an unset one", `{"gcThresh1": 4096, "gcThresh3": 1024}`, true),
+			Entry("negative threshold", `{"gcThresh1": -1}`, true),
+			Entry("threshold above the int range", `{"gcThresh3": 2147483648}`, true),
+		)
+	})
+	Context("Checking LoadConf function - ingress QoS map", func() {
+		DescribeTable("Validates the mappings",
+			func(qosMap string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "ingressQoSMap": %s
+                        }`, qosMap))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("dscp and pcp mappings", `{"dscp": "10:1, 46:5", "pcp": "1:1,5:2"}`, false),
+			Entry("only dscp mappings", `{"dscp": "63:7"}`, false),
+			Entry("no mapping", `{}`, true),
+			Entry("dscp out of range", `{"dscp": "64:1"}`, true),
+			Entry("priority out of range", `{"dscp": "10:8"}`, true),
+			Entry("traffic class out of range", `{"pcp": "1:8"}`, true),
+			Entry("pcp mapped twice", `{"pcp": "1:1,1:2"}`, true),
+			Entry("malformed mapping", `{"dscp": "10"}`, true),
+		)
+	})
+	Context("Checking LoadConf function - ETS bandwidth allocation", func() {
+		DescribeTable("Validates the allocation",
+			func(ets string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "ets": %s
+                        }`, ets))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(MatchError(ContainSubstring("invalid ets")))
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("two traffic classes", `[{"tc": 0, "bandwidthPercent": 60}, {"tc": 1, "bandwidthPercent": 40}]`, false),
+			Entry("a single traffic class", `[{"tc": 7, "bandwidthPercent": 100}]`, false),
+			Entry("percentages below 100", `[{"tc": 0, "bandwidthPercent": 60}, {"tc": 1, "bandwidthPercent": 30}]`, true),
+			Entry("percentages above 100", `[{"tc": 0, "bandwidthPercent": 60}, {"tc": 1, "bandwidthPercent": 50}]`, true),
+			Entry("traffic class out of range", `[{"tc": 8, "bandwidthPercent": 100}]`, true),
+			Entry("traffic class set twice", `[{"tc": 1, "bandwidthPercent": 50}, {"tc": 1, "bandwidthPercent": 50}]`, true),
+			Entry("negative percentage", `[{"tc": 0, "bandwidthPercent": 110}, {"tc": 1, "bandwidthPercent": -10}]`, true),
+		)
+	})
+	Context("Checking LoadConf function - static routes", func() {
+		DescribeTable("Validates the routes",
+			func(routes, ipam string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "routes": %s%s
+                        }`, routes, ipam))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("route with gateway", `[{"dst": "10.0.0.0/8", "gw": "192.168.1.1"}]`, `, "ipam": {"type": "static"}`, false),
+			Entry("direct route", `[{"dst": "fd01::/64"}]`, `, "ipam": {"type": "static"}`, false),
+			Entry("dst without prefix length", `[{"dst": "10.0.0.1"}]`, `, "ipam": {"type": "static"}`, true),
+			Entry("invalid gateway", `[{"dst": "10.0.0.0/8", "gw": "192.168.1"}]`, `, "ipam": {"type": "static"}`, true),
+			Entry("gateway of another family", `[{"dst": "10.0.0.0/8", "gw": "fd00::1"}]`, `, "ipam": {"type": "static"}`, true),
+			Entry("without ipam", `[{"dst": "10.0.0.0/8", "gw": "192.168.1.1"}]`, "", true),
+		)
+	})
+	Context("Checking LoadConf function - route table", func() {
+		DescribeTable("Validates the route table",
+			func(table int, ipam string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "routeTable": %d%s
+                        }`, table, ipam))
+				_, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("valid table", 100, `,
+        "ipam": {"type": "host-local"}`, false),
+			Entry("largest table", 4294967295, `,
+        "ipam": {"type": "host-local"}`, false),
+			Entry("table 0", 0, `,
+        "ipam": {"type": "host-local"}`, true),
+			Entry("main table", 254, `,
+        "ipam": {"type": "host-local"}`, true),
+			Entry("table out of range", 4294967296, `,
+        "ipam": {"type": "host-local"}`, true),
+			Entry("without ipam", 100, "", true),
+		)
+	})
+	Context("Checking LoadConf function - rebind on DEL", func() {
+		It("Rejects rebindOnDel without driverOverride", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "rebindOnDel": false
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking LoadConf function - VFID cross-check", func() {
+		It("Assuming a VFID matching the deviceID", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "VFID": 1
+                        }`)
+			netconf, err := LoadConf(conf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.VFID).To(Equal(1))
+		})
+		It("Assuming a VFID not matching the deviceID", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "VFID": 0
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(MatchError(ContainSubstring("VFID 0 does not match the deviceID VF, which is VF 1")))
+		})
+	})
+	Context("Checking LoadConf function - tx queue length", func() {
+		DescribeTable("Validates txQLen",
+			func(txQLen int, fields string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "txQLen": %d%s
+                        }`, txQLen, fields))
+				netConf, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(*netConf.TxQLen).To(Equal(txQLen))
+				}
+			},
+			Entry("valid length", 10000, "", false),
+			Entry("zero length", 0, "", false),
+			Entry("negative length", -1, "", true),
+			Entry("VF bound to a userspace driver", 10000, `, "driverOverride": "vfio-pci"`, true),
+		)
+	})
+	Context("Checking LoadConf function - keep in host netns", func() {
+		DescribeTable("Validates keepInHostNetns",
+			func(fields string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "keepInHostNetns": true%s
+                        }`, fields))
+				netConf, err := LoadConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(netConf.KeepsInHostNetns()).To(BeTrue())
+				}
+			},
+			Entry("with ifNameTemplate", `, "ifNameTemplate": "sriov%d"`, false),
+			Entry("without ifNameTemplate", "", true),
+			Entry("with driverOverride", `, "ifNameTemplate": "sriov%d", "driverOverride": "vfio-pci"`, true),
+			Entry("with neigh", `, "ifNameTemplate": "sriov%d", "neigh": {"gcThresh1": 128}`, true),
+		)
+	})
+	Context("Checking LoadBondConf function", func() {
+		DescribeTable("Bond",
+			func(bond string, attributes string, failure bool) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "bond": %s%s
+                        }`, bond, attributes))
+				_, err := LoadBondConf(conf)
+				if failure {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).ToNot(HaveOccurred())
+				}
+			},
+			Entry("active-backup", `{"mode": "active-backup", "members": ["0000:af:06.0", "0000:af:06.1"]}`, ``, false),
+			Entry("802.3ad with ifName", `{"mode": "802.3ad", "members": ["0000:af:06.0", "0000:af:06.1"], "ifName": "bond0"}`, ``, false),
+			Entry("unknown mode", `{"mode": "balance-rr", "members": ["0000:af:06.0", "0000:af:06.1"]}`, ``, true),
+			Entry("single member", `{"mode": "active-backup", "members": ["0000:af:06.0"]}`, ``, true),
+			Entry("duplicated member", `{"mode": "active-backup", "members": ["0000:af:06.0", "0000:af:06.0"]}`, ``, true),
+			Entry("too long ifName", `{"mode": "active-backup", "members": ["0000:af:06.0", "0000:af:06.1"], "ifName": "bond0123456789ab"}`, ``, true),
+			Entry("with deviceID", `{"mode": "active-backup", "members": ["0000:af:06.0", "0000:af:06.1"]}`, `, "deviceID": "0000:af:06.0"`, true),
+			Entry("with driverOverride", `{"mode": "active-backup", "members": ["0000:af:06.0", "0000:af:06.1"]}`, `, "driverOverride": "vfio-pci"`, true),
+			Entry("with mac", `{"mode": "active-backup", "members": ["0000:af:06.0", "0000:af:06.1"]}`, `, "mac": "0a:00:00:00:00:01"`, true),
+			Entry("with runtime mac", `{"mode": "active-backup", "members": ["0000:af:06.0", "0000:af:06.1"]}`, `, "runtimeConfig": {"mac": "0a:00:00:00:00:01"}`, true),
+		)
+	})
+	Context("Checking LoadBondMemberConfs function", func() {
+		It("Loads each member as the deviceID of the netconf", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "vlan": 100,
+        "bond": {"mode": "active-backup", "members": ["0000:af:06.0", "0000:af:06.1"]}
+                        }`)
+			Expect(IsBondConf(conf)).To(BeTrue())
+			confs, err := LoadBondMemberConfs(conf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(HaveLen(2))
+			Expect(confs[0].DeviceID).To(Equal("0000:af:06.0"))
+			Expect(confs[0].VFID).To(Equal(0))
+			Expect(confs[1].DeviceID).To(Equal("0000:af:06.1"))
+			Expect(confs[1].VFID).To(Equal(1))
+			for _, c := range confs {
+				Expect(c.Bond).To(BeNil())
+				Expect(c.Master).To(Equal("enp175s0f1"))
+				Expect(*c.Vlan).To(Equal(100))
+			}
+		})
+		It("Names the members after the bond", func() {
+			bondConf := &types.NetConf{SriovNetConf: types.SriovNetConf{Bond: &types.Bond{IfName: "bond0"}}}
+			Expect(BondIfName(bondConf, "net1")).To(Equal("bond0"))
+			Expect(BondIfName(&types.NetConf{}, "net1")).To(Equal("net1"))
+			ifName, err := BondMemberIfName("bond0", 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ifName).To(Equal("bond0_1"))
+			_, err = BondMemberIfName("bond0123456789", 1)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Does not consider a netconf without bond as a bond", func() {
+			Expect(IsBondConf([]byte(`{"name": "mynet", "type": "sriov", "deviceID": "0000:af:06.1"}`))).To(BeFalse())
+		})
+	})
+	Context("Checking LoadRequestedConf function", func() {
+		var cached *types.NetConf
+
+		BeforeEach(func() {
+			cached = &types.NetConf{SriovNetConf: types.SriovNetConf{
+				DeviceID:    "0000:af:06.1",
+				Master:      "enp175s0f1",
+				VFID:        1,
+				OrigVfState: types.VfState{HostIFName: "enp175s6"},
+			}}
+		})
+
+		It("Takes the VF details from the cached NetConf", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "vlan": 100
+                        }`)
+			netconf, err := LoadRequestedConf(conf, cached)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netconf.Master).To(Equal("enp175s0f1"))
+			Expect(netconf.VFID).To(Equal(1))
+			Expect(netconf.DPDKMode).To(BeFalse())
+			Expect(*netconf.VlanQoS).To(Equal(0))
+			Expect(*netconf.VlanProto).To(Equal(types.Proto8021q))
+		})
+		It("Rejects another VF than the cached one", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.0"
+                        }`)
+			_, err := LoadRequestedConf(conf, cached)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking LoadAllConfsFromCache function", func() {
+		It("Loads every cached NetConf and skips the other files", func() {
+			tmpdir, err := os.MkdirTemp("/tmp", "sriovplugin-cache-test-")
+			Expect(err).ShouldNot(HaveOccurred())
+			origCNIDir := DefaultCNIDir
+			DefaultCNIDir = tmpdir
+			defer func() {
+				DefaultCNIDir = origCNIDir
+				os.RemoveAll(tmpdir)
+			}()
+
+			netconf := &types.NetConf{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.1", Master: "enp175s0f1", VFID: 1}}
+			Expect(utils.SaveNetConf("container1", tmpdir, "net1", netconf)).To(Succeed())
+			Expect(utils.SaveNetConf("container2", tmpdir, "net1", netconf)).To(Succeed())
+			Expect(utils.NewPCIAllocator(tmpdir).SaveAllocatedPCI("0000:af:06.1", "/proc/self/ns/net")).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(tmpdir, "container3-net1"), []byte("{"), 0600)).To(Succeed())
+
+			netConfs, err := LoadAllConfsFromCache()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netConfs).To(HaveLen(2))
+			Expect(netConfs).To(HaveKey("container1-net1"))
+			Expect(netConfs["container2-net1"].DeviceID).To(Equal("0000:af:06.1"))
+		})
+
+		It("Does not load the MAC registry as a cached NetConf, for the maintenance commands", func() {
+			tmpdir, err := os.MkdirTemp("/tmp", "sriovplugin-cache-test-")
+			Expect(err).ShouldNot(HaveOccurred())
+			origCNIDir := DefaultCNIDir
+			DefaultCNIDir = tmpdir
+			defer func() {
+				DefaultCNIDir = origCNIDir
+				os.RemoveAll(tmpdir)
+			}()
+
+			netconf := &types.NetConf{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.1", Master: "enp175s0f1", VFID: 1, MAC: "0a:00:00:00:00:01"}}
+			Expect(utils.SaveNetConf("container1", tmpdir, "net1", netconf)).To(Succeed())
+			Expect(utils.NewMACRegistry(tmpdir).Reserve(netconf.MAC, netconf.DeviceID)).To(Succeed())
+
+			netConfs, err := LoadAllConfsFromCache()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netConfs).To(HaveLen(1))
+			Expect(netConfs["container1-net1"].Master).To(Equal("enp175s0f1"))
+		})
+
+		It("Does not load the node lock as a cached NetConf", func() {
+			tmpdir, err := os.MkdirTemp("/tmp", "sriovplugin-cache-test-")
+			Expect(err).ShouldNot(HaveOccurred())
+			origCNIDir := DefaultCNIDir
+			DefaultCNIDir = tmpdir
+			defer func() {
+				DefaultCNIDir = origCNIDir
+				os.RemoveAll(tmpdir)
+			}()
+
+			netconf := &types.NetConf{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.1", Master: "enp175s0f1", VFID: 1}}
+			Expect(utils.SaveNetConf("container1", tmpdir, "net1", netconf)).To(Succeed())
+			lock := utils.NewNodeLock(tmpdir)
+			Expect(lock.Lock()).To(Succeed())
+
+			netConfs, err := LoadAllConfsFromCache()
+			Expect(lock.Unlock()).To(Succeed())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netConfs).To(HaveLen(1))
+			Expect(netConfs).To(HaveKey("container1-net1"))
+		})
+	})
+	Context("Checking ListManagedVFs function", func() {
+		It("Lists the VFs of the cached NetConf", func() {
+			tmpdir, err := os.MkdirTemp("/tmp", "sriovplugin-cache-test-")
+			Expect(err).ShouldNot(HaveOccurred())
+			origCNIDir := DefaultCNIDir
+			DefaultCNIDir = tmpdir
+			defer func() {
+				DefaultCNIDir = origCNIDir
+				os.RemoveAll(tmpdir)
+			}()
+
+			withResult := &types.NetConf{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.1", Master: "enp175s0f1", VFID: 1,
+				AddResult: &current.Result{Interfaces: []*current.Interface{{Name: "net1", Sandbox: "/var/run/netns/pod1"}}}}}
+			Expect(utils.SaveNetConf("container2", tmpdir, "net1", withResult)).To(Succeed())
+			withoutResult := &types.NetConf{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.2", Master: "enp175s0f1", VFID: 2}}
+			Expect(utils.SaveNetConf("container1", tmpdir, "net-fast", withoutResult)).To(Succeed())
+			Expect(utils.NewPCIAllocator(tmpdir).SaveAllocatedPCI("0000:af:06.2", "/var/run/netns/pod2")).To(Succeed())
+			Expect(utils.NewMACRegistry(tmpdir).Reserve("0a:00:00:00:00:01", "0000:af:06.1")).To(Succeed())
+
+			vfs, err := ListManagedVFs()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vfs).To(Equal([]ManagedVF{
+				{DeviceID: "0000:af:06.2", ContainerID: "container1", Netns: "/var/run/netns/pod2", IfName: "net-fast"},
+				{DeviceID: "0000:af:06.1", ContainerID: "container2", Netns: "/var/run/netns/pod1", IfName: "net1"},
+			}))
+		})
+		It("Returns no VF when nothing was cached", func() {
+			origCNIDir := DefaultCNIDir
+			DefaultCNIDir = "/tmp/sriovplugin-missing-cache"
+			defer func() { DefaultCNIDir = origCNIDir }()
+
+			vfs, err := ListManagedVFs()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vfs).To(BeEmpty())
+		})
+	})
+	Context("Checking LoadSiblingMinTxRates function", func() {
+		It("Returns the cached min_tx_rate of the other VFs of the PF", func() {
+			tmpdir, err := os.MkdirTemp("/tmp", "sriovplugin-cache-test-")
+			Expect(err).ShouldNot(HaveOccurred())
+			defer os.RemoveAll(tmpdir)
+
+			rate1, rate2, rate3 := 1000, 2000, 3000
+			cached := []*types.NetConf{
+				{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.1", Master: "enp175s0f1", VFID: 1, MinTxRate: &rate1}},
+				{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.2", Master: "enp175s0f1", VFID: 2, MinTxRate: &rate2}},
+				{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.3", Master: "enp175s0f1", VFID: 3}},
+				{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:0e.1", Master: "enp175s0f0", VFID: 1, MinTxRate: &rate3}},
+			}
+			for i, netconf := range cached {
+				Expect(utils.SaveNetConf(fmt.Sprintf("container%d", i), tmpdir, "net1", netconf)).To(Succeed())
+			}
+
+			netconf := &types.NetConf{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.2", Master: "enp175s0f1", VFID: 2, CacheDir: tmpdir}}
+			rates, err := LoadSiblingMinTxRates(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rates).To(Equal(map[int]int{1: 1000}))
+		})
+
+		It("Returns no rate when nothing was cached yet", func() {
+			netconf := &types.NetConf{SriovNetConf: types.SriovNetConf{Master: "enp175s0f1", CacheDir: "/tmp/sriovplugin-no-such-cache"}}
+			rates, err := LoadSiblingMinTxRates(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rates).To(BeEmpty())
+		})
+	})
+	Context("Checking the binary state file of the cache", func() {
+		var (
+			tmpdir  string
+			netconf *types.NetConf
+		)
+
+		BeforeEach(func() {
+			var err error
+			tmpdir, err = os.MkdirTemp("/tmp", "sriovplugin-cache-test-")
+			Expect(err).ShouldNot(HaveOccurred())
+
+			netconf = &types.NetConf{}
+			Expect(json.Unmarshal([]byte(cachedNetConfJSON), netconf)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tmpdir)
+		})
+
+		It("Loads the same NetConf as the JSON file", func() {
+			Expect(NewCache(tmpdir).Save("container1", "net1", netconf)).To(Succeed())
+			fromJSON, err := NewCache(tmpdir).Load("container1", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fromJSON.BinaryCache).To(BeTrue())
+
+			fromState, err := loadState(filepath.Join(tmpdir, "state", "container1-net1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fromState).To(Equal(fromJSON))
+			Expect(*fromState.Vlan).To(Equal(0))
+			Expect(*fromState.AddResult.IPs[0].Interface).To(Equal(0))
+		})
+		It("Loads the binary state file on DEL", func() {
+			Expect(NewCache(tmpdir).Save("container1", "net1", netconf)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(tmpdir, "container1-net1"), []byte("{"), 0600)).To(Succeed())
+
+			args := &skel.CmdArgs{
+				ContainerID: "container1",
+				IfName:      "net1",
+				StdinData:   []byte(fmt.Sprintf(`{"name": "mynet", "type": "sriov", "cacheDir": %q}`, tmpdir)),
+			}
+			cached, cache, err := LoadConfFromCache(args)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached.DeviceID).To(Equal("0000:af:06.1"))
+
+			Expect(cache.Remove("container1", "net1")).To(Succeed())
+			_, err = os.Stat(filepath.Join(tmpdir, "state", "container1-net1"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+		It("Falls back to the JSON file without binary state file", func() {
+			netconf.BinaryCache = false
+			Expect(NewCache(tmpdir).Save("container1", "net1", netconf)).To(Succeed())
+			_, err := os.Stat(filepath.Join(tmpdir, "state", "container1-net1"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			cached, err := NewCache(tmpdir).Load("container1", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached.DeviceID).To(Equal("0000:af:06.1"))
+		})
+	})
+	Context("Checking the schema version of the cache", func() {
+		var tmpdir string
+
+		// v1NetConf is a NetConf cached before the cache format was versioned
+		v1NetConf := `{"name": "mynet", "type": "sriov", "deviceID": "0000:af:06.1", "OldMaster": "enp175s0f1", "VFID": 1}`
+
+		BeforeEach(func() {
+			var err error
+			tmpdir, err = os.MkdirTemp("/tmp", "sriovplugin-cache-test-")
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tmpdir)
+		})
+
+		// useHypotheticalV2 makes the cache format version 2, which renames the OldMaster key of version 1 to Master
+		useHypotheticalV2 := func() {
+			cacheSchemaVersion = 2
+			cacheMigrations[1] = func(netConf map[string]interface{}) error {
+				if master, ok := netConf["OldMaster"]; ok {
+					netConf["Master"] = master
+					delete(netConf, "OldMaster")
+				}
+				return nil
+			}
+			DeferCleanup(func() {
+				cacheSchemaVersion = 1
+				delete(cacheMigrations, 1)
+			})
+		}
+
+		It("Writes the schema version in the cached NetConf", func() {
+			netconf := &types.NetConf{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.1"}}
+			Expect(NewCache(tmpdir).Save("container1", "net1", netconf)).To(Succeed())
+			data, err := os.ReadFile(filepath.Join(tmpdir, "container1-net1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring(`"SchemaVersion":1`))
+		})
+		It("Loads a NetConf without schema version as version 1", func() {
+			Expect(os.WriteFile(filepath.Join(tmpdir, "container1-net1"), []byte(v1NetConf), 0600)).To(Succeed())
+			cached, err := NewCache(tmpdir).Load("container1", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached.SchemaVersion).To(Equal(1))
+			Expect(cached.DeviceID).To(Equal("0000:af:06.1"))
+			Expect(cached.VFID).To(Equal(1))
+		})
+		It("Migrates a version 1 NetConf to version 2", func() {
+			useHypotheticalV2()
+			Expect(os.WriteFile(filepath.Join(tmpdir, "container1-net1"), []byte(v1NetConf), 0600)).To(Succeed())
+			cached, err := NewCache(tmpdir).Load("container1", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached.SchemaVersion).To(Equal(2))
+			Expect(cached.Master).To(Equal("enp175s0f1"))
+			Expect(cached.VFID).To(Equal(1))
+		})
+		It("Loads a version 2 NetConf without migrating it", func() {
+			useHypotheticalV2()
+			v2NetConf := `{"name": "mynet", "type": "sriov", "deviceID": "0000:af:06.1", "Master": "enp175s0f0", "OldMaster": "enp175s0f1", "SchemaVersion": 2}`
+			Expect(os.WriteFile(filepath.Join(tmpdir, "container1-net1"), []byte(v2NetConf), 0600)).To(Succeed())
+			cached, err := NewCache(tmpdir).Load("container1", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached.Master).To(Equal("enp175s0f0"))
+		})
+		It("Reads the JSON file over a binary state file of a previous version", func() {
+			netconf := &types.NetConf{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.1", Master: "enp175s0f0", BinaryCache: true}}
+			Expect(NewCache(tmpdir).Save("container1", "net1", netconf)).To(Succeed())
+			useHypotheticalV2()
+			Expect(os.WriteFile(filepath.Join(tmpdir, "container1-net1"), []byte(v1NetConf), 0600)).To(Succeed())
+			cached, err := NewCache(tmpdir).Load("container1", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached.Master).To(Equal("enp175s0f1"))
+		})
+		It("Loads a NetConf of a newer version as is", func() {
+			Expect(os.WriteFile(filepath.Join(tmpdir, "container1-net1"),
+				[]byte(`{"name": "mynet", "type": "sriov", "deviceID": "0000:af:06.1", "SchemaVersion": 3}`), 0600)).To(Succeed())
+			cached, err := NewCache(tmpdir).Load("container1", "net1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached.DeviceID).To(Equal("0000:af:06.1"))
+			Expect(cached.SchemaVersion).To(Equal(3))
+		})
+		It("Fails without a migration from the cached version", func() {
+			cacheSchemaVersion = 2
+			defer func() { cacheSchemaVersion = 1 }()
+			Expect(os.WriteFile(filepath.Join(tmpdir, "container1-net1"), []byte(v1NetConf), 0600)).To(Succeed())
+			_, err := NewCache(tmpdir).Load("container1", "net1")
+			Expect(err).To(MatchError(ContainSubstring("no migration of the cached NetConf from version 1")))
+		})
+	})
+	Context("Checking IPAMStdinData function", func() {
+		conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "ipamDataDir": "/run/sriov/ipam",
+        "ipam": {
+            "type": "host-local",
+            "subnet": "10.55.206.0/26"
+        }
+                        }`)
+
+		It("Sets the ipamDataDir as the dataDir of the ipam config", func() {
+			netconf, err := LoadConf(conf)
+			Expect(err).NotTo(HaveOccurred())
+
+			ipamStdinData, err := IPAMStdinData(netconf, conf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipamStdinData).To(MatchJSON(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "ipamDataDir": "/run/sriov/ipam",
+        "ipam": {
+            "type": "host-local",
+            "subnet": "10.55.206.0/26",
+            "dataDir": "/run/sriov/ipam"
+        }
+                        }`))
+		})
+		It("Keeps the dataDir of the ipam config", func() {
+			confWithDataDir := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "ipamDataDir": "/run/sriov/ipam",
+        "ipam": {"type": "host-local", "dataDir": "/var/lib/cni/networks"}
+                        }`)
+			netconf := &types.NetConf{SriovNetConf: types.SriovNetConf{IPAMDataDir: "/run/sriov/ipam"}}
+			ipamStdinData, err := IPAMStdinData(netconf, confWithDataDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipamStdinData).To(Equal(confWithDataDir))
+		})
+		It("Passes the netconf unchanged without ipamDataDir", func() {
+			ipamStdinData, err := IPAMStdinData(&types.NetConf{}, conf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipamStdinData).To(Equal(conf))
+		})
+		It("Assuming a relative ipamDataDir", func() {
+			_, err := LoadConf([]byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "ipamDataDir": "run/sriov/ipam"
+                        }`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking LoadConfFromCache function", func() {
+		netconf := &types.NetConf{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.1", Master: "enp175s0f1", VFID: 1}}
+
+		It("Loads the NetConf cached in the cacheDir of the netconf", func() {
+			tmpdir, err := os.MkdirTemp("/tmp", "sriovplugin-cache-test-")
+			Expect(err).ShouldNot(HaveOccurred())
+			defer os.RemoveAll(tmpdir)
+
+			Expect(NewCache(tmpdir).Save("container1", "net1", netconf)).To(Succeed())
+			args := &skel.CmdArgs{
+				ContainerID: "container1",
+				IfName:      "net1",
+				StdinData:   []byte(fmt.Sprintf(`{"name": "mynet", "type": "sriov", "cacheDir": %q}`, tmpdir)),
+			}
+
+			cached, cache, err := LoadConfFromCache(args)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached.DeviceID).To(Equal("0000:af:06.1"))
+			Expect(cached.VFID).To(Equal(1))
+
+			Expect(cache.Remove("container1", "net1")).To(Succeed())
+			_, err = os.Stat(filepath.Join(tmpdir, "container1-net1"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+		It("Uses the Cache returned by NewCache", func() {
+			origNewCache := NewCache
+			defer func() { NewCache = origNewCache }()
+			memCache := &memoryCache{netConfs: map[string]*types.NetConf{}}
+			var cacheDir string
+			NewCache = func(dir string) Cache {
+				cacheDir = dir
+				return memCache
+			}
+
+			Expect(memCache.Save("container1", "net1", netconf)).To(Succeed())
+			args := &skel.CmdArgs{ContainerID: "container1", IfName: "net1", StdinData: []byte(`{"name": "mynet", "type": "sriov"}`)}
+
+			cached, _, err := LoadConfFromCache(args)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached).To(Equal(netconf))
+			Expect(cacheDir).To(Equal(DefaultCNIDir))
+
+			args.IfName = "net2"
+			_, _, err = LoadConfFromCache(args)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Assuming a relative cacheDir", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "vf": 0,
+        "cacheDir": "var/lib/cni/sriov"
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking SetLogging function", func() {
+		conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "logLevel": "info"
+                        }`)
+
+		AfterEach(func() {
+			Expect(os.Unsetenv(LogLevelEnv)).To(Succeed())
+		})
+
+		It("Uses the netconf log level", func() {
+			Expect(SetLogging(conf, "", "", "")).To(Succeed())
+			Expect(cnilog.GetLogLevel()).To(Equal(cnilog.InfoLevel))
+		})
+		It("Overrides the log level from the environment", func() {
+			Expect(os.Setenv(LogLevelEnv, "debug")).To(Succeed())
+			Expect(SetLogging(conf, "", "", "")).To(Succeed())
+			Expect(cnilog.GetLogLevel()).To(Equal(cnilog.DebugLevel))
+		})
+		It("Ignores an invalid log level in the environment", func() {
+			Expect(os.Setenv(LogLevelEnv, "loud")).To(Succeed())
+			Expect(SetLogging(conf, "", "", "")).To(Succeed())
+			Expect(cnilog.GetLogLevel()).To(Equal(cnilog.InfoLevel))
+		})
+	})
+	Context("Checking LoadConf function - packet capture on failure", func() {
+		It("Defaults the capture directory to the cache directory", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "cacheDir": "/var/lib/cni/custom",
+        "captureOnFailure": true
+                        }`)
+			netconf, err := LoadConf(conf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netconf.CaptureDir).To(Equal("/var/lib/cni/custom/captures"))
+		})
+		It("Rejects a relative capture directory", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "captureOnFailure": true,
+        "captureDir": "captures"
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(MatchError(ContainSubstring(`captureDir "captures" invalid`)))
+		})
+	})
+	Context("Checking LockNode function", func() {
+		var cacheDir string
+
+		BeforeEach(func() {
+			var err error
+			cacheDir, err = os.MkdirTemp("", "sriov-lock-node-")
+			Expect(err).ToNot(HaveOccurred())
+			DeferCleanup(os.RemoveAll, cacheDir)
+		})
+
+		netConf := func(globalSerialize bool) []byte {
+			return []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "cacheDir": %q,
+        "globalSerialize": %t
+                        }`, cacheDir, globalSerialize))
+		}
+
+		It("Serializes the invocations when globalSerialize is set", func() {
+			unlock, err := LockNode(netConf(true))
+			Expect(err).ToNot(HaveOccurred())
+
+			acquired := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				unlockOther, err := LockNode(netConf(true))
+				Expect(err).ToNot(HaveOccurred())
+				close(acquired)
+				unlockOther()
+			}()
+
+			Consistently(acquired, "100ms").ShouldNot(BeClosed())
+			unlock()
+			Eventually(acquired).Should(BeClosed())
+		})
+
+		It("Does not lock when globalSerialize is not set", func() {
+			unlock, err := LockNode(netConf(false))
+			Expect(err).ToNot(HaveOccurred())
+			defer unlock()
+
+			unlockOther, err := LockNode(netConf(false))
+			Expect(err).ToNot(HaveOccurred())
+			unlockOther()
+			Expect(filepath.Join(cacheDir, "lock", "node.lock")).ToNot(BeAnExistingFile())
+		})
+	})
+	Context("Checking ValidateConf function", func() {
+		It("Assuming valid config of a VF not present on the host", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:3b:02.0",
+        "vlan": 100,
+        "vlanQoS": 5,
+        "vlanProto": "802.1AD",
+        "spoofchk": "on",
+        "trust": "off",
+        "min_tx_rate": 100,
+        "max_tx_rate": 200
+                        }`)
+			err := ValidateConf(conf)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("Assuming invalid config lists every problem", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "vlan": 5000,
+        "vlanQoS": 9,
+        "vlanProto": "802.1x",
+        "spoofchk": "yes",
+        "trust": "true",
+        "min_tx_rate": 300,
+        "max_tx_rate": 200
+                        }`)
+			err := ValidateConf(conf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("VF pci addr is required"))
+			Expect(err.Error()).To(ContainSubstring("vlan id 5000 invalid"))
+			Expect(err.Error()).To(ContainSubstring("vlan QoS PCP 9 invalid"))
+			Expect(err.Error()).To(ContainSubstring("vlan Proto 802.1x invalid"))
+			Expect(err.Error()).To(ContainSubstring("invalid spoofchk value: yes"))
+			Expect(err.Error()).To(ContainSubstring("invalid trust value: true"))
+			Expect(err.Error()).To(ContainSubstring("min_tx_rate 300 must be less than or equal to max_tx_rate 200"))
+		})
+		It("Assuming malformed json", func() {
+			err := ValidateConf([]byte(`{"name": "mynet",`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking RunValidate function", func() {
+		It("Succeeds for a valid config of a VF not present on the host", func() {
+			conf := `{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:3b:02.0",
+        "vlan": 100,
+        "spoofchk": "on"
+                        }`
+			Expect(RunValidate(strings.NewReader(conf))).To(BeNil())
+		})
+		It("Returns an invalid network configuration error listing every problem", func() {
+			conf := `{
+        "name": "mynet",
+        "type": "sriov",
+        "vlan": 5000,
+        "trust": "true"
+                        }`
+			e := RunValidate(strings.NewReader(conf))
+			Expect(e).NotTo(BeNil())
+			Expect(e.Code).To(Equal(cnitypes.ErrInvalidNetworkConfig))
+			Expect(e.Details).To(ContainSubstring("VF pci addr is required"))
+			Expect(e.Details).To(ContainSubstring("vlan id 5000 invalid"))
+			Expect(e.Details).To(ContainSubstring("invalid trust value: true"))
+		})
+		It("Returns a decoding error for malformed json", func() {
+			e := RunValidate(strings.NewReader(`{"name": "mynet",`))
+			Expect(e).NotTo(BeNil())
+			Expect(e.Code).To(Equal(cnitypes.ErrDecodingFailure))
+		})
+	})
 	Context("Checking getVfInfo function", func() {
 		It("Assuming existing PF", func() {
 			_, _, err := getVfInfo("0000:af:06.0")
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
+)
+
+// NetworkAttachmentDefinition is the minimal k8s.cni.cncf.io/v1 NetworkAttachmentDefinition emitted by ExportNAD
+type NetworkAttachmentDefinition struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Config string `json:"config"`
+	} `json:"spec"`
+}
+
+// exportSkippedKeys are the cached NetConf keys that are discovered or set at runtime rather than configured in a NAD
+var exportSkippedKeys = []string{"OrigVfState", "AddedAltMACs", "AddedFdbMAC", "AddResult", "SchemaVersion", "Master", "MTU", "VFID", "deviceID", "runtimeConfig", "prevResult"}
+
+// exportRedactedKeys are the NetConf keys identifying the VF that are omitted when redacting
+var exportRedactedKeys = []string{"mac", "altMACs", "guid", "rssHashKey"}
+
+// LoadConfsFromCache retrieves the cached NetConf of every interface of a container
+func LoadConfsFromCache(containerID string) ([]*sriovtypes.NetConf, error) {
+	cRefPaths, err := filepath.Glob(filepath.Join(DefaultCNIDir, containerID+"-*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached NetConf in %s: %v", DefaultCNIDir, err)
+	}
+	if len(cRefPaths) == 0 {
+		return nil, fmt.Errorf("no cached NetConf in %s for container %s", DefaultCNIDir, containerID)
+	}
+	sort.Strings(cRefPaths)
+
+	netConfs := make([]*sriovtypes.NetConf, 0, len(cRefPaths))
+	for _, cRefPath := range cRefPaths {
+		netConfBytes, err := utils.ReadScratchNetConf(cRefPath)
+		if err != nil {
+			return nil, err
+		}
+
+		netConf, err := parseCachedNetConf(netConfBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse NetConf %s: %q", cRefPath, err)
+		}
+		netConfs = append(netConfs, netConf)
+	}
+
+	return netConfs, nil
+}
+
+// ExportNAD returns a NetworkAttachmentDefinition reproducing the configuration of a cached NetConf.
+// Only the user provided configuration is kept, MAC addresses, GUIDs and RSS hash keys are omitted if redact is set.
+// The IPAM plugin configuration is not cached, so only the IPAM type is exported.
+func ExportNAD(netConf *sriovtypes.NetConf, redact bool) ([]byte, error) {
+	netConfBytes, err := json.Marshal(netConf)
+	if err != nil {
+		return nil, fmt.Errorf("ExportNAD(): failed to serialize netconf: %v", err)
+	}
+
+	netConfMap := make(map[string]interface{})
+	if err = json.Unmarshal(netConfBytes, &netConfMap); err != nil {
+		return nil, fmt.Errorf("ExportNAD(): failed to parse netconf: %v", err)
+	}
+
+	// The MAC field has no json tag, use the key documented in the configuration reference
+	if mac, ok := netConfMap["MAC"]; ok {
+		delete(netConfMap, "MAC")
+		netConfMap["mac"] = mac
+	}
+
+	for _, k := range exportSkippedKeys {
+		delete(netConfMap, k)
+	}
+	if redact {
+		for _, k := range exportRedactedKeys {
+			delete(netConfMap, k)
+		}
+	}
+	for k, v := range netConfMap {
+		if v == nil || v == "" {
+			delete(netConfMap, k)
+		}
+	}
+
+	configBytes, err := json.Marshal(netConfMap)
+	if err != nil {
+		return nil, fmt.Errorf("ExportNAD(): failed to serialize config: %v", err)
+	}
+
+	nad := NetworkAttachmentDefinition{
+		APIVersion: "k8s.cni.cncf.io/v1",
+		Kind:       "NetworkAttachmentDefinition",
+	}
+	nad.Metadata.Name = strings.ToLower(netConf.Name)
+	nad.Spec.Config = string(configBytes)
+
+	return json.MarshalIndent(nad, "", "  ")
+}
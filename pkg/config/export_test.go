@@ -0,0 +1,123 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
+)
+
+var _ = Describe("Export", func() {
+	var netconf *types.NetConf
+
+	BeforeEach(func() {
+		vlan := 100
+		vlanQoS := 0
+		vlanProto := types.Proto8021q
+		maxTxRate := 1000
+		netconf = &types.NetConf{
+			NetConf: cnitypes.NetConf{
+				CNIVersion: "1.0.0",
+				Name:       "sriov-net",
+				Type:       "sriov",
+				IPAM:       cnitypes.IPAM{Type: "host-local"},
+			},
+			SriovNetConf: types.SriovNetConf{
+				Master:    "enp175s0f1",
+				DeviceID:  "0000:af:06.0",
+				VFID:      0,
+				MAC:       "aa:f3:8d:65:1b:d4",
+				Vlan:      &vlan,
+				VlanQoS:   &vlanQoS,
+				VlanProto: &vlanProto,
+				MaxTxRate: &maxTxRate,
+				SpoofChk:  "on",
+				QueueRates: []types.QueueRate{
+					{Queue: 0, MaxRate: 500},
+				},
+				OrigVfState: types.VfState{
+					HostIFName: "enp175s6",
+					AdminMAC:   "00:00:00:00:00:00",
+				},
+			},
+		}
+	})
+
+	parseNAD := func(nadBytes []byte) (*NetworkAttachmentDefinition, *types.NetConf) {
+		nad := &NetworkAttachmentDefinition{}
+		Expect(json.Unmarshal(nadBytes, nad)).To(Succeed())
+		exported := &types.NetConf{}
+		Expect(json.Unmarshal([]byte(nad.Spec.Config), exported)).To(Succeed())
+		return nad, exported
+	}
+
+	Context("Checking ExportNAD function", func() {
+		It("Emits a NAD whose config round-trips through the parser", func() {
+			nadBytes, err := ExportNAD(netconf, false)
+			Expect(err).NotTo(HaveOccurred())
+
+			nad, exported := parseNAD(nadBytes)
+			Expect(nad.Kind).To(Equal("NetworkAttachmentDefinition"))
+			Expect(nad.Metadata.Name).To(Equal("sriov-net"))
+			Expect(exported.Name).To(Equal(netconf.Name))
+			Expect(exported.Type).To(Equal(netconf.Type))
+			Expect(exported.IPAM.Type).To(Equal("host-local"))
+			Expect(exported.MAC).To(Equal(netconf.MAC))
+			Expect(exported.Vlan).To(Equal(netconf.Vlan))
+			Expect(exported.VlanProto).To(Equal(netconf.VlanProto))
+			Expect(exported.MaxTxRate).To(Equal(netconf.MaxTxRate))
+			Expect(exported.SpoofChk).To(Equal(netconf.SpoofChk))
+			Expect(exported.QueueRates).To(Equal(netconf.QueueRates))
+
+			// runtime state is not part of the exported configuration
+			Expect(exported.DeviceID).To(BeEmpty())
+			Expect(exported.Master).To(BeEmpty())
+			Expect(exported.OrigVfState).To(Equal(types.VfState{}))
+
+			// the exported config is valid once the device plugin provides the deviceID
+			exported.DeviceID = netconf.DeviceID
+			config, err := json.Marshal(exported)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ValidateConf(config)).To(Succeed())
+		})
+
+		It("Omits MAC addresses when redacting", func() {
+			nadBytes, err := ExportNAD(netconf, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			nad, exported := parseNAD(nadBytes)
+			Expect(nad.Spec.Config).NotTo(ContainSubstring(netconf.MAC))
+			Expect(exported.MAC).To(BeEmpty())
+			Expect(exported.Vlan).To(Equal(netconf.Vlan))
+		})
+	})
+
+	Context("Checking LoadConfsFromCache function", func() {
+		It("Loads the cached NetConf of every interface of a container", func() {
+			tmpdir, err := os.MkdirTemp("/tmp", "sriovplugin-export-test-")
+			Expect(err).ShouldNot(HaveOccurred())
+			origCNIDir := DefaultCNIDir
+			DefaultCNIDir = tmpdir
+			defer func() {
+				DefaultCNIDir = origCNIDir
+				os.RemoveAll(tmpdir)
+			}()
+
+			Expect(utils.SaveNetConf("container1", tmpdir, "net1", netconf)).To(Succeed())
+			Expect(utils.SaveNetConf("container1", tmpdir, "net2", netconf)).To(Succeed())
+			Expect(utils.SaveNetConf("container2", tmpdir, "net1", netconf)).To(Succeed())
+
+			netConfs, err := LoadConfsFromCache("container1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netConfs).To(HaveLen(2))
+
+			_, err = LoadConfsFromCache("container3")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
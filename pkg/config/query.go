@@ -0,0 +1,82 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+)
+
+// ListenQuerySocket listens on the unix socket at path, replacing the socket left by a previous query server. The
+// socket is only accessible to its owner since the NetConf hold the VF MAC addresses.
+func ListenQuerySocket(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the query socket directory(%q): %v", filepath.Dir(path), err)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove the stale query socket %s: %v", path, err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on the query socket %s: %v", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to restrict the access to the query socket %s: %v", path, err)
+	}
+	return l, nil
+}
+
+// ServeQuery serves the cached NetConf of the attachments over HTTP on l until l is closed, e.g. with
+// curl --unix-socket <path> http://localhost/. The response is a JSON object of the NetConf by cache file name,
+// <containerID>-<ifName>, the containerID query parameter only returns the attachments of that container.
+func ServeQuery(l net.Listener) error {
+	err := http.Serve(l, http.HandlerFunc(serveCachedConfs))
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// serveCachedConfs reads the cache on every request so that the attachments added and deleted since the server
+// started are reported
+func serveCachedConfs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	netConfs, err := LoadAllConfsFromCache()
+	if errors.Is(err, os.ErrNotExist) {
+		// nothing was cached yet
+		netConfs = map[string]*sriovtypes.NetConf{}
+	} else if err != nil {
+		logging.Error("Failed to load the cached NetConf",
+			"func", "serveCachedConfs",
+			"err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	containerID := r.URL.Query().Get("containerID")
+	for name, netConf := range netConfs {
+		// the other records of the cache directory have no device ID
+		if netConf.DeviceID == "" || (containerID != "" && !strings.HasPrefix(name, containerID+"-")) {
+			delete(netConfs, name)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(netConfs); err != nil {
+		logging.Warning("Failed to write the query response",
+			"func", "serveCachedConfs",
+			"err", err)
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
+)
+
+var _ = Describe("Query", func() {
+	var (
+		tmpdir      string
+		origCNIDir  string
+		socketPath  string
+		listener    net.Listener
+		client      *http.Client
+		served      chan error
+		netConfNet1 *types.NetConf
+		netConfNet2 *types.NetConf
+	)
+
+	query := func(url string) map[string]*types.NetConf {
+		resp, err := client.Get(url)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		netConfs := map[string]*types.NetConf{}
+		Expect(json.NewDecoder(resp.Body).Decode(&netConfs)).To(Succeed())
+		return netConfs
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpdir, err = os.MkdirTemp("/tmp", "sriovplugin-query-test-")
+		Expect(err).NotTo(HaveOccurred())
+		origCNIDir = DefaultCNIDir
+		DefaultCNIDir = filepath.Join(tmpdir, "cache")
+
+		netConfNet1 = &types.NetConf{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.1", Master: "enp175s0f1", VFID: 1, MAC: "0a:00:00:00:00:01"}}
+		netConfNet2 = &types.NetConf{SriovNetConf: types.SriovNetConf{DeviceID: "0000:af:06.2", Master: "enp175s0f1", VFID: 2}}
+		Expect(utils.SaveNetConf("container1", DefaultCNIDir, "net1", netConfNet1)).To(Succeed())
+		Expect(utils.SaveNetConf("container2", DefaultCNIDir, "net1", netConfNet2)).To(Succeed())
+		Expect(utils.NewMACRegistry(DefaultCNIDir).Reserve("0a:00:00:00:00:01", "0000:af:06.1")).To(Succeed())
+
+		socketPath = filepath.Join(tmpdir, "run", "query.sock")
+		listener, err = ListenQuerySocket(socketPath)
+		Expect(err).NotTo(HaveOccurred())
+		served = make(chan error, 1)
+		go func() { served <- ServeQuery(listener) }()
+
+		client = &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		}}
+	})
+
+	AfterEach(func() {
+		Expect(listener.Close()).To(Succeed())
+		Eventually(served).Should(Receive(BeNil()))
+		DefaultCNIDir = origCNIDir
+		Expect(os.RemoveAll(tmpdir)).To(Succeed())
+	})
+
+	It("Serves the cached NetConf of every attachment", func() {
+		netConfs := query("http://localhost/")
+		Expect(netConfs).To(HaveLen(2))
+		Expect(netConfs).To(HaveKey("container1-net1"))
+		Expect(netConfs["container1-net1"].DeviceID).To(Equal(netConfNet1.DeviceID))
+		Expect(netConfs["container1-net1"].VFID).To(Equal(netConfNet1.VFID))
+		Expect(netConfs["container1-net1"].MAC).To(Equal(netConfNet1.MAC))
+		Expect(netConfs).To(HaveKey("container2-net1"))
+		Expect(netConfs["container2-net1"].DeviceID).To(Equal(netConfNet2.DeviceID))
+	})
+
+	It("Serves the cached NetConf of a container", func() {
+		netConfs := query("http://localhost/?containerID=container2")
+		Expect(netConfs).To(HaveLen(1))
+		Expect(netConfs).To(HaveKey("container2-net1"))
+		Expect(netConfs["container2-net1"].Master).To(Equal(netConfNet2.Master))
+	})
+
+	It("Reports the attachments cached after the server started", func() {
+		Expect(utils.SaveNetConf("container3", DefaultCNIDir, "net2", netConfNet2)).To(Succeed())
+		Expect(query("http://localhost/")).To(HaveKey("container3-net2"))
+	})
+
+	It("Serves no NetConf when nothing was cached", func() {
+		Expect(os.RemoveAll(DefaultCNIDir)).To(Succeed())
+		Expect(query("http://localhost/")).To(BeEmpty())
+	})
+
+	It("Rejects the other methods", func() {
+		resp, err := client.Post("http://localhost/", "application/json", nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("Restricts the socket to its owner", func() {
+		info, err := os.Stat(socketPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+	})
+})
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+)
+
+// SchemaID is the identifier of the NetConf JSON schema
+const SchemaID = "https://github.com/k8snetworkplumbingwg/sriov-cni/netconf.schema.json"
+
+// schemaUntaggedFields are the NetConf fields without a json tag that are configured in a
+// NetworkAttachmentDefinition, by the key they are matched with. Other untagged fields are set at runtime.
+var schemaUntaggedFields = map[string]string{"MAC": "mac"}
+
+// schemaConstraints are the enums and ranges of the NetConf keys, which the field types do not describe
+var schemaConstraints = map[string]map[string]interface{}{
+	"vlan":                     {"minimum": 0, "maximum": 4094},
+	"vlanQoS":                  {"minimum": 0, "maximum": 7},
+	"vlanProto":                {"enum": []string{sriovtypes.Proto8021q, sriovtypes.Proto8021ad}},
+	"min_tx_rate":              {"minimum": 0},
+	"max_tx_rate":              {"minimum": 0},
+	"spoofchk":                 {"enum": []string{"on", "off"}},
+	"trust":                    {"enum": []string{"on", "off"}},
+	"allMulti":                 {"enum": []string{"on", "off"}},
+	"link_state":               {"enum": []string{"auto", "enable", "disable"}},
+	"resetScope":               {"enum": []string{sriovtypes.ResetScopeAll, sriovtypes.ResetScopeL3Only, sriovtypes.ResetScopeL2Only}},
+	"enforceRateCeiling":       {"enum": []string{sriovtypes.RateCeilingReject, sriovtypes.RateCeilingClamp}},
+	"enforceSchedulerCapacity": {"enum": []string{sriovtypes.SchedulerCapacityWarn, sriovtypes.SchedulerCapacityReject}},
+	"logFormat":                {"enum": []string{logging.FormatText, logging.FormatJSON}},
+	"mode":                     {"enum": []string{sriovtypes.ModeMacvlanHost}},
+	"drainDelay":               {"minimum": 0, "maximum": sriovtypes.MaxDrainDelay},
+	"maxMacChanges":            {"minimum": 0},
+	"linkUpTimeout":            {"minimum": 0},
+	"netlinkTimeout":           {"minimum": 1},
+	"representorVlan":          {"minimum": 1, "maximum": 4094},
+	"fdbVni":                   {"minimum": 1, "maximum": sriovtypes.MaxVNI},
+	"routeTable":               {"minimum": 1, "maximum": sriovtypes.MaxRouteTable},
+	"egressQoSMap":             {"pattern": `^\s*[0-7]:[0-7]\s*(,\s*[0-7]:[0-7]\s*)*$`},
+}
+
+// NetConfSchema returns a JSON schema of the netconf, derived from the json tags of the NetConf fields
+func NetConfSchema() ([]byte, error) {
+	schema := map[string]interface{}{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"$id":        SchemaID,
+		"title":      "SR-IOV CNI network configuration",
+		"type":       "object",
+		"required":   []string{"type"},
+		"properties": schemaProperties(reflect.TypeOf(sriovtypes.NetConf{})),
+	}
+
+	properties := schema["properties"].(map[string]interface{})
+	for key, constraints := range schemaConstraints {
+		property, ok := properties[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for k, v := range constraints {
+			property[k] = v
+		}
+	}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaProperties returns the schema of the json keys of a struct type, including the keys of its embedded structs
+func schemaProperties(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			for k, v := range schemaProperties(field.Type) {
+				properties[k] = v
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		key := schemaKey(field)
+		if key == "" {
+			continue
+		}
+		properties[key] = schemaType(field.Type)
+	}
+	return properties
+}
+
+// schemaKey returns the json key of a struct field, or the empty string if the field is not configured
+func schemaKey(field reflect.StructField) string {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return schemaUntaggedFields[field.Name]
+	}
+	key := strings.Split(tag, ",")[0]
+	if key == "-" {
+		return ""
+	}
+	if key == "" {
+		return field.Name
+	}
+	return key
+}
+
+// schemaType returns the schema of the values of a Go type
+func schemaType(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaType(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaType(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaType(t.Elem())}
+	case reflect.Struct:
+		return map[string]interface{}{"type": "object", "properties": schemaProperties(t)}
+	default:
+		// interfaces hold any json value
+		return map[string]interface{}{}
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+)
+
+var _ = Describe("Schema", func() {
+	var properties map[string]map[string]interface{}
+
+	BeforeEach(func() {
+		data, err := NetConfSchema()
+		Expect(err).NotTo(HaveOccurred())
+		schema := struct {
+			Properties map[string]map[string]interface{} `json:"properties"`
+		}{}
+		Expect(json.Unmarshal(data, &schema)).To(Succeed())
+		properties = schema.Properties
+	})
+
+	It("Describes every json-tagged NetConf field", func() {
+		for _, t := range []reflect.Type{reflect.TypeOf(cnitypes.NetConf{}), reflect.TypeOf(types.SriovNetConf{})} {
+			for i := 0; i < t.NumField(); i++ {
+				tag, ok := t.Field(i).Tag.Lookup("json")
+				key := strings.Split(tag, ",")[0]
+				if !ok || key == "-" {
+					continue
+				}
+				Expect(properties).To(HaveKey(key), "field %s", t.Field(i).Name)
+			}
+		}
+	})
+
+	It("Describes the configured untagged fields and skips the runtime ones", func() {
+		Expect(properties).To(HaveKey("mac"))
+		Expect(properties).NotTo(HaveKey("OrigVfState"))
+		Expect(properties).NotTo(HaveKey("VFID"))
+		Expect(properties).NotTo(HaveKey("DPDKMode"))
+	})
+
+	It("Describes the enums and ranges", func() {
+		Expect(properties["vlanProto"]["enum"]).To(ConsistOf("802.1q", "802.1ad"))
+		Expect(properties["spoofchk"]["enum"]).To(ConsistOf("on", "off"))
+		Expect(properties["trust"]["enum"]).To(ConsistOf("on", "off"))
+		Expect(properties["vlan"]).To(HaveKeyWithValue("minimum", BeNumerically("==", 0)))
+		Expect(properties["vlan"]).To(HaveKeyWithValue("maximum", BeNumerically("==", 4094)))
+		Expect(properties["max_tx_rate"]).To(HaveKeyWithValue("type", "integer"))
+		Expect(properties["max_tx_rate"]).To(HaveKeyWithValue("minimum", BeNumerically("==", 0)))
+		Expect(properties["min_tx_rate"]).To(HaveKeyWithValue("minimum", BeNumerically("==", 0)))
+	})
+
+	It("Describes nested structs and maps", func() {
+		Expect(properties["rss"]["type"]).To(Equal("object"))
+		Expect(properties["rss"]["properties"]).To(HaveKey("indirTable"))
+		Expect(properties["privFlags"]["additionalProperties"]).To(HaveKeyWithValue("type", "boolean"))
+	})
+})
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	cnilog "github.com/k8snetworkplumbingwg/cni-log"
+)
+
+const (
+	// FormatText logs each record as key="value" pairs, the cni-log structured format
+	FormatText = "text"
+	// FormatJSON logs each record as a single JSON object
+	FormatJSON = "json"
+
+	// labelLoggingFailure reports a log call with an odd number of arguments, like cni-log does
+	labelLoggingFailure = "logging_failure"
+)
+
+var logFormat = FormatText
+
+// jsonPrefixer is the StructuredPrefixer of the JSON format, the time, level and message of a record.
+var jsonPrefixer = cnilog.StructuredPrefixerFunc(func(loggingLevel cnilog.Level, msg string) []interface{} {
+	return []interface{}{
+		"time", time.Now().Format(time.RFC3339Nano),
+		"level", loggingLevel,
+		"msg", msg,
+	}
+})
+
+// IsValidFormat returns true if f is text or json.
+func IsValidFormat(f string) bool {
+	return f == FormatText || f == FormatJSON
+}
+
+// SetLogFormat sets the format of the log records to either text or json. An empty or invalid format uses text.
+// In json format cni-log is left without prefix and is handed the whole JSON record as the message.
+func SetLogFormat(f string) {
+	if f == FormatJSON {
+		logFormat = FormatJSON
+		cnilog.SetPrefixer(cnilog.PrefixerFunc(func(cnilog.Level) string { return "" }))
+		cnilog.SetStructuredPrefixer(jsonPrefixer)
+		return
+	}
+	logFormat = FormatText
+	cnilog.SetDefaultPrefixer()
+	cnilog.SetDefaultStructuredPrefixer()
+}
+
+// jsonMessage returns the record of msg and the key value pairs of args as a single line JSON object. Keys are
+// kept in order, the prefix ones first, and values are their %+v string representation as in the text format.
+func jsonMessage(loggingLevel cnilog.Level, msg string, args ...interface{}) string {
+	fields := jsonPrefixer.CreateStructuredPrefix(loggingLevel, msg)
+	oddArgs := len(args)%2 != 0
+	if oddArgs {
+		fields = append(fields, labelLoggingFailure, "odd number of arguments passed as key-value pairs for logging")
+	} else {
+		fields = append(fields, args...)
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i < len(fields)-1; i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		writeJSONString(&b, fmt.Sprintf("%+v", fields[i]))
+		b.WriteByte(':')
+		writeJSONString(&b, fmt.Sprintf("%+v", fields[i+1]))
+	}
+	b.WriteByte('}')
+
+	if oddArgs {
+		panic(b.String())
+	}
+	return b.String()
+}
+
+// writeJSONString writes s to b as a JSON string, escaping quotes, backslashes and control characters.
+func writeJSONString(b *strings.Builder, s string) {
+	// marshaling a string cannot fail
+	data, _ := json.Marshal(s)
+	b.Write(data)
+}
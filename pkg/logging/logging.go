@@ -3,6 +3,11 @@
 package logging
 
 import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+
 	cnilog "github.com/k8snetworkplumbingwg/cni-log"
 )
 
@@ -11,6 +16,7 @@ const (
 	labelContainerID = "containerID"
 	labelNetNS       = "netns"
 	labelIFName      = "ifname"
+	labelCaller      = "caller"
 	cniName          = "sriov-cni"
 )
 
@@ -19,8 +25,15 @@ var (
 	containerID     = ""
 	netNS           = ""
 	ifName          = ""
+	logFile         = ""
+	logCaller       = false
+	logOptions      *LogOptions
 )
 
+// LogOptions are the rotation settings of the log files, cni-log defaults the unset ones to a MaxSize of 100
+// megabytes, a MaxAge of 5 days, 5 MaxBackups and compressed backups.
+type LogOptions = cnilog.LogOptions
+
 // Init initializes logging with the requested parameters in this order: log level, log file, container ID,
 // network namespace and interface name.
 func Init(logLevel, logFile, containerIdentification, networkNamespace, interfaceName string) {
@@ -41,8 +54,20 @@ func setLogLevel(l string) {
 	cnilog.SetLogLevel(ll)
 }
 
+// SetLogLevel overrides the log level set by Init. If l is not a valid level, the log level is left unchanged and
+// false is returned.
+func SetLogLevel(l string) bool {
+	ll := cnilog.StringToLevel(l)
+	if ll == cnilog.InvalidLevel {
+		return false
+	}
+	cnilog.SetLogLevel(ll)
+	return true
+}
+
 // setLogFile sets the log file for logging. If the empty string is provided, it uses stderr.
 func setLogFile(fileName string) {
+	logFile = fileName
 	if fileName == "" {
 		cnilog.SetLogStderr(true)
 		cnilog.SetLogFile("")
@@ -52,28 +77,100 @@ func setLogFile(fileName string) {
 	cnilog.SetLogStderr(false)
 }
 
+// SetLogStderr enables or disables logging to stderr independently of the log file. Logging to stderr is kept
+// enabled when no log file is set so that logs are never dropped.
+func SetLogStderr(enable bool) {
+	if !enable && logFile == "" {
+		enable = true
+	}
+	cnilog.SetLogStderr(enable)
+}
+
+// SetLogOptions sets the rotation settings of the log file and of the level files, nil restores the defaults. It
+// must be called before SetLevelFiles.
+func SetLogOptions(options *LogOptions) {
+	logOptions = options
+	cnilog.SetLogOptions(options)
+}
+
+// SetLevelFiles additionally logs the lines of each level to the file mapped to it, e.g. {"error": "/var/log/error.log"}.
+// The files are rotated like the log file. It must be called after Init.
+func SetLevelFiles(levelFiles map[string]string) {
+	if len(levelFiles) == 0 {
+		return
+	}
+	cnilog.SetOutput(newLevelWriter(logFile, levelFiles))
+}
+
+// SetLogCaller enables or disables the caller field, the file:line of the log call, of the debug records. It is off
+// by default since looking up the caller of every debug record is costly.
+func SetLogCaller(enable bool) {
+	logCaller = enable
+}
+
+// caller returns the package directory, file name and line of the caller skip frames above the caller of caller,
+// e.g. sriov/sriov.go:274
+func caller(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s:%d", filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file)), line)
+}
+
+// IsValidLevel returns true if l is one of panic, error, warning, info or debug.
+func IsValidLevel(l string) bool {
+	return cnilog.StringToLevel(l) != cnilog.InvalidLevel
+}
+
 // Debug provides structured logging for log level >= debug.
 func Debug(msg string, args ...interface{}) {
+	if logCaller && cnilog.GetLogLevel() >= cnilog.DebugLevel {
+		args = append(args, labelCaller, caller(1))
+	}
+	if logFormat == FormatJSON {
+		cnilog.Debugf("%s", jsonMessage(cnilog.DebugLevel, msg, prependArgs(args)...))
+		return
+	}
 	cnilog.DebugStructured(msg, prependArgs(args)...)
 }
 
 // Info provides structured logging for log level >= info.
 func Info(msg string, args ...interface{}) {
+	if logFormat == FormatJSON {
+		cnilog.Infof("%s", jsonMessage(cnilog.InfoLevel, msg, prependArgs(args)...))
+		return
+	}
 	cnilog.InfoStructured(msg, prependArgs(args)...)
 }
 
 // Warning provides structured logging for log level >= warning.
 func Warning(msg string, args ...interface{}) {
+	if logFormat == FormatJSON {
+		cnilog.Warningf("%s", jsonMessage(cnilog.WarningLevel, msg, prependArgs(args)...))
+		return
+	}
 	cnilog.WarningStructured(msg, prependArgs(args)...)
 }
 
 // Error provides structured logging for log level >= error.
 func Error(msg string, args ...interface{}) {
+	if logFormat == FormatJSON {
+		_ = cnilog.Errorf("%s", jsonMessage(cnilog.ErrorLevel, msg, prependArgs(args)...))
+		return
+	}
 	_ = cnilog.ErrorStructured(msg, prependArgs(args)...)
 }
 
 // Panic provides structured logging for log level >= panic.
 func Panic(msg string, args ...interface{}) {
+	if logFormat == FormatJSON {
+		// cni-log prints the stack trace of Panicf over several lines, the JSON record carries it as a field
+		// and is printed like an error record
+		args = append(prependArgs(args), "stacktrace", string(debug.Stack()))
+		_ = cnilog.Errorf("%s", jsonMessage(cnilog.PanicLevel, msg, args...))
+		return
+	}
 	cnilog.PanicStructured(msg, prependArgs(args)...)
 }
 
@@ -1,12 +1,16 @@
 package logging
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 
 	g "github.com/onsi/ginkgo/v2"
 	o "github.com/onsi/gomega"
+	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
 var _ = g.Describe("Logging", func() {
@@ -171,6 +175,32 @@ var _ = g.Describe("Logging", func() {
 		})
 	})
 
+	g.Context("log level override", func() {
+		g.BeforeEach(func() {
+			Init("info", "", "", "", "")
+		})
+
+		g.It("debug messages are logged to stderr after raising the level", func() {
+			o.Expect(SetLogLevel("debug")).To(o.BeTrue())
+			Debug("test message", "a", "b")
+			_, _ = stderrFile.Seek(0, 0)
+			out, err := io.ReadAll(stderrFile)
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(out).Should(o.ContainSubstring("test message"))
+		})
+
+		g.It("an invalid level leaves the level unchanged", func() {
+			o.Expect(SetLogLevel("I'm invalid")).To(o.BeFalse())
+			Info("info message", "a", "b")
+			Debug("debug message", "a", "b")
+			_, _ = stderrFile.Seek(0, 0)
+			out, err := io.ReadAll(stderrFile)
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(out).Should(o.ContainSubstring("info message"))
+			o.Expect(out).ShouldNot(o.ContainSubstring("debug message"))
+		})
+	})
+
 	g.Context("log files", func() {
 		var logFile *os.File
 
@@ -226,5 +256,242 @@ var _ = g.Describe("Logging", func() {
 				o.Expect(out).Should(o.ContainSubstring("test message"))
 			})
 		})
+
+		g.When("the log file is set and stderr logging is enabled", func() {
+			g.BeforeEach(func() {
+				Init("", logFile.Name(), "", "", "")
+				SetLogStderr(true)
+			})
+
+			g.It("error messages are also logged to stderr", func() {
+				Error("test message", "a", "b")
+				_, _ = stderrFile.Seek(0, 0)
+				out, err := io.ReadAll(stderrFile)
+				o.Expect(err).NotTo(o.HaveOccurred())
+				o.Expect(out).Should(o.ContainSubstring("test message"))
+			})
+		})
+
+		g.When("the log file is not set and stderr logging is disabled", func() {
+			g.BeforeEach(func() {
+				Init("", "", "", "", "")
+				SetLogStderr(false)
+			})
+
+			g.It("falls back to logging to stderr", func() {
+				Error("test message", "a", "b")
+				_, _ = stderrFile.Seek(0, 0)
+				out, err := io.ReadAll(stderrFile)
+				o.Expect(err).NotTo(o.HaveOccurred())
+				o.Expect(out).Should(o.ContainSubstring("test message"))
+			})
+		})
+	})
+
+	g.Context("log options", func() {
+		g.AfterEach(func() {
+			SetLogOptions(nil)
+		})
+
+		g.It("applies the options to the level files", func() {
+			maxSize, maxBackups, compress := 10, 2, false
+			SetLogOptions(&LogOptions{MaxSize: &maxSize, MaxBackups: &maxBackups, Compress: &compress})
+
+			l, ok := newRotatedFile("/tmp/sriov-error.log").(*lumberjack.Logger)
+			o.Expect(ok).To(o.BeTrue())
+			o.Expect(l.MaxSize).To(o.Equal(10))
+			o.Expect(l.MaxAge).To(o.Equal(5))
+			o.Expect(l.MaxBackups).To(o.Equal(2))
+			o.Expect(l.Compress).To(o.BeFalse())
+		})
+
+		g.It("uses the defaults when no options are set", func() {
+			SetLogOptions(nil)
+
+			l, ok := newRotatedFile("/tmp/sriov-error.log").(*lumberjack.Logger)
+			o.Expect(ok).To(o.BeTrue())
+			o.Expect(l.MaxSize).To(o.Equal(100))
+			o.Expect(l.MaxAge).To(o.Equal(5))
+			o.Expect(l.MaxBackups).To(o.Equal(5))
+			o.Expect(l.Compress).To(o.BeTrue())
+		})
+	})
+
+	g.Context("level files", func() {
+		var errorFile, infoFile *os.File
+
+		g.BeforeEach(func() {
+			var err error
+			errorFile, err = os.CreateTemp("", "")
+			o.Expect(err).NotTo(o.HaveOccurred())
+			infoFile, err = os.CreateTemp("", "")
+			o.Expect(err).NotTo(o.HaveOccurred())
+
+			Init("", "", "", "", "")
+			SetLevelFiles(map[string]string{"error": errorFile.Name(), "info": infoFile.Name()})
+		})
+
+		g.AfterEach(func() {
+			Init("", "", "", "", "")
+			o.Expect(errorFile.Close()).To(o.Succeed())
+			o.Expect(os.RemoveAll(errorFile.Name())).To(o.Succeed())
+			o.Expect(infoFile.Close()).To(o.Succeed())
+			o.Expect(os.RemoveAll(infoFile.Name())).To(o.Succeed())
+		})
+
+		g.It("logs the lines of each level to the mapped file", func() {
+			Error("error message", "a", "b")
+			Info("info message", "a", "b")
+			Warning("warning message", "a", "b")
+
+			out, err := os.ReadFile(errorFile.Name())
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(out).Should(o.ContainSubstring(`msg="error message"`))
+			o.Expect(out).ShouldNot(o.ContainSubstring("info message"))
+			o.Expect(out).ShouldNot(o.ContainSubstring("warning message"))
+
+			out, err = os.ReadFile(infoFile.Name())
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(out).Should(o.ContainSubstring(`msg="info message"`))
+			o.Expect(out).ShouldNot(o.ContainSubstring("error message"))
+			o.Expect(out).ShouldNot(o.ContainSubstring("warning message"))
+		})
+
+		g.It("still logs every line to stderr", func() {
+			Error("error message", "a", "b")
+			Warning("warning message", "a", "b")
+			_, _ = stderrFile.Seek(0, 0)
+			out, err := io.ReadAll(stderrFile)
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(out).Should(o.ContainSubstring("error message"))
+			o.Expect(out).Should(o.ContainSubstring("warning message"))
+		})
+
+		g.It("logs the json records of each level to the mapped file", func() {
+			SetLogFormat(FormatJSON)
+			defer SetLogFormat(FormatText)
+			Error("error message", "a", "b")
+			Info("info message", "a", "b")
+
+			out, err := os.ReadFile(errorFile.Name())
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(out).Should(o.ContainSubstring(`"msg":"error message"`))
+			o.Expect(out).ShouldNot(o.ContainSubstring("info message"))
+		})
+	})
+	g.Context("json format", func() {
+		g.BeforeEach(func() {
+			Init("info", "", "test-containerid", "", "")
+			SetLogFormat(FormatJSON)
+		})
+
+		g.AfterEach(func() {
+			SetLogFormat(FormatText)
+		})
+
+		readRecords := func() []map[string]string {
+			_, _ = stderrFile.Seek(0, 0)
+			out, err := io.ReadAll(stderrFile)
+			o.Expect(err).NotTo(o.HaveOccurred())
+			var records []map[string]string
+			for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+				record := map[string]string{}
+				o.Expect(json.Unmarshal([]byte(line), &record)).To(o.Succeed(), line)
+				records = append(records, record)
+			}
+			return records
+		}
+
+		g.It("logs each record as a single JSON object with the time and level fields", func() {
+			Info("info message", "a", "b")
+			Error("error message", "err", fmt.Errorf("failed"))
+
+			records := readRecords()
+			o.Expect(records).To(o.HaveLen(2))
+			o.Expect(records[0]).To(o.HaveKeyWithValue("level", "info"))
+			o.Expect(records[0]).To(o.HaveKeyWithValue("msg", "info message"))
+			o.Expect(records[0]).To(o.HaveKeyWithValue("a", "b"))
+			o.Expect(records[0]).To(o.HaveKeyWithValue(labelCNIName, cniName))
+			o.Expect(records[0]).To(o.HaveKeyWithValue(labelContainerID, "test-containerid"))
+			_, err := time.Parse(time.RFC3339Nano, records[0]["time"])
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(records[1]).To(o.HaveKeyWithValue("level", "error"))
+			o.Expect(records[1]).To(o.HaveKeyWithValue("err", "failed"))
+		})
+
+		g.It("escapes the special characters of the values", func() {
+			Info("quoted \"message\"", "path", `C:\dir`, "lines", "first\nsecond\ttab")
+
+			records := readRecords()
+			o.Expect(records).To(o.HaveLen(1))
+			o.Expect(records[0]).To(o.HaveKeyWithValue("msg", `quoted "message"`))
+			o.Expect(records[0]).To(o.HaveKeyWithValue("path", `C:\dir`))
+			o.Expect(records[0]).To(o.HaveKeyWithValue("lines", "first\nsecond\ttab"))
+		})
+
+		g.It("logs the panic records with their stack trace as a field", func() {
+			Panic("panic message", "a", "b")
+
+			records := readRecords()
+			o.Expect(records).To(o.HaveLen(1))
+			o.Expect(records[0]).To(o.HaveKeyWithValue("level", "panic"))
+			o.Expect(records[0]).To(o.HaveKey("stacktrace"))
+		})
+
+		g.It("does not log the records below the log level", func() {
+			Debug("debug message", "a", "b")
+			Info("info message", "a", "b")
+
+			records := readRecords()
+			o.Expect(records).To(o.HaveLen(1))
+			o.Expect(records[0]).To(o.HaveKeyWithValue("msg", "info message"))
+		})
+
+		g.It("switches back to the text format", func() {
+			SetLogFormat(FormatText)
+			Info("info message", "a", "b")
+			_, _ = stderrFile.Seek(0, 0)
+			out, err := io.ReadAll(stderrFile)
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(out).Should(o.ContainSubstring(`msg="info message"`))
+			o.Expect(out).Should(o.MatchRegexp(`^time="`))
+		})
+	})
+	g.Context("caller field", func() {
+		g.BeforeEach(func() {
+			Init("debug", "", "", "", "")
+		})
+
+		g.AfterEach(func() {
+			SetLogCaller(false)
+		})
+
+		g.It("is added to the debug records when enabled", func() {
+			SetLogCaller(true)
+			Debug("debug message", "a", "b")
+			_, _ = stderrFile.Seek(0, 0)
+			out, err := io.ReadAll(stderrFile)
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(out).Should(o.MatchRegexp(`caller="logging/logging_test.go:\d+"`))
+		})
+
+		g.It("is not added to the records of the other levels", func() {
+			SetLogCaller(true)
+			Info("info message", "a", "b")
+			_, _ = stderrFile.Seek(0, 0)
+			out, err := io.ReadAll(stderrFile)
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(out).Should(o.ContainSubstring("info message"))
+			o.Expect(out).ShouldNot(o.ContainSubstring(labelCaller))
+		})
+
+		g.It("is not added by default", func() {
+			Debug("debug message", "a", "b")
+			_, _ = stderrFile.Seek(0, 0)
+			out, err := io.ReadAll(stderrFile)
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(out).Should(o.ContainSubstring("debug message"))
+			o.Expect(out).ShouldNot(o.ContainSubstring(labelCaller))
+		})
 	})
 })
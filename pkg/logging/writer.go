@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sync"
+
+	lumberjack "gopkg.in/natefinch/lumberjack.v2"
+)
+
+// levelRegexp matches the level of a structured log line, e.g. level="info" in text format or "level":"info" in
+// json format
+var levelRegexp = regexp.MustCompile(`\blevel"?[=:]"([a-z]+)"`)
+
+// levelWriter is a fan-out writer routing every log line to the main log file, if any, and to the file of its level.
+// cni-log writes a line and its trailing newline separately, so lines are buffered until they are complete.
+type levelWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	main    io.Writer
+	writers map[string]io.Writer
+}
+
+// newRotatedFile returns a writer to a rotated log file, using the same rotation settings as cni-log.
+func newRotatedFile(fileName string) io.Writer {
+	l := &lumberjack.Logger{
+		Filename:   fileName,
+		MaxSize:    100,
+		MaxAge:     5,
+		MaxBackups: 5,
+		Compress:   true,
+	}
+	if logOptions != nil {
+		if logOptions.MaxSize != nil {
+			l.MaxSize = *logOptions.MaxSize
+		}
+		if logOptions.MaxAge != nil {
+			l.MaxAge = *logOptions.MaxAge
+		}
+		if logOptions.MaxBackups != nil {
+			l.MaxBackups = *logOptions.MaxBackups
+		}
+		if logOptions.Compress != nil {
+			l.Compress = *logOptions.Compress
+		}
+	}
+	return l
+}
+
+// newLevelWriter returns a levelWriter for the main log file, which may be empty, and the files of each level.
+func newLevelWriter(mainFile string, levelFiles map[string]string) *levelWriter {
+	w := &levelWriter{writers: make(map[string]io.Writer, len(levelFiles))}
+	if mainFile != "" {
+		w.main = newRotatedFile(mainFile)
+	}
+	for level, fileName := range levelFiles {
+		w.writers[level] = newRotatedFile(fileName)
+	}
+	return w
+}
+
+// Write implements io.Writer.
+func (w *levelWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		i := bytes.IndexByte(w.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := w.buf.Next(i + 1)
+		if err := w.writeLine(line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// writeLine writes a complete line to the main log file and to the file of its level.
+func (w *levelWriter) writeLine(line []byte) error {
+	if w.main != nil {
+		if _, err := w.main.Write(line); err != nil {
+			return err
+		}
+	}
+
+	m := levelRegexp.FindSubmatch(line)
+	if m == nil {
+		return nil
+	}
+	if levelFile, ok := w.writers[string(m[1])]; ok {
+		if _, err := levelFile.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package sriov
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+)
+
+// BatchVF is one of the VFs configured together for a pod by SetupVFs
+type BatchVF struct {
+	Conf      *sriovtypes.NetConf
+	PodIfName string
+}
+
+// SetupVFs configures the VFs of a pod concurrently, running at most maxWorkers of them at a time, or all of them
+// when maxWorkers is not positive. Each VF goes through FillOriginalVfInfo, ApplyVFConfig and SetupVF. When a VF
+// fails, every VF whose original state was recorded is rolled back, the configured ones included, and the failure
+// of each VF is returned.
+func (s *sriovManager) SetupVFs(vfs []BatchVF, netns ns.NetNS, maxWorkers int) error {
+	if maxWorkers <= 0 || maxWorkers > len(vfs) {
+		maxWorkers = len(vfs)
+	}
+
+	errs := make([]error, len(vfs))
+	// the VFs whose original state is known, only those are rolled back
+	filled := make([]bool, len(vfs))
+	workers := make(chan struct{}, maxWorkers)
+	var wg sync.WaitGroup
+	for i, vf := range vfs {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			// SetupVF enters the pod netns, the switch must not leak to the other goroutines run by the thread
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+			if err := s.FillOriginalVfInfo(vf.Conf); err != nil {
+				errs[i] = fmt.Errorf("failed to get original information of VF %s: %w", vf.Conf.DeviceID, err)
+				return
+			}
+			filled[i] = true
+			if err := s.setupBatchVF(vf, netns); err != nil {
+				errs[i] = fmt.Errorf("failed to set up VF %s as %s: %w", vf.Conf.DeviceID, vf.PodIfName, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	err := errors.Join(errs...)
+	if err == nil {
+		return nil
+	}
+
+	logging.Error("Failed to set up the VFs of the batch, rolling back all of them",
+		"func", "SetupVFs",
+		"err", err)
+	for i, vf := range vfs {
+		if filled[i] {
+			s.rollbackBatchVF(vf, netns)
+		}
+	}
+	return err
+}
+
+// RollbackVFs reverts the configuration of VFs set up by SetupVFs, for a caller failing after SetupVFs succeeded
+func (s *sriovManager) RollbackVFs(vfs []BatchVF, netns ns.NetNS) {
+	for _, vf := range vfs {
+		s.rollbackBatchVF(vf, netns)
+	}
+}
+
+// setupBatchVF configures a VF of a batch like cmdAdd, once its original state is recorded
+func (s *sriovManager) setupBatchVF(vf BatchVF, netns ns.NetNS) error {
+	if err := s.ApplyVFConfig(vf.Conf); err != nil {
+		return err
+	}
+	return s.SetupVF(vf.Conf, vf.PodIfName, netns)
+}
+
+// rollbackBatchVF reverts the configuration of a VF of a batch, the VF is returned to the host when it reached the
+// pod netns. Failures are ignored, like the rollback of cmdAdd.
+func (s *sriovManager) rollbackBatchVF(vf BatchVF, netns ns.NetNS) {
+	// a failed cmdAdd reverts everything
+	vf.Conf.ResetScope = sriovtypes.ResetScopeAll
+	inPod := netns.Do(func(_ ns.NetNS) error {
+		_, err := s.nLink.LinkByName(vf.PodIfName)
+		return err
+	}) == nil
+	if inPod {
+		_ = s.ReleaseVF(vf.Conf, vf.PodIfName, netns)
+	}
+	_ = s.ResetVFConfig(vf.Conf)
+}
@@ -0,0 +1,82 @@
+package sriov
+
+import (
+	"fmt"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/vishvananda/netlink"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+)
+
+// bondMiimon is the link monitoring interval of the bond in milliseconds, so that the bond fails over when a member
+// VF loses its carrier
+const bondMiimon = 100
+
+// SetupBond creates the bond bondIfName in the pod netns and enslaves the member VFs, already moved to the pod netns
+// as memberIfNames by SetupVFs. The bond is deleted when a member cannot be enslaved, the members are left in the pod
+// netns for the caller to release.
+func (s *sriovManager) SetupBond(conf *sriovtypes.NetConf, bondIfName string, memberIfNames []string, netns ns.NetNS) error {
+	mode := netlink.StringToBondMode(conf.Bond.Mode)
+	if mode == netlink.BOND_MODE_UNKNOWN {
+		return fmt.Errorf("unknown bond mode %q", conf.Bond.Mode)
+	}
+
+	return netns.Do(func(_ ns.NetNS) (err error) {
+		bond := netlink.NewLinkBond(netlink.LinkAttrs{Name: bondIfName})
+		bond.Mode = mode
+		bond.Miimon = bondMiimon
+		if err = s.nLink.LinkAdd(bond); err != nil {
+			return fmt.Errorf("failed to create bond %s: %w", bondIfName, err)
+		}
+		defer func() {
+			if err != nil {
+				_ = s.nLink.LinkDel(bond)
+			}
+		}()
+
+		for _, memberIfName := range memberIfNames {
+			member, err := s.nLink.LinkByName(memberIfName)
+			if err != nil {
+				return fmt.Errorf("failed to get bond member %s: %w", memberIfName, err)
+			}
+			// a bond only enslaves a netdev that is down
+			if err = s.nLink.LinkSetDown(member); err != nil {
+				return fmt.Errorf("failed to set bond member %s down: %w", memberIfName, err)
+			}
+			if err = s.nLink.LinkSetMaster(member, bond); err != nil {
+				return fmt.Errorf("failed to enslave %s to bond %s: %w", memberIfName, bondIfName, err)
+			}
+		}
+
+		if err = s.nLink.LinkSetUp(bond); err != nil {
+			return fmt.Errorf("failed to set bond %s up: %w", bondIfName, err)
+		}
+		logging.Debug("Bond created",
+			"func", "SetupBond",
+			"bondIfName", bondIfName,
+			"mode", conf.Bond.Mode,
+			"members", memberIfNames)
+		return nil
+	})
+}
+
+// ReleaseBond deletes the bond bondIfName from the pod netns, which frees its members to be returned to the host by
+// ReleaseVF. A bond already gone is not an error.
+func (s *sriovManager) ReleaseBond(bondIfName string, netns ns.NetNS) error {
+	return netns.Do(func(_ ns.NetNS) error {
+		bond, err := s.nLink.LinkByName(bondIfName)
+		if err != nil {
+			logging.Debug("Bond not found, nothing to delete",
+				"func", "ReleaseBond",
+				"bondIfName", bondIfName,
+				"err", err)
+			return nil
+		}
+		if err = s.nLink.LinkDel(bond); err != nil {
+			return fmt.Errorf("failed to delete bond %s: %w", bondIfName, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,97 @@
+package sriov
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+)
+
+// VFAttrDiff is a VF attribute whose live value differs from the configured one
+type VFAttrDiff struct {
+	Attr     string
+	Expected string
+	Actual   string
+}
+
+func (d VFAttrDiff) String() string {
+	return fmt.Sprintf("%s expected=%s actual=%s", d.Attr, d.Expected, d.Actual)
+}
+
+// VFConfigDiffError is returned by CheckVFConfig when VF attributes drifted from the configuration, it lists
+// every drifted attribute
+type VFConfigDiffError struct {
+	VFID  int
+	Diffs []VFAttrDiff
+}
+
+func (e *VFConfigDiffError) Error() string {
+	diffs := make([]string, 0, len(e.Diffs))
+	for _, d := range e.Diffs {
+		diffs = append(diffs, d.String())
+	}
+	return fmt.Sprintf("vf %d drifted from its configuration: %s", e.VFID, strings.Join(diffs, ", "))
+}
+
+// LogArgs returns the drifted attributes as structured log arguments, each attribute keyed by its name
+func (e *VFConfigDiffError) LogArgs() []interface{} {
+	args := make([]interface{}, 0, 2*len(e.Diffs))
+	for _, d := range e.Diffs {
+		args = append(args, d.Attr, fmt.Sprintf("expected=%s actual=%s", d.Expected, d.Actual))
+	}
+	return args
+}
+
+// onOff formats a boolean VF attribute like the netconf does
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// diffVFConfig compares the administrative attributes of the configuration with the live VF attributes, the
+// attributes the configuration does not set are not compared. The link state is compared by CheckVFConfig.
+func diffVFConfig(conf *sriovtypes.NetConf, vfInfo *netlink.VfInfo) []VFAttrDiff {
+	var diffs []VFAttrDiff
+	add := func(attr, expected, actual string) {
+		diffs = append(diffs, VFAttrDiff{Attr: attr, Expected: expected, Actual: actual})
+	}
+
+	if conf.Vlan != nil {
+		if vfInfo.Vlan != *conf.Vlan {
+			add("vlan", strconv.Itoa(*conf.Vlan), strconv.Itoa(vfInfo.Vlan))
+		}
+		if conf.VlanQoS != nil && vfInfo.Qos != *conf.VlanQoS {
+			add("vlanQoS", strconv.Itoa(*conf.VlanQoS), strconv.Itoa(vfInfo.Qos))
+		}
+		// the proto of the VF is only meaningful with a vlan
+		if conf.VlanProto != nil && *conf.Vlan != 0 && vfInfo.VlanProto != sriovtypes.VlanProtoInt[*conf.VlanProto] {
+			add("vlanProto", *conf.VlanProto, vlanProtoName(vfInfo.VlanProto))
+		}
+	}
+
+	if conf.MAC != "" && !conf.SkipsMACConfig() && !strings.EqualFold(vfInfo.Mac.String(), conf.MAC) {
+		add("mac", conf.MAC, vfInfo.Mac.String())
+	}
+
+	// tx rates are only compared when they are configured
+	if conf.MinTxRate != nil && vfInfo.MinTxRate != uint32(*conf.MinTxRate) {
+		add("min_tx_rate", strconv.Itoa(*conf.MinTxRate), strconv.FormatUint(uint64(vfInfo.MinTxRate), 10))
+	}
+	if conf.MaxTxRate != nil && vfInfo.MaxTxRate != uint32(*conf.MaxTxRate) {
+		add("max_tx_rate", strconv.Itoa(*conf.MaxTxRate), strconv.FormatUint(uint64(vfInfo.MaxTxRate), 10))
+	}
+
+	if conf.SpoofChk != "" && vfInfo.Spoofchk != (conf.SpoofChk == "on") {
+		add("spoofchk", conf.SpoofChk, onOff(vfInfo.Spoofchk))
+	}
+	if conf.Trust != "" && (vfInfo.Trust != 0) != (conf.Trust == "on") {
+		add("trust", conf.Trust, onOff(vfInfo.Trust != 0))
+	}
+
+	return diffs
+}
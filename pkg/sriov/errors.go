@@ -0,0 +1,60 @@
+package sriov
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+)
+
+var (
+	// ErrVFNotFound is returned when the VF, its netdev or its PF does not exist
+	ErrVFNotFound = errors.New("VF not found")
+	// ErrVFBusy is returned when the VF is in use and the operation may succeed if retried
+	ErrVFBusy = errors.New("VF busy")
+	// ErrInvalidVFConfig is returned when the VF configuration is rejected by the plugin, the driver or the device
+	ErrInvalidVFConfig = errors.New("invalid VF configuration")
+	// ErrNetlinkTimeout is returned when a netlink operation did not complete within the netlink timeout
+	ErrNetlinkTimeout = errors.New("netlink operation timed out")
+)
+
+// vfError is an error of a VF operation of one of the kinds above. Its message is the one of the underlying
+// error, both the kind and the underlying error can be matched with errors.Is.
+type vfError struct {
+	kind error
+	err  error
+}
+
+func (e *vfError) Error() string {
+	return e.err.Error()
+}
+
+func (e *vfError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// newVFError returns err as an error of the given kind
+func newVFError(kind, err error) error {
+	return &vfError{kind: kind, err: err}
+}
+
+// classifyVFError returns err as an error of the kind of the netlink or sysfs error it wraps. Errors that already
+// have a kind, and errors whose underlying error has none, are returned unchanged.
+func classifyVFError(err error) error {
+	if err == nil || errors.Is(err, ErrVFNotFound) || errors.Is(err, ErrVFBusy) || errors.Is(err, ErrInvalidVFConfig) ||
+		errors.Is(err, ErrNetlinkTimeout) {
+		return err
+	}
+
+	var linkNotFound netlink.LinkNotFoundError
+	switch {
+	case errors.As(err, &linkNotFound), errors.Is(err, syscall.ENODEV), errors.Is(err, os.ErrNotExist):
+		return newVFError(ErrVFNotFound, err)
+	case errors.Is(err, syscall.EBUSY), errors.Is(err, syscall.EAGAIN):
+		return newVFError(ErrVFBusy, err)
+	case errors.Is(err, syscall.EINVAL), errors.Is(err, syscall.ERANGE), errors.Is(err, syscall.EOPNOTSUPP):
+		return newVFError(ErrInvalidVFConfig, err)
+	}
+	return err
+}
@@ -2,13 +2,85 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+
+	utils "github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
+)
 
 // PciUtils is an autogenerated mock type for the pciUtils type
 type PciUtils struct {
 	mock.Mock
 }
 
+// AddDCBApps provides a mock function with given fields: ifName, apps
+func (_m *PciUtils) AddDCBApps(ifName string, apps []utils.DCBApp) error {
+	ret := _m.Called(ifName, apps)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []utils.DCBApp) error); ok {
+		r0 = rf(ifName, apps)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// BindDriver provides a mock function with given fields: pciAddr, driver
+func (_m *PciUtils) BindDriver(pciAddr string, driver string) error {
+	ret := _m.Called(pciAddr, driver)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(pciAddr, driver)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CapturePackets provides a mock function with given fields: ifName, path, duration
+func (_m *PciUtils) CapturePackets(ifName string, path string, duration time.Duration) (int, error) {
+	ret := _m.Called(ifName, path, duration)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, time.Duration) (int, error)); ok {
+		return rf(ifName, path, duration)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, time.Duration) int); ok {
+		r0 = rf(ifName, path, duration)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, time.Duration) error); ok {
+		r1 = rf(ifName, path, duration)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DelDCBApps provides a mock function with given fields: ifName, apps
+func (_m *PciUtils) DelDCBApps(ifName string, apps []utils.DCBApp) error {
+	ret := _m.Called(ifName, apps)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []utils.DCBApp) error); ok {
+		r0 = rf(ifName, apps)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // EnableArpAndNdiscNotify provides a mock function with given fields: ifName
 func (_m *PciUtils) EnableArpAndNdiscNotify(ifName string) error {
 	ret := _m.Called(ifName)
@@ -37,6 +109,209 @@ func (_m *PciUtils) EnableOptimisticDad(ifName string) error {
 	return r0
 }
 
+// GetAdaptiveCoalesce provides a mock function with given fields: ifName
+func (_m *PciUtils) GetAdaptiveCoalesce(ifName string) (bool, bool, error) {
+	ret := _m.Called(ifName)
+
+	var r0 bool
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string) (bool, bool, error)); ok {
+		return rf(ifName)
+	}
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(ifName)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(ifName)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(ifName)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetDCBETS provides a mock function with given fields: ifName
+func (_m *PciUtils) GetDCBETS(ifName string) (*utils.DCBETS, error) {
+	ret := _m.Called(ifName)
+
+	var r0 *utils.DCBETS
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*utils.DCBETS, error)); ok {
+		return rf(ifName)
+	}
+	if rf, ok := ret.Get(0).(func(string) *utils.DCBETS); ok {
+		r0 = rf(ifName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*utils.DCBETS)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(ifName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDrvInfo provides a mock function with given fields: ifName
+func (_m *PciUtils) GetDrvInfo(ifName string) (*utils.DrvInfo, error) {
+	ret := _m.Called(ifName)
+
+	var r0 *utils.DrvInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*utils.DrvInfo, error)); ok {
+		return rf(ifName)
+	}
+	if rf, ok := ret.Get(0).(func(string) *utils.DrvInfo); ok {
+		r0 = rf(ifName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*utils.DrvInfo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(ifName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLoopback provides a mock function with given fields: ifName
+func (_m *PciUtils) GetLoopback(ifName string) (bool, error) {
+	ret := _m.Called(ifName)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (bool, error)); ok {
+		return rf(ifName)
+	}
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(ifName)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(ifName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetNUMANodeCPUMask provides a mock function with given fields: ifName
+func (_m *PciUtils) GetNUMANodeCPUMask(ifName string) (string, error) {
+	ret := _m.Called(ifName)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(ifName)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(ifName)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(ifName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPFDriver provides a mock function with given fields: pfName
+func (_m *PciUtils) GetPFDriver(pfName string) (string, error) {
+	ret := _m.Called(pfName)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(pfName)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(pfName)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pfName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPFLinkSpeed provides a mock function with given fields: pfName
+func (_m *PciUtils) GetPFLinkSpeed(pfName string) (int, error) {
+	ret := _m.Called(pfName)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(pfName)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(pfName)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pfName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPFTotalVFs provides a mock function with given fields: pfName
+func (_m *PciUtils) GetPFTotalVFs(pfName string) (int, error) {
+	ret := _m.Called(pfName)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(pfName)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(pfName)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pfName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetPciAddress provides a mock function with given fields: ifName, vf
 func (_m *PciUtils) GetPciAddress(ifName string, vf int) (string, error) {
 	ret := _m.Called(ifName, vf)
@@ -61,8 +336,34 @@ func (_m *PciUtils) GetPciAddress(ifName string, vf int) (string, error) {
 	return r0, r1
 }
 
-// GetSriovNumVfs provides a mock function with given fields: ifName
-func (_m *PciUtils) GetSriovNumVfs(ifName string) (int, error) {
+// GetPrivFlags provides a mock function with given fields: ifName
+func (_m *PciUtils) GetPrivFlags(ifName string) (map[string]bool, error) {
+	ret := _m.Called(ifName)
+
+	var r0 map[string]bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (map[string]bool, error)); ok {
+		return rf(ifName)
+	}
+	if rf, ok := ret.Get(0).(func(string) map[string]bool); ok {
+		r0 = rf(ifName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]bool)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(ifName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRSSIndirSize provides a mock function with given fields: ifName
+func (_m *PciUtils) GetRSSIndirSize(ifName string) (int, error) {
 	ret := _m.Called(ifName)
 
 	var r0 int
@@ -85,25 +386,23 @@ func (_m *PciUtils) GetSriovNumVfs(ifName string) (int, error) {
 	return r0, r1
 }
 
-// GetVFLinkNamesFromVFID provides a mock function with given fields: pfName, vfID
-func (_m *PciUtils) GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, error) {
-	ret := _m.Called(pfName, vfID)
+// GetRxQueueCount provides a mock function with given fields: ifName
+func (_m *PciUtils) GetRxQueueCount(ifName string) (int, error) {
+	ret := _m.Called(ifName)
 
-	var r0 []string
+	var r0 int
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, int) ([]string, error)); ok {
-		return rf(pfName, vfID)
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(ifName)
 	}
-	if rf, ok := ret.Get(0).(func(string, int) []string); ok {
-		r0 = rf(pfName, vfID)
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(ifName)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]string)
-		}
+		r0 = ret.Get(0).(int)
 	}
 
-	if rf, ok := ret.Get(1).(func(string, int) error); ok {
-		r1 = rf(pfName, vfID)
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(ifName)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -111,16 +410,506 @@ func (_m *PciUtils) GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, e
 	return r0, r1
 }
 
-// NewPciUtils creates a new instance of PciUtils. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
-// The first argument is typically a *testing.T value.
-func NewPciUtils(t interface {
-	mock.TestingT
-	Cleanup(func())
-}) *PciUtils {
-	mock := &PciUtils{}
-	mock.Mock.Test(t)
-
-	t.Cleanup(func() { mock.AssertExpectations(t) })
+// GetSriovNumVfs provides a mock function with given fields: ifName
+func (_m *PciUtils) GetSriovNumVfs(ifName string) (int, error) {
+	ret := _m.Called(ifName)
 
-	return mock
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(ifName)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(ifName)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(ifName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTxQueueCount provides a mock function with given fields: ifName
+func (_m *PciUtils) GetTxQueueCount(ifName string) (int, error) {
+	ret := _m.Called(ifName)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(ifName)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(ifName)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(ifName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVFDriver provides a mock function with given fields: pciAddr
+func (_m *PciUtils) GetVFDriver(pciAddr string) (string, error) {
+	ret := _m.Called(pciAddr)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVFIRQs provides a mock function with given fields: ifName
+func (_m *PciUtils) GetVFIRQs(ifName string) ([]int, error) {
+	ret := _m.Called(ifName)
+
+	var r0 []int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]int, error)); ok {
+		return rf(ifName)
+	}
+	if rf, ok := ret.Get(0).(func(string) []int); ok {
+		r0 = rf(ifName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(ifName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVFKernelDriver provides a mock function with given fields: dataDir, pciAddr
+func (_m *PciUtils) GetVFKernelDriver(dataDir string, pciAddr string) (string, error) {
+	ret := _m.Called(dataDir, pciAddr)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) (string, error)); ok {
+		return rf(dataDir, pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) string); ok {
+		r0 = rf(dataDir, pciAddr)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(dataDir, pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVFLinkNamesFromVFID provides a mock function with given fields: pfName, vfID
+func (_m *PciUtils) GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, error) {
+	ret := _m.Called(pfName, vfID)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) ([]string, error)); ok {
+		return rf(pfName, vfID)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) []string); ok {
+		r0 = rf(pfName, vfID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(pfName, vfID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVFMaxMacChanges provides a mock function with given fields: pfName, vfID
+func (_m *PciUtils) GetVFMaxMacChanges(pfName string, vfID int) (int, error) {
+	ret := _m.Called(pfName, vfID)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) (int, error)); ok {
+		return rf(pfName, vfID)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) int); ok {
+		r0 = rf(pfName, vfID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(pfName, vfID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVFMaxTxRateCeiling provides a mock function with given fields: pfName, vfID
+func (_m *PciUtils) GetVFMaxTxRateCeiling(pfName string, vfID int) (int, error) {
+	ret := _m.Called(pfName, vfID)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) (int, error)); ok {
+		return rf(pfName, vfID)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) int); ok {
+		r0 = rf(pfName, vfID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(pfName, vfID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVFRepresentor provides a mock function with given fields: pfName, vfIndex
+func (_m *PciUtils) GetVFRepresentor(pfName string, vfIndex int) (string, error) {
+	ret := _m.Called(pfName, vfIndex)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) (string, error)); ok {
+		return rf(pfName, vfIndex)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) string); ok {
+		r0 = rf(pfName, vfIndex)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(pfName, vfIndex)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVFVlanAntiSpoof provides a mock function with given fields: pfName, vfID
+func (_m *PciUtils) GetVFVlanAntiSpoof(pfName string, vfID int) (bool, error) {
+	ret := _m.Called(pfName, vfID)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) (bool, error)); ok {
+		return rf(pfName, vfID)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) bool); ok {
+		r0 = rf(pfName, vfID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(pfName, vfID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IdentifyPort provides a mock function with given fields: ifName, seconds
+func (_m *PciUtils) IdentifyPort(ifName string, seconds int) error {
+	ret := _m.Called(ifName, seconds)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int) error); ok {
+		r0 = rf(ifName, seconds)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreDriver provides a mock function with given fields: pciAddr, driver
+func (_m *PciUtils) RestoreDriver(pciAddr string, driver string) error {
+	ret := _m.Called(pciAddr, driver)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(pciAddr, driver)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RunSelfTest provides a mock function with given fields: ifName
+func (_m *PciUtils) RunSelfTest(ifName string) (*utils.SelfTestResult, error) {
+	ret := _m.Called(ifName)
+
+	var r0 *utils.SelfTestResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*utils.SelfTestResult, error)); ok {
+		return rf(ifName)
+	}
+	if rf, ok := ret.Get(0).(func(string) *utils.SelfTestResult); ok {
+		r0 = rf(ifName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*utils.SelfTestResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(ifName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveVFKernelDriver provides a mock function with given fields: dataDir, pciAddr, driver
+func (_m *PciUtils) SaveVFKernelDriver(dataDir string, pciAddr string, driver string) error {
+	ret := _m.Called(dataDir, pciAddr, driver)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
+		r0 = rf(dataDir, pciAddr, driver)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetAdaptiveCoalesce provides a mock function with given fields: ifName, rx, tx
+func (_m *PciUtils) SetAdaptiveCoalesce(ifName string, rx bool, tx bool) error {
+	ret := _m.Called(ifName, rx, tx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, bool, bool) error); ok {
+		r0 = rf(ifName, rx, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetDCBETS provides a mock function with given fields: ifName, ets
+func (_m *PciUtils) SetDCBETS(ifName string, ets *utils.DCBETS) error {
+	ret := _m.Called(ifName, ets)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, *utils.DCBETS) error); ok {
+		r0 = rf(ifName, ets)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetIRQAffinity provides a mock function with given fields: irq, mask
+func (_m *PciUtils) SetIRQAffinity(irq int, mask string) error {
+	ret := _m.Called(irq, mask)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int, string) error); ok {
+		r0 = rf(irq, mask)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetInterfaceSysctl provides a mock function with given fields: ifName, name, value
+func (_m *PciUtils) SetInterfaceSysctl(ifName string, name string, value string) error {
+	ret := _m.Called(ifName, name, value)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
+		r0 = rf(ifName, name, value)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetLoopback provides a mock function with given fields: ifName, enable
+func (_m *PciUtils) SetLoopback(ifName string, enable bool) error {
+	ret := _m.Called(ifName, enable)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, bool) error); ok {
+		r0 = rf(ifName, enable)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetPrivFlags provides a mock function with given fields: ifName, flags
+func (_m *PciUtils) SetPrivFlags(ifName string, flags map[string]bool) error {
+	ret := _m.Called(ifName, flags)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, map[string]bool) error); ok {
+		r0 = rf(ifName, flags)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetRSSIndirTable provides a mock function with given fields: ifName, table
+func (_m *PciUtils) SetRSSIndirTable(ifName string, table []uint32) error {
+	ret := _m.Called(ifName, table)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []uint32) error); ok {
+		r0 = rf(ifName, table)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetTxQueueMaxRate provides a mock function with given fields: ifName, queue, rate
+func (_m *PciUtils) SetTxQueueMaxRate(ifName string, queue int, rate int) error {
+	ret := _m.Called(ifName, queue, rate)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int, int) error); ok {
+		r0 = rf(ifName, queue, rate)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetVFMaxMacChanges provides a mock function with given fields: pfName, vfID, limit
+func (_m *PciUtils) SetVFMaxMacChanges(pfName string, vfID int, limit int) error {
+	ret := _m.Called(pfName, vfID, limit)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int, int) error); ok {
+		r0 = rf(pfName, vfID, limit)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetVFVlanAntiSpoof provides a mock function with given fields: pfName, vfID, on
+func (_m *PciUtils) SetVFVlanAntiSpoof(pfName string, vfID int, on bool) error {
+	ret := _m.Called(pfName, vfID, on)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int, bool) error); ok {
+		r0 = rf(pfName, vfID, on)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewPciUtils creates a new instance of PciUtils. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewPciUtils(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *PciUtils {
+	mock := &PciUtils{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// GetRSSHashKeySize provides a mock function with given fields: ifName
+func (_m *PciUtils) GetRSSHashKeySize(ifName string) (int, error) {
+	ret := _m.Called(ifName)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(ifName)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(ifName)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(ifName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetRSSHashKey provides a mock function with given fields: ifName, key
+func (_m *PciUtils) SetRSSHashKey(ifName string, key []byte) error {
+	ret := _m.Called(ifName, key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []byte) error); ok {
+		r0 = rf(ifName, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
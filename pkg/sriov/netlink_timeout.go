@@ -0,0 +1,319 @@
+package sriov
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+)
+
+// defaultNetlinkTimeout is how long a netlink operation may take when no timeout is configured
+const defaultNetlinkTimeout = 10 * time.Second
+
+// timeoutNetlink is a NetlinkManager aborting the operations of the wrapped NetlinkManager that do not complete
+// within the timeout. An aborted operation keeps running in the background until the kernel answers it.
+type timeoutNetlink struct {
+	nLink   utils.NetlinkManager
+	timeout time.Duration
+}
+
+// withTimeout runs op in the network namespace of the calling thread and returns ErrNetlinkTimeout if it does
+// not complete within the timeout
+func withTimeout[T any](t *timeoutNetlink, name string, op func() (T, error)) (T, error) {
+	var (
+		res T
+		err error
+	)
+
+	// netlink sockets are bound to the network namespace of the thread opening them, which may be a pod
+	// network namespace entered by the caller
+	curNS, err := ns.GetCurrentNS()
+	if err != nil {
+		return res, fmt.Errorf("failed to get the current netns for netlink %s: %w", name, err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer curNS.Close()
+		if nsErr := curNS.Do(func(ns.NetNS) error {
+			res, err = op()
+			return nil
+		}); nsErr != nil {
+			err = fmt.Errorf("failed to enter netns %s for netlink %s: %w", curNS.Path(), name, nsErr)
+		}
+	}()
+
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return res, err
+	case <-timer.C:
+		var zero T
+		return zero, newVFError(ErrNetlinkTimeout, fmt.Errorf("netlink %s did not complete within %s", name, t.timeout))
+	}
+}
+
+// withTimeoutErr is withTimeout for the operations returning only an error
+func withTimeoutErr(t *timeoutNetlink, name string, op func() error) error {
+	_, err := withTimeout(t, name, func() (struct{}, error) {
+		return struct{}{}, op()
+	})
+	return err
+}
+
+// LinkByName implements NetlinkManager
+func (t *timeoutNetlink) LinkByName(name string) (netlink.Link, error) {
+	return withTimeout(t, "LinkByName", func() (netlink.Link, error) {
+		return t.nLink.LinkByName(name)
+	})
+}
+
+// LinkSetVfVlanQosProto implements NetlinkManager
+func (t *timeoutNetlink) LinkSetVfVlanQosProto(link netlink.Link, vf, vlan, qos, proto int) error {
+	return withTimeoutErr(t, "LinkSetVfVlanQosProto", func() error {
+		return t.nLink.LinkSetVfVlanQosProto(link, vf, vlan, qos, proto)
+	})
+}
+
+// LinkSetVfHardwareAddr implements NetlinkManager
+func (t *timeoutNetlink) LinkSetVfHardwareAddr(link netlink.Link, vf int, hwaddr net.HardwareAddr) error {
+	return withTimeoutErr(t, "LinkSetVfHardwareAddr", func() error {
+		return t.nLink.LinkSetVfHardwareAddr(link, vf, hwaddr)
+	})
+}
+
+// LinkSetHardwareAddr implements NetlinkManager
+func (t *timeoutNetlink) LinkSetHardwareAddr(link netlink.Link, hwaddr net.HardwareAddr) error {
+	return withTimeoutErr(t, "LinkSetHardwareAddr", func() error {
+		return t.nLink.LinkSetHardwareAddr(link, hwaddr)
+	})
+}
+
+// LinkSetUp implements NetlinkManager
+func (t *timeoutNetlink) LinkSetUp(link netlink.Link) error {
+	return withTimeoutErr(t, "LinkSetUp", func() error {
+		return t.nLink.LinkSetUp(link)
+	})
+}
+
+// LinkSetDown implements NetlinkManager
+func (t *timeoutNetlink) LinkSetDown(link netlink.Link) error {
+	return withTimeoutErr(t, "LinkSetDown", func() error {
+		return t.nLink.LinkSetDown(link)
+	})
+}
+
+// LinkSetNsFd implements NetlinkManager
+func (t *timeoutNetlink) LinkSetNsFd(link netlink.Link, fd int) error {
+	return withTimeoutErr(t, "LinkSetNsFd", func() error {
+		return t.nLink.LinkSetNsFd(link, fd)
+	})
+}
+
+// LinkSetName implements NetlinkManager
+func (t *timeoutNetlink) LinkSetName(link netlink.Link, name string) error {
+	return withTimeoutErr(t, "LinkSetName", func() error {
+		return t.nLink.LinkSetName(link, name)
+	})
+}
+
+// LinkSetVfRate implements NetlinkManager
+func (t *timeoutNetlink) LinkSetVfRate(link netlink.Link, vf, minRate, maxRate int) error {
+	return withTimeoutErr(t, "LinkSetVfRate", func() error {
+		return t.nLink.LinkSetVfRate(link, vf, minRate, maxRate)
+	})
+}
+
+// LinkSetVfSpoofchk implements NetlinkManager
+func (t *timeoutNetlink) LinkSetVfSpoofchk(link netlink.Link, vf int, check bool) error {
+	return withTimeoutErr(t, "LinkSetVfSpoofchk", func() error {
+		return t.nLink.LinkSetVfSpoofchk(link, vf, check)
+	})
+}
+
+// LinkSetVfTrust implements NetlinkManager
+func (t *timeoutNetlink) LinkSetVfTrust(link netlink.Link, vf int, state bool) error {
+	return withTimeoutErr(t, "LinkSetVfTrust", func() error {
+		return t.nLink.LinkSetVfTrust(link, vf, state)
+	})
+}
+
+// LinkSetVfState implements NetlinkManager
+func (t *timeoutNetlink) LinkSetVfState(link netlink.Link, vf int, state uint32) error {
+	return withTimeoutErr(t, "LinkSetVfState", func() error {
+		return t.nLink.LinkSetVfState(link, vf, state)
+	})
+}
+
+// LinkSetVfNodeGUID implements NetlinkManager
+func (t *timeoutNetlink) LinkSetVfNodeGUID(link netlink.Link, vf int, nodeguid net.HardwareAddr) error {
+	return withTimeoutErr(t, "LinkSetVfNodeGUID", func() error {
+		return t.nLink.LinkSetVfNodeGUID(link, vf, nodeguid)
+	})
+}
+
+// LinkSetVfPortGUID implements NetlinkManager
+func (t *timeoutNetlink) LinkSetVfPortGUID(link netlink.Link, vf int, portguid net.HardwareAddr) error {
+	return withTimeoutErr(t, "LinkSetVfPortGUID", func() error {
+		return t.nLink.LinkSetVfPortGUID(link, vf, portguid)
+	})
+}
+
+// LinkGetVfNodeGUID implements NetlinkManager
+func (t *timeoutNetlink) LinkGetVfNodeGUID(link netlink.Link, vf int) (net.HardwareAddr, error) {
+	return withTimeout(t, "LinkGetVfNodeGUID", func() (net.HardwareAddr, error) {
+		return t.nLink.LinkGetVfNodeGUID(link, vf)
+	})
+}
+
+// LinkGetVfPortGUID implements NetlinkManager
+func (t *timeoutNetlink) LinkGetVfPortGUID(link netlink.Link, vf int) (net.HardwareAddr, error) {
+	return withTimeout(t, "LinkGetVfPortGUID", func() (net.HardwareAddr, error) {
+		return t.nLink.LinkGetVfPortGUID(link, vf)
+	})
+}
+
+// LinkDelAltName implements NetlinkManager
+func (t *timeoutNetlink) LinkDelAltName(link netlink.Link, altName string) error {
+	return withTimeoutErr(t, "LinkDelAltName", func() error {
+		return t.nLink.LinkDelAltName(link, altName)
+	})
+}
+
+// BridgeVlanList implements NetlinkManager
+func (t *timeoutNetlink) BridgeVlanList() (map[int32][]*nl.BridgeVlanInfo, error) {
+	return withTimeout(t, "BridgeVlanList", t.nLink.BridgeVlanList)
+}
+
+// DevLinkGetDeviceByName implements NetlinkManager
+func (t *timeoutNetlink) DevLinkGetDeviceByName(bus, device string) (*netlink.DevlinkDevice, error) {
+	return withTimeout(t, "DevLinkGetDeviceByName", func() (*netlink.DevlinkDevice, error) {
+		return t.nLink.DevLinkGetDeviceByName(bus, device)
+	})
+}
+
+// NeighAppend implements NetlinkManager
+func (t *timeoutNetlink) NeighAppend(neigh *netlink.Neigh) error {
+	return withTimeoutErr(t, "NeighAppend", func() error {
+		return t.nLink.NeighAppend(neigh)
+	})
+}
+
+// NeighDel implements NetlinkManager
+func (t *timeoutNetlink) NeighDel(neigh *netlink.Neigh) error {
+	return withTimeoutErr(t, "NeighDel", func() error {
+		return t.nLink.NeighDel(neigh)
+	})
+}
+
+// QdiscAdd implements NetlinkManager
+func (t *timeoutNetlink) QdiscAdd(qdisc netlink.Qdisc) error {
+	return withTimeoutErr(t, "QdiscAdd", func() error {
+		return t.nLink.QdiscAdd(qdisc)
+	})
+}
+
+// QdiscDel implements NetlinkManager
+func (t *timeoutNetlink) QdiscDel(qdisc netlink.Qdisc) error {
+	return withTimeoutErr(t, "QdiscDel", func() error {
+		return t.nLink.QdiscDel(qdisc)
+	})
+}
+
+// FilterAdd implements NetlinkManager
+func (t *timeoutNetlink) FilterAdd(filter netlink.Filter) error {
+	return withTimeoutErr(t, "FilterAdd", func() error {
+		return t.nLink.FilterAdd(filter)
+	})
+}
+
+// BridgeVlanAdd implements NetlinkManager
+func (t *timeoutNetlink) BridgeVlanAdd(link netlink.Link, vid uint16, pvid, untagged, self, master bool) error {
+	return withTimeoutErr(t, "BridgeVlanAdd", func() error {
+		return t.nLink.BridgeVlanAdd(link, vid, pvid, untagged, self, master)
+	})
+}
+
+// BridgeVlanDel implements NetlinkManager
+func (t *timeoutNetlink) BridgeVlanDel(link netlink.Link, vid uint16, pvid, untagged, self, master bool) error {
+	return withTimeoutErr(t, "BridgeVlanDel", func() error {
+		return t.nLink.BridgeVlanDel(link, vid, pvid, untagged, self, master)
+	})
+}
+
+// LinkSetVlanEgressQoSMap implements NetlinkManager
+func (t *timeoutNetlink) LinkSetVlanEgressQoSMap(link netlink.Link, qosMap map[uint32]uint32) error {
+	return withTimeoutErr(t, "LinkSetVlanEgressQoSMap", func() error {
+		return t.nLink.LinkSetVlanEgressQoSMap(link, qosMap)
+	})
+}
+
+// LinkSetAllmulticastOn implements NetlinkManager
+func (t *timeoutNetlink) LinkSetAllmulticastOn(link netlink.Link) error {
+	return withTimeoutErr(t, "LinkSetAllmulticastOn", func() error {
+		return t.nLink.LinkSetAllmulticastOn(link)
+	})
+}
+
+// LinkSetAllmulticastOff implements NetlinkManager
+func (t *timeoutNetlink) LinkSetAllmulticastOff(link netlink.Link) error {
+	return withTimeoutErr(t, "LinkSetAllmulticastOff", func() error {
+		return t.nLink.LinkSetAllmulticastOff(link)
+	})
+}
+
+// LinkSetTxQLen implements NetlinkManager
+func (t *timeoutNetlink) LinkSetTxQLen(link netlink.Link, qlen int) error {
+	return withTimeoutErr(t, "LinkSetTxQLen", func() error {
+		return t.nLink.LinkSetTxQLen(link, qlen)
+	})
+}
+
+// RouteAdd implements NetlinkManager
+func (t *timeoutNetlink) RouteAdd(route *netlink.Route) error {
+	return withTimeoutErr(t, "RouteAdd", func() error {
+		return t.nLink.RouteAdd(route)
+	})
+}
+
+// RuleAdd implements NetlinkManager
+func (t *timeoutNetlink) RuleAdd(rule *netlink.Rule) error {
+	return withTimeoutErr(t, "RuleAdd", func() error {
+		return t.nLink.RuleAdd(rule)
+	})
+}
+
+// RuleDel implements NetlinkManager
+func (t *timeoutNetlink) RuleDel(rule *netlink.Rule) error {
+	return withTimeoutErr(t, "RuleDel", func() error {
+		return t.nLink.RuleDel(rule)
+	})
+}
+
+// LinkAdd implements NetlinkManager
+func (t *timeoutNetlink) LinkAdd(link netlink.Link) error {
+	return withTimeoutErr(t, "LinkAdd", func() error {
+		return t.nLink.LinkAdd(link)
+	})
+}
+
+// LinkDel implements NetlinkManager
+func (t *timeoutNetlink) LinkDel(link netlink.Link) error {
+	return withTimeoutErr(t, "LinkDel", func() error {
+		return t.nLink.LinkDel(link)
+	})
+}
+
+// LinkSetMaster implements NetlinkManager
+func (t *timeoutNetlink) LinkSetMaster(link, master netlink.Link) error {
+	return withTimeoutErr(t, "LinkSetMaster", func() error {
+		return t.nLink.LinkSetMaster(link, master)
+	})
+}
@@ -1,22 +1,101 @@
 package sriov
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/plugins/pkg/ipam"
 	"github.com/containernetworking/plugins/pkg/ns"
 
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/config"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
 	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 )
 
+const (
+	// defaultLinkUpTimeout is how long SetupVF waits for the VF link to be up when no timeout is configured
+	defaultLinkUpTimeout = 5 * time.Second
+	// linkUpPollInterval is the interval between two link state polls while waiting for the VF link to be up
+	linkUpPollInterval = 50 * time.Millisecond
+)
+
+// captureDuration is how long the packets of the VF are captured after an ADD failure
+const captureDuration = time.Second
+
+// zeroMAC is the administrative MAC address of a VF whose MAC address is assigned by the driver
+const zeroMAC = "00:00:00:00:00:00"
+
+// eswitchModeSwitchdev is the devlink e-switch mode of PFs exposing VF representors
+const eswitchModeSwitchdev = "switchdev"
+
+// microburstPrivFlags are the private flags smoothing rx and tx bursts of the VF drivers that have them, the
+// other drivers use adaptive interrupt coalescing
+var microburstPrivFlags = map[string][]string{
+	"mlx5_core": {"rx_cqe_moder", "tx_cqe_moder"},
+}
+
+// vlanQoS8021adUnsupported lists the PF drivers that only support a vlan QoS with 802.1q
+var vlanQoS8021adUnsupported = map[string]bool{
+	"i40e":  true,
+	"ixgbe": true,
+}
+
 type pciUtils interface {
 	GetSriovNumVfs(ifName string) (int, error)
 	GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, error)
 	GetPciAddress(ifName string, vf int) (string, error)
 	EnableArpAndNdiscNotify(ifName string) error
 	EnableOptimisticDad(ifName string) error
+	SetInterfaceSysctl(ifName, name, value string) error
+	GetTxQueueCount(ifName string) (int, error)
+	SetTxQueueMaxRate(ifName string, queue, rate int) error
+	GetVFDriver(pciAddr string) (string, error)
+	BindDriver(pciAddr, driver string) error
+	RestoreDriver(pciAddr, driver string) error
+	GetRSSHashKeySize(ifName string) (int, error)
+	SetRSSHashKey(ifName string, key []byte) error
+	GetRxQueueCount(ifName string) (int, error)
+	GetRSSIndirSize(ifName string) (int, error)
+	SetRSSIndirTable(ifName string, table []uint32) error
+	GetVFMaxMacChanges(pfName string, vfID int) (int, error)
+	SetVFMaxMacChanges(pfName string, vfID, limit int) error
+	GetVFVlanAntiSpoof(pfName string, vfID int) (bool, error)
+	SetVFVlanAntiSpoof(pfName string, vfID int, on bool) error
+	GetVFMaxTxRateCeiling(pfName string, vfID int) (int, error)
+	GetPrivFlags(ifName string) (map[string]bool, error)
+	SetPrivFlags(ifName string, flags map[string]bool) error
+	GetVFRepresentor(pfName string, vfIndex int) (string, error)
+	SaveVFKernelDriver(dataDir, pciAddr, driver string) error
+	GetVFKernelDriver(dataDir, pciAddr string) (string, error)
+	GetDrvInfo(ifName string) (*utils.DrvInfo, error)
+	GetPFDriver(pfName string) (string, error)
+	GetAdaptiveCoalesce(ifName string) (bool, bool, error)
+	SetAdaptiveCoalesce(ifName string, rx, tx bool) error
+	GetVFIRQs(ifName string) ([]int, error)
+	GetNUMANodeCPUMask(ifName string) (string, error)
+	SetIRQAffinity(irq int, mask string) error
+	GetPFLinkSpeed(pfName string) (int, error)
+	IdentifyPort(ifName string, seconds int) error
+	GetLoopback(ifName string) (bool, error)
+	SetLoopback(ifName string, enable bool) error
+	RunSelfTest(ifName string) (*utils.SelfTestResult, error)
+	GetPFTotalVFs(pfName string) (int, error)
+	GetDCBETS(ifName string) (*utils.DCBETS, error)
+	SetDCBETS(ifName string, ets *utils.DCBETS) error
+	AddDCBApps(ifName string, apps []utils.DCBApp) error
+	DelDCBApps(ifName string, apps []utils.DCBApp) error
+	CapturePackets(ifName, path string, duration time.Duration) (int, error)
 }
 
 type pciUtilsImpl struct{}
@@ -41,13 +120,191 @@ func (p *pciUtilsImpl) EnableOptimisticDad(ifName string) error {
 	return utils.EnableOptimisticDad(ifName)
 }
 
+func (p *pciUtilsImpl) SetInterfaceSysctl(ifName, name, value string) error {
+	return utils.SetInterfaceSysctl(ifName, name, value)
+}
+
+func (p *pciUtilsImpl) GetTxQueueCount(ifName string) (int, error) {
+	return utils.GetTxQueueCount(ifName)
+}
+
+func (p *pciUtilsImpl) SetTxQueueMaxRate(ifName string, queue, rate int) error {
+	return utils.SetTxQueueMaxRate(ifName, queue, rate)
+}
+
+func (p *pciUtilsImpl) GetVFDriver(pciAddr string) (string, error) {
+	return utils.GetVFDriver(pciAddr)
+}
+
+func (p *pciUtilsImpl) BindDriver(pciAddr, driver string) error {
+	return utils.BindDriver(pciAddr, driver)
+}
+
+func (p *pciUtilsImpl) RestoreDriver(pciAddr, driver string) error {
+	return utils.RestoreDriver(pciAddr, driver)
+}
+
+func (p *pciUtilsImpl) GetRSSHashKeySize(ifName string) (int, error) {
+	return utils.GetRSSHashKeySize(ifName)
+}
+
+func (p *pciUtilsImpl) SetRSSHashKey(ifName string, key []byte) error {
+	return utils.SetRSSHashKey(ifName, key)
+}
+
+func (p *pciUtilsImpl) GetRxQueueCount(ifName string) (int, error) {
+	return utils.GetRxQueueCount(ifName)
+}
+
+func (p *pciUtilsImpl) GetRSSIndirSize(ifName string) (int, error) {
+	return utils.GetRSSIndirSize(ifName)
+}
+
+func (p *pciUtilsImpl) SetRSSIndirTable(ifName string, table []uint32) error {
+	return utils.SetRSSIndirTable(ifName, table)
+}
+
+func (p *pciUtilsImpl) GetVFMaxMacChanges(pfName string, vfID int) (int, error) {
+	return utils.GetVFMaxMacChanges(pfName, vfID)
+}
+
+func (p *pciUtilsImpl) SetVFMaxMacChanges(pfName string, vfID, limit int) error {
+	return utils.SetVFMaxMacChanges(pfName, vfID, limit)
+}
+
+func (p *pciUtilsImpl) GetVFVlanAntiSpoof(pfName string, vfID int) (bool, error) {
+	return utils.GetVFVlanAntiSpoof(pfName, vfID)
+}
+
+func (p *pciUtilsImpl) SetVFVlanAntiSpoof(pfName string, vfID int, on bool) error {
+	return utils.SetVFVlanAntiSpoof(pfName, vfID, on)
+}
+
+func (p *pciUtilsImpl) GetVFMaxTxRateCeiling(pfName string, vfID int) (int, error) {
+	return utils.GetVFMaxTxRateCeiling(pfName, vfID)
+}
+
+func (p *pciUtilsImpl) GetPrivFlags(ifName string) (map[string]bool, error) {
+	return utils.GetPrivFlags(ifName)
+}
+
+func (p *pciUtilsImpl) SetPrivFlags(ifName string, flags map[string]bool) error {
+	return utils.SetPrivFlags(ifName, flags)
+}
+
+func (p *pciUtilsImpl) GetVFRepresentor(pfName string, vfIndex int) (string, error) {
+	return utils.GetVFRepresentor(pfName, vfIndex)
+}
+
+func (p *pciUtilsImpl) SaveVFKernelDriver(dataDir, pciAddr, driver string) error {
+	return utils.SaveVFKernelDriver(dataDir, pciAddr, driver)
+}
+
+func (p *pciUtilsImpl) GetVFKernelDriver(dataDir, pciAddr string) (string, error) {
+	return utils.GetVFKernelDriver(dataDir, pciAddr)
+}
+
+func (p *pciUtilsImpl) GetDrvInfo(ifName string) (*utils.DrvInfo, error) {
+	return utils.GetDrvInfo(ifName)
+}
+
+func (p *pciUtilsImpl) GetPFDriver(pfName string) (string, error) {
+	return utils.GetPFDriver(pfName)
+}
+
+func (p *pciUtilsImpl) GetAdaptiveCoalesce(ifName string) (bool, bool, error) {
+	return utils.GetAdaptiveCoalesce(ifName)
+}
+
+func (p *pciUtilsImpl) SetAdaptiveCoalesce(ifName string, rx, tx bool) error {
+	return utils.SetAdaptiveCoalesce(ifName, rx, tx)
+}
+
+func (p *pciUtilsImpl) GetVFIRQs(ifName string) ([]int, error) {
+	return utils.GetVFIRQs(ifName)
+}
+
+func (p *pciUtilsImpl) GetNUMANodeCPUMask(ifName string) (string, error) {
+	return utils.GetNUMANodeCPUMask(ifName)
+}
+
+func (p *pciUtilsImpl) SetIRQAffinity(irq int, mask string) error {
+	return utils.SetIRQAffinity(irq, mask)
+}
+
+func (p *pciUtilsImpl) GetPFLinkSpeed(pfName string) (int, error) {
+	return utils.GetPFLinkSpeed(pfName)
+}
+
+func (p *pciUtilsImpl) IdentifyPort(ifName string, seconds int) error {
+	return utils.IdentifyPort(ifName, seconds)
+}
+
+func (p *pciUtilsImpl) GetLoopback(ifName string) (bool, error) {
+	return utils.GetLoopback(ifName)
+}
+
+func (p *pciUtilsImpl) SetLoopback(ifName string, enable bool) error {
+	return utils.SetLoopback(ifName, enable)
+}
+
+func (p *pciUtilsImpl) RunSelfTest(ifName string) (*utils.SelfTestResult, error) {
+	return utils.RunSelfTest(ifName)
+}
+
+func (p *pciUtilsImpl) GetPFTotalVFs(pfName string) (int, error) {
+	return utils.GetPFTotalVFs(pfName)
+}
+
+func (p *pciUtilsImpl) GetDCBETS(ifName string) (*utils.DCBETS, error) {
+	return utils.GetDCBETS(ifName)
+}
+
+func (p *pciUtilsImpl) SetDCBETS(ifName string, ets *utils.DCBETS) error {
+	return utils.SetDCBETS(ifName, ets)
+}
+
+func (p *pciUtilsImpl) AddDCBApps(ifName string, apps []utils.DCBApp) error {
+	return utils.AddDCBApps(ifName, apps)
+}
+
+func (p *pciUtilsImpl) DelDCBApps(ifName string, apps []utils.DCBApp) error {
+	return utils.DelDCBApps(ifName, apps)
+}
+
+func (p *pciUtilsImpl) CapturePackets(ifName, path string, duration time.Duration) (int, error) {
+	return utils.CapturePackets(ifName, path, duration)
+}
+
 // Manager provides interface invoke sriov nic related operations
 type Manager interface {
 	SetupVF(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS) error
+	SetupVFs(vfs []BatchVF, netns ns.NetNS, maxWorkers int) error
+	RollbackVFs(vfs []BatchVF, netns ns.NetNS)
+	SetupBond(conf *sriovtypes.NetConf, bondIfName string, memberIfNames []string, netns ns.NetNS) error
+	ReleaseBond(bondIfName string, netns ns.NetNS) error
 	ReleaseVF(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS) error
 	ResetVFConfig(conf *sriovtypes.NetConf) error
 	ApplyVFConfig(conf *sriovtypes.NetConf) error
 	FillOriginalVfInfo(conf *sriovtypes.NetConf) error
+	BindVFDriver(conf *sriovtypes.NetConf) error
+	RestoreVFDriver(conf *sriovtypes.NetConf) error
+	CheckVFConfig(conf *sriovtypes.NetConf) error
+	CheckVFDriver(conf *sriovtypes.NetConf) error
+	CompareVFConfig(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS) error
+	ReconcileVFConfig(conf *sriovtypes.NetConf, dryRun bool) ([]string, error)
+	SetupRouteTable(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS, result *current.Result) error
+	ConfigureIPAMResult(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS, result *current.Result) error
+	GetRepresentor(conf *sriovtypes.NetConf) (string, error)
+	QuarantineRepresentor(conf *sriovtypes.NetConf) (string, error)
+	ReclaimRepresentor(repName string) error
+	LocatePort(pciAddr string, seconds int) (string, error)
+	SelfTestVF(pciAddr string) (*utils.SelfTestResult, error)
+	CheckPFNumVFs(conf *sriovtypes.NetConf) error
+	CheckVFDevice(conf *sriovtypes.NetConf) error
+	CaptureOnFailure(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS, addErr error)
+	DrainVF(conf *sriovtypes.NetConf)
+	SignalVFDown(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS)
 }
 
 type sriovManager struct {
@@ -55,25 +312,47 @@ type sriovManager struct {
 	utils pciUtils
 }
 
-// NewSriovManager returns an instance of SriovManager
+// NewSriovManager returns an instance of SriovManager whose netlink operations time out after the default
+// netlink timeout
 func NewSriovManager() Manager {
+	return newSriovManager(defaultNetlinkTimeout)
+}
+
+// NewSriovManagerForConf returns an instance of SriovManager whose netlink operations time out after the
+// netlinkTimeout of conf, or the default netlink timeout
+func NewSriovManagerForConf(conf *sriovtypes.NetConf) Manager {
+	timeout := defaultNetlinkTimeout
+	if conf.NetlinkTimeout != nil {
+		timeout = time.Duration(*conf.NetlinkTimeout) * time.Second
+	}
+	return newSriovManager(timeout)
+}
+
+func newSriovManager(netlinkTimeout time.Duration) Manager {
 	return &sriovManager{
-		nLink: &utils.MyNetlink{},
+		nLink: &timeoutNetlink{nLink: &utils.MyNetlink{}, timeout: netlinkTimeout},
 		utils: &pciUtilsImpl{},
 	}
 }
 
-// SetupVF sets up a VF in Pod netns
-func (s *sriovManager) SetupVF(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS) error {
+// SetupVF sets up a VF in Pod netns. Errors wrap ErrVFNotFound, ErrVFBusy or ErrInvalidVFConfig when their
+// cause is known.
+func (s *sriovManager) SetupVF(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS) (err error) {
+	defer func() { err = classifyVFError(err) }()
+
 	linkName := conf.OrigVfState.HostIFName
 
 	linkObj, err := s.nLink.LinkByName(linkName)
 	if err != nil {
-		return fmt.Errorf("error getting VF netdevice with name %s", linkName)
+		return newVFError(ErrVFNotFound, fmt.Errorf("error getting VF netdevice with name %s: %w", linkName, err))
 	}
 
 	// Save the original effective MAC address before overriding it
 	conf.OrigVfState.EffectiveMAC = linkObj.Attrs().HardwareAddr.String()
+	// Save the original allmulticast flag, it is kept by the VF netdev once it is released
+	conf.OrigVfState.AllMulti = linkObj.Attrs().Allmulti != 0
+	// Save the original transmit queue length, it is kept by the VF netdev once it is released
+	conf.OrigVfState.TxQLen = linkObj.Attrs().TxQLen
 
 	// tempName used as intermediary name to avoid name conflicts
 	tempName := fmt.Sprintf("%s%d", "temp_", linkObj.Attrs().Index)
@@ -83,7 +362,7 @@ func (s *sriovManager) SetupVF(conf *sriovtypes.NetConf, podifName string, netns
 		"func", "SetupVF",
 		"linkObj", linkObj)
 	if err := s.nLink.LinkSetDown(linkObj); err != nil {
-		return fmt.Errorf("failed to down vf device %q: %v", linkName, err)
+		return fmt.Errorf("failed to down vf device %q: %w", linkName, err)
 	}
 
 	// 2. Set temp name
@@ -103,28 +382,63 @@ func (s *sriovManager) SetupVF(conf *sriovtypes.NetConf, podifName string, netns
 		"tempName", tempName)
 	linkObj, err = s.nLink.LinkByName(tempName)
 	if err != nil {
-		return fmt.Errorf("error getting VF netdevice with name %s: %v", tempName, err)
+		return fmt.Errorf("error getting VF netdevice with name %s: %w", tempName, err)
 	}
 	for _, altName := range linkObj.Attrs().AltNames {
 		if altName == linkName {
 			if err := s.nLink.LinkDelAltName(linkObj, linkName); err != nil {
-				return fmt.Errorf("error removing VF altname %s: %v", linkName, err)
+				return fmt.Errorf("error removing VF altname %s: %w", linkName, err)
 			}
 		}
 	}
 
-	// 4. Change netns
-	logging.Debug("4. Change netns",
-		"func", "SetupVF",
-		"linkObj", linkObj,
-		"netns.Fd()", int(netns.Fd()))
-	if err := s.nLink.LinkSetNsFd(linkObj, int(netns.Fd())); err != nil {
-		return fmt.Errorf("failed to move IF %s to netns: %q", tempName, err)
+	// 4. Set tx queue rate limits
+	if len(conf.QueueRates) > 0 {
+		logging.Debug("4. Set tx queue rate limits",
+			"func", "SetupVF",
+			"tempName", tempName,
+			"conf.QueueRates", conf.QueueRates)
+		if err := s.setQueueRates(tempName, conf.QueueRates); err != nil {
+			return err
+		}
+	}
+
+	// 5. Pin the VF IRQs to the CPUs of its local NUMA node
+	if conf.IRQAffinity != nil && *conf.IRQAffinity {
+		logging.Debug("5. Pin the VF IRQs to the CPUs of its local NUMA node",
+			"func", "SetupVF",
+			"tempName", tempName)
+		if err := s.setIRQAffinity(tempName); err != nil {
+			return err
+		}
+	}
+
+	// 6. Change netns, a VF kept in the host netns is configured where it is
+	if !conf.KeepsInHostNetns() {
+		logging.Debug("6. Change netns",
+			"func", "SetupVF",
+			"linkObj", linkObj,
+			"netns.Fd()", int(netns.Fd()))
+		if err := s.nLink.LinkSetNsFd(linkObj, int(netns.Fd())); err != nil {
+			return fmt.Errorf("failed to move IF %s to netns: %w", tempName, err)
+		}
+	}
+
+	// 7. Set admin MAC address on the PF once the VF is moved, with the legacy ordering. By default ApplyVFConfig
+	// sets it before the move, so that the pod never sees the VF with its previous MAC.
+	if conf.MAC != "" && conf.UsesLegacyMACOrder() {
+		logging.Debug("7. Set admin MAC address",
+			"func", "SetupVF",
+			"conf.Master", conf.Master,
+			"conf.MAC", conf.MAC)
+		if err := utils.SetVFHardwareMAC(s.nLink, conf.Master, conf.VFID, conf.MAC); err != nil {
+			return fmt.Errorf("failed to set MAC address to %s: %w", conf.MAC, err)
+		}
 	}
 
 	if err := netns.Do(func(_ ns.NetNS) error {
-		// 5. Set Pod IF name
-		logging.Debug("5. Set Pod IF name",
+		// 8. Set Pod IF name
+		logging.Debug("8. Set Pod IF name",
 			"func", "SetupVF",
 			"linkObj", linkObj,
 			"podifName", podifName)
@@ -132,41 +446,220 @@ func (s *sriovManager) SetupVF(conf *sriovtypes.NetConf, podifName string, netns
 			return fmt.Errorf("error setting container interface name %s for %s", linkName, tempName)
 		}
 
-		// 6. Enable IPv4 ARP notify and IPv6 Network Discovery notify
+		// 9. Enable IPv4 ARP notify and IPv6 Network Discovery notify
 		// Error is ignored here because enabling this feature is only a performance enhancement.
-		logging.Debug("6. Enable IPv4 ARP notify and IPv6 Network Discovery notify",
+		logging.Debug("9. Enable IPv4 ARP notify and IPv6 Network Discovery notify",
 			"func", "SetupVF",
 			"podifName", podifName)
 		_ = s.utils.EnableArpAndNdiscNotify(podifName)
 
-		// 7. Set MAC address
+		// 10. Set MAC address
 		if conf.MAC != "" {
-			logging.Debug("7. Set MAC address",
+			logging.Debug("10. Set MAC address",
 				"func", "SetupVF",
 				"s.nLink", s.nLink,
 				"podifName", podifName,
 				"conf.MAC", conf.MAC)
 			err = utils.SetVFEffectiveMAC(s.nLink, podifName, conf.MAC)
 			if err != nil {
-				return fmt.Errorf("failed to set netlink MAC address to %s: %v", conf.MAC, err)
+				return fmt.Errorf("failed to set netlink MAC address to %s: %w", conf.MAC, err)
+			}
+		}
+
+		// 11. Set RSS hash key and indirection table
+		hashKey := conf.RSSHashKey
+		var indirTable []int
+		if conf.RSS != nil {
+			if conf.RSS.HashKey != "" {
+				hashKey = conf.RSS.HashKey
+			}
+			indirTable = conf.RSS.IndirTable
+		}
+		if hashKey != "" || len(indirTable) > 0 {
+			logging.Debug("11. Set RSS hash key and indirection table",
+				"func", "SetupVF",
+				"podifName", podifName,
+				"hashKey", hashKey,
+				"indirTable", indirTable)
+		}
+		if hashKey != "" {
+			if err := s.setRSSHashKey(podifName, hashKey); err != nil {
+				return err
+			}
+		}
+		if len(indirTable) > 0 {
+			if err := s.setRSSIndirTable(podifName, indirTable); err != nil {
+				return err
+			}
+		}
+
+		// 12. Set private flags
+		if len(conf.PrivFlags) > 0 {
+			logging.Debug("12. Set private flags",
+				"func", "SetupVF",
+				"podifName", podifName,
+				"conf.PrivFlags", conf.PrivFlags)
+			if err := s.setPrivFlags(podifName, conf); err != nil {
+				return err
+			}
+		}
+
+		// 13. Enable microburst protection
+		if conf.MicroburstProtection {
+			logging.Debug("13. Enable microburst protection",
+				"func", "SetupVF",
+				"podifName", podifName)
+			s.setMicroburstProtection(podifName, conf)
+		}
+
+		// 14. Add secondary MAC addresses
+		if len(conf.AltMACs) > 0 {
+			logging.Debug("14. Add secondary MAC addresses",
+				"func", "SetupVF",
+				"linkObj", linkObj,
+				"conf.AltMACs", conf.AltMACs)
+			if err := s.addAltMACs(linkObj, conf); err != nil {
+				return err
+			}
+		}
+
+		// 15. Set allmulticast
+		if conf.AllMulti != "" {
+			logging.Debug("15. Set allmulticast",
+				"func", "SetupVF",
+				"linkObj", linkObj,
+				"conf.AllMulti", conf.AllMulti)
+			if err := s.setAllMulti(linkObj, conf.AllMulti == "on"); err != nil {
+				return err
+			}
+		}
+
+		// 16. Set ingress policing
+		if conf.IngressPolice != nil {
+			logging.Debug("16. Set ingress policing",
+				"func", "SetupVF",
+				"linkObj", linkObj,
+				"conf.IngressPolice", conf.IngressPolice)
+			if err := s.setIngressPolice(linkObj, conf.IngressPolice); err != nil {
+				return err
+			}
+		}
+
+		// 17. Set VLAN egress QoS map. It sets the PCP of the VLAN tags the VF netdev inserts by skb priority,
+		// while the port VLAN configured on the PF is inserted by the NIC with the vlanQoS PCP, which takes
+		// precedence for that tag.
+		if conf.EgressQoSMap != "" {
+			logging.Debug("17. Set VLAN egress QoS map",
+				"func", "SetupVF",
+				"linkObj", linkObj,
+				"conf.EgressQoSMap", conf.EgressQoSMap)
+			qosMap, err := utils.ParseEgressQoSMap(conf.EgressQoSMap)
+			if err != nil {
+				return err
+			}
+			if err := s.nLink.LinkSetVlanEgressQoSMap(linkObj, qosMap); err != nil {
+				return fmt.Errorf("failed to set vlan egress qos map %s on %s: %w", conf.EgressQoSMap, podifName, err)
+			}
+		}
+
+		// 18. Set DCB ingress QoS map. The DSCP of the received packets selects their priority, which selects
+		// their traffic class.
+		if conf.IngressQoSMap != nil {
+			logging.Debug("18. Set DCB ingress QoS map",
+				"func", "SetupVF",
+				"podifName", podifName,
+				"conf.IngressQoSMap", conf.IngressQoSMap)
+			if err := s.setIngressQoSMap(podifName, conf); err != nil {
+				return err
+			}
+		}
+
+		// 19. Set DCB ETS bandwidth allocation
+		if len(conf.ETS) > 0 {
+			logging.Debug("19. Set DCB ETS bandwidth allocation",
+				"func", "SetupVF",
+				"podifName", podifName,
+				"conf.ETS", conf.ETS)
+			if err := s.setETS(podifName, conf); err != nil {
+				return err
+			}
+		}
+
+		// 20. Set tx queue length
+		if conf.TxQLen != nil {
+			logging.Debug("20. Set tx queue length",
+				"func", "SetupVF",
+				"linkObj", linkObj,
+				"conf.TxQLen", *conf.TxQLen)
+			if err := s.nLink.LinkSetTxQLen(linkObj, *conf.TxQLen); err != nil {
+				return fmt.Errorf("failed to set tx queue length of %s to %d: %w", podifName, *conf.TxQLen, err)
 			}
 		}
 
-		logging.Debug("8. Enable Optimistic DAD for IPv6 addresses", "func", "SetupVF",
+		// 21. Enable Optimistic DAD for IPv6 addresses
+		logging.Debug("21. Enable Optimistic DAD for IPv6 addresses", "func", "SetupVF",
 			"linkObj", linkObj)
 		_ = s.utils.EnableOptimisticDad(podifName)
 
-		// 9. Bring IF up in Pod netns
-		logging.Debug("9. Bring IF up in Pod netns",
+		// 22. Bring IF up in Pod netns
+		logging.Debug("22. Bring IF up in Pod netns",
 			"func", "SetupVF",
 			"linkObj", linkObj)
 		if err := s.nLink.LinkSetUp(linkObj); err != nil {
-			return fmt.Errorf("error bringing interface up in container ns: %q", err)
+			return fmt.Errorf("error bringing interface up in container ns: %w", err)
+		}
+
+		// 23. Wait for the link to be up
+		if conf.WaitForLinkUp != nil && *conf.WaitForLinkUp {
+			timeout := defaultLinkUpTimeout
+			if conf.LinkUpTimeout != nil {
+				timeout = time.Duration(*conf.LinkUpTimeout) * time.Second
+			}
+			logging.Debug("23. Wait for the link to be up",
+				"func", "SetupVF",
+				"podifName", podifName,
+				"timeout", timeout)
+			if err := s.waitForLinkUp(podifName, timeout); err != nil {
+				return err
+			}
+		}
+
+		// 24. Apply the interface sysctls
+		if len(conf.Sysctls) > 0 {
+			logging.Debug("24. Apply the interface sysctls",
+				"func", "SetupVF",
+				"podifName", podifName,
+				"conf.Sysctls", conf.Sysctls)
+			if err := s.setSysctls(podifName, conf.Sysctls); err != nil {
+				return err
+			}
+		}
+
+		// 25. Apply the neighbor table garbage collection thresholds
+		if conf.Neigh != nil {
+			logging.Debug("25. Apply the neighbor table garbage collection thresholds",
+				"func", "SetupVF",
+				"podifName", podifName,
+				"conf.Neigh", conf.Neigh)
+			if err := s.setSysctls(podifName, conf.Neigh.Sysctls()); err != nil {
+				return err
+			}
 		}
 
 		return nil
 	}); err != nil {
-		return fmt.Errorf("error setting up interface in container namespace: %q", err)
+		return fmt.Errorf("error setting up interface in container namespace: %w", err)
+	}
+
+	// 26. Read back the administrative and effective MAC addresses
+	if conf.VerifyMAC && conf.MAC != "" {
+		logging.Debug("26. Read back the administrative and effective MAC addresses",
+			"func", "SetupVF",
+			"podifName", podifName,
+			"conf.MAC", conf.MAC)
+		if err = s.verifyMAC(conf, podifName, netns); err != nil {
+			return err
+		}
 	}
 
 	// Copy the MTU value to a new variable
@@ -177,21 +670,82 @@ func (s *sriovManager) SetupVF(conf *sriovtypes.NetConf, podifName string, netns
 	return nil
 }
 
-// ReleaseVF reset a VF from Pod netns and return it to init netns
-func (s *sriovManager) ReleaseVF(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS) error {
+// setSysctls sets the sysctls of the current netns, the interface ones for the VF netdev, in the order of their names
+func (s *sriovManager) setSysctls(podifName string, sysctls map[string]string) error {
+	names := make([]string, 0, len(sysctls))
+	for name := range sysctls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := s.utils.SetInterfaceSysctl(podifName, name, sysctls[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyMAC reads back the administrative MAC address of the VF from its PF and the effective MAC address of
+// the VF netdevice in the pod netns, and fails when either differs from the configured MAC address
+func (s *sriovManager) verifyMAC(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS) error {
+	mac, err := net.ParseMAC(conf.MAC)
+	if err != nil {
+		return fmt.Errorf("failed to parse MAC address %s: %w", conf.MAC, err)
+	}
+
+	vfInfo, err := s.getVfInfoByName(conf.Master, conf.VFID)
+	if err != nil {
+		return err
+	}
+	if vfInfo.Mac.String() != mac.String() {
+		return fmt.Errorf("administrative MAC address %s of vf %d differs from the requested %s", vfInfo.Mac, conf.VFID, mac)
+	}
+
+	var effectiveMAC net.HardwareAddr
+	if err = netns.Do(func(_ ns.NetNS) error {
+		linkObj, err := s.nLink.LinkByName(podifName)
+		if err != nil {
+			return fmt.Errorf("failed to get netlink device with name %s: %w", podifName, err)
+		}
+		effectiveMAC = linkObj.Attrs().HardwareAddr
+		return nil
+	}); err != nil {
+		return err
+	}
+	if effectiveMAC.String() != mac.String() {
+		return fmt.Errorf("effective MAC address %s of %s differs from the requested %s", effectiveMAC, podifName, mac)
+	}
+
+	return nil
+}
+
+// ReleaseVF reset a VF from Pod netns and return it to init netns. Errors wrap ErrVFNotFound, ErrVFBusy or
+// ErrInvalidVFConfig when their cause is known.
+func (s *sriovManager) ReleaseVF(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS) (err error) {
+	defer func() { err = classifyVFError(err) }()
+
+	if err := s.CheckVFDevice(conf); errors.Is(err, ErrVFNotFound) {
+		logging.Warning("The VF no longer exists, nothing to release",
+			"func", "ReleaseVF",
+			"deviceID", conf.DeviceID,
+			"err", err)
+		return nil
+	}
+
 	initns, err := ns.GetCurrentNS()
 	if err != nil {
-		return fmt.Errorf("failed to get init netns: %v", err)
+		return fmt.Errorf("failed to get init netns: %w", err)
 	}
 
-	return netns.Do(func(_ ns.NetNS) error {
+	err = netns.Do(func(_ ns.NetNS) error {
 		// get VF device
 		logging.Debug("Get VF device",
 			"func", "ReleaseVF",
 			"podifName", podifName)
 		linkObj, err := s.nLink.LinkByName(podifName)
 		if err != nil {
-			return fmt.Errorf("failed to get netlink device with name %s: %q", podifName, err)
+			return fmt.Errorf("failed to get netlink device with name %s: %w", podifName, err)
 		}
 
 		// shutdown VF device
@@ -199,7 +753,7 @@ func (s *sriovManager) ReleaseVF(conf *sriovtypes.NetConf, podifName string, net
 			"func", "ReleaseVF",
 			"linkObj", linkObj)
 		if err = s.nLink.LinkSetDown(linkObj); err != nil {
-			return fmt.Errorf("failed to set link %s down: %q", podifName, err)
+			return fmt.Errorf("failed to set link %s down: %w", podifName, err)
 		}
 
 		// rename VF device
@@ -209,63 +763,1615 @@ func (s *sriovManager) ReleaseVF(conf *sriovtypes.NetConf, podifName string, net
 			"conf.OrigVfState.HostIFName", conf.OrigVfState.HostIFName)
 		err = s.nLink.LinkSetName(linkObj, conf.OrigVfState.HostIFName)
 		if err != nil {
-			return fmt.Errorf("failed to rename link %s to host name %s: %q", podifName, conf.OrigVfState.HostIFName, err)
+			return fmt.Errorf("failed to rename link %s to host name %s: %w", podifName, conf.OrigVfState.HostIFName, err)
 		}
 
-		if conf.MAC != "" {
-			// reset effective MAC address
-			logging.Debug("Reset effective MAC address",
+		if len(conf.AddedAltMACs) > 0 && conf.ResetsL2() {
+			// remove secondary MAC addresses
+			logging.Debug("Remove secondary MAC addresses",
 				"func", "ReleaseVF",
-				"s.nLink", s.nLink,
-				"conf.OrigVfState.HostIFName", conf.OrigVfState.HostIFName,
-				"conf.OrigVfState.EffectiveMAC", conf.OrigVfState.EffectiveMAC)
-			err = utils.SetVFEffectiveMAC(s.nLink, conf.OrigVfState.HostIFName, conf.OrigVfState.EffectiveMAC)
-			if err != nil {
-				return fmt.Errorf("failed to restore original effective netlink MAC address %s: %v", conf.OrigVfState.EffectiveMAC, err)
+				"linkObj", linkObj,
+				"conf.AddedAltMACs", conf.AddedAltMACs)
+			if err = s.delAltMACs(linkObj, conf.AddedAltMACs); err != nil {
+				return err
 			}
 		}
 
-		// move VF device to init netns
-		logging.Debug("Move VF device to init netns",
-			"func", "ReleaseVF",
-			"linkObj", linkObj,
-			"initns.Fd()", int(initns.Fd()))
-		if err = s.nLink.LinkSetNsFd(linkObj, int(initns.Fd())); err != nil {
-			return fmt.Errorf("failed to move interface %s to init netns: %v", conf.OrigVfState.HostIFName, err)
+		if len(conf.OrigVfState.PrivFlags) > 0 && conf.ResetsL2() {
+			// restore private flags
+			logging.Debug("Restore private flags",
+				"func", "ReleaseVF",
+				"conf.OrigVfState.HostIFName", conf.OrigVfState.HostIFName,
+				"conf.OrigVfState.PrivFlags", conf.OrigVfState.PrivFlags)
+			if err = s.utils.SetPrivFlags(conf.OrigVfState.HostIFName, conf.OrigVfState.PrivFlags); err != nil {
+				return fmt.Errorf("failed to restore private flags of %s: %w", conf.OrigVfState.HostIFName, err)
+			}
 		}
 
-		return nil
-	})
-}
+		if conf.OrigVfState.AdaptiveCoalesce != nil && conf.ResetsL2() {
+			// restore adaptive interrupt coalescing
+			logging.Debug("Restore adaptive interrupt coalescing",
+				"func", "ReleaseVF",
+				"conf.OrigVfState.HostIFName", conf.OrigVfState.HostIFName,
+				"conf.OrigVfState.AdaptiveCoalesce", conf.OrigVfState.AdaptiveCoalesce)
+			orig := conf.OrigVfState.AdaptiveCoalesce
+			if err = s.utils.SetAdaptiveCoalesce(conf.OrigVfState.HostIFName, orig.Rx, orig.Tx); err != nil {
+				return fmt.Errorf("failed to restore adaptive interrupt coalescing of %s: %w", conf.OrigVfState.HostIFName, err)
+			}
+		}
 
-func getVfInfo(link netlink.Link, id int) *netlink.VfInfo {
-	attrs := link.Attrs()
-	for _, vf := range attrs.Vfs {
-		if vf.ID == id {
-			return &vf
+		if conf.AllMulti != "" && conf.ResetsL2() {
+			// restore allmulticast
+			logging.Debug("Restore allmulticast",
+				"func", "ReleaseVF",
+				"linkObj", linkObj,
+				"conf.OrigVfState.AllMulti", conf.OrigVfState.AllMulti)
+			if err = s.setAllMulti(linkObj, conf.OrigVfState.AllMulti); err != nil {
+				return err
+			}
 		}
-	}
-	return nil
-}
 
-// ApplyVFConfig configure a VF with parameters given in NetConf
-func (s *sriovManager) ApplyVFConfig(conf *sriovtypes.NetConf) error {
-	pfLink, err := s.nLink.LinkByName(conf.Master)
-	if err != nil {
-		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
-	}
-	// 1. Set vlan
-	if conf.Vlan != nil {
-		if err = s.nLink.LinkSetVfVlanQosProto(pfLink, conf.VFID, *conf.Vlan, *conf.VlanQoS, sriovtypes.VlanProtoInt[*conf.VlanProto]); err != nil {
-			return fmt.Errorf("failed to set vf %d vlan configuration - id %d, qos %d and proto %s: %v", conf.VFID, *conf.Vlan, *conf.VlanQoS, *conf.VlanProto, err)
+		if conf.TxQLen != nil && conf.ResetsL2() {
+			// restore tx queue length
+			logging.Debug("Restore tx queue length",
+				"func", "ReleaseVF",
+				"linkObj", linkObj,
+				"conf.OrigVfState.TxQLen", conf.OrigVfState.TxQLen)
+			if err = s.nLink.LinkSetTxQLen(linkObj, conf.OrigVfState.TxQLen); err != nil {
+				return fmt.Errorf("failed to restore tx queue length of %s to %d: %w", podifName, conf.OrigVfState.TxQLen, err)
+			}
 		}
-	}
 
-	// 2. Set mac address
-	if conf.MAC != "" {
-		// when we restore the original hardware mac address we may get a device or resource busy. so we introduce retry
-		if err := utils.SetVFHardwareMAC(s.nLink, conf.Master, conf.VFID, conf.MAC); err != nil {
-			return fmt.Errorf("failed to set MAC address to %s: %v", conf.MAC, err)
+		if conf.IngressPolice != nil && conf.ResetsL2() {
+			// remove ingress policing
+			logging.Debug("Remove ingress policing",
+				"func", "ReleaseVF",
+				"linkObj", linkObj)
+			if err = s.nLink.QdiscDel(clsactQdisc(linkObj)); err != nil {
+				return fmt.Errorf("failed to remove ingress policing from %s: %w", podifName, err)
+			}
+		}
+
+		if conf.EgressQoSMap != "" && conf.ResetsL2() {
+			// reset the mapped priorities to PCP 0, the kernel default
+			logging.Debug("Reset VLAN egress QoS map",
+				"func", "ReleaseVF",
+				"linkObj", linkObj,
+				"conf.EgressQoSMap", conf.EgressQoSMap)
+			qosMap, err := utils.ParseEgressQoSMap(conf.EgressQoSMap)
+			if err != nil {
+				return err
+			}
+			for from := range qosMap {
+				qosMap[from] = 0
+			}
+			if err = s.nLink.LinkSetVlanEgressQoSMap(linkObj, qosMap); err != nil {
+				return fmt.Errorf("failed to reset vlan egress qos map of %s: %w", podifName, err)
+			}
+		}
+
+		if len(conf.ETS) > 0 && conf.OrigVfState.DCBTCBandwidth != nil && conf.ResetsL2() {
+			// restore the DCB ETS bandwidth allocation, the VF netdev was renamed to its host name
+			logging.Debug("Restore DCB ETS bandwidth allocation",
+				"func", "ReleaseVF",
+				"conf.OrigVfState.HostIFName", conf.OrigVfState.HostIFName,
+				"conf.OrigVfState.DCBTCBandwidth", conf.OrigVfState.DCBTCBandwidth)
+			if err = s.resetETS(conf.OrigVfState.HostIFName, conf); err != nil {
+				return err
+			}
+		}
+
+		if conf.IngressQoSMap != nil && conf.OrigVfState.DCBPrioTC != nil && conf.ResetsL2() {
+			// remove the DCB ingress QoS map, the VF netdev was renamed to its host name
+			logging.Debug("Remove DCB ingress QoS map",
+				"func", "ReleaseVF",
+				"conf.OrigVfState.HostIFName", conf.OrigVfState.HostIFName,
+				"conf.OrigVfState.DCBPrioTC", conf.OrigVfState.DCBPrioTC)
+			if err = s.resetIngressQoSMap(conf.OrigVfState.HostIFName, conf); err != nil {
+				return err
+			}
+		}
+
+		if conf.MAC != "" && conf.ResetsL2() {
+			// reset effective MAC address
+			logging.Debug("Reset effective MAC address",
+				"func", "ReleaseVF",
+				"s.nLink", s.nLink,
+				"conf.OrigVfState.HostIFName", conf.OrigVfState.HostIFName,
+				"conf.OrigVfState.EffectiveMAC", conf.OrigVfState.EffectiveMAC)
+			err = utils.SetVFEffectiveMAC(s.nLink, conf.OrigVfState.HostIFName, conf.OrigVfState.EffectiveMAC)
+			if err != nil {
+				return fmt.Errorf("failed to restore original effective netlink MAC address %s: %w", conf.OrigVfState.EffectiveMAC, err)
+			}
+		}
+
+		if conf.RouteTable != nil && conf.AddResult != nil && conf.ResetsL3() {
+			// remove the source rules, the routes of the table go with the VF device
+			logging.Debug("Remove the source rules of the VF route table",
+				"func", "ReleaseVF",
+				"conf.RouteTable", *conf.RouteTable)
+			for _, ipc := range conf.AddResult.IPs {
+				err = s.nLink.RuleDel(sourceRule(ipc.Address.IP, *conf.RouteTable))
+				if err != nil && !errors.Is(err, unix.ENOENT) {
+					return fmt.Errorf("failed to remove the rule from %s lookup table %d: %w", ipc.Address.IP, *conf.RouteTable, err)
+				}
+			}
+		}
+
+		// a VF kept in the host netns is already in init netns
+		if conf.KeepsInHostNetns() {
+			return nil
+		}
+
+		// move VF device to init netns
+		logging.Debug("Move VF device to init netns",
+			"func", "ReleaseVF",
+			"linkObj", linkObj,
+			"initns.Fd()", int(initns.Fd()))
+		if err = s.nLink.LinkSetNsFd(linkObj, int(initns.Fd())); err != nil {
+			return fmt.Errorf("failed to move interface %s to init netns: %w", conf.OrigVfState.HostIFName, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// reset tx queue rate limits
+	if len(conf.QueueRates) > 0 && conf.ResetsL2() {
+		logging.Debug("Reset tx queue rate limits",
+			"func", "ReleaseVF",
+			"conf.OrigVfState.HostIFName", conf.OrigVfState.HostIFName,
+			"conf.QueueRates", conf.QueueRates)
+		if err = s.resetQueueRates(conf.OrigVfState.HostIFName, conf.QueueRates); err != nil {
+			return err
+		}
+	}
+
+	// reset the VF to its factory defaults, whatever the cached configuration restored
+	if conf.FullResets() {
+		if err = s.resetVFToFactory(conf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resetVFToFactory sets every administrative attribute of the VF to its default, regardless of its original
+// state: untagged 802.1q vlan, spoofchk on, trust off, link state auto, no rate limiting and an all-zeros MAC
+// address which lets the driver assign a new one.
+func (s *sriovManager) resetVFToFactory(conf *sriovtypes.NetConf) error {
+	logging.Debug("Reset VF to its factory defaults",
+		"func", "resetVFToFactory",
+		"conf.Master", conf.Master,
+		"conf.VFID", conf.VFID)
+
+	pfLink, err := s.nLink.LinkByName(conf.Master)
+	if err != nil {
+		return fmt.Errorf("failed to lookup master %q: %w", conf.Master, err)
+	}
+
+	if err = s.nLink.LinkSetVfVlanQosProto(pfLink, conf.VFID, 0, 0, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]); err != nil {
+		return fmt.Errorf("failed to reset vlan configuration of vf %d: %w", conf.VFID, err)
+	}
+	if err = s.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, true); err != nil {
+		return fmt.Errorf("failed to reset spoofchk of vf %d: %w", conf.VFID, err)
+	}
+	if err = s.nLink.LinkSetVfTrust(pfLink, conf.VFID, false); err != nil {
+		return fmt.Errorf("failed to reset trust of vf %d: %w", conf.VFID, err)
+	}
+	if err = s.nLink.LinkSetVfState(pfLink, conf.VFID, netlink.VF_LINK_STATE_AUTO); err != nil {
+		return fmt.Errorf("failed to reset link state of vf %d: %w", conf.VFID, err)
+	}
+	if err = s.nLink.LinkSetVfRate(pfLink, conf.VFID, 0, 0); err != nil {
+		return fmt.Errorf("failed to disable rate limiting for vf %d: %w", conf.VFID, err)
+	}
+	if err = utils.SetVFHardwareMAC(s.nLink, conf.Master, conf.VFID, zeroMAC); err != nil {
+		return fmt.Errorf("failed to reset administrative MAC address of vf %d: %w", conf.VFID, err)
+	}
+
+	return nil
+}
+
+// dscpApps returns the DCB APP entries of the DSCP to priority mappings, in the order of the DSCPs
+func dscpApps(qosMap string) ([]utils.DCBApp, error) {
+	dscpMap, err := utils.ParseIngressQoSMap(qosMap, "dscp", "priority", 63)
+	if err != nil {
+		return nil, err
+	}
+	apps := make([]utils.DCBApp, 0, len(dscpMap))
+	for dscp, prio := range dscpMap {
+		apps = append(apps, utils.DCBApp{Selector: utils.DCBAppSelDSCP, Priority: prio, Protocol: uint16(dscp)})
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Protocol < apps[j].Protocol })
+	return apps, nil
+}
+
+// setIngressQoSMap adds the DSCP to priority mappings to the DCB APP table of the VF netdev and sets the PCP to
+// traffic class mappings in its ETS configuration, recording the original priority to traffic class table. It is
+// skipped with a warning when the VF driver has no DCB support.
+func (s *sriovManager) setIngressQoSMap(ifName string, conf *sriovtypes.NetConf) error {
+	ets, err := s.utils.GetDCBETS(ifName)
+	if errors.Is(err, utils.ErrNotSupported) {
+		logging.Warning("VF driver does not support DCB, the ingress QoS map is not set",
+			"func", "setIngressQoSMap",
+			"ifName", ifName)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the DCB configuration of %s: %w", ifName, err)
+	}
+
+	var pcpMap map[uint8]uint8
+	if conf.IngressQoSMap.PCP != "" {
+		pcpMap, err = utils.ParseIngressQoSMap(conf.IngressQoSMap.PCP, "pcp", "tc", 7)
+		if err != nil {
+			return err
+		}
+		for pcp, tc := range pcpMap {
+			if tc >= ets.ETSCap {
+				return newVFError(ErrInvalidVFConfig,
+					fmt.Errorf("invalid ingress qos mapping %d:%d: %s supports %d traffic classes", pcp, tc, ifName, ets.ETSCap))
+			}
+		}
+	}
+	var apps []utils.DCBApp
+	if conf.IngressQoSMap.DSCP != "" {
+		if apps, err = dscpApps(conf.IngressQoSMap.DSCP); err != nil {
+			return err
+		}
+	}
+
+	orig := make([]int, len(ets.PrioTC))
+	for prio, tc := range ets.PrioTC {
+		orig[prio] = int(tc)
+	}
+	conf.OrigVfState.DCBPrioTC = orig
+
+	if len(pcpMap) > 0 {
+		for pcp, tc := range pcpMap {
+			ets.PrioTC[pcp] = tc
+		}
+		if err := s.utils.SetDCBETS(ifName, ets); err != nil {
+			return fmt.Errorf("failed to set the DCB priority to traffic class mappings of %s: %w", ifName, err)
+		}
+	}
+	if len(apps) > 0 {
+		if err := s.utils.AddDCBApps(ifName, apps); err != nil {
+			return fmt.Errorf("failed to add the DCB DSCP to priority mappings of %s: %w", ifName, err)
+		}
+	}
+	return nil
+}
+
+// resetIngressQoSMap removes the DSCP to priority mappings from the DCB APP table of the VF netdev and restores its
+// original priority to traffic class table
+func (s *sriovManager) resetIngressQoSMap(ifName string, conf *sriovtypes.NetConf) error {
+	if conf.IngressQoSMap.DSCP != "" {
+		apps, err := dscpApps(conf.IngressQoSMap.DSCP)
+		if err != nil {
+			return err
+		}
+		if err := s.utils.DelDCBApps(ifName, apps); err != nil {
+			return fmt.Errorf("failed to remove the DCB DSCP to priority mappings of %s: %w", ifName, err)
+		}
+	}
+	if conf.IngressQoSMap.PCP != "" {
+		ets, err := s.utils.GetDCBETS(ifName)
+		if err != nil {
+			return fmt.Errorf("failed to get the DCB configuration of %s: %w", ifName, err)
+		}
+		for prio, tc := range conf.OrigVfState.DCBPrioTC {
+			if prio < len(ets.PrioTC) {
+				ets.PrioTC[prio] = uint8(tc)
+			}
+		}
+		if err := s.utils.SetDCBETS(ifName, ets); err != nil {
+			return fmt.Errorf("failed to restore the DCB priority to traffic class mappings of %s: %w", ifName, err)
+		}
+	}
+	return nil
+}
+
+// setETS allocates the transmit bandwidth of the VF netdev to the traffic classes with DCB ETS, the other traffic
+// classes keep their transmission selection algorithm with no ETS bandwidth. The original allocation is recorded. It
+// is skipped with a warning when the VF driver has no DCB support.
+func (s *sriovManager) setETS(ifName string, conf *sriovtypes.NetConf) error {
+	ets, err := s.utils.GetDCBETS(ifName)
+	if errors.Is(err, utils.ErrNotSupported) {
+		logging.Warning("VF driver does not support DCB, the ETS bandwidth allocation is not set",
+			"func", "setETS",
+			"ifName", ifName)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the DCB configuration of %s: %w", ifName, err)
+	}
+	for _, bw := range conf.ETS {
+		if bw.TC >= int(ets.ETSCap) {
+			return newVFError(ErrInvalidVFConfig,
+				fmt.Errorf("invalid ets tc %d: %s supports %d traffic classes", bw.TC, ifName, ets.ETSCap))
+		}
+	}
+
+	origBW := make([]int, len(ets.TCTxBW))
+	origTSA := make([]int, len(ets.TCTSA))
+	for tc := range ets.TCTxBW {
+		origBW[tc] = int(ets.TCTxBW[tc])
+		origTSA[tc] = int(ets.TCTSA[tc])
+		ets.TCTxBW[tc] = 0
+	}
+	conf.OrigVfState.DCBTCBandwidth = origBW
+	conf.OrigVfState.DCBTCTSA = origTSA
+
+	for _, bw := range conf.ETS {
+		ets.TCTxBW[bw.TC] = uint8(bw.BandwidthPercent)
+		ets.TCTSA[bw.TC] = utils.DCBTSAETS
+	}
+	if err := s.utils.SetDCBETS(ifName, ets); err != nil {
+		return fmt.Errorf("failed to set the DCB ETS bandwidth allocation of %s: %w", ifName, err)
+	}
+	return nil
+}
+
+// resetETS restores the original DCB ETS bandwidth allocation of the VF netdev
+func (s *sriovManager) resetETS(ifName string, conf *sriovtypes.NetConf) error {
+	ets, err := s.utils.GetDCBETS(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to get the DCB configuration of %s: %w", ifName, err)
+	}
+	for tc, bw := range conf.OrigVfState.DCBTCBandwidth {
+		if tc < len(ets.TCTxBW) {
+			ets.TCTxBW[tc] = uint8(bw)
+		}
+	}
+	for tc, tsa := range conf.OrigVfState.DCBTCTSA {
+		if tc < len(ets.TCTSA) {
+			ets.TCTSA[tc] = uint8(tsa)
+		}
+	}
+	if err := s.utils.SetDCBETS(ifName, ets); err != nil {
+		return fmt.Errorf("failed to restore the DCB ETS bandwidth allocation of %s: %w", ifName, err)
+	}
+	return nil
+}
+
+// setAllMulti turns the reception of all multicast packets by the VF netdevice on or off
+func (s *sriovManager) setAllMulti(linkObj netlink.Link, on bool) error {
+	setAllMulti, state := s.nLink.LinkSetAllmulticastOff, "off"
+	if on {
+		setAllMulti, state = s.nLink.LinkSetAllmulticastOn, "on"
+	}
+	if err := setAllMulti(linkObj); err != nil {
+		return fmt.Errorf("failed to set allmulticast %s on %s: %w", state, linkObj.Attrs().Name, err)
+	}
+	return nil
+}
+
+// setQueueRates sets the max rate of the requested tx queues of the VF netdevice.
+// Queues whose driver does not support per-queue rate limiting are skipped with a warning.
+func (s *sriovManager) setQueueRates(ifName string, queueRates []sriovtypes.QueueRate) error {
+	numQueues, err := s.utils.GetTxQueueCount(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to get tx queue count of %s: %w", ifName, err)
+	}
+
+	for _, qr := range queueRates {
+		if qr.Queue >= numQueues {
+			return fmt.Errorf("invalid tx queue %d for %s: device has %d tx queues", qr.Queue, ifName, numQueues)
+		}
+		if err := s.utils.SetTxQueueMaxRate(ifName, qr.Queue, qr.MaxRate); err != nil {
+			if errors.Is(err, utils.ErrNotSupported) {
+				logging.Warning("Per-queue rate limiting is not supported, skipping",
+					"func", "setQueueRates",
+					"ifName", ifName,
+					"queue", qr.Queue,
+					"err", err)
+				continue
+			}
+			return fmt.Errorf("failed to set tx queue %d max rate to %d Mbps: %w", qr.Queue, qr.MaxRate, err)
+		}
+	}
+
+	return nil
+}
+
+// resetQueueRates disables rate limiting on the tx queues configured by setQueueRates
+func (s *sriovManager) resetQueueRates(ifName string, queueRates []sriovtypes.QueueRate) error {
+	for _, qr := range queueRates {
+		if err := s.utils.SetTxQueueMaxRate(ifName, qr.Queue, 0); err != nil && !errors.Is(err, utils.ErrNotSupported) {
+			return fmt.Errorf("failed to reset tx queue %d max rate: %w", qr.Queue, err)
+		}
+	}
+
+	return nil
+}
+
+// setIRQAffinity sets the affinity of the MSI-X vectors of the VF netdev to the CPUs of the NUMA node local to
+// the VF. The plugin may not be allowed to write the IRQ affinity, which is then logged and skipped.
+func (s *sriovManager) setIRQAffinity(ifName string) error {
+	mask, err := s.utils.GetNUMANodeCPUMask(ifName)
+	if errors.Is(err, utils.ErrNotSupported) {
+		logging.Warning("VF has no local NUMA node, skipping IRQ affinity",
+			"func", "setIRQAffinity",
+			"ifName", ifName,
+			"err", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	irqs, err := s.utils.GetVFIRQs(ifName)
+	if err != nil {
+		return err
+	}
+	for _, irq := range irqs {
+		if err := s.utils.SetIRQAffinity(irq, mask); err != nil {
+			if errors.Is(err, os.ErrPermission) {
+				logging.Warning("Not allowed to set the VF IRQ affinity, skipping it",
+					"func", "setIRQAffinity",
+					"ifName", ifName,
+					"err", err)
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// setRSSHashKey sets the RSS hash key of netdev after checking it matches the key size of the driver
+func (s *sriovManager) setRSSHashKey(ifName, hashKey string) error {
+	key, err := utils.ParseRSSHashKey(hashKey)
+	if err != nil {
+		return err
+	}
+
+	keySize, err := s.utils.GetRSSHashKeySize(ifName)
+	if err != nil {
+		return err
+	}
+	if keySize == 0 {
+		return fmt.Errorf("device %s does not support setting the RSS hash key", ifName)
+	}
+	if len(key) != keySize {
+		return fmt.Errorf("invalid RSS hash key length %d for %s: driver expects %d bytes", len(key), ifName, keySize)
+	}
+
+	if err := s.utils.SetRSSHashKey(ifName, key); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// setPrivFlags sets the private flags of the VF netdev, saving the original value of the changed flags
+// in OrigVfState so that they are restored on cmdDel. Flags not supported by the driver fail with the available flags.
+func (s *sriovManager) setPrivFlags(ifName string, conf *sriovtypes.NetConf) error {
+	current, err := s.utils.GetPrivFlags(ifName)
+	if err != nil {
+		return err
+	}
+
+	orig := make(map[string]bool, len(conf.PrivFlags))
+	for name := range conf.PrivFlags {
+		value, ok := current[name]
+		if !ok {
+			available := make([]string, 0, len(current))
+			for n := range current {
+				available = append(available, n)
+			}
+			sort.Strings(available)
+			return fmt.Errorf("private flag %q is not supported by device %s, available flags: [%s]",
+				name, ifName, strings.Join(available, ", "))
+		}
+		orig[name] = value
+	}
+
+	if err := s.utils.SetPrivFlags(ifName, conf.PrivFlags); err != nil {
+		return err
+	}
+	conf.OrigVfState.PrivFlags = orig
+
+	return nil
+}
+
+// setMicroburstProtection enables the moderation features of the VF driver smoothing rx and tx bursts, the
+// private flags of the drivers listed in microburstPrivFlags or else adaptive interrupt coalescing. The
+// original state is recorded in conf.OrigVfState so that cmdDel restores it. Private flags configured with
+// privFlags are left as configured. Unsupported features are only logged.
+func (s *sriovManager) setMicroburstProtection(ifName string, conf *sriovtypes.NetConf) {
+	drvInfo, err := s.utils.GetDrvInfo(ifName)
+	if err != nil {
+		logging.Warning("Cannot read the VF driver, microburst protection is not enabled",
+			"func", "setMicroburstProtection",
+			"ifName", ifName,
+			"err", err)
+		return
+	}
+
+	if flagNames, ok := microburstPrivFlags[drvInfo.Driver]; ok {
+		current, err := s.utils.GetPrivFlags(ifName)
+		if err != nil {
+			logging.Warning("Cannot read the private flags, microburst protection is not enabled",
+				"func", "setMicroburstProtection",
+				"ifName", ifName,
+				"err", err)
+			return
+		}
+		flags := map[string]bool{}
+		for _, name := range flagNames {
+			if _, configured := conf.PrivFlags[name]; configured {
+				continue
+			}
+			value, ok := current[name]
+			if !ok {
+				logging.Warning("Microburst protection private flag is not supported by the device",
+					"func", "setMicroburstProtection",
+					"ifName", ifName,
+					"driver", drvInfo.Driver,
+					"flag", name)
+				continue
+			}
+			if !value {
+				flags[name] = true
+			}
+		}
+		if len(flags) == 0 {
+			return
+		}
+		if err := s.utils.SetPrivFlags(ifName, flags); err != nil {
+			logging.Warning("Failed to enable microburst protection",
+				"func", "setMicroburstProtection",
+				"ifName", ifName,
+				"err", err)
+			return
+		}
+		if conf.OrigVfState.PrivFlags == nil {
+			conf.OrigVfState.PrivFlags = map[string]bool{}
+		}
+		for name := range flags {
+			conf.OrigVfState.PrivFlags[name] = false
+		}
+		return
+	}
+
+	rx, tx, err := s.utils.GetAdaptiveCoalesce(ifName)
+	if err != nil {
+		logging.Warning("Microburst protection is not supported by the device",
+			"func", "setMicroburstProtection",
+			"ifName", ifName,
+			"driver", drvInfo.Driver,
+			"err", err)
+		return
+	}
+	if rx && tx {
+		return
+	}
+	if err := s.utils.SetAdaptiveCoalesce(ifName, true, true); err != nil {
+		logging.Warning("Failed to enable microburst protection",
+			"func", "setMicroburstProtection",
+			"ifName", ifName,
+			"driver", drvInfo.Driver,
+			"err", err)
+		return
+	}
+	conf.OrigVfState.AdaptiveCoalesce = &sriovtypes.AdaptiveCoalesce{Rx: rx, Tx: tx}
+}
+
+// setRSSIndirTable sets the RSS indirection table of netdev. The table is repeated to the indirection table size
+// of the driver, which must be a multiple of its length, and its entries must be rx queues of netdev.
+func (s *sriovManager) setRSSIndirTable(ifName string, indirTable []int) error {
+	numQueues, err := s.utils.GetRxQueueCount(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to get rx queue count of %s: %w", ifName, err)
+	}
+	for _, queue := range indirTable {
+		if queue >= numQueues {
+			return fmt.Errorf("invalid RSS indirection table entry %d for %s: device has %d rx queues", queue, ifName, numQueues)
+		}
+	}
+
+	indirSize, err := s.utils.GetRSSIndirSize(ifName)
+	if err != nil {
+		return err
+	}
+	if indirSize == 0 {
+		return fmt.Errorf("device %s does not support setting the RSS indirection table", ifName)
+	}
+	if indirSize%len(indirTable) != 0 {
+		return fmt.Errorf("invalid RSS indirection table length %d for %s: driver table size %d is not a multiple of it",
+			len(indirTable), ifName, indirSize)
+	}
+
+	table := make([]uint32, indirSize)
+	for i := range table {
+		table[i] = uint32(indirTable[i%len(indirTable)])
+	}
+	if err := s.utils.SetRSSIndirTable(ifName, table); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// enforceRateCeiling checks the requested max tx rate against the per-VF ceiling exposed by the PF driver.
+// Depending on EnforceRateCeiling a rate above the ceiling is rejected or clamped to it.
+func (s *sriovManager) enforceRateCeiling(conf *sriovtypes.NetConf, maxTxRate int) (int, error) {
+	ceiling, err := s.utils.GetVFMaxTxRateCeiling(conf.Master, conf.VFID)
+	if err != nil {
+		if errors.Is(err, utils.ErrNotSupported) {
+			logging.Debug("PF does not expose a per-VF max tx rate ceiling",
+				"func", "enforceRateCeiling",
+				"conf.Master", conf.Master,
+				"conf.VFID", conf.VFID)
+			return maxTxRate, nil
+		}
+		return 0, fmt.Errorf("failed to get vf %d max tx rate ceiling: %w", conf.VFID, err)
+	}
+
+	if ceiling <= 0 || maxTxRate <= ceiling {
+		return maxTxRate, nil
+	}
+
+	if conf.EnforceRateCeiling == sriovtypes.RateCeilingReject {
+		return 0, newVFError(ErrInvalidVFConfig,
+			fmt.Errorf("vf %d max_tx_rate %d Mbps exceeds the PF ceiling of %d Mbps", conf.VFID, maxTxRate, ceiling))
+	}
+
+	logging.Warning("Clamping max_tx_rate to the PF ceiling",
+		"func", "enforceRateCeiling",
+		"conf.VFID", conf.VFID,
+		"maxTxRate", maxTxRate,
+		"ceiling", ceiling)
+	return ceiling, nil
+}
+
+// checkMinTxRate rejects a min_tx_rate floor the VF cannot be guaranteed, above its max_tx_rate, which may have
+// been clamped to the PF ceiling, or above the PF link speed. Drivers silently ignore such a floor. The PF link
+// speed check is skipped when the speed is unknown, e.g. when the PF link is down.
+func (s *sriovManager) checkMinTxRate(conf *sriovtypes.NetConf, minTxRate, maxTxRate int) error {
+	if maxTxRate > 0 && minTxRate > maxTxRate {
+		return newVFError(ErrInvalidVFConfig,
+			fmt.Errorf("vf %d min_tx_rate %d Mbps exceeds its max_tx_rate %d Mbps", conf.VFID, minTxRate, maxTxRate))
+	}
+
+	speed, err := s.utils.GetPFLinkSpeed(conf.Master)
+	if err != nil {
+		logging.Debug("Cannot read the PF link speed, skipping the min_tx_rate check",
+			"func", "checkMinTxRate",
+			"conf.Master", conf.Master,
+			"err", err)
+		return nil
+	}
+	if minTxRate > speed {
+		return newVFError(ErrInvalidVFConfig,
+			fmt.Errorf("vf %d min_tx_rate %d Mbps exceeds the PF %s link speed of %d Mbps", conf.VFID, minTxRate, conf.Master, speed))
+	}
+	return nil
+}
+
+// checkSchedulerCapacity warns about or rejects a min_tx_rate that, added to the min_tx_rate of the other VFs
+// of the PF, exceeds the PF link speed, as the PF scheduler cannot guarantee all of them. The rate of another VF
+// is the larger of its live rate and the one of its cached NetConf. The check is skipped when the PF link speed
+// is unknown.
+func (s *sriovManager) checkSchedulerCapacity(pfLink netlink.Link, conf *sriovtypes.NetConf, minTxRate int) error {
+	speed, err := s.utils.GetPFLinkSpeed(conf.Master)
+	if err != nil {
+		logging.Debug("Cannot read the PF link speed, skipping the scheduler capacity check",
+			"func", "checkSchedulerCapacity",
+			"conf.Master", conf.Master,
+			"err", err)
+		return nil
+	}
+
+	total := minTxRate
+	for _, vf := range pfLink.Attrs().Vfs {
+		if vf.ID == conf.VFID {
+			continue
+		}
+		total += max(int(vf.MinTxRate), conf.SiblingMinTxRates[vf.ID])
+	}
+	if total <= speed {
+		return nil
+	}
+
+	if conf.EnforceSchedulerCapacity == sriovtypes.SchedulerCapacityReject {
+		return newVFError(ErrInvalidVFConfig,
+			fmt.Errorf("vf %d min_tx_rate %d Mbps brings the min_tx_rate of the VFs of PF %s to %d Mbps, above its link speed of %d Mbps",
+				conf.VFID, minTxRate, conf.Master, total, speed))
+	}
+
+	logging.Warning("The min_tx_rate of the VFs exceeds the PF link speed, they cannot all be guaranteed",
+		"func", "checkSchedulerCapacity",
+		"conf.VFID", conf.VFID,
+		"conf.Master", conf.Master,
+		"minTxRate", minTxRate,
+		"total", total,
+		"speed", speed)
+	return nil
+}
+
+// setVlanAntiSpoof turns the vlan anti-spoofing of a VF on or off.
+// Drivers that do not support it are skipped with a warning.
+func (s *sriovManager) setVlanAntiSpoof(pfName string, vfID int, on bool) error {
+	if err := s.utils.SetVFVlanAntiSpoof(pfName, vfID, on); err != nil {
+		if errors.Is(err, utils.ErrNotSupported) {
+			logging.Warning("Controlling the vlan tags of the guest is not supported, skipping",
+				"func", "setVlanAntiSpoof",
+				"pfName", pfName,
+				"vfID", vfID,
+				"err", err)
+			return nil
+		}
+		return fmt.Errorf("failed to set vf %d vlan anti-spoofing to %t: %w", vfID, on, err)
+	}
+
+	return nil
+}
+
+// setMaxMacChanges limits the number of MAC changes allowed to a VF.
+// Drivers that do not support the limit are skipped with a warning.
+func (s *sriovManager) setMaxMacChanges(pfName string, vfID, limit int) error {
+	if err := s.utils.SetVFMaxMacChanges(pfName, vfID, limit); err != nil {
+		if errors.Is(err, utils.ErrNotSupported) {
+			logging.Warning("Limiting VF MAC changes is not supported, skipping",
+				"func", "setMaxMacChanges",
+				"pfName", pfName,
+				"vfID", vfID,
+				"err", err)
+			return nil
+		}
+		return fmt.Errorf("failed to set vf %d MAC change limit to %d: %w", vfID, limit, err)
+	}
+
+	return nil
+}
+
+// CheckVFConfig verifies that the VF configuration applied by cmdAdd did not drift. The drifted attributes are
+// returned in a *VFConfigDiffError. A drifted link state is re-applied when FixLinkStateOnCheck is set instead.
+// A passthrough VF has no configuration to verify.
+func (s *sriovManager) CheckVFConfig(conf *sriovtypes.NetConf) error {
+	if conf.IsPassthrough() {
+		logging.Debug("Skip checking the configuration of a passthrough VF",
+			"func", "CheckVFConfig",
+			"conf.DeviceID", conf.DeviceID)
+		return nil
+	}
+
+	vfInfo, err := s.getVfInfoByName(conf.Master, conf.VFID)
+	if err != nil {
+		return err
+	}
+
+	diffs := diffVFConfig(conf, vfInfo)
+
+	if conf.LinkState != "" {
+		state, err := linkStateFromString(conf.LinkState)
+		if err != nil {
+			return fmt.Errorf("unknown link state %s configured for vf %d: %w", conf.LinkState, conf.VFID, err)
+		}
+		if vfInfo.LinkState != state {
+			if !conf.FixLinkStateOnCheck {
+				diffs = append(diffs, VFAttrDiff{Attr: "link_state", Expected: conf.LinkState, Actual: linkStateToString(vfInfo.LinkState)})
+			} else {
+				pfLink, err := s.nLink.LinkByName(conf.Master)
+				if err != nil {
+					return fmt.Errorf("failed to lookup master %q: %w", conf.Master, err)
+				}
+				if err = s.nLink.LinkSetVfState(pfLink, conf.VFID, state); err != nil {
+					return fmt.Errorf("failed to re-apply vf %d link state %d: %w", conf.VFID, state, err)
+				}
+				logging.Info("Audit: re-applied drifted VF link state",
+					"func", "CheckVFConfig",
+					"conf.Master", conf.Master,
+					"conf.VFID", conf.VFID,
+					"expected", linkStateToString(state),
+					"found", linkStateToString(vfInfo.LinkState))
+			}
+		}
+	}
+
+	if len(diffs) > 0 {
+		return &VFConfigDiffError{VFID: conf.VFID, Diffs: diffs}
+	}
+	return nil
+}
+
+// CheckVFDriver verifies that the VF is bound to the kind of driver its configuration expects: the override driver,
+// or else a userspace driver, in DPDK mode and a kernel driver otherwise
+func (s *sriovManager) CheckVFDriver(conf *sriovtypes.NetConf) error {
+	driver, err := s.utils.GetVFDriver(conf.DeviceID)
+	if err != nil {
+		return fmt.Errorf("failed to get driver of vf %s: %w", conf.DeviceID, err)
+	}
+	found := driver
+	if found == "" {
+		found = "no driver"
+	}
+
+	if conf.DPDKMode {
+		if conf.DriverOverride != "" && driver != conf.DriverOverride {
+			return fmt.Errorf("vf %s driver drifted: expected %s, found %s", conf.DeviceID, conf.DriverOverride, found)
+		}
+		if !utils.IsUserspaceDriver(driver) {
+			return fmt.Errorf("vf %s driver drifted: expected a userspace driver, found %s", conf.DeviceID, found)
+		}
+		return nil
+	}
+	if driver == "" || utils.IsUserspaceDriver(driver) {
+		return fmt.Errorf("vf %s driver drifted: expected a kernel driver, found %s", conf.DeviceID, found)
+	}
+	return nil
+}
+
+// ReconcileVFConfig re-applies the administrative VF attributes of a cached configuration that drifted from it, and
+// returns a description of each drifted attribute. Nothing is changed when dryRun is set.
+func (s *sriovManager) ReconcileVFConfig(conf *sriovtypes.NetConf, dryRun bool) ([]string, error) {
+	if conf.IsPassthrough() {
+		return nil, nil
+	}
+
+	pfLink, err := s.nLink.LinkByName(conf.Master)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup master %q: %w", conf.Master, err)
+	}
+	vfInfo := getVfInfo(pfLink, conf.VFID)
+	if vfInfo == nil {
+		return nil, fmt.Errorf("failed to find vf %d", conf.VFID)
+	}
+
+	var drifts []string
+	reconcile := func(drift string, fix func() error) error {
+		drifts = append(drifts, drift)
+		if dryRun {
+			return nil
+		}
+		if err := fix(); err != nil {
+			return fmt.Errorf("failed to re-apply vf %d %s: %w", conf.VFID, drift, err)
+		}
+		return nil
+	}
+
+	if conf.Vlan != nil {
+		vlanQoS, vlanProto := 0, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]
+		if conf.VlanQoS != nil {
+			vlanQoS = *conf.VlanQoS
+		}
+		if conf.VlanProto != nil && *conf.Vlan != 0 {
+			vlanProto = sriovtypes.VlanProtoInt[*conf.VlanProto]
+		}
+		if vfInfo.Vlan != *conf.Vlan || vfInfo.Qos != vlanQoS || (*conf.Vlan != 0 && vfInfo.VlanProto != vlanProto) {
+			drift := fmt.Sprintf("vlan: expected %d qos %d proto %d, found %d qos %d proto %d",
+				*conf.Vlan, vlanQoS, vlanProto, vfInfo.Vlan, vfInfo.Qos, vfInfo.VlanProto)
+			if err = reconcile(drift, func() error {
+				return s.nLink.LinkSetVfVlanQosProto(pfLink, conf.VFID, *conf.Vlan, vlanQoS, vlanProto)
+			}); err != nil {
+				return drifts, err
+			}
+		}
+	}
+
+	if conf.MAC != "" && !strings.EqualFold(vfInfo.Mac.String(), conf.MAC) {
+		drift := fmt.Sprintf("MAC address: expected %s, found %s", conf.MAC, vfInfo.Mac)
+		if err = reconcile(drift, func() error {
+			return utils.SetVFHardwareMAC(s.nLink, conf.Master, conf.VFID, conf.MAC)
+		}); err != nil {
+			return drifts, err
+		}
+	}
+
+	if conf.MinTxRate != nil || conf.MaxTxRate != nil {
+		minTxRate, maxTxRate := int(vfInfo.MinTxRate), int(vfInfo.MaxTxRate)
+		if conf.MinTxRate != nil {
+			minTxRate = *conf.MinTxRate
+		}
+		// a clamped max tx rate is applied below the requested one
+		if conf.MaxTxRate != nil && !(conf.EnforceRateCeiling == sriovtypes.RateCeilingClamp &&
+			vfInfo.MaxTxRate > 0 && vfInfo.MaxTxRate < uint32(*conf.MaxTxRate)) {
+			maxTxRate = *conf.MaxTxRate
+		}
+		if uint32(minTxRate) != vfInfo.MinTxRate || uint32(maxTxRate) != vfInfo.MaxTxRate {
+			drift := fmt.Sprintf("tx rates: expected min %d max %d, found min %d max %d",
+				minTxRate, maxTxRate, vfInfo.MinTxRate, vfInfo.MaxTxRate)
+			if err = reconcile(drift, func() error {
+				return s.nLink.LinkSetVfRate(pfLink, conf.VFID, minTxRate, maxTxRate)
+			}); err != nil {
+				return drifts, err
+			}
+		}
+	}
+
+	if conf.SpoofChk != "" && vfInfo.Spoofchk != (conf.SpoofChk == "on") {
+		drift := fmt.Sprintf("spoofchk: expected %s, found %t", conf.SpoofChk, vfInfo.Spoofchk)
+		if err = reconcile(drift, func() error {
+			return s.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, conf.SpoofChk == "on")
+		}); err != nil {
+			return drifts, err
+		}
+	}
+
+	if conf.Trust != "" && (vfInfo.Trust != 0) != (conf.Trust == "on") {
+		drift := fmt.Sprintf("trust: expected %s, found %d", conf.Trust, vfInfo.Trust)
+		if err = reconcile(drift, func() error {
+			return s.nLink.LinkSetVfTrust(pfLink, conf.VFID, conf.Trust == "on")
+		}); err != nil {
+			return drifts, err
+		}
+	}
+
+	if conf.LinkState != "" {
+		state, err := linkStateFromString(conf.LinkState)
+		if err != nil {
+			return drifts, fmt.Errorf("unknown link state %s configured for vf %d: %w", conf.LinkState, conf.VFID, err)
+		}
+		if vfInfo.LinkState != state {
+			drift := fmt.Sprintf("link state: expected %s, found %s", linkStateToString(state), linkStateToString(vfInfo.LinkState))
+			if err = reconcile(drift, func() error {
+				return s.nLink.LinkSetVfState(pfLink, conf.VFID, state)
+			}); err != nil {
+				return drifts, err
+			}
+		}
+	}
+
+	return drifts, nil
+}
+
+// CompareVFConfig returns an error describing the first difference between the live state of a VF
+// configured by a previous cmdAdd and the VF configuration requested by conf
+func (s *sriovManager) CompareVFConfig(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS) error {
+	if err := resolveMAC(conf); err != nil {
+		return err
+	}
+	resolveSpoofChk(conf)
+
+	vfInfo, err := s.getVfInfoByName(conf.Master, conf.VFID)
+	if err != nil {
+		return err
+	}
+
+	if conf.Vlan != nil {
+		if vfInfo.Vlan != *conf.Vlan || vfInfo.Qos != *conf.VlanQoS {
+			return fmt.Errorf("vf %d vlan differs: expected %d qos %d, found %d qos %d", conf.VFID, *conf.Vlan, *conf.VlanQoS, vfInfo.Vlan, vfInfo.Qos)
+		}
+		if *conf.Vlan != 0 && vfInfo.VlanProto != sriovtypes.VlanProtoInt[*conf.VlanProto] {
+			return fmt.Errorf("vf %d vlan proto differs: expected %s, found %d", conf.VFID, *conf.VlanProto, vfInfo.VlanProto)
+		}
+	}
+
+	if conf.MAC != "" && !strings.EqualFold(vfInfo.Mac.String(), conf.MAC) {
+		return fmt.Errorf("vf %d MAC address differs: expected %s, found %s", conf.VFID, conf.MAC, vfInfo.Mac)
+	}
+
+	if conf.MinTxRate != nil && vfInfo.MinTxRate != uint32(*conf.MinTxRate) {
+		return fmt.Errorf("vf %d min_tx_rate differs: expected %d, found %d", conf.VFID, *conf.MinTxRate, vfInfo.MinTxRate)
+	}
+	if conf.MaxTxRate != nil && vfInfo.MaxTxRate != uint32(*conf.MaxTxRate) {
+		// a clamped max tx rate is applied below the requested one
+		clamped := conf.EnforceRateCeiling == sriovtypes.RateCeilingClamp && vfInfo.MaxTxRate > 0 && vfInfo.MaxTxRate < uint32(*conf.MaxTxRate)
+		if !clamped {
+			return fmt.Errorf("vf %d max_tx_rate differs: expected %d, found %d", conf.VFID, *conf.MaxTxRate, vfInfo.MaxTxRate)
+		}
+	}
+
+	if conf.SpoofChk != "" && vfInfo.Spoofchk != (conf.SpoofChk == "on") {
+		return fmt.Errorf("vf %d spoofchk differs: expected %s, found %t", conf.VFID, conf.SpoofChk, vfInfo.Spoofchk)
+	}
+	if conf.Trust != "" && (vfInfo.Trust != 0) != (conf.Trust == "on") {
+		return fmt.Errorf("vf %d trust differs: expected %s, found %d", conf.VFID, conf.Trust, vfInfo.Trust)
+	}
+
+	if conf.LinkState != "" {
+		state, err := linkStateFromString(conf.LinkState)
+		if err != nil {
+			return fmt.Errorf("unknown link state %s configured for vf %d: %w", conf.LinkState, conf.VFID, err)
+		}
+		if vfInfo.LinkState != state {
+			return fmt.Errorf("vf %d link state differs: expected %s, found %s", conf.VFID, linkStateToString(state), linkStateToString(vfInfo.LinkState))
+		}
+	}
+
+	// A VF bound to a userspace driver has no netdev in the pod netns
+	if conf.DPDKMode {
+		return nil
+	}
+
+	return netns.Do(func(_ ns.NetNS) error {
+		linkObj, err := s.nLink.LinkByName(podifName)
+		if err != nil {
+			return fmt.Errorf("interface %s not found in netns %s: %w", podifName, netns.Path(), err)
+		}
+		drvInfo, err := s.utils.GetDrvInfo(podifName)
+		if err != nil {
+			return fmt.Errorf("failed to get the bus info of interface %s: %w", podifName, err)
+		}
+		if drvInfo.BusInfo != conf.DeviceID {
+			return fmt.Errorf("interface %s is %s, not vf %s", podifName, drvInfo.BusInfo, conf.DeviceID)
+		}
+		if conf.MAC != "" && !strings.EqualFold(linkObj.Attrs().HardwareAddr.String(), conf.MAC) {
+			return fmt.Errorf("interface %s MAC address differs: expected %s, found %s", podifName, conf.MAC, linkObj.Attrs().HardwareAddr)
+		}
+		return nil
+	})
+}
+
+// getVfInfoByName returns the current state of a VF of the PF netdevice
+func (s *sriovManager) getVfInfoByName(pfName string, vfID int) (*netlink.VfInfo, error) {
+	pfLink, err := s.nLink.LinkByName(pfName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup master %q: %w", pfName, err)
+	}
+	vfInfo := getVfInfo(pfLink, vfID)
+	if vfInfo == nil {
+		return nil, fmt.Errorf("failed to find vf %d", vfID)
+	}
+
+	return vfInfo, nil
+}
+
+// resolveMAC sets the MAC address of conf when it is derived from the VF pci address or from the hostname.
+// The MAC address is cleared when the MAC configuration is skipped, so that it is neither set nor compared.
+func resolveMAC(conf *sriovtypes.NetConf) error {
+	if conf.SkipsMACConfig() {
+		if conf.MAC != "" {
+			logging.Debug("Skipping the configured MAC address, the VF MAC is left untouched",
+				"func", "resolveMAC",
+				"conf.VFID", conf.VFID,
+				"conf.MAC", conf.MAC)
+		}
+		conf.MAC = ""
+		return nil
+	}
+	if conf.MAC == sriovtypes.MACAuto {
+		// the derived MAC is cached with the netconf and set as the effective MAC by SetupVF
+		conf.MAC = utils.MACFromPCI(conf.DeviceID).String()
+		logging.Debug("Derived MAC address from the VF pci address",
+			"func", "resolveMAC",
+			"conf.DeviceID", conf.DeviceID,
+			"conf.MAC", conf.MAC)
+	}
+	if conf.MAC == "" && conf.MacFromHostname {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("failed to get hostname to derive the MAC address of vf %d: %w", conf.VFID, err)
+		}
+		conf.MAC = utils.MACFromHostname(hostname, conf.Master, conf.VFID).String()
+		logging.Debug("Derived MAC address from the hostname",
+			"func", "resolveMAC",
+			"hostname", hostname,
+			"conf.VFID", conf.VFID,
+			"conf.MAC", conf.MAC)
+	}
+	return nil
+}
+
+// resolveSpoofChk sets the spoofchk flag of conf when it is derived from the trust flag
+func resolveSpoofChk(conf *sriovtypes.NetConf) {
+	if conf.SpoofChk == "" && conf.Trust != "" && conf.SpoofChkFollowsTrust != nil && *conf.SpoofChkFollowsTrust {
+		conf.SpoofChk = "on"
+		if conf.Trust == "on" {
+			conf.SpoofChk = "off"
+		}
+		logging.Debug("Derived spoofchk from trust",
+			"func", "resolveSpoofChk",
+			"conf.Trust", conf.Trust,
+			"conf.SpoofChk", conf.SpoofChk)
+	}
+}
+
+// linkStateFromString returns the netlink VF link state of a link_state configuration value
+func linkStateFromString(linkState string) (uint32, error) {
+	switch linkState {
+	case "auto":
+		return netlink.VF_LINK_STATE_AUTO, nil
+	case "enable":
+		return netlink.VF_LINK_STATE_ENABLE, nil
+	case "disable":
+		return netlink.VF_LINK_STATE_DISABLE, nil
+	}
+	return 0, fmt.Errorf("invalid link state %s", linkState)
+}
+
+// linkStateToString maps a netlink VF link state back to its link_state string, for logging
+func linkStateToString(linkState uint32) string {
+	switch linkState {
+	case netlink.VF_LINK_STATE_AUTO:
+		return "auto"
+	case netlink.VF_LINK_STATE_ENABLE:
+		return "enable"
+	case netlink.VF_LINK_STATE_DISABLE:
+		return "disable"
+	}
+	return fmt.Sprintf("unknown(%d)", linkState)
+}
+
+// waitForLinkUp polls the link until it is up and has carrier, i.e. IFF_UP|IFF_RUNNING as iproute2 reports it,
+// and returns an error if it is not up within timeout. It must be called in the netns of the link.
+func (s *sriovManager) waitForLinkUp(ifName string, timeout time.Duration) error {
+	start := time.Now()
+	for {
+		linkObj, err := s.nLink.LinkByName(ifName)
+		if err != nil {
+			return fmt.Errorf("failed to get netlink device with name %s: %w", ifName, err)
+		}
+
+		up := linkObj.Attrs().RawFlags&(unix.IFF_UP|unix.IFF_RUNNING) == (unix.IFF_UP | unix.IFF_RUNNING)
+		logging.Debug("Poll link state",
+			"func", "waitForLinkUp",
+			"ifName", ifName,
+			"operState", linkObj.Attrs().OperState.String(),
+			"up", up,
+			"elapsed", time.Since(start))
+		if up {
+			return nil
+		}
+
+		if time.Since(start) >= timeout {
+			return fmt.Errorf("link %s is not up after %v, operstate is %s", ifName, timeout, linkObj.Attrs().OperState)
+		}
+		time.Sleep(linkUpPollInterval)
+	}
+}
+
+// clsactQdisc returns the clsact qdisc of the link holding the ingress policing filter
+func clsactQdisc(link netlink.Link) *netlink.Clsact {
+	return &netlink.Clsact{QdiscAttrs: netlink.QdiscAttrs{
+		LinkIndex: link.Attrs().Index,
+		Handle:    netlink.MakeHandle(0xffff, 0),
+		Parent:    netlink.HANDLE_CLSACT,
+	}}
+}
+
+// setIngressPolice polices the traffic received by the link with a matchall filter on its clsact qdisc, like
+// `tc filter add dev <link> ingress matchall action police rate <rate>mbit burst <burst> drop` does.
+func (s *sriovManager) setIngressPolice(linkObj netlink.Link, police *sriovtypes.IngressPolice) error {
+	if err := s.nLink.QdiscAdd(clsactQdisc(linkObj)); err != nil {
+		return fmt.Errorf("failed to add clsact qdisc to %s: %w", linkObj.Attrs().Name, err)
+	}
+
+	action := netlink.NewPoliceAction()
+	action.Rate = uint32(uint64(police.Rate) * 1000 * 1000 / 8) // Mbps to bytes per second
+	action.Burst = uint32(police.Burst)
+	action.ExceedAction = netlink.TC_POLICE_SHOT
+	filter := &netlink.MatchAll{
+		FilterAttrs: netlink.FilterAttrs{
+			LinkIndex: linkObj.Attrs().Index,
+			Parent:    netlink.HANDLE_MIN_INGRESS,
+			Priority:  1,
+			Protocol:  unix.ETH_P_ALL,
+		},
+		Actions: []netlink.Action{action},
+	}
+	if err := s.nLink.FilterAdd(filter); err != nil {
+		return fmt.Errorf("failed to add ingress police filter to %s: %w", linkObj.Attrs().Name, err)
+	}
+
+	return nil
+}
+
+// altMACEntry returns the FDB entry adding mac to the unicast address list of the link, like `bridge fdb add <mac>
+// dev <link> self` does. The driver then accepts the frames sent to mac as for the primary address.
+func altMACEntry(link netlink.Link, mac net.HardwareAddr) *netlink.Neigh {
+	return &netlink.Neigh{
+		LinkIndex:    link.Attrs().Index,
+		Family:       unix.AF_BRIDGE,
+		Flags:        netlink.NTF_SELF,
+		State:        netlink.NUD_PERMANENT,
+		HardwareAddr: mac,
+	}
+}
+
+// addAltMACs adds the secondary MAC addresses of the netconf to the VF netdev and records them in
+// conf.AddedAltMACs so that cmdDel removes them. Drivers that do not support secondary addresses are skipped.
+func (s *sriovManager) addAltMACs(linkObj netlink.Link, conf *sriovtypes.NetConf) error {
+	for _, altMAC := range conf.AltMACs {
+		mac, err := net.ParseMAC(altMAC)
+		if err != nil {
+			return fmt.Errorf("failed to parse secondary MAC address %s: %w", altMAC, err)
+		}
+
+		if err = s.nLink.NeighAppend(altMACEntry(linkObj, mac)); err != nil {
+			if errors.Is(err, unix.EOPNOTSUPP) {
+				logging.Warning("Secondary MAC addresses are not supported by the VF driver, skipping",
+					"func", "addAltMACs",
+					"link", linkObj.Attrs().Name)
+				return nil
+			}
+			return fmt.Errorf("failed to add secondary MAC address %s to %s: %w", altMAC, linkObj.Attrs().Name, err)
+		}
+		conf.AddedAltMACs = append(conf.AddedAltMACs, mac.String())
+	}
+
+	return nil
+}
+
+// delAltMACs removes the secondary MAC addresses added by addAltMACs from the VF netdev
+func (s *sriovManager) delAltMACs(linkObj netlink.Link, altMACs []string) error {
+	for _, altMAC := range altMACs {
+		mac, err := net.ParseMAC(altMAC)
+		if err != nil {
+			return fmt.Errorf("failed to parse secondary MAC address %s: %w", altMAC, err)
+		}
+
+		if err = s.nLink.NeighDel(altMACEntry(linkObj, mac)); err != nil {
+			return fmt.Errorf("failed to remove secondary MAC address %s from %s: %w", altMAC, linkObj.Attrs().Name, err)
+		}
+	}
+
+	return nil
+}
+
+// fdbEntry returns the FDB entry of mac tagged with vni on the PF, like `bridge fdb add <mac> dev <pf> self vni <vni>`
+func fdbEntry(pfLink netlink.Link, mac net.HardwareAddr, vni int) *netlink.Neigh {
+	return &netlink.Neigh{
+		LinkIndex:    pfLink.Attrs().Index,
+		Family:       unix.AF_BRIDGE,
+		Flags:        netlink.NTF_SELF,
+		State:        netlink.NUD_PERMANENT,
+		HardwareAddr: mac,
+		VNI:          vni,
+	}
+}
+
+// addFdbEntry adds the FDB entry of the VF MAC tagged with the VNI of the netconf to the PF and records the MAC in
+// conf.AddedFdbMAC so that cmdDel removes it. The VF MAC is the configured one, or else the VF admin MAC. PF drivers
+// that do not support tagged FDB entries are skipped.
+func (s *sriovManager) addFdbEntry(pfLink netlink.Link, conf *sriovtypes.NetConf) error {
+	var mac net.HardwareAddr
+	if conf.MAC != "" {
+		var err error
+		if mac, err = net.ParseMAC(conf.MAC); err != nil {
+			return fmt.Errorf("failed to parse MAC address %s: %w", conf.MAC, err)
+		}
+	} else if vfInfo := getVfInfo(pfLink, conf.VFID); vfInfo != nil {
+		mac = vfInfo.Mac
+	}
+	if len(mac) == 0 || mac.String() == zeroMAC {
+		return newVFError(ErrInvalidVFConfig, fmt.Errorf("vf %d has no MAC address to add an FDB entry for", conf.VFID))
+	}
+
+	if err := s.nLink.NeighAppend(fdbEntry(pfLink, mac, *conf.FdbVNI)); err != nil {
+		if errors.Is(err, unix.EOPNOTSUPP) {
+			logging.Warning("Tagged FDB entries are not supported by the PF driver, skipping",
+				"func", "addFdbEntry",
+				"pf", conf.Master,
+				"conf.FdbVNI", *conf.FdbVNI)
+			return nil
+		}
+		return fmt.Errorf("failed to add FDB entry %s vni %d to %s: %w", mac, *conf.FdbVNI, conf.Master, err)
+	}
+	conf.AddedFdbMAC = mac.String()
+	return nil
+}
+
+// checkSwitchdevMode verifies that the e-switch of the PF of the VF is in switchdev mode. VF representors only
+// exist in switchdev mode, so it must be checked before using representor paths.
+func (s *sriovManager) checkSwitchdevMode(conf *sriovtypes.NetConf) error {
+	pfPci, err := utils.GetPfPciFromVfPci(conf.DeviceID)
+	if err != nil {
+		return fmt.Errorf("failed to get PF pci address of VF %s: %w", conf.DeviceID, err)
+	}
+
+	dev, err := s.nLink.DevLinkGetDeviceByName("pci", pfPci)
+	if err != nil {
+		return fmt.Errorf("failed to get devlink device of PF %s: %w", pfPci, err)
+	}
+
+	if mode := dev.Attrs.Eswitch.Mode; mode != eswitchModeSwitchdev {
+		return fmt.Errorf("PF %s e-switch mode is %q, VF representors require %q mode", pfPci, mode, eswitchModeSwitchdev)
+	}
+
+	return nil
+}
+
+// getRepresentorLink returns the representor netdev of the VF, the PF e-switch must be in switchdev mode
+func (s *sriovManager) getRepresentorLink(conf *sriovtypes.NetConf) (netlink.Link, error) {
+	if err := s.checkSwitchdevMode(conf); err != nil {
+		return nil, err
+	}
+	repName, err := s.utils.GetVFRepresentor(conf.Master, conf.VFID)
+	if err != nil {
+		return nil, err
+	}
+	repLink, err := s.nLink.LinkByName(repName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup representor %q: %w", repName, err)
+	}
+	return repLink, nil
+}
+
+// GetRepresentor returns the name of the representor netdev of the VF. The error wraps utils.ErrLegacyMode when
+// the PF is in legacy mode, its VFs have no representor then.
+func (s *sriovManager) GetRepresentor(conf *sriovtypes.NetConf) (string, error) {
+	return s.utils.GetVFRepresentor(conf.Master, conf.VFID)
+}
+
+// setRepresentorVlan sets the vlan as the untagged pvid of the VF representor on its bridge, so that the
+// VF traffic is tagged in the offloaded datapath instead of by the VF vlan of legacy mode
+func (s *sriovManager) setRepresentorVlan(conf *sriovtypes.NetConf) error {
+	repLink, err := s.getRepresentorLink(conf)
+	if err != nil {
+		return err
+	}
+	if err = s.nLink.BridgeVlanAdd(repLink, uint16(*conf.RepresentorVlan), true, true, false, true); err != nil {
+		return fmt.Errorf("failed to set vlan %d on representor %s: %w", *conf.RepresentorVlan, repLink.Attrs().Name, err)
+	}
+	return nil
+}
+
+// setRepresentorState sets the representor of the VF up or down
+func (s *sriovManager) setRepresentorState(conf *sriovtypes.NetConf, up bool) error {
+	repLink, err := s.getRepresentorLink(conf)
+	if err != nil {
+		return err
+	}
+	repName := repLink.Attrs().Name
+	action := "down"
+	if up {
+		action = "up"
+		err = s.nLink.LinkSetUp(repLink)
+	} else {
+		err = s.nLink.LinkSetDown(repLink)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set representor %s %s: %w", repName, action, err)
+	}
+	logging.Info("Set the VF representor admin state",
+		"func", "setRepresentorState",
+		"representor", repName,
+		"action", action,
+		"conf.DeviceID", conf.DeviceID)
+	return nil
+}
+
+// QuarantineRepresentor sets the representor of the VF down after cmdDel reset the VF, so that the VF has no
+// connectivity in the offloaded datapath until an operator reclaims it. It returns the name of the representor.
+func (s *sriovManager) QuarantineRepresentor(conf *sriovtypes.NetConf) (string, error) {
+	repLink, err := s.getRepresentorLink(conf)
+	if err != nil {
+		return "", err
+	}
+	repName := repLink.Attrs().Name
+	if err = s.nLink.LinkSetDown(repLink); err != nil {
+		return "", fmt.Errorf("failed to set representor %s down: %w", repName, err)
+	}
+	logging.Info("Quarantined the VF representor",
+		"func", "QuarantineRepresentor",
+		"representor", repName,
+		"conf.DeviceID", conf.DeviceID)
+	return repName, nil
+}
+
+// ReclaimRepresentor sets a quarantined representor up again
+func (s *sriovManager) ReclaimRepresentor(repName string) error {
+	repLink, err := s.nLink.LinkByName(repName)
+	if err != nil {
+		return fmt.Errorf("failed to lookup representor %q: %w", repName, err)
+	}
+	if err = s.nLink.LinkSetUp(repLink); err != nil {
+		return fmt.Errorf("failed to set representor %s up: %w", repName, err)
+	}
+	return nil
+}
+
+// LocatePort blinks the port identification LED of the PF for the given number of seconds, to locate the NIC
+// in the datacenter. pciAddr is the one of the PF or of one of its VFs. It returns the PF netdev name.
+func (s *sriovManager) LocatePort(pciAddr string, seconds int) (string, error) {
+	pfName, err := utils.GetPfName(pciAddr)
+	if errors.Is(err, utils.ErrNotVF) {
+		pfName, err = utils.GetVFLinkName(pciAddr)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get the PF netdev of device %s: %w", pciAddr, err)
+	}
+	if err := s.utils.IdentifyPort(pfName, seconds); err != nil {
+		return "", err
+	}
+	return pfName, nil
+}
+
+// SelfTestVF runs the offline self-test of the VF netdev with its loopback feature enabled, for NIC bring-up
+// validation, then restores the loopback feature. The VF traffic is interrupted while the test runs.
+func (s *sriovManager) SelfTestVF(pciAddr string) (result *utils.SelfTestResult, err error) {
+	ifName, err := utils.GetVFLinkName(pciAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the netdev of VF %s: %w", pciAddr, err)
+	}
+
+	loopback, err := s.utils.GetLoopback(ifName)
+	if err != nil {
+		return nil, err
+	}
+	if !loopback {
+		if err := s.utils.SetLoopback(ifName, true); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if restoreErr := s.utils.SetLoopback(ifName, false); restoreErr != nil && err == nil {
+				err = fmt.Errorf("failed to restore the loopback feature of VF %s: %w", pciAddr, restoreErr)
+			}
+		}()
+	}
+
+	return s.utils.RunSelfTest(ifName)
+}
+
+// CheckPFNumVFs compares the sriov_numvfs of the PF with the one recorded on ADD. It returns ErrVFNotFound if the
+// PF no longer has the VF, e.g. after a driver reload, the VF index may then be the one of another VF. A PF whose
+// number of VFs changed but still has the VF is only logged.
+func (s *sriovManager) CheckPFNumVFs(conf *sriovtypes.NetConf) error {
+	numVFs, err := s.utils.GetSriovNumVfs(conf.Master)
+	if err != nil {
+		return err
+	}
+
+	if numVFs <= conf.VFID {
+		msg := fmt.Sprintf("vf %d no longer exists, the sriov_numvfs of PF %s changed from %d to %d", conf.VFID, conf.Master,
+			conf.OrigVfState.PFNumVFs, numVFs)
+		if totalVFs, err := s.utils.GetPFTotalVFs(conf.Master); err == nil {
+			msg += fmt.Sprintf(" out of %d supported VFs", totalVFs)
+		}
+		return newVFError(ErrVFNotFound, errors.New(msg))
+	}
+
+	// configurations cached before the number of VFs was recorded have none
+	if conf.OrigVfState.PFNumVFs != 0 && numVFs != conf.OrigVfState.PFNumVFs {
+		logging.Warning("The sriov_numvfs of the PF changed since the VF was configured",
+			"func", "CheckPFNumVFs",
+			"pf", conf.Master,
+			"vfID", conf.VFID,
+			"cached", conf.OrigVfState.PFNumVFs,
+			"current", numVFs)
+	}
+	return nil
+}
+
+// CheckVFDevice checks the sysfs devices of the VF and its PF still exist. It returns ErrVFNotFound if they do not,
+// e.g. when the PF driver was unloaded, in which case there is nothing left to clean up on the VF.
+func (s *sriovManager) CheckVFDevice(conf *sriovtypes.NetConf) error {
+	exists, err := utils.VFDeviceExists(conf.Master, conf.DeviceID)
+	if err != nil {
+		return fmt.Errorf("failed to check the sysfs device of vf %s: %w", conf.DeviceID, err)
+	}
+	if !exists {
+		return newVFError(ErrVFNotFound,
+			fmt.Errorf("the sysfs device of vf %s or of its PF %s no longer exists, the PF driver may be unloaded", conf.DeviceID, conf.Master))
+	}
+	return nil
+}
+
+// CaptureOnFailure records a brief sample of the packets of the VF netdev in the pod netns to a pcap file of the
+// capture directory when cmdAdd failed with addErr and captureOnFailure is set. The capture is best effort, its
+// failures are logged.
+func (s *sriovManager) CaptureOnFailure(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS, addErr error) {
+	if addErr == nil || !conf.CaptureOnFailure {
+		return
+	}
+
+	path := filepath.Join(conf.CaptureDir, fmt.Sprintf("%s-%s.pcap", conf.DeviceID, time.Now().UTC().Format("20060102T150405Z")))
+	var count int
+	err := netns.Do(func(_ ns.NetNS) error {
+		var err error
+		count, err = s.utils.CapturePackets(podifName, path, captureDuration)
+		return err
+	})
+	if err != nil {
+		logging.Warning("Failed to capture the VF packets after the ADD failure",
+			"func", "CaptureOnFailure",
+			"podifName", podifName,
+			"path", path,
+			"err", err)
+		return
+	}
+	logging.Info("Captured the VF packets after the ADD failure",
+		"func", "CaptureOnFailure",
+		"podifName", podifName,
+		"path", path,
+		"packets", count,
+		"addErr", addErr)
+}
+
+// isInfiniBandLink returns true if the link is an InfiniBand (IPoIB) netdevice
+func isInfiniBandLink(link netlink.Link) bool {
+	return link.Attrs().EncapType == "infiniband"
+}
+
+// setVFGUID sets the node and the port GUID of an InfiniBand VF
+func (s *sriovManager) setVFGUID(pfLink netlink.Link, vfID int, nodeGUID, portGUID string) error {
+	nodeAddr, err := utils.ParseGUID(nodeGUID)
+	if err != nil {
+		return err
+	}
+	portAddr, err := utils.ParseGUID(portGUID)
+	if err != nil {
+		return err
+	}
+	if err := s.nLink.LinkSetVfNodeGUID(pfLink, vfID, nodeAddr); err != nil {
+		return fmt.Errorf("failed to set vf %d node GUID to %s: %w", vfID, nodeGUID, err)
+	}
+	if err := s.nLink.LinkSetVfPortGUID(pfLink, vfID, portAddr); err != nil {
+		return fmt.Errorf("failed to set vf %d port GUID to %s: %w", vfID, portGUID, err)
+	}
+
+	return nil
+}
+
+func getVfInfo(link netlink.Link, id int) *netlink.VfInfo {
+	attrs := link.Attrs()
+	for _, vf := range attrs.Vfs {
+		if vf.ID == id {
+			return &vf
+		}
+	}
+	return nil
+}
+
+// ApplyVFConfig configure a VF with parameters given in NetConf. Errors wrap ErrVFNotFound, ErrVFBusy or
+// ErrInvalidVFConfig when their cause is known. A passthrough VF is left unconfigured.
+func (s *sriovManager) ApplyVFConfig(conf *sriovtypes.NetConf) (err error) {
+	defer func() { err = classifyVFError(err) }()
+
+	if conf.IsPassthrough() {
+		logging.Debug("Skip configuring a passthrough VF",
+			"func", "ApplyVFConfig",
+			"conf.DeviceID", conf.DeviceID)
+		// drop the MAC address requested by the runtime, SetupVF must not set it either
+		return resolveMAC(conf)
+	}
+
+	pfLink, err := s.nLink.LinkByName(conf.Master)
+	if err != nil {
+		return fmt.Errorf("failed to lookup master %q: %w", conf.Master, err)
+	}
+	// 1. Set vlan
+	if conf.Vlan != nil {
+		if conf.CheckUplinkVlan && *conf.Vlan != 0 {
+			s.checkUplinkVlan(pfLink, *conf.Vlan)
+		}
+		if conf.EnforceVlanExclusivity && *conf.Vlan != 0 {
+			if err = checkVlanExclusivity(pfLink, conf); err != nil {
+				return newVFError(ErrInvalidVFConfig, err)
+			}
+		}
+		if err = s.checkVlanQoSProto(conf); err != nil {
+			return newVFError(ErrInvalidVFConfig, err)
+		}
+		if err = s.nLink.LinkSetVfVlanQosProto(pfLink, conf.VFID, *conf.Vlan, *conf.VlanQoS, sriovtypes.VlanProtoInt[*conf.VlanProto]); err != nil {
+			return fmt.Errorf("failed to set vf %d vlan configuration - id %d, qos %d and proto %s: %w", conf.VFID, *conf.Vlan, *conf.VlanQoS, *conf.VlanProto, err)
+		}
+		if *conf.Vlan != 0 && *conf.VlanProto == sriovtypes.Proto8021ad {
+			if err = s.verifyVlanProto(conf); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 2. Set mac address, or node and port GUID of InfiniBand VFs
+	if err = resolveMAC(conf); err != nil {
+		return err
+	}
+	if conf.MAC != "" {
+		if isInfiniBandLink(pfLink) {
+			return newVFError(ErrInvalidVFConfig, fmt.Errorf("failed to set MAC address to %s: vf %d is an InfiniBand VF, configure a guid instead", conf.MAC, conf.VFID))
+		}
+		// with the legacy ordering, SetupVF sets the MAC of a VF with a netdev once the VF is moved to the pod netns
+		if !conf.UsesLegacyMACOrder() || conf.DPDKMode {
+			// when we restore the original hardware mac address we may get a device or resource busy. so we introduce retry
+			if err := utils.SetVFHardwareMAC(s.nLink, conf.Master, conf.VFID, conf.MAC); err != nil {
+				return fmt.Errorf("failed to set MAC address to %s: %w", conf.MAC, err)
+			}
+		}
+	}
+	if conf.GUID != "" {
+		if !isInfiniBandLink(pfLink) {
+			return newVFError(ErrInvalidVFConfig, fmt.Errorf("failed to set GUID to %s: vf %d is not an InfiniBand VF", conf.GUID, conf.VFID))
+		}
+		if err = s.setVFGUID(pfLink, conf.VFID, conf.GUID, conf.GUID); err != nil {
+			return err
 		}
 	}
 
@@ -280,23 +2386,42 @@ func (s *sriovManager) ApplyVFConfig(conf *sriovtypes.NetConf) error {
 	if conf.MaxTxRate != nil {
 		maxTxRate = *conf.MaxTxRate
 		rateConfigured = true
+
+		if conf.EnforceRateCeiling != "" && maxTxRate > 0 {
+			if maxTxRate, err = s.enforceRateCeiling(conf, maxTxRate); err != nil {
+				return err
+			}
+			conf.MaxTxRate = &maxTxRate
+		}
+	}
+
+	if minTxRate > 0 {
+		if err = s.checkMinTxRate(conf, minTxRate, maxTxRate); err != nil {
+			return err
+		}
+		if conf.EnforceSchedulerCapacity != "" {
+			if err = s.checkSchedulerCapacity(pfLink, conf, minTxRate); err != nil {
+				return err
+			}
+		}
 	}
 
 	if rateConfigured {
 		if err = s.nLink.LinkSetVfRate(pfLink, conf.VFID, minTxRate, maxTxRate); err != nil {
-			return fmt.Errorf("failed to set vf %d min_tx_rate to %d Mbps: max_tx_rate to %d Mbps: %v",
+			return fmt.Errorf("failed to set vf %d min_tx_rate to %d Mbps: max_tx_rate to %d Mbps: %w",
 				conf.VFID, minTxRate, maxTxRate, err)
 		}
 	}
 
-	// 4. Set spoofchk flag
+	// 4. Set spoofchk flag, derived from the trust flag when requested
+	resolveSpoofChk(conf)
 	if conf.SpoofChk != "" {
 		spoofChk := false
 		if conf.SpoofChk == "on" {
 			spoofChk = true
 		}
 		if err = s.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, spoofChk); err != nil {
-			return fmt.Errorf("failed to set vf %d spoofchk flag to %s: %v", conf.VFID, conf.SpoofChk, err)
+			return fmt.Errorf("failed to set vf %d spoofchk flag to %s: %w", conf.VFID, conf.SpoofChk, err)
 		}
 	}
 
@@ -307,26 +2432,81 @@ func (s *sriovManager) ApplyVFConfig(conf *sriovtypes.NetConf) error {
 			trust = true
 		}
 		if err = s.nLink.LinkSetVfTrust(pfLink, conf.VFID, trust); err != nil {
-			return fmt.Errorf("failed to set vf %d trust flag to %s: %v", conf.VFID, conf.Trust, err)
+			return fmt.Errorf("failed to set vf %d trust flag to %s: %w", conf.VFID, conf.Trust, err)
+		}
+		if err = s.verifyTrust(conf, trust); err != nil {
+			return err
+		}
+
+		// limit the MAC changes allowed to the trusted VF
+		if trust && conf.MaxMacChanges != nil {
+			if err = s.setMaxMacChanges(conf.Master, conf.VFID, *conf.MaxMacChanges); err != nil {
+				return err
+			}
 		}
 	}
 
 	// 6. Set link state
 	if conf.LinkState != "" {
-		var state uint32
-		switch conf.LinkState {
-		case "auto":
-			state = netlink.VF_LINK_STATE_AUTO
-		case "enable":
-			state = netlink.VF_LINK_STATE_ENABLE
-		case "disable":
-			state = netlink.VF_LINK_STATE_DISABLE
-		default:
+		state, err := linkStateFromString(conf.LinkState)
+		if err != nil {
 			// the value should have been validated earlier, return error if we somehow got here
-			return fmt.Errorf("unknown link state %s when setting it for vf %d: %v", conf.LinkState, conf.VFID, err)
+			return fmt.Errorf("unknown link state %s when setting it for vf %d: %w", conf.LinkState, conf.VFID, err)
 		}
 		if err = s.nLink.LinkSetVfState(pfLink, conf.VFID, state); err != nil {
-			return fmt.Errorf("failed to set vf %d link state to %d: %v", conf.VFID, state, err)
+			return fmt.Errorf("failed to set vf %d link state to %d: %w", conf.VFID, state, err)
+		}
+
+		// verify the driver applied the link state
+		vfInfo, err := s.getVfInfoByName(conf.Master, conf.VFID)
+		if err != nil {
+			return err
+		}
+		if vfInfo.LinkState != state {
+			return fmt.Errorf("vf %d link state is %d after setting it to %d", conf.VFID, vfInfo.LinkState, state)
+		}
+	}
+
+	// 7. Set vlan on the VF representor
+	if conf.RepresentorVlan != nil {
+		logging.Debug("7. Set vlan on the VF representor",
+			"func", "ApplyVFConfig",
+			"conf.VFID", conf.VFID,
+			"conf.RepresentorVlan", *conf.RepresentorVlan)
+		if err = s.setRepresentorVlan(conf); err != nil {
+			return err
+		}
+	}
+
+	// 8. Add the FDB entry of the VF MAC tagged with the VNI
+	if conf.FdbVNI != nil {
+		logging.Debug("8. Add the FDB entry of the VF MAC tagged with the VNI",
+			"func", "ApplyVFConfig",
+			"conf.VFID", conf.VFID,
+			"conf.FdbVNI", *conf.FdbVNI)
+		if err = s.addFdbEntry(pfLink, conf); err != nil {
+			return err
+		}
+	}
+
+	// 9. Allow or deny the vlan tags of the guest, by turning the vlan anti-spoofing of the PF off or on
+	if conf.AllowGuestVlan != nil {
+		logging.Debug("9. Allow or deny the vlan tags of the guest",
+			"func", "ApplyVFConfig",
+			"conf.VFID", conf.VFID,
+			"conf.AllowGuestVlan", *conf.AllowGuestVlan)
+		if err = s.setVlanAntiSpoof(conf.Master, conf.VFID, !*conf.AllowGuestVlan); err != nil {
+			return err
+		}
+	}
+
+	// 10. Set the VF representor up, so that the VF traffic flows through the offloaded datapath
+	if conf.ManagesRepresentor() && conf.LinkState == "enable" {
+		logging.Debug("10. Set the VF representor up",
+			"func", "ApplyVFConfig",
+			"conf.VFID", conf.VFID)
+		if err = s.setRepresentorState(conf, true); err != nil {
+			return err
 		}
 	}
 
@@ -338,11 +2518,129 @@ func (s *sriovManager) ApplyVFConfig(conf *sriovtypes.NetConf) error {
 	return nil
 }
 
+// checkVlanQoSProto rejects a vlan QoS with 802.1ad when the PF driver only supports a QoS with 802.1q.
+// The check is skipped when the PF driver cannot be read.
+func (s *sriovManager) checkVlanQoSProto(conf *sriovtypes.NetConf) error {
+	if *conf.VlanQoS == 0 || *conf.VlanProto != sriovtypes.Proto8021ad {
+		return nil
+	}
+
+	driver, err := s.utils.GetPFDriver(conf.Master)
+	if err != nil {
+		logging.Debug("Cannot read the PF driver, skipping the vlan QoS and proto check",
+			"func", "checkVlanQoSProto",
+			"conf.Master", conf.Master,
+			"err", err)
+		return nil
+	}
+	if vlanQoS8021adUnsupported[driver] {
+		return fmt.Errorf("vf %d vlan qos %d with proto %s is not supported by driver %s of PF %s, use proto %s or qos 0",
+			conf.VFID, *conf.VlanQoS, sriovtypes.Proto8021ad, driver, conf.Master, sriovtypes.Proto8021q)
+	}
+	return nil
+}
+
+// verifyTrust reads back the trust flag of the VF, as the firmware of some NICs does not support trusted VFs and
+// the driver accepts the flag without applying it, leaving the VF unable to enforce it
+func (s *sriovManager) verifyTrust(conf *sriovtypes.NetConf, trust bool) error {
+	vfInfo, err := s.getVfInfoByName(conf.Master, conf.VFID)
+	if err != nil {
+		return fmt.Errorf("failed to read back vf %d trust flag: %w", conf.VFID, err)
+	}
+	applied := sriovtypes.VfState{}
+	applied.FillFromVfInfo(vfInfo)
+	if applied.Trust != trust {
+		logging.Warning("The firmware of the NIC rejected the VF trust flag, the NIC cannot enforce it",
+			"func", "verifyTrust",
+			"conf.DeviceID", conf.DeviceID,
+			"conf.Master", conf.Master,
+			"conf.VFID", conf.VFID,
+			"conf.Trust", conf.Trust)
+		return newVFError(ErrInvalidVFConfig, fmt.Errorf("vf %d trust flag is %s after setting it to %s, the firmware of PF %s does not support it",
+			conf.VFID, onOff(applied.Trust), conf.Trust, conf.Master))
+	}
+	return nil
+}
+
+// verifyVlanProto reads back the vlan proto of the VF, as some drivers accept 802.1ad and silently fall back to
+// 802.1q. The check is skipped when the kernel does not report the vlan proto of the VF.
+func (s *sriovManager) verifyVlanProto(conf *sriovtypes.NetConf) error {
+	vfInfo, err := s.getVfInfoByName(conf.Master, conf.VFID)
+	if err != nil {
+		return fmt.Errorf("failed to read back vf %d vlan proto: %w", conf.VFID, err)
+	}
+	if vfInfo.VlanProto == 0 {
+		logging.Debug("The kernel does not report the vlan proto of the VF, skipping its verification",
+			"func", "verifyVlanProto",
+			"conf.Master", conf.Master,
+			"conf.VFID", conf.VFID)
+		return nil
+	}
+	if vfInfo.VlanProto != sriovtypes.VlanProtoInt[*conf.VlanProto] {
+		return newVFError(ErrInvalidVFConfig, fmt.Errorf("vf %d vlan proto is %s instead of %s, the driver of PF %s does not support QinQ",
+			conf.VFID, vlanProtoName(vfInfo.VlanProto), *conf.VlanProto, conf.Master))
+	}
+	return nil
+}
+
+// vlanProtoName returns the name of a vlan proto, or its hexadecimal ethertype when it is not a known one
+func vlanProtoName(proto int) string {
+	for name, value := range sriovtypes.VlanProtoInt {
+		if value == proto {
+			return name
+		}
+	}
+	return fmt.Sprintf("0x%04x", proto)
+}
+
+// checkUplinkVlan warns if the vlan is not a member of the PF uplink vlan set.
+// The check is skipped when the PF does not expose its vlan membership (e.g. not a bridge port).
+func (s *sriovManager) checkUplinkVlan(pfLink netlink.Link, vlan int) {
+	vlanInfo, err := s.nLink.BridgeVlanList()
+	if err != nil {
+		logging.Warning("Failed to read PF uplink vlan membership",
+			"func", "checkUplinkVlan",
+			"pf", pfLink.Attrs().Name,
+			"err", err)
+		return
+	}
+
+	pfVlans, ok := vlanInfo[int32(pfLink.Attrs().Index)]
+	if !ok {
+		logging.Debug("PF uplink does not expose vlan membership, skipping check",
+			"func", "checkUplinkVlan",
+			"pf", pfLink.Attrs().Name)
+		return
+	}
+
+	for _, v := range pfVlans {
+		if int(v.Vid) == vlan {
+			return
+		}
+	}
+
+	logging.Warning("VF vlan is not carried by the PF uplink",
+		"func", "checkUplinkVlan",
+		"pf", pfLink.Attrs().Name,
+		"vlan", vlan)
+}
+
+// checkVlanExclusivity rejects the vlan of the VF if it is already set on another VF of the PF, as read from
+// the VF info of the PF netlink link
+func checkVlanExclusivity(pfLink netlink.Link, conf *sriovtypes.NetConf) error {
+	for _, vf := range pfLink.Attrs().Vfs {
+		if vf.ID != conf.VFID && vf.Vlan == *conf.Vlan {
+			return fmt.Errorf("vlan %d of vf %d is already set on vf %d of PF %s", *conf.Vlan, conf.VFID, vf.ID, conf.Master)
+		}
+	}
+	return nil
+}
+
 // FillOriginalVfInfo fills the original vf info
 func (s *sriovManager) FillOriginalVfInfo(conf *sriovtypes.NetConf) error {
 	pfLink, err := s.nLink.LinkByName(conf.Master)
 	if err != nil {
-		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
+		return fmt.Errorf("failed to lookup master %q: %w", conf.Master, err)
 	}
 	// Save current the VF state before modifying it
 	vfState := getVfInfo(pfLink, conf.VFID)
@@ -351,14 +2649,143 @@ func (s *sriovManager) FillOriginalVfInfo(conf *sriovtypes.NetConf) error {
 	}
 	conf.OrigVfState.FillFromVfInfo(vfState)
 
+	// Save the number of VFs of the PF, a change of it on DEL means the VF index may be the one of another VF
+	numVFs, err := s.utils.GetSriovNumVfs(conf.Master)
+	if err != nil {
+		return err
+	}
+	conf.OrigVfState.PFNumVFs = numVFs
+
+	// Save the node and port GUID of the VF, they may differ
+	if conf.GUID != "" {
+		nodeGUID, err := s.nLink.LinkGetVfNodeGUID(pfLink, conf.VFID)
+		if err != nil {
+			return fmt.Errorf("failed to get node GUID of vf %d: %w", conf.VFID, err)
+		}
+		conf.OrigVfState.NodeGUID = nodeGUID.String()
+		portGUID, err := s.nLink.LinkGetVfPortGUID(pfLink, conf.VFID)
+		if err != nil {
+			return fmt.Errorf("failed to get port GUID of vf %d: %w", conf.VFID, err)
+		}
+		conf.OrigVfState.PortGUID = portGUID.String()
+	}
+
+	// Save the MAC change limit of the VF, drivers that do not support it are left untouched
+	if conf.MaxMacChanges != nil {
+		limit, err := s.utils.GetVFMaxMacChanges(conf.Master, conf.VFID)
+		if err != nil && !errors.Is(err, utils.ErrNotSupported) {
+			return fmt.Errorf("failed to get MAC change limit of vf %d: %w", conf.VFID, err)
+		}
+		conf.OrigVfState.MaxMacChanges = limit
+	}
+
+	// Save the vlan anti-spoofing of the VF, drivers that do not support it are left untouched
+	if conf.AllowGuestVlan != nil {
+		antiSpoof, err := s.utils.GetVFVlanAntiSpoof(conf.Master, conf.VFID)
+		if err != nil && !errors.Is(err, utils.ErrNotSupported) {
+			return fmt.Errorf("failed to get vlan anti-spoofing of vf %d: %w", conf.VFID, err)
+		}
+		conf.OrigVfState.VlanAntiSpoof = antiSpoof
+	}
+
+	// Save the VF driver so it can be restored after binding the VF to the override driver. The kernel driver is
+	// recorded as well, a VF left bound to a userspace driver on a previous cmdDel gets it from the record.
+	if conf.DriverOverride != "" {
+		driver, err := s.utils.GetVFDriver(conf.DeviceID)
+		if err != nil {
+			return fmt.Errorf("failed to get driver of vf %s: %w", conf.DeviceID, err)
+		}
+		conf.OrigVfState.Driver = driver
+
+		if driver != "" && !utils.IsUserspaceDriver(driver) {
+			conf.OrigVfState.KernelDriver = driver
+			if err := s.utils.SaveVFKernelDriver(config.CacheDir(conf), conf.DeviceID, driver); err != nil {
+				return err
+			}
+		} else {
+			kernelDriver, err := s.utils.GetVFKernelDriver(config.CacheDir(conf), conf.DeviceID)
+			if err != nil {
+				return err
+			}
+			conf.OrigVfState.KernelDriver = kernelDriver
+		}
+	}
+
 	return err
 }
 
-// ResetVFConfig reset a VF to its original state
+// BindVFDriver binds the VF to the driver requested in DriverOverride
+func (s *sriovManager) BindVFDriver(conf *sriovtypes.NetConf) error {
+	if conf.OrigVfState.Driver == conf.DriverOverride {
+		logging.Debug("VF already bound to the override driver",
+			"func", "BindVFDriver",
+			"conf.DeviceID", conf.DeviceID,
+			"conf.DriverOverride", conf.DriverOverride)
+		return nil
+	}
+
+	logging.Debug("Bind VF to the override driver",
+		"func", "BindVFDriver",
+		"conf.DeviceID", conf.DeviceID,
+		"conf.OrigVfState.Driver", conf.OrigVfState.Driver,
+		"conf.DriverOverride", conf.DriverOverride)
+	if err := s.utils.BindDriver(conf.DeviceID, conf.DriverOverride); err != nil {
+		return fmt.Errorf("failed to bind vf %s to driver %s: %w", conf.DeviceID, conf.DriverOverride, err)
+	}
+
+	return nil
+}
+
+// RestoreVFDriver binds the VF back to its kernel driver, or to the driver it used before BindVFDriver if the
+// kernel driver is unknown. When RebindOnDel is off the VF is left bound to the override driver for the next pod.
+func (s *sriovManager) RestoreVFDriver(conf *sriovtypes.NetConf) error {
+	if !conf.RebindsOnDel() {
+		logging.Info("Leave VF bound to the override driver",
+			"func", "RestoreVFDriver",
+			"conf.DeviceID", conf.DeviceID,
+			"conf.DriverOverride", conf.DriverOverride,
+			"conf.OrigVfState.KernelDriver", conf.OrigVfState.KernelDriver)
+		return nil
+	}
+
+	driver := conf.OrigVfState.KernelDriver
+	if driver == "" {
+		driver = conf.OrigVfState.Driver
+	}
+	if driver == "" || driver == conf.DriverOverride {
+		return nil
+	}
+
+	logging.Debug("Restore VF original driver",
+		"func", "RestoreVFDriver",
+		"conf.DeviceID", conf.DeviceID,
+		"driver", driver)
+	if err := s.utils.RestoreDriver(conf.DeviceID, driver); err != nil {
+		return fmt.Errorf("failed to restore vf %s driver %s: %w", conf.DeviceID, driver, err)
+	}
+
+	return nil
+}
+
+// ResetVFConfig reset a VF to its original state, a passthrough VF was not configured and is left as it is
 func (s *sriovManager) ResetVFConfig(conf *sriovtypes.NetConf) error {
+	if conf.IsPassthrough() {
+		logging.Debug("Skip resetting a passthrough VF",
+			"func", "ResetVFConfig",
+			"conf.DeviceID", conf.DeviceID)
+		return nil
+	}
+
+	if !conf.ResetsL2() {
+		logging.Debug("Skip resetting VF L2 configuration",
+			"func", "ResetVFConfig",
+			"conf.ResetScope", conf.ResetScope)
+		return nil
+	}
+
 	pfLink, err := s.nLink.LinkByName(conf.Master)
 	if err != nil {
-		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
+		return fmt.Errorf("failed to lookup master %q: %w", conf.Master, err)
 	}
 
 	// Set 802.1q as default in case cache config does not have a value for vlan proto.
@@ -366,49 +2793,399 @@ func (s *sriovManager) ResetVFConfig(conf *sriovtypes.NetConf) error {
 		conf.OrigVfState.VlanProto = sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]
 	}
 
-	if conf.Vlan != nil {
-		if err = s.nLink.LinkSetVfVlanQosProto(pfLink, conf.VFID, conf.OrigVfState.Vlan, conf.OrigVfState.VlanQoS, conf.OrigVfState.VlanProto); err != nil {
-			return fmt.Errorf("failed to set vf %d vlan configuration - id %d, qos %d and proto %d: %v", conf.VFID, conf.OrigVfState.Vlan, conf.OrigVfState.VlanQoS, conf.OrigVfState.VlanProto, err)
+	// QoS and 802.1ad are only valid with a non-zero vlan id, some drivers report stale values
+	// for an untagged VF so make sure they are cleared together with the vlan id.
+	if conf.OrigVfState.Vlan == 0 {
+		conf.OrigVfState.VlanQoS = 0
+		conf.OrigVfState.VlanProto = sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]
+	}
+
+	// Restore the vlan configuration
+	resetVlan := func() error {
+		if conf.Vlan == nil {
+			return nil
+		}
+		if err := s.nLink.LinkSetVfVlanQosProto(pfLink, conf.VFID, conf.OrigVfState.Vlan, conf.OrigVfState.VlanQoS, conf.OrigVfState.VlanProto); err != nil {
+			return fmt.Errorf("failed to set vf %d vlan configuration - id %d, qos %d and proto %d: %w", conf.VFID, conf.OrigVfState.Vlan, conf.OrigVfState.VlanQoS, conf.OrigVfState.VlanProto, err)
 		}
+		return nil
 	}
 
 	// Restore spoofchk
-	if conf.SpoofChk != "" {
-		if err = s.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, conf.OrigVfState.SpoofChk); err != nil {
-			return fmt.Errorf("failed to restore spoofchk for vf %d: %v", conf.VFID, err)
+	resetSpoofChk := func() error {
+		if conf.SpoofChk == "" {
+			return nil
 		}
+		if err := s.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, conf.OrigVfState.SpoofChk); err != nil {
+			return fmt.Errorf("failed to restore spoofchk for vf %d: %w", conf.VFID, err)
+		}
+		return nil
 	}
 
 	// Restore the original administrative MAC address
-	if conf.MAC != "" {
+	resetMAC := func() error {
+		if conf.MAC == "" {
+			return nil
+		}
 		// when we restore the original hardware mac address we may get a device or resource busy. so we introduce retry
 		if err := utils.SetVFHardwareMAC(s.nLink, conf.Master, conf.VFID, conf.OrigVfState.AdminMAC); err != nil {
-			return fmt.Errorf("failed to restore original administrative MAC address %s: %v", conf.OrigVfState.AdminMAC, err)
+			return fmt.Errorf("failed to restore original administrative MAC address %s: %w", conf.OrigVfState.AdminMAC, err)
+		}
+		return nil
+	}
+
+	// Restore the original node and port GUID
+	resetGUID := func() error {
+		if conf.GUID == "" || conf.OrigVfState.NodeGUID == "" || conf.OrigVfState.PortGUID == "" {
+			return nil
+		}
+		if err := s.setVFGUID(pfLink, conf.VFID, conf.OrigVfState.NodeGUID, conf.OrigVfState.PortGUID); err != nil {
+			return fmt.Errorf("failed to restore original node GUID %s and port GUID %s: %w",
+				conf.OrigVfState.NodeGUID, conf.OrigVfState.PortGUID, err)
+		}
+		return nil
+	}
+
+	// Restore the MAC change limit
+	resetMaxMacChanges := func() error {
+		if conf.MaxMacChanges == nil {
+			return nil
+		}
+		err := s.utils.SetVFMaxMacChanges(conf.Master, conf.VFID, conf.OrigVfState.MaxMacChanges)
+		if err != nil && !errors.Is(err, utils.ErrNotSupported) {
+			return fmt.Errorf("failed to restore MAC change limit for vf %d: %w", conf.VFID, err)
 		}
+		return nil
+	}
+
+	// Restore the vlan anti-spoofing
+	resetVlanAntiSpoof := func() error {
+		if conf.AllowGuestVlan == nil {
+			return nil
+		}
+		err := s.utils.SetVFVlanAntiSpoof(conf.Master, conf.VFID, conf.OrigVfState.VlanAntiSpoof)
+		if err != nil && !errors.Is(err, utils.ErrNotSupported) {
+			return fmt.Errorf("failed to restore vlan anti-spoofing for vf %d: %w", conf.VFID, err)
+		}
+		return nil
 	}
 
 	// Restore VF trust
-	if conf.Trust != "" {
-		if err = s.nLink.LinkSetVfTrust(pfLink, conf.VFID, conf.OrigVfState.Trust); err != nil {
-			return fmt.Errorf("failed to set trust for vf %d: %v", conf.VFID, err)
+	resetTrust := func() error {
+		if conf.Trust == "" {
+			return nil
+		}
+		if err := s.nLink.LinkSetVfTrust(pfLink, conf.VFID, conf.OrigVfState.Trust); err != nil {
+			return fmt.Errorf("failed to set trust for vf %d: %w", conf.VFID, err)
 		}
+		return nil
 	}
 
 	// Restore rate limiting
-	if conf.MinTxRate != nil || conf.MaxTxRate != nil {
-		if err = s.nLink.LinkSetVfRate(pfLink, conf.VFID, conf.OrigVfState.MinTxRate, conf.OrigVfState.MaxTxRate); err != nil {
-			return fmt.Errorf("failed to disable rate limiting for vf %d %v", conf.VFID, err)
+	resetRate := func() error {
+		if conf.MinTxRate == nil && conf.MaxTxRate == nil {
+			return nil
 		}
+		if err := s.nLink.LinkSetVfRate(pfLink, conf.VFID, conf.OrigVfState.MinTxRate, conf.OrigVfState.MaxTxRate); err != nil {
+			return fmt.Errorf("failed to disable rate limiting for vf %d %w", conf.VFID, err)
+		}
+		return nil
 	}
 
-	// Restore link state to `auto`
-	if conf.LinkState != "" {
+	// Restore the original link state, a VF left disabled would be inherited by the next pod
+	resetLinkState := func() error {
 		// Reset only when link_state was explicitly specified, to  accommodate for drivers / NICs
 		// that don't support the netlink command (e.g. igb driver)
-		if err = s.nLink.LinkSetVfState(pfLink, conf.VFID, conf.OrigVfState.LinkState); err != nil {
-			return fmt.Errorf("failed to set link state to auto for vf %d: %v", conf.VFID, err)
+		if conf.LinkState == "" {
+			return nil
+		}
+		logging.Debug("Restore VF link state",
+			"func", "ResetVFConfig",
+			"conf.VFID", conf.VFID,
+			"conf.LinkState", conf.LinkState,
+			"conf.OrigVfState.LinkState", linkStateToString(conf.OrigVfState.LinkState))
+		if err := s.nLink.LinkSetVfState(pfLink, conf.VFID, conf.OrigVfState.LinkState); err != nil {
+			return fmt.Errorf("failed to restore link state %s for vf %d: %w",
+				linkStateToString(conf.OrigVfState.LinkState), conf.VFID, err)
+		}
+		return nil
+	}
+
+	// The trust flag is restored once the MAC address is, as some drivers refuse to change the MAC address
+	// of an untrusted VF. The resets of a stage are independent of each other.
+	stages := [][]func() error{
+		{resetVlan, resetSpoofChk, resetMAC, resetGUID, resetMaxMacChanges, resetVlanAntiSpoof},
+		{resetTrust},
+		{resetRate, resetLinkState},
+	}
+	for _, stage := range stages {
+		if err = runResets(stage, conf.ParallelReset); err != nil {
+			return err
+		}
+	}
+
+	// Remove the FDB entry of the VF MAC
+	if conf.AddedFdbMAC != "" && conf.FdbVNI != nil {
+		mac, err := net.ParseMAC(conf.AddedFdbMAC)
+		if err != nil {
+			return fmt.Errorf("failed to parse FDB entry MAC address %s: %w", conf.AddedFdbMAC, err)
+		}
+		logging.Debug("Remove the FDB entry of the VF MAC",
+			"func", "ResetVFConfig",
+			"conf.AddedFdbMAC", conf.AddedFdbMAC,
+			"conf.FdbVNI", *conf.FdbVNI)
+		if err = s.nLink.NeighDel(fdbEntry(pfLink, mac, *conf.FdbVNI)); err != nil {
+			return fmt.Errorf("failed to remove FDB entry %s vni %d from %s: %w", conf.AddedFdbMAC, *conf.FdbVNI, conf.Master, err)
+		}
+	}
+
+	// Remove the vlan from the VF representor
+	if conf.RepresentorVlan != nil {
+		repLink, err := s.getRepresentorLink(conf)
+		if err != nil {
+			return err
+		}
+		logging.Debug("Remove vlan from the VF representor",
+			"func", "ResetVFConfig",
+			"representor", repLink.Attrs().Name,
+			"conf.RepresentorVlan", *conf.RepresentorVlan)
+		if err = s.nLink.BridgeVlanDel(repLink, uint16(*conf.RepresentorVlan), true, true, false, true); err != nil {
+			return fmt.Errorf("failed to remove vlan %d from representor %s: %w", *conf.RepresentorVlan, repLink.Attrs().Name, err)
+		}
+	}
+
+	// Set the VF representor down
+	if conf.ManagesRepresentor() && conf.LinkState == "enable" {
+		if err = s.setRepresentorState(conf, false); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
+
+// runResets runs the resets in order and returns the first failure. With parallel set, the resets are run
+// concurrently and all their failures are returned.
+func runResets(resets []func() error, parallel bool) error {
+	if !parallel {
+		for _, reset := range resets {
+			if err := reset(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make([]error, len(resets))
+	var wg sync.WaitGroup
+	for i, reset := range resets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = reset()
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// ConfigureIPAMResult applies the addresses and routes of the IPAM result to the VF in the pod netns. A result
+// with only IPv4 or only IPv6 addresses is applied as is, each route goes through the gateway of its address
+// family. The routes of a VF with its own route table are installed in that table instead of the main one.
+func (s *sriovManager) ConfigureIPAMResult(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS, result *current.Result) error {
+	ifaceResult := result
+	if conf.RouteTable != nil {
+		withoutRoutes := *result
+		withoutRoutes.Routes = nil
+		ifaceResult = &withoutRoutes
+	}
+	err := netns.Do(func(_ ns.NetNS) error {
+		return ipam.ConfigureIface(podifName, ifaceResult)
+	})
+	if err != nil {
+		return err
+	}
+	if err = s.SetupRouteTable(conf, podifName, netns, result); err != nil {
+		return err
+	}
+	return s.addStaticRoutes(conf, podifName, netns, result)
+}
+
+// addStaticRoutes adds the static routes of the netconf to the VF in the pod netns, in its route table if any.
+// A route that already exists is left as is. The gateway of a route must be in the subnet of one of the VF
+// addresses, or be a link-local address.
+func (s *sriovManager) addStaticRoutes(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS, result *current.Result) error {
+	if len(conf.Routes) == 0 {
+		return nil
+	}
+	table := 0
+	if conf.RouteTable != nil {
+		table = *conf.RouteTable
+	}
+
+	return netns.Do(func(_ ns.NetNS) error {
+		linkObj, err := s.nLink.LinkByName(podifName)
+		if err != nil {
+			return fmt.Errorf("failed to get netlink device with name %s: %w", podifName, err)
+		}
+
+		for _, r := range conf.Routes {
+			_, dst, err := net.ParseCIDR(r.Dst)
+			if err != nil {
+				return fmt.Errorf("failed to parse route dst %s: %w", r.Dst, err)
+			}
+			route := &netlink.Route{LinkIndex: linkObj.Attrs().Index, Dst: dst, Table: table}
+			if r.GW != "" {
+				route.Gw = net.ParseIP(r.GW)
+				if !gatewayReachable(route.Gw, result.IPs) {
+					return newVFError(ErrInvalidVFConfig,
+						fmt.Errorf("gateway %s of route %s is not reachable through the subnets of %s", route.Gw, dst, podifName))
+				}
+			} else {
+				route.Scope = netlink.SCOPE_LINK
+			}
+
+			if err = s.nLink.RouteAdd(route); err != nil {
+				if errors.Is(err, unix.EEXIST) {
+					logging.Debug("Static route already exists",
+						"func", "addStaticRoutes",
+						"podifName", podifName,
+						"dst", dst)
+					continue
+				}
+				return fmt.Errorf("failed to add route %s via %s dev %s: %w", dst, route.Gw, podifName, err)
+			}
+		}
+		return nil
+	})
+}
+
+// gatewayReachable returns true if gw is a link-local address or is in the subnet of one of the addresses
+func gatewayReachable(gw net.IP, ips []*current.IPConfig) bool {
+	if gw.IsLinkLocalUnicast() {
+		return true
+	}
+	for _, ipc := range ips {
+		if ipc.Address.Contains(gw) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetupRouteTable installs the routes of the IPAM result and a subnet route per address of the VF in its routing
+// table in the pod netns, and adds a rule looking the table up for the traffic sourced from each address, so that
+// a multi-homed pod replies through the interface it was reached on
+func (s *sriovManager) SetupRouteTable(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS, result *current.Result) error {
+	if conf.RouteTable == nil {
+		return nil
+	}
+	table := *conf.RouteTable
+
+	return netns.Do(func(_ ns.NetNS) error {
+		linkObj, err := s.nLink.LinkByName(podifName)
+		if err != nil {
+			return fmt.Errorf("failed to get netlink device with name %s: %w", podifName, err)
+		}
+		linkIndex := linkObj.Attrs().Index
+
+		var v4gw, v6gw net.IP
+		for _, ipc := range result.IPs {
+			subnet := &net.IPNet{IP: ipc.Address.IP.Mask(ipc.Address.Mask), Mask: ipc.Address.Mask}
+			route := &netlink.Route{LinkIndex: linkIndex, Dst: subnet, Src: ipc.Address.IP, Scope: netlink.SCOPE_LINK, Table: table}
+			if err = s.nLink.RouteAdd(route); err != nil {
+				return fmt.Errorf("failed to add route %s dev %s table %d: %w", subnet, podifName, table, err)
+			}
+			if ipc.Gateway == nil {
+				continue
+			}
+			if ipc.Gateway.To4() != nil && v4gw == nil {
+				v4gw = ipc.Gateway
+			} else if ipc.Gateway.To4() == nil && v6gw == nil {
+				v6gw = ipc.Gateway
+			}
+		}
+
+		// like ipam.ConfigureIface, a route without gateway goes through the gateway of its address family
+		for _, r := range result.Routes {
+			gw := r.GW
+			if gw == nil {
+				if r.Dst.IP.To4() != nil {
+					gw = v4gw
+				} else {
+					gw = v6gw
+				}
+			}
+			dst := r.Dst
+			route := &netlink.Route{LinkIndex: linkIndex, Dst: &dst, Gw: gw, Table: table}
+			if err = s.nLink.RouteAdd(route); err != nil {
+				return fmt.Errorf("failed to add route %s via %s dev %s table %d: %w", &dst, gw, podifName, table, err)
+			}
+		}
+
+		for _, ipc := range result.IPs {
+			if err = s.nLink.RuleAdd(sourceRule(ipc.Address.IP, table)); err != nil {
+				return fmt.Errorf("failed to add rule from %s lookup table %d: %w", ipc.Address.IP, table, err)
+			}
+		}
+
+		logging.Debug("Installed the VF routes and source rules in its route table",
+			"func", "SetupRouteTable",
+			"podifName", podifName,
+			"table", table)
+		return nil
+	})
+}
+
+// sourceRule returns the rule looking the table up for the traffic sourced from ip
+func sourceRule(ip net.IP, table int) *netlink.Rule {
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	rule := netlink.NewRule()
+	rule.Src = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	rule.Table = table
+	return rule
+}
+
+// SignalVFDown sets the link of the VF down in the pod netns at the start of cmdDel, so that the peers
+// of a bond or failover setup detect the loss before the VF is reset. A failure is only logged.
+func (s *sriovManager) SignalVFDown(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS) {
+	if !conf.SignalDownOnDel || conf.DPDKMode {
+		return
+	}
+
+	err := netns.Do(func(_ ns.NetNS) error {
+		linkObj, err := s.nLink.LinkByName(podifName)
+		if err != nil {
+			return fmt.Errorf("failed to get netlink device with name %s: %w", podifName, err)
+		}
+		return s.nLink.LinkSetDown(linkObj)
+	})
+	if err != nil {
+		logging.Warning("Failed to set the VF link down at the start of cmdDel",
+			"func", "SignalVFDown",
+			"podifName", podifName,
+			"err", err)
+		return
+	}
+	logging.Debug("Set the VF link down at the start of cmdDel",
+		"func", "SignalVFDown",
+		"podifName", podifName)
+}
+
+// DrainVF keeps the VF configured and its link up for the configured drain delay before cmdDel resets it,
+// so that long-lived connections can be shut down gracefully.
+func (s *sriovManager) DrainVF(conf *sriovtypes.NetConf) {
+	if conf.DrainDelay <= 0 {
+		return
+	}
+
+	delay := time.Duration(conf.DrainDelay) * time.Millisecond
+	logging.Info("Draining VF before reset",
+		"func", "DrainVF",
+		"vfID", conf.VFID,
+		"delay", delay)
+	time.Sleep(delay)
+}
@@ -1,12 +1,21 @@
 package sriov
 
 import (
+	"errors"
+	"fmt"
 	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
 
+	cnitypes "github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/containernetworking/plugins/pkg/testutils"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/config"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/sriov/mocks"
 	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 	mocks_utils "github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils/mocks"
@@ -14,6 +23,8 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/mock"
 	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
 )
 
 var _ = Describe("Sriov", func() {
@@ -281,16 +292,19 @@ var _ = Describe("Sriov", func() {
 			netconf.SpoofChk = "on"
 			netconf.Trust = "on"
 			netconf.LinkState = "enable"
+			fakeLink.Vfs = []netlink.VfInfo{{ID: 0, LinkState: netlink.VF_LINK_STATE_ENABLE}}
 
 			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
 			mocked.On("LinkSetVfVlanQosProto", fakeLink, netconf.VFID, *netconf.Vlan, *netconf.VlanQoS, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]).Return(nil)
 			mocked.On("LinkSetVfHardwareAddr", fakeLink, netconf.VFID, hwaddr).Return(nil)
 			mocked.On("LinkSetVfRate", fakeLink, netconf.VFID, *netconf.MinTxRate, *netconf.MaxTxRate).Return(nil)
 			mocked.On("LinkSetVfSpoofchk", fakeLink, netconf.VFID, true).Return(nil)
-			mocked.On("LinkSetVfTrust", fakeLink, netconf.VFID, true).Return(nil)
+			mocked.On("LinkSetVfTrust", fakeLink, netconf.VFID, true).Return(nil).Run(setFakeVfTrust)
 			mocked.On("LinkSetVfState", fakeLink, netconf.VFID, netlink.VF_LINK_STATE_ENABLE).Return(nil)
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetPFLinkSpeed", netconf.Master).Return(25000, nil)
 
-			sm := sriovManager{nLink: mocked}
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
 			err = sm.ApplyVFConfig(netconf)
 			Expect(err).NotTo(HaveOccurred())
 		})
@@ -444,9 +458,12 @@ var _ = Describe("Sriov", func() {
 				},
 			}}
 			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
-			sm := sriovManager{nLink: mocked}
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetSriovNumVfs", netconf.Master).Return(2, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
 			err = sm.FillOriginalVfInfo(netconf)
 			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.PFNumVFs).To(Equal(2))
 			mocked.AssertExpectations(t)
 		})
 	})
@@ -564,4 +581,4396 @@ var _ = Describe("Sriov", func() {
 			mocked.AssertExpectations(t)
 		})
 	})
-})
+	Context("Checking SetupVF function - tx queue rate limits", func() {
+		var (
+			podifName string
+			netconf   *sriovtypes.NetConf
+		)
+
+		BeforeEach(func() {
+			podifName = "net1"
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				QueueRates: []sriovtypes.QueueRate{
+					{Queue: 0, MaxRate: 1000},
+					{Queue: 1, MaxRate: 2000},
+				},
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+		})
+
+		It("Sets the max rate of each configured queue", func() {
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("GetTxQueueCount", "temp_1000").Return(2, nil)
+			mockedPciUtils.On("SetTxQueueMaxRate", "temp_1000", 0, 1000).Return(nil)
+			mockedPciUtils.On("SetTxQueueMaxRate", "temp_1000", 1, 2000).Return(utils.ErrNotSupported)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, podifName, targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Fails when a queue index exceeds the number of tx queues", func() {
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mockedPciUtils.On("GetTxQueueCount", "temp_1000").Return(1, nil)
+			mockedPciUtils.On("SetTxQueueMaxRate", "temp_1000", 0, 1000).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, podifName, targetNetNS)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid tx queue 1"))
+		})
+	})
+	Context("Checking ReleaseVF function - tx queue rate limits", func() {
+		It("Disables rate limiting on the configured queues", func() {
+			netconf := &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:     "enp175s0f1",
+				DeviceID:   "0000:af:06.0",
+				VFID:       0,
+				QueueRates: []sriovtypes.QueueRate{{Queue: 1, MaxRate: 2000}},
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", "net1").Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mockedPciUtils.On("SetTxQueueMaxRate", "enp175s6", 1, 0).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.ReleaseVF(netconf, "net1", targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mockedPciUtils.AssertExpectations(t)
+		})
+	})
+	Context("Checking ApplyVFConfig function - uplink vlan check", func() {
+		var (
+			netconf  *sriovtypes.NetConf
+			mocked   *mocks_utils.NetlinkManager
+			fakeLink *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			vlan := 100
+			qos := 0
+			vlanProto := sriovtypes.Proto8021q
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:          "enp175s0f1",
+				VFID:            0,
+				Vlan:            &vlan,
+				VlanQoS:         &qos,
+				VlanProto:       &vlanProto,
+				CheckUplinkVlan: true,
+			}}
+			mocked = &mocks_utils.NetlinkManager{}
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1"}}
+			mocked.On("LinkByName", "enp175s0f1").Return(fakeLink, nil)
+			mocked.On("LinkSetVfVlanQosProto", fakeLink, 0, 100, 0, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]).Return(nil)
+		})
+
+		It("reads the PF uplink vlan set when the vlan is carried", func() {
+			mocked.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{
+				1000: {{Vid: 1}, {Vid: 100}},
+			}, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+
+		It("does not fail when the vlan is missing from the PF uplink", func() {
+			mocked.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{
+				1000: {{Vid: 1}, {Vid: 200}},
+			}, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+
+		It("does not read the PF uplink vlan set when the check is disabled", func() {
+			netconf.CheckUplinkVlan = false
+			sm := sriovManager{nLink: mocked}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertNotCalled(t, "BridgeVlanList")
+		})
+	})
+	Context("Checking driver override functions", func() {
+		var (
+			netconf        *sriovtypes.NetConf
+			mockedPciUtils *mocks.PciUtils
+		)
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:         "enp175s0f1",
+				DeviceID:       "0000:af:06.0",
+				VFID:           0,
+				DriverOverride: "vfio-pci",
+			}}
+			mockedPciUtils = &mocks.PciUtils{}
+		})
+
+		It("FillOriginalVfInfo saves the original driver", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{
+				Index: 1000,
+				Name:  "dummylink",
+				Vfs:   []netlink.VfInfo{{ID: 0}},
+			}}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mockedPciUtils.On("GetSriovNumVfs", netconf.Master).Return(2, nil)
+			mockedPciUtils.On("GetVFDriver", "0000:af:06.0").Return("iavf", nil)
+			// the kernel driver is recorded in the cache directory, which may differ from the default one
+			netconf.CacheDir = "/run/sriov"
+			mockedPciUtils.On("SaveVFKernelDriver", "/run/sriov", "0000:af:06.0", "iavf").Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.FillOriginalVfInfo(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.Driver).To(Equal("iavf"))
+			Expect(netconf.OrigVfState.KernelDriver).To(Equal("iavf"))
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("FillOriginalVfInfo gets the recorded kernel driver of a VF left bound to the override driver", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{
+				Index: 1000,
+				Name:  "dummylink",
+				Vfs:   []netlink.VfInfo{{ID: 0}},
+			}}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mockedPciUtils.On("GetSriovNumVfs", netconf.Master).Return(2, nil)
+			mockedPciUtils.On("GetVFDriver", "0000:af:06.0").Return("vfio-pci", nil)
+			mockedPciUtils.On("GetVFKernelDriver", config.DefaultCNIDir, "0000:af:06.0").Return("iavf", nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.FillOriginalVfInfo(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.Driver).To(Equal("vfio-pci"))
+			Expect(netconf.OrigVfState.KernelDriver).To(Equal("iavf"))
+			mockedPciUtils.AssertNotCalled(t, "SaveVFKernelDriver", mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("BindVFDriver binds the VF to the override driver", func() {
+			netconf.OrigVfState.Driver = "iavf"
+			mockedPciUtils.On("BindDriver", "0000:af:06.0", "vfio-pci").Return(nil)
+			sm := sriovManager{utils: mockedPciUtils}
+			Expect(sm.BindVFDriver(netconf)).To(Succeed())
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("BindVFDriver does nothing when the VF is already bound to the override driver", func() {
+			netconf.OrigVfState.Driver = "vfio-pci"
+			sm := sriovManager{utils: mockedPciUtils}
+			Expect(sm.BindVFDriver(netconf)).To(Succeed())
+			mockedPciUtils.AssertNotCalled(t, "BindDriver", mock.Anything, mock.Anything)
+		})
+
+		It("RestoreVFDriver binds the VF back to the original driver", func() {
+			netconf.OrigVfState.Driver = "iavf"
+			mockedPciUtils.On("RestoreDriver", "0000:af:06.0", "iavf").Return(nil)
+			sm := sriovManager{utils: mockedPciUtils}
+			Expect(sm.RestoreVFDriver(netconf)).To(Succeed())
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("RestoreVFDriver rebinds a VF left bound to the override driver to its kernel driver", func() {
+			netconf.OrigVfState.Driver = "vfio-pci"
+			netconf.OrigVfState.KernelDriver = "iavf"
+			mockedPciUtils.On("RestoreDriver", "0000:af:06.0", "iavf").Return(nil)
+			sm := sriovManager{utils: mockedPciUtils}
+			Expect(sm.RestoreVFDriver(netconf)).To(Succeed())
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("RestoreVFDriver leaves the VF bound to the override driver when rebindOnDel is off", func() {
+			rebindOnDel := false
+			netconf.RebindOnDel = &rebindOnDel
+			netconf.OrigVfState.Driver = "iavf"
+			netconf.OrigVfState.KernelDriver = "iavf"
+			sm := sriovManager{utils: mockedPciUtils}
+			Expect(sm.RestoreVFDriver(netconf)).To(Succeed())
+			mockedPciUtils.AssertNotCalled(t, "RestoreDriver", mock.Anything, mock.Anything)
+		})
+	})
+	Context("Checking ResetVFConfig function - restore vlan QoS and proto", func() {
+		It("Fully resets a VF configured with QinQ and priority 5", func() {
+			vlan := 100
+			vlanQos := 5
+			vlanProto := sriovtypes.Proto8021ad
+			netconf := &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:    "enp175s0f1",
+				DeviceID:  "0000:af:06.0",
+				VFID:      0,
+				Vlan:      &vlan,
+				VlanQoS:   &vlanQos,
+				VlanProto: &vlanProto,
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+					Vlan:       0,
+					VlanQoS:    5,
+					VlanProto:  sriovtypes.VlanProtoInt[sriovtypes.Proto8021ad],
+				}},
+			}
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfVlanQosProto", fakeLink, netconf.VFID, 0, 0, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]).Return(nil).Once()
+			sm := sriovManager{nLink: mocked}
+			err := sm.ResetVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking SetupVF function - RSS hash key", func() {
+		var (
+			podifName string
+			netconf   *sriovtypes.NetConf
+		)
+
+		BeforeEach(func() {
+			podifName = "net1"
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:     "enp175s0f1",
+				DeviceID:   "0000:af:06.0",
+				VFID:       0,
+				RSSHashKey: "6d:5a:56:da:25:5b:0e:c2",
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+		})
+
+		It("Sets the RSS hash key on the pod interface", func() {
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("GetRSSHashKeySize", podifName).Return(8, nil)
+			mockedPciUtils.On("SetRSSHashKey", podifName, []byte{0x6d, 0x5a, 0x56, 0xda, 0x25, 0x5b, 0x0e, 0xc2}).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, podifName, targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Fails when the key length does not match the driver key size", func() {
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("GetRSSHashKeySize", podifName).Return(40, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, podifName, targetNetNS)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("driver expects 40 bytes"))
+			mockedPciUtils.AssertNotCalled(t, "SetRSSHashKey", mock.Anything, mock.Anything)
+		})
+	})
+	Context("Checking reset scope", func() {
+		var (
+			podifName string
+			netconf   *sriovtypes.NetConf
+		)
+
+		BeforeEach(func() {
+			podifName = "net1"
+			vlan := 6
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				MAC:      "d2:fc:22:a7:0d:e8",
+				Vlan:     &vlan,
+				QueueRates: []sriovtypes.QueueRate{
+					{Queue: 0, MaxRate: 1000},
+				},
+				OrigVfState: sriovtypes.VfState{
+					HostIFName:   "enp175s6",
+					AdminMAC:     "aa:f3:8d:65:1b:d4",
+					EffectiveMAC: "aa:f3:8d:65:1b:d4",
+					Vlan:         1,
+				}},
+			}
+		})
+
+		It("ResetVFConfig leaves the VF L2 attributes with l3only scope", func() {
+			netconf.ResetScope = sriovtypes.ResetScopeL3Only
+			mocked := &mocks_utils.NetlinkManager{}
+			sm := sriovManager{nLink: mocked}
+			err := sm.ResetVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertNotCalled(t, "LinkByName", mock.Anything)
+			mocked.AssertNotCalled(t, "LinkSetVfVlanQosProto", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("ResetVFConfig restores the VF L2 attributes with l2only scope", func() {
+			netconf.ResetScope = sriovtypes.ResetScopeL2Only
+			origMac, err := net.ParseMAC(netconf.OrigVfState.AdminMAC)
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", Vfs: []netlink.VfInfo{
+				{Mac: origMac},
+			}}}
+
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfVlanQosProto", fakeLink, netconf.VFID, 1, 0, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]).Return(nil)
+			mocked.On("LinkSetVfHardwareAddr", fakeLink, netconf.VFID, origMac).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			err = sm.ResetVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+
+		It("ReleaseVF moves the VF back without restoring L2 attributes with l3only scope", func() {
+			netconf.ResetScope = sriovtypes.ResetScopeL3Only
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", podifName).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.ReleaseVF(netconf, podifName, targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mocked.AssertNotCalled(t, "LinkSetHardwareAddr", mock.Anything, mock.Anything)
+			mockedPciUtils.AssertNotCalled(t, "SetTxQueueMaxRate", mock.Anything, mock.Anything, mock.Anything)
+		})
+	})
+	Context("Checking InfiniBand GUID configuration", func() {
+		var (
+			netconf *sriovtypes.NetConf
+			pfLink  *utils.FakeLink
+			guid    net.HardwareAddr
+		)
+
+		BeforeEach(func() {
+			var err error
+			guid, err = net.ParseMAC("00:11:22:33:44:55:66:77")
+			Expect(err).NotTo(HaveOccurred())
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "ibp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				GUID:     guid.String(),
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "ibp175s6",
+				}},
+			}
+			pfLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{
+				Index:     1000,
+				Name:      "ibp175s0f1",
+				EncapType: "infiniband",
+				Vfs:       []netlink.VfInfo{{ID: 0}},
+			}}
+		})
+
+		It("FillOriginalVfInfo saves the original node and port GUID of the VF", func() {
+			nodeGUID, err := net.ParseMAC("aa:bb:cc:dd:ee:ff:00:11")
+			Expect(err).NotTo(HaveOccurred())
+			portGUID, err := net.ParseMAC("aa:bb:cc:dd:ee:ff:00:22")
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkGetVfNodeGUID", pfLink, netconf.VFID).Return(nodeGUID, nil)
+			mocked.On("LinkGetVfPortGUID", pfLink, netconf.VFID).Return(portGUID, nil)
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetSriovNumVfs", netconf.Master).Return(2, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.FillOriginalVfInfo(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.NodeGUID).To(Equal("aa:bb:cc:dd:ee:ff:00:11"))
+			Expect(netconf.OrigVfState.PortGUID).To(Equal("aa:bb:cc:dd:ee:ff:00:22"))
+			mocked.AssertExpectations(t)
+		})
+
+		It("FillOriginalVfInfo fails when the GUID of the VF is not reported", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkGetVfNodeGUID", pfLink, netconf.VFID).Return(nil, errors.New("no GUID reported"))
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetSriovNumVfs", netconf.Master).Return(2, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.FillOriginalVfInfo(netconf)
+			Expect(err).To(MatchError(ContainSubstring("failed to get node GUID of vf 0")))
+		})
+
+		It("ApplyVFConfig sets the node and port GUID on an InfiniBand VF", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfNodeGUID", pfLink, netconf.VFID, guid).Return(nil)
+			mocked.On("LinkSetVfPortGUID", pfLink, netconf.VFID, guid).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+
+		It("ApplyVFConfig rejects a MAC address on an InfiniBand VF", func() {
+			netconf.GUID = ""
+			netconf.MAC = "aa:f3:8d:65:1b:d4"
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			mocked.AssertNotCalled(t, "LinkSetVfHardwareAddr", mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("ApplyVFConfig rejects a GUID on an Ethernet VF", func() {
+			pfLink.EncapType = "ether"
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			mocked.AssertNotCalled(t, "LinkSetVfNodeGUID", mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("ResetVFConfig restores the original node and port GUID", func() {
+			netconf.OrigVfState.NodeGUID = "aa:bb:cc:dd:ee:ff:00:11"
+			netconf.OrigVfState.PortGUID = "aa:bb:cc:dd:ee:ff:00:22"
+			origNodeGUID, err := net.ParseMAC(netconf.OrigVfState.NodeGUID)
+			Expect(err).NotTo(HaveOccurred())
+			origPortGUID, err := net.ParseMAC(netconf.OrigVfState.PortGUID)
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfNodeGUID", pfLink, netconf.VFID, origNodeGUID).Return(nil)
+			mocked.On("LinkSetVfPortGUID", pfLink, netconf.VFID, origPortGUID).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			err = sm.ResetVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking MAC change limit of trusted VFs", func() {
+		var (
+			netconf  *sriovtypes.NetConf
+			fakeLink *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			maxMacChanges := 3
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:        "enp175s0f1",
+				DeviceID:      "0000:af:06.0",
+				VFID:          0,
+				Trust:         "on",
+				MaxMacChanges: &maxMacChanges,
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+		})
+
+		It("ApplyVFConfig applies the limit after turning trust on", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfTrust", fakeLink, netconf.VFID, true).Return(nil).Run(setFakeVfTrust)
+			mockedPciUtils.On("SetVFMaxMacChanges", netconf.Master, netconf.VFID, 3).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("ApplyVFConfig does not fail when the driver does not support the limit", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfTrust", fakeLink, netconf.VFID, true).Return(nil).Run(setFakeVfTrust)
+			mockedPciUtils.On("SetVFMaxMacChanges", netconf.Master, netconf.VFID, 3).Return(utils.ErrNotSupported)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("ResetVFConfig restores the original limit", func() {
+			netconf.OrigVfState.MaxMacChanges = 0
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfTrust", fakeLink, netconf.VFID, false).Return(nil)
+			mockedPciUtils.On("SetVFMaxMacChanges", netconf.Master, netconf.VFID, 0).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ResetVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mockedPciUtils.AssertExpectations(t)
+		})
+	})
+	Context("Checking the MAC address ordering of ADD", func() {
+		var (
+			targetNetNS ns.NetNS
+			netconf     *sriovtypes.NetConf
+			mocked      *mocks_utils.NetlinkManager
+			sm          sriovManager
+			calls       []string
+		)
+
+		BeforeEach(func() {
+			var err error
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+
+			mac, err := net.ParseMAC("02:11:22:33:44:55")
+			Expect(err).NotTo(HaveOccurred())
+			origMac, err := net.ParseMAC("6e:16:06:0e:b7:e9")
+			Expect(err).NotTo(HaveOccurred())
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:      "enp175s0f1",
+				DeviceID:    "0000:af:06.0",
+				VFID:        0,
+				MAC:         mac.String(),
+				OrigVfState: sriovtypes.VfState{HostIFName: "enp175s6"},
+			}}
+
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 10, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: mac},
+			}}}
+			vfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s6", HardwareAddr: origMac}}
+			tempLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "temp_1000", HardwareAddr: mac}}
+			podLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "net1", HardwareAddr: mac}}
+
+			calls = nil
+			mocked = &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkByName", "enp175s6").Return(vfLink, nil)
+			mocked.On("LinkByName", "temp_1000").Return(tempLink, nil)
+			mocked.On("LinkByName", "net1").Return(podLink, nil)
+			mocked.On("LinkSetVfHardwareAddr", pfLink, netconf.VFID, mac).Return(nil).Run(func(mock.Arguments) {
+				calls = append(calls, "LinkSetVfHardwareAddr")
+			})
+			mocked.On("LinkSetHardwareAddr", mock.Anything, mac).Return(nil).Run(func(args mock.Arguments) {
+				calls = append(calls, "LinkSetHardwareAddr "+args.Get(0).(netlink.Link).Attrs().Name)
+			})
+			mocked.On("LinkSetNsFd", mock.Anything, mock.AnythingOfType("int")).Return(nil).Run(func(mock.Arguments) {
+				calls = append(calls, "LinkSetNsFd")
+			})
+			mocked.On("LinkSetDown", mock.Anything).Return(nil)
+			mocked.On("LinkSetName", mock.Anything, mock.Anything).Return(nil)
+			mocked.On("LinkSetUp", mock.Anything).Return(nil)
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+			sm = sriovManager{nLink: mocked, utils: mockedPciUtils}
+		})
+
+		AfterEach(func() {
+			Expect(targetNetNS.Close()).To(Succeed())
+		})
+
+		It("Sets the admin MAC on the PF before moving the VF to the pod netns, and the effective MAC after", func() {
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			Expect(sm.SetupVF(netconf, "net1", targetNetNS)).To(Succeed())
+			Expect(calls).To(Equal([]string{"LinkSetVfHardwareAddr", "LinkSetNsFd", "LinkSetHardwareAddr net1"}))
+		})
+
+		It("Sets the admin MAC on the PF after moving the VF with the legacy ordering", func() {
+			legacyMACOrder := true
+			netconf.LegacyMACOrder = &legacyMACOrder
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			Expect(sm.SetupVF(netconf, "net1", targetNetNS)).To(Succeed())
+			Expect(calls).To(Equal([]string{"LinkSetNsFd", "LinkSetVfHardwareAddr", "LinkSetHardwareAddr net1"}))
+		})
+	})
+	Context("Checking runtimeConfig MAC address", func() {
+		It("Sets the admin and effective MAC to the same value and restores the original effective MAC", func() {
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+
+			origMac, err := net.ParseMAC("6e:16:06:0e:b7:e9")
+			Expect(err).NotTo(HaveOccurred())
+			runtimeMac, err := net.ParseMAC("02:11:22:33:44:55")
+			Expect(err).NotTo(HaveOccurred())
+
+			netconf := &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				MAC:      runtimeMac.String(),
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+			netconf.RuntimeConfig.Mac = runtimeMac.String()
+
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 10, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: runtimeMac},
+			}}}
+			vfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s6", HardwareAddr: origMac}}
+			podLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "net1", HardwareAddr: runtimeMac}}
+			hostLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s6", HardwareAddr: origMac}}
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfHardwareAddr", pfLink, netconf.VFID, runtimeMac).Return(nil)
+			mocked.On("LinkByName", "enp175s6").Return(vfLink, nil).Once()
+			mocked.On("LinkByName", "temp_1000").Return(vfLink, nil)
+			mocked.On("LinkByName", "net1").Return(podLink, nil)
+			mocked.On("LinkSetDown", mock.Anything).Return(nil)
+			mocked.On("LinkSetName", mock.Anything, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", mock.Anything, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetHardwareAddr", podLink, runtimeMac).Return(nil)
+			mocked.On("LinkSetUp", vfLink).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+
+			err = sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			err = sm.SetupVF(netconf, "net1", targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.EffectiveMAC).To(Equal(origMac.String()))
+			mocked.AssertCalled(t, "LinkSetVfHardwareAddr", pfLink, netconf.VFID, runtimeMac)
+			mocked.AssertCalled(t, "LinkSetHardwareAddr", podLink, runtimeMac)
+
+			mocked.On("LinkByName", "enp175s6").Return(hostLink, nil)
+			mocked.On("LinkSetHardwareAddr", hostLink, origMac).Return(nil)
+			err = sm.ReleaseVF(netconf, "net1", targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertCalled(t, "LinkSetHardwareAddr", hostLink, origMac)
+		})
+	})
+	Context("Checking VF link state verification", func() {
+		var (
+			netconf  *sriovtypes.NetConf
+			fakeLink *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:    "enp175s0f1",
+				DeviceID:  "0000:af:06.0",
+				VFID:      0,
+				LinkState: "disable",
+			}}
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, LinkState: netlink.VF_LINK_STATE_AUTO},
+			}}}
+		})
+
+		It("ApplyVFConfig fails when the link state is not applied by the driver", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfState", fakeLink, netconf.VFID, uint32(netlink.VF_LINK_STATE_DISABLE)).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("link state is 0 after setting it to 2"))
+		})
+
+		It("CheckVFConfig succeeds when the link state did not drift", func() {
+			fakeLink.Vfs[0].LinkState = netlink.VF_LINK_STATE_DISABLE
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.CheckVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertNotCalled(t, "LinkSetVfState", mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("CheckVFConfig detects a drifted link state", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.CheckVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("link_state expected=disable actual=auto"))
+			mocked.AssertNotCalled(t, "LinkSetVfState", mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("CheckVFConfig re-applies a drifted link state when FixLinkStateOnCheck is set", func() {
+			netconf.FixLinkStateOnCheck = true
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfState", fakeLink, netconf.VFID, uint32(netlink.VF_LINK_STATE_DISABLE)).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.CheckVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking max tx rate ceiling of the PF", func() {
+		var (
+			netconf  *sriovtypes.NetConf
+			fakeLink *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			maxTxRate := 25000
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:    "enp175s0f1",
+				DeviceID:  "0000:af:06.0",
+				VFID:      0,
+				MaxTxRate: &maxTxRate,
+			}}
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1"}}
+		})
+
+		It("rejects a max tx rate above the ceiling", func() {
+			netconf.EnforceRateCeiling = sriovtypes.RateCeilingReject
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mockedPciUtils.On("GetVFMaxTxRateCeiling", netconf.Master, netconf.VFID).Return(10000, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("exceeds the PF ceiling of 10000 Mbps"))
+			mocked.AssertNotCalled(t, "LinkSetVfRate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("clamps a max tx rate above the ceiling", func() {
+			netconf.EnforceRateCeiling = sriovtypes.RateCeilingClamp
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfRate", fakeLink, netconf.VFID, 0, 10000).Return(nil)
+			mockedPciUtils.On("GetVFMaxTxRateCeiling", netconf.Master, netconf.VFID).Return(10000, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*netconf.MaxTxRate).To(Equal(10000))
+			mocked.AssertExpectations(t)
+		})
+
+		It("applies the requested rate when the PF has no ceiling", func() {
+			netconf.EnforceRateCeiling = sriovtypes.RateCeilingReject
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfRate", fakeLink, netconf.VFID, 0, 25000).Return(nil)
+			mockedPciUtils.On("GetVFMaxTxRateCeiling", netconf.Master, netconf.VFID).Return(0, utils.ErrNotSupported)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+
+		It("does not read the ceiling when enforcement is disabled", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfRate", fakeLink, netconf.VFID, 0, 25000).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mockedPciUtils.AssertNotCalled(t, "GetVFMaxTxRateCeiling", mock.Anything, mock.Anything)
+		})
+	})
+	Context("Checking DrainVF function", func() {
+		var (
+			netconf *sriovtypes.NetConf
+		)
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:     "enp175s0f1",
+				DeviceID:   "0000:af:06.0",
+				VFID:       0,
+				DrainDelay: 200,
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+		})
+		It("Waits for the drain delay before the VF is reset", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			var drained time.Time
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil).Run(func(args mock.Arguments) {
+				drained = time.Now()
+			})
+			sm := sriovManager{nLink: mocked}
+
+			start := time.Now()
+			sm.DrainVF(netconf)
+			Expect(time.Since(start)).To(BeNumerically(">=", 200*time.Millisecond))
+			mocked.AssertNotCalled(t, "LinkByName", netconf.Master)
+
+			err := sm.ResetVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drained.Sub(start)).To(BeNumerically(">=", 200*time.Millisecond))
+			mocked.AssertExpectations(t)
+		})
+		It("Does not wait when no drain delay is configured", func() {
+			netconf.DrainDelay = 0
+			sm := sriovManager{nLink: &mocks_utils.NetlinkManager{}}
+
+			start := time.Now()
+			sm.DrainVF(netconf)
+			Expect(time.Since(start)).To(BeNumerically("<", 200*time.Millisecond))
+		})
+	})
+	Context("Checking checkSwitchdevMode function", func() {
+		var (
+			netconf *sriovtypes.NetConf
+		)
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+			}}
+		})
+		It("Succeeds when the PF e-switch is in switchdev mode", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			devlinkDev := &netlink.DevlinkDevice{BusName: "pci", DeviceName: "0000:af:00.1"}
+			devlinkDev.Attrs.Eswitch.Mode = "switchdev"
+			mocked.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(devlinkDev, nil)
+
+			sm := sriovManager{nLink: mocked}
+			err := sm.checkSwitchdevMode(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("Fails when the PF e-switch is in legacy mode", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			devlinkDev := &netlink.DevlinkDevice{BusName: "pci", DeviceName: "0000:af:00.1"}
+			devlinkDev.Attrs.Eswitch.Mode = "legacy"
+			mocked.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(devlinkDev, nil)
+
+			sm := sriovManager{nLink: mocked}
+			err := sm.checkSwitchdevMode(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`e-switch mode is "legacy"`))
+			mocked.AssertExpectations(t)
+		})
+		It("Fails when the PF has no devlink device", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(nil, fmt.Errorf("no such device"))
+
+			sm := sriovManager{nLink: mocked}
+			err := sm.checkSwitchdevMode(netconf)
+			Expect(err).To(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking SetupVF function - secondary MAC addresses", func() {
+		var (
+			podifName string
+			netconf   *sriovtypes.NetConf
+		)
+
+		BeforeEach(func() {
+			podifName = "net1"
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				AltMACs:  []string{"02:00:00:00:00:01", "02:00:00:00:00:02"},
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+		})
+
+		setupMocks := func(appendErr error) (*mocks_utils.NetlinkManager, *mocks.PciUtils) {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mocked.On("NeighAppend", mock.MatchedBy(func(neigh *netlink.Neigh) bool {
+				return neigh.LinkIndex == 1000 && neigh.Flags == netlink.NTF_SELF
+			})).Return(appendErr)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+			return mocked, mockedPciUtils
+		}
+
+		It("Adds the secondary MAC addresses and records them", func() {
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked, mockedPciUtils := setupMocks(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, podifName, targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertNumberOfCalls(t, "NeighAppend", 2)
+			Expect(netconf.AddedAltMACs).To(Equal(netconf.AltMACs))
+		})
+
+		It("Skips the secondary MAC addresses when the driver does not support them", func() {
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked, mockedPciUtils := setupMocks(unix.EOPNOTSUPP)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, podifName, targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.AddedAltMACs).To(BeEmpty())
+		})
+	})
+	Context("Checking ReleaseVF function - secondary MAC addresses", func() {
+		It("Removes the secondary MAC addresses added on setup", func() {
+			netconf := &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:       "enp175s0f1",
+				DeviceID:     "0000:af:06.0",
+				VFID:         0,
+				AltMACs:      []string{"02:00:00:00:00:01", "02:00:00:00:00:02"},
+				AddedAltMACs: []string{"02:00:00:00:00:01"},
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", "net1").Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("NeighDel", mock.MatchedBy(func(neigh *netlink.Neigh) bool {
+				return neigh.LinkIndex == 1000 && neigh.HardwareAddr.String() == "02:00:00:00:00:01"
+			})).Return(nil).Once()
+			sm := sriovManager{nLink: mocked}
+			err = sm.ReleaseVF(netconf, "net1", targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking CheckVFConfig function - tx rates", func() {
+		var (
+			netconf  *sriovtypes.NetConf
+			fakeLink *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			minTxRate := 1000
+			maxTxRate := 4000
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:    "enp175s0f1",
+				DeviceID:  "0000:af:06.0",
+				VFID:      0,
+				MinTxRate: &minTxRate,
+				MaxTxRate: &maxTxRate,
+			}}
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, MinTxRate: 1000, MaxTxRate: 4000},
+			}}}
+		})
+
+		It("Succeeds when the tx rates did not drift", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.CheckVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Detects a drifted min tx rate", func() {
+			fakeLink.Vfs[0].MinTxRate = 0
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.CheckVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("min_tx_rate expected=1000 actual=0"))
+		})
+
+		It("Detects a drifted max tx rate", func() {
+			fakeLink.Vfs[0].MaxTxRate = 2000
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.CheckVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("max_tx_rate expected=4000 actual=2000"))
+		})
+
+		It("Does not compare the tx rates that are not configured", func() {
+			netconf.MinTxRate = nil
+			netconf.MaxTxRate = nil
+			fakeLink.Vfs[0].MinTxRate = 100
+			fakeLink.Vfs[0].MaxTxRate = 200
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.CheckVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+	Context("Checking CheckVFDriver function", func() {
+		var (
+			netconf    *sriovtypes.NetConf
+			driverLink string
+		)
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+			}}
+			driverLink = filepath.Join(utils.SysBusPci, netconf.DeviceID, "driver")
+		})
+
+		// bindFakeDriver binds the VF to the driver in the fake sysfs
+		bindFakeDriver := func(driver string) {
+			driverPath, err := filepath.EvalSymlinks(driverLink)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Remove(driverLink)).To(Succeed())
+			Expect(os.Symlink(filepath.Join(filepath.Dir(driverPath), driver), driverLink)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(os.Remove(driverLink)).To(Succeed())
+				Expect(os.Symlink(driverPath, driverLink)).To(Succeed())
+			})
+		}
+
+		It("Succeeds for a kernel driver VF bound to its kernel driver", func() {
+			sm := sriovManager{utils: &pciUtilsImpl{}}
+			Expect(sm.CheckVFDriver(netconf)).To(Succeed())
+		})
+
+		It("Detects a kernel driver VF bound to a userspace driver", func() {
+			bindFakeDriver("vfio-pci")
+			sm := sriovManager{utils: &pciUtilsImpl{}}
+			err := sm.CheckVFDriver(netconf)
+			Expect(err).To(MatchError(ContainSubstring("vf 0000:af:06.0 driver drifted: expected a kernel driver, found vfio-pci")))
+		})
+
+		It("Succeeds for a DPDK mode VF bound to the override driver", func() {
+			bindFakeDriver("vfio-pci")
+			netconf.DPDKMode = true
+			netconf.DriverOverride = "vfio-pci"
+			sm := sriovManager{utils: &pciUtilsImpl{}}
+			Expect(sm.CheckVFDriver(netconf)).To(Succeed())
+		})
+
+		It("Detects a DPDK mode VF bound to a kernel driver", func() {
+			netconf.DPDKMode = true
+			netconf.DriverOverride = "vfio-pci"
+			sm := sriovManager{utils: &pciUtilsImpl{}}
+			err := sm.CheckVFDriver(netconf)
+			Expect(err).To(MatchError(ContainSubstring("vf 0000:af:06.0 driver drifted: expected vfio-pci, found iavf")))
+		})
+
+		It("Detects an unbound VF", func() {
+			netconf.DeviceID = "0000:af:06.1"
+			netconf.VFID = 1
+			sm := sriovManager{utils: &pciUtilsImpl{}}
+			err := sm.CheckVFDriver(netconf)
+			Expect(err).To(MatchError(ContainSubstring("expected a kernel driver, found no driver")))
+		})
+	})
+	Context("Checking CheckVFConfig function - diff", func() {
+		var (
+			netconf  *sriovtypes.NetConf
+			fakeLink *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			vlan, vlanQoS, vlanProto := 100, 0, sriovtypes.Proto8021q
+			maxTxRate := 4000
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:    "enp175s0f1",
+				DeviceID:  "0000:af:06.0",
+				VFID:      0,
+				Vlan:      &vlan,
+				VlanQoS:   &vlanQoS,
+				VlanProto: &vlanProto,
+				MAC:       "e4:11:22:33:44:55",
+				MaxTxRate: &maxTxRate,
+				SpoofChk:  "off",
+				Trust:     "on",
+				LinkState: "enable",
+			}}
+			mac, err := net.ParseMAC(netconf.MAC)
+			Expect(err).NotTo(HaveOccurred())
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, Vlan: 100, VlanProto: sriovtypes.VlanProtoInt[sriovtypes.Proto8021q], Mac: mac, MaxTxRate: 4000,
+					Spoofchk: false, Trust: 1, LinkState: netlink.VF_LINK_STATE_ENABLE},
+			}}}
+		})
+
+		It("Succeeds when no attribute drifted", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.CheckVFConfig(netconf)).To(Succeed())
+		})
+
+		It("Lists every drifted attribute", func() {
+			otherMac, err := net.ParseMAC("e4:11:22:33:44:56")
+			Expect(err).NotTo(HaveOccurred())
+			fakeLink.Vfs[0].Vlan = 0
+			fakeLink.Vfs[0].Mac = otherMac
+			fakeLink.Vfs[0].Spoofchk = true
+			fakeLink.Vfs[0].Trust = 0
+			fakeLink.Vfs[0].LinkState = netlink.VF_LINK_STATE_AUTO
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			sm := sriovManager{nLink: mocked}
+
+			err = sm.CheckVFConfig(netconf)
+			var diffErr *VFConfigDiffError
+			Expect(errors.As(err, &diffErr)).To(BeTrue())
+			Expect(diffErr.Diffs).To(Equal([]VFAttrDiff{
+				{Attr: "vlan", Expected: "100", Actual: "0"},
+				{Attr: "mac", Expected: "e4:11:22:33:44:55", Actual: "e4:11:22:33:44:56"},
+				{Attr: "spoofchk", Expected: "off", Actual: "on"},
+				{Attr: "trust", Expected: "on", Actual: "off"},
+				{Attr: "link_state", Expected: "enable", Actual: "auto"},
+			}))
+			Expect(err.Error()).To(ContainSubstring("vlan expected=100 actual=0, mac expected=e4:11:22:33:44:55 actual=e4:11:22:33:44:56"))
+			Expect(diffErr.LogArgs()).To(ContainElements("vlan", "expected=100 actual=0"))
+		})
+
+		It("Does not compare the attributes that are not configured", func() {
+			netconf.Vlan = nil
+			netconf.VlanQoS = nil
+			netconf.VlanProto = nil
+			netconf.MAC = ""
+			netconf.MaxTxRate = nil
+			netconf.SpoofChk = ""
+			netconf.Trust = ""
+			netconf.LinkState = ""
+			fakeLink.Vfs[0] = netlink.VfInfo{ID: 0, Vlan: 200, MaxTxRate: 100, Spoofchk: true, LinkState: netlink.VF_LINK_STATE_DISABLE}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.CheckVFConfig(netconf)).To(Succeed())
+		})
+	})
+	Context("Checking SetupVF function - wait for link up", func() {
+		var (
+			podifName string
+			netconf   *sriovtypes.NetConf
+		)
+
+		BeforeEach(func() {
+			waitForLinkUp := true
+			linkUpTimeout := 1
+			podifName = "net1"
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:        "enp175s0f1",
+				DeviceID:      "0000:af:06.0",
+				VFID:          0,
+				WaitForLinkUp: &waitForLinkUp,
+				LinkUpTimeout: &linkUpTimeout,
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+		})
+
+		setupVF := func(fakeLink *utils.FakeLink) error {
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			return sm.SetupVF(netconf, podifName, targetNetNS)
+		}
+
+		It("Returns once the link is up", func() {
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink",
+				RawFlags: unix.IFF_UP | unix.IFF_RUNNING, OperState: netlink.OperUp}}
+			Expect(setupVF(fakeLink)).To(Succeed())
+		})
+
+		It("Fails when the link is not up within the timeout", func() {
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink",
+				RawFlags: unix.IFF_UP, OperState: netlink.OperDown}}
+			start := time.Now()
+			err := setupVF(fakeLink)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("is not up after 1s"))
+			Expect(time.Since(start)).To(BeNumerically(">=", time.Second))
+		})
+	})
+	Context("Checking SetupVF function - ingress policing", func() {
+		It("Installs a police filter on the clsact qdisc of the pod interface", func() {
+			netconf := &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:        "enp175s0f1",
+				DeviceID:      "0000:af:06.0",
+				VFID:          0,
+				IngressPolice: &sriovtypes.IngressPolice{Rate: 1000, Burst: 65536},
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mocked.On("QdiscAdd", mock.MatchedBy(func(qdisc netlink.Qdisc) bool {
+				return qdisc.Type() == "clsact" && qdisc.Attrs().LinkIndex == 1000
+			})).Return(nil)
+			mocked.On("FilterAdd", mock.MatchedBy(func(filter netlink.Filter) bool {
+				matchAll, ok := filter.(*netlink.MatchAll)
+				if !ok || matchAll.Parent != netlink.HANDLE_MIN_INGRESS || len(matchAll.Actions) != 1 {
+					return false
+				}
+				police, ok := matchAll.Actions[0].(*netlink.PoliceAction)
+				return ok && police.Rate == 125000000 && police.Burst == 65536 && police.ExceedAction == netlink.TC_POLICE_SHOT
+			})).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, "net1", targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking ReleaseVF function - ingress policing", func() {
+		It("Removes the clsact qdisc of the pod interface", func() {
+			netconf := &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:        "enp175s0f1",
+				DeviceID:      "0000:af:06.0",
+				VFID:          0,
+				IngressPolice: &sriovtypes.IngressPolice{Rate: 1000, Burst: 65536},
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", "net1").Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("QdiscDel", mock.MatchedBy(func(qdisc netlink.Qdisc) bool {
+				return qdisc.Type() == "clsact" && qdisc.Attrs().LinkIndex == 1000
+			})).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			err = sm.ReleaseVF(netconf, "net1", targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking passthrough mode", func() {
+		var netconf *sriovtypes.NetConf
+
+		BeforeEach(func() {
+			passthrough := true
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:      "enp175s0f1",
+				DeviceID:    "0000:af:06.0",
+				VFID:        0,
+				MAC:         "0a:00:00:00:00:01",
+				Passthrough: &passthrough,
+				OrigVfState: sriovtypes.VfState{HostIFName: "enp175s6"},
+			}}
+		})
+
+		It("Does not configure the VF nor keep the requested MAC", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			sm := sriovManager{nLink: mocked, utils: &mocks.PciUtils{}}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			Expect(netconf.MAC).To(BeEmpty())
+			mocked.AssertNotCalled(t, "LinkByName", mock.Anything)
+		})
+
+		It("Does not reset the VF", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			sm := sriovManager{nLink: mocked, utils: &mocks.PciUtils{}}
+			Expect(sm.ResetVFConfig(netconf)).To(Succeed())
+			mocked.AssertNotCalled(t, "LinkByName", mock.Anything)
+		})
+
+		It("Does not compare the VF attributes on CHECK", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.CheckVFConfig(netconf)).To(Succeed())
+			mocked.AssertNotCalled(t, "LinkByName", mock.Anything)
+		})
+
+		It("Has nothing to reconcile", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			sm := sriovManager{nLink: mocked}
+			drifts, err := sm.ReconcileVFConfig(netconf, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drifts).To(BeEmpty())
+			mocked.AssertNotCalled(t, "LinkByName", mock.Anything)
+		})
+	})
+	Context("Checking ApplyVFConfig function - MAC derived from the pci address", func() {
+		It("Sets the MAC derived from the VF pci address", func() {
+			netconf := &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				MAC:      sriovtypes.MACAuto,
+			}}
+			derivedMac := utils.MACFromPCI(netconf.DeviceID)
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: derivedMac},
+			}}}
+
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfHardwareAddr", fakeLink, netconf.VFID, derivedMac).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.MAC).To(Equal(derivedMac.String()))
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking ApplyVFConfig function - MAC derived from the hostname", func() {
+		It("Sets the MAC derived from the hostname, the PF and the VF index", func() {
+			netconf := &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:          "enp175s0f1",
+				DeviceID:        "0000:af:06.1",
+				VFID:            1,
+				MacFromHostname: true,
+			}}
+			hostname, err := os.Hostname()
+			Expect(err).NotTo(HaveOccurred())
+			derivedMac := utils.MACFromHostname(hostname, netconf.Master, netconf.VFID)
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0},
+				{ID: 1, Mac: derivedMac},
+			}}}
+
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfHardwareAddr", fakeLink, netconf.VFID, derivedMac).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			err = sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.MAC).To(Equal(derivedMac.String()))
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking ApplyVFConfig function - trust verification", func() {
+		var (
+			netconf  *sriovtypes.NetConf
+			fakeLink *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				Trust:    "on",
+			}}
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{{ID: 0}}}}
+		})
+
+		It("Succeeds when the trust flag is applied", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfTrust", fakeLink, netconf.VFID, true).Return(nil).Run(setFakeVfTrust)
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+		})
+
+		It("Fails when the firmware does not apply the trust flag", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfTrust", fakeLink, netconf.VFID, true).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).To(MatchError(ContainSubstring("vf 0 trust flag is off after setting it to on")))
+			Expect(errors.Is(err, ErrInvalidVFConfig)).To(BeTrue())
+		})
+	})
+	Context("Checking ApplyVFConfig function - spoofchk following trust", func() {
+		var (
+			netconf  *sriovtypes.NetConf
+			fakeLink *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			spoofChkFollowsTrust := true
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:               "enp175s0f1",
+				DeviceID:             "0000:af:06.0",
+				VFID:                 0,
+				SpoofChkFollowsTrust: &spoofChkFollowsTrust,
+			}}
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1"}}
+		})
+
+		DescribeTable("Derives spoofchk from trust",
+			func(trust, expectedSpoofChk string) {
+				netconf.Trust = trust
+				mocked := &mocks_utils.NetlinkManager{}
+				mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+				mocked.On("LinkSetVfSpoofchk", fakeLink, netconf.VFID, expectedSpoofChk == "on").Return(nil)
+				mocked.On("LinkSetVfTrust", fakeLink, netconf.VFID, trust == "on").Return(nil).Run(setFakeVfTrust)
+				sm := sriovManager{nLink: mocked}
+				err := sm.ApplyVFConfig(netconf)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(netconf.SpoofChk).To(Equal(expectedSpoofChk))
+				mocked.AssertExpectations(t)
+			},
+			Entry("trust on", "on", "off"),
+			Entry("trust off", "off", "on"),
+		)
+
+		It("Keeps an explicit spoofchk", func() {
+			netconf.Trust = "on"
+			netconf.SpoofChk = "on"
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfSpoofchk", fakeLink, netconf.VFID, true).Return(nil)
+			mocked.On("LinkSetVfTrust", fakeLink, netconf.VFID, true).Return(nil).Run(setFakeVfTrust)
+			sm := sriovManager{nLink: mocked}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.SpoofChk).To(Equal("on"))
+			mocked.AssertExpectations(t)
+		})
+
+		It("Does not derive spoofchk when not requested", func() {
+			netconf.SpoofChkFollowsTrust = nil
+			netconf.Trust = "on"
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfTrust", fakeLink, netconf.VFID, true).Return(nil).Run(setFakeVfTrust)
+			sm := sriovManager{nLink: mocked}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.SpoofChk).To(BeEmpty())
+			mocked.AssertNotCalled(t, "LinkSetVfSpoofchk", mock.Anything, mock.Anything, mock.Anything)
+		})
+	})
+	Context("Checking ResetVFConfig function - link state restoration", func() {
+		DescribeTable("Restores the original link state of a VF disabled on ADD",
+			func(origLinkState uint32) {
+				netconf := &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+					Master:      "enp175s0f1",
+					DeviceID:    "0000:af:06.0",
+					VFID:        0,
+					LinkState:   "disable",
+					OrigVfState: sriovtypes.VfState{HostIFName: "enp175s6", LinkState: origLinkState},
+				}}
+				fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1"}}
+				mocked := &mocks_utils.NetlinkManager{}
+				mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+				mocked.On("LinkSetVfState", fakeLink, netconf.VFID, origLinkState).Return(nil)
+
+				sm := sriovManager{nLink: mocked}
+				err := sm.ResetVFConfig(netconf)
+				Expect(err).NotTo(HaveOccurred())
+				mocked.AssertExpectations(t)
+			},
+			Entry("auto", uint32(netlink.VF_LINK_STATE_AUTO)),
+			Entry("enable", uint32(netlink.VF_LINK_STATE_ENABLE)),
+		)
+
+		DescribeTable("Maps the link state back to its string",
+			func(linkState uint32, expected string) {
+				Expect(linkStateToString(linkState)).To(Equal(expected))
+			},
+			Entry("auto", uint32(netlink.VF_LINK_STATE_AUTO), "auto"),
+			Entry("enable", uint32(netlink.VF_LINK_STATE_ENABLE), "enable"),
+			Entry("disable", uint32(netlink.VF_LINK_STATE_DISABLE), "disable"),
+			Entry("unknown", uint32(7), "unknown(7)"),
+		)
+	})
+	Context("Checking SetupVF function - private flags", func() {
+		var (
+			podifName string
+			netconf   *sriovtypes.NetConf
+		)
+
+		BeforeEach(func() {
+			podifName = "net1"
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:    "enp175s0f1",
+				DeviceID:  "0000:af:06.0",
+				VFID:      0,
+				PrivFlags: map[string]bool{"vf-true-promisc-support": true},
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+		})
+
+		It("Sets the private flags and saves their original values", func() {
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("GetPrivFlags", podifName).Return(map[string]bool{
+				"link-down-on-close":      false,
+				"vf-true-promisc-support": false,
+			}, nil)
+			mockedPciUtils.On("SetPrivFlags", podifName, netconf.PrivFlags).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, podifName, targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.PrivFlags).To(Equal(map[string]bool{"vf-true-promisc-support": false}))
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Fails listing the available flags when a flag is not supported", func() {
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("GetPrivFlags", podifName).Return(map[string]bool{
+				"link-down-on-close": false,
+				"legacy-rx":          true,
+			}, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, podifName, targetNetNS)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("available flags: [legacy-rx, link-down-on-close]"))
+			Expect(netconf.OrigVfState.PrivFlags).To(BeNil())
+			mockedPciUtils.AssertNotCalled(t, "SetPrivFlags", mock.Anything, mock.Anything)
+		})
+
+		It("Restores the original private flags on release", func() {
+			netconf.OrigVfState.PrivFlags = map[string]bool{"vf-true-promisc-support": false}
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", podifName).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mockedPciUtils.On("SetPrivFlags", netconf.OrigVfState.HostIFName, netconf.OrigVfState.PrivFlags).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.ReleaseVF(netconf, podifName, targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mockedPciUtils.AssertExpectations(t)
+		})
+	})
+	Context("Checking representor vlan", func() {
+		var (
+			netconf    *sriovtypes.NetConf
+			pfLink     *utils.FakeLink
+			repLink    *utils.FakeLink
+			devlinkDev *netlink.DevlinkDevice
+		)
+
+		BeforeEach(func() {
+			repVlan := 100
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:          "enp175s0f1",
+				DeviceID:        "0000:af:06.0",
+				VFID:            0,
+				RepresentorVlan: &repVlan,
+			}}
+			pfLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1"}}
+			repLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "enp175s0f1_0"}}
+			devlinkDev = &netlink.DevlinkDevice{BusName: "pci", DeviceName: "0000:af:00.1"}
+			devlinkDev.Attrs.Eswitch.Mode = "switchdev"
+		})
+
+		It("ApplyVFConfig sets the vlan as untagged pvid of the representor", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mocked.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(devlinkDev, nil)
+			mockedPciUtils.On("GetVFRepresentor", netconf.Master, netconf.VFID).Return("enp175s0f1_0", nil)
+			mocked.On("BridgeVlanAdd", repLink, uint16(100), true, true, false, true).Return(nil)
+
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("ApplyVFConfig fails when the PF e-switch is in legacy mode", func() {
+			devlinkDev.Attrs.Eswitch.Mode = "legacy"
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(devlinkDev, nil)
+
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`e-switch mode is "legacy"`))
+			mocked.AssertNotCalled(t, "BridgeVlanAdd", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("ResetVFConfig removes the vlan from the representor", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mocked.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(devlinkDev, nil)
+			mockedPciUtils.On("GetVFRepresentor", netconf.Master, netconf.VFID).Return("enp175s0f1_0", nil)
+			mocked.On("BridgeVlanDel", repLink, uint16(100), true, true, false, true).Return(nil)
+
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ResetVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking setRSSIndirTable function", func() {
+		It("Repeats the table to the driver indirection table size", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetRxQueueCount", "net1").Return(4, nil)
+			mockedPciUtils.On("GetRSSIndirSize", "net1").Return(8, nil)
+			mockedPciUtils.On("SetRSSIndirTable", "net1", []uint32{0, 1, 0, 1, 0, 1, 0, 1}).Return(nil)
+			sm := sriovManager{utils: mockedPciUtils}
+			err := sm.setRSSIndirTable("net1", []int{0, 1})
+			Expect(err).NotTo(HaveOccurred())
+			mockedPciUtils.AssertExpectations(t)
+		})
+		It("Fails when an entry is not an rx queue of the device", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetRxQueueCount", "net1").Return(2, nil)
+			sm := sriovManager{utils: mockedPciUtils}
+			err := sm.setRSSIndirTable("net1", []int{0, 1, 2, 3})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("device has 2 rx queues"))
+			mockedPciUtils.AssertNotCalled(t, "SetRSSIndirTable", mock.Anything, mock.Anything)
+		})
+		It("Fails when the driver table size is not a multiple of the table length", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetRxQueueCount", "net1").Return(8, nil)
+			mockedPciUtils.On("GetRSSIndirSize", "net1").Return(4, nil)
+			sm := sriovManager{utils: mockedPciUtils}
+			err := sm.setRSSIndirTable("net1", []int{0, 1, 2, 3, 4, 5, 6, 7})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("driver table size 4"))
+			mockedPciUtils.AssertNotCalled(t, "SetRSSIndirTable", mock.Anything, mock.Anything)
+		})
+	})
+	Context("Checking CompareVFConfig function", func() {
+		var (
+			netconf   *sriovtypes.NetConf
+			pfLink    *utils.FakeLink
+			podLink   *utils.FakeLink
+			podifName string
+		)
+
+		BeforeEach(func() {
+			vlan := 100
+			qos := 0
+			proto := sriovtypes.Proto8021q
+			podifName = "net1"
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:    "enp175s0f1",
+				DeviceID:  "0000:af:06.0",
+				VFID:      0,
+				MAC:       "e4:11:22:33:44:55",
+				Vlan:      &vlan,
+				VlanQoS:   &qos,
+				VlanProto: &proto,
+				Trust:     "on",
+			}}
+			mac, err := net.ParseMAC(netconf.MAC)
+			Expect(err).NotTo(HaveOccurred())
+			pfLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: mac, Vlan: 100, VlanProto: sriovtypes.VlanProtoInt[proto], Trust: 1},
+			}}}
+			podLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: podifName, HardwareAddr: mac}}
+		})
+
+		It("Succeeds when a retried ADD finds the VF configured as requested", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkByName", podifName).Return(podLink, nil)
+			mockedPciUtils.On("GetDrvInfo", podifName).Return(&utils.DrvInfo{BusInfo: netconf.DeviceID}, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.CompareVFConfig(netconf, podifName, targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Detects a stale vlan left by the previous ADD", func() {
+			pfLink.Vfs[0].Vlan = 200
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.CompareVFConfig(netconf, podifName, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("vf 0 vlan differs: expected 100 qos 0, found 200 qos 0"))
+		})
+
+		It("Detects a stale trust flag left by the previous ADD", func() {
+			pfLink.Vfs[0].Trust = 0
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.CompareVFConfig(netconf, podifName, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("vf 0 trust differs: expected on, found 0"))
+		})
+
+		It("Detects another device behind the pod interface", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkByName", podifName).Return(podLink, nil)
+			mockedPciUtils.On("GetDrvInfo", podifName).Return(&utils.DrvInfo{BusInfo: "0000:af:06.1"}, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.CompareVFConfig(netconf, podifName, targetNetNS)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("interface net1 is 0000:af:06.1, not vf 0000:af:06.0"))
+		})
+	})
+	Context("Checking ApplyVFConfig function - vlan QoS and proto", func() {
+		var (
+			netconf  *sriovtypes.NetConf
+			mocked   *mocks_utils.NetlinkManager
+			fakeLink *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			vlan := 100
+			qos := 3
+			vlanProto := sriovtypes.Proto8021ad
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:    "enp175s0f1",
+				VFID:      0,
+				Vlan:      &vlan,
+				VlanQoS:   &qos,
+				VlanProto: &vlanProto,
+			}}
+			mocked = &mocks_utils.NetlinkManager{}
+			fakeLink = &utils.FakeLink{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+		})
+
+		It("Rejects a QoS with 802.1ad when the PF driver lacks the combination", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetPFDriver", netconf.Master).Return("i40e", nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("vf 0 vlan qos 3 with proto 802.1ad is not supported by driver i40e of PF enp175s0f1"))
+			mocked.AssertNotCalled(t, "LinkSetVfVlanQosProto", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("Applies a QoS with 802.1ad when the PF driver supports the combination", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetPFDriver", netconf.Master).Return("mlx5_core", nil)
+			mocked.On("LinkSetVfVlanQosProto", fakeLink, netconf.VFID, 100, 3, sriovtypes.VlanProtoInt[sriovtypes.Proto8021ad]).Return(nil)
+			fakeLink.Vfs = []netlink.VfInfo{{ID: 0, Vlan: 100, Qos: 3, VlanProto: sriovtypes.VlanProtoInt[sriovtypes.Proto8021ad]}}
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+
+		It("Fails when the driver falls back to 802.1q", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetPFDriver", netconf.Master).Return("mlx5_core", nil)
+			mocked.On("LinkSetVfVlanQosProto", fakeLink, netconf.VFID, 100, 3, sriovtypes.VlanProtoInt[sriovtypes.Proto8021ad]).Return(nil)
+			fakeLink.Vfs = []netlink.VfInfo{{ID: 0, Vlan: 100, Qos: 3, VlanProto: sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]}}
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).To(MatchError(ErrInvalidVFConfig))
+			Expect(err).To(MatchError(ContainSubstring("vf 0 vlan proto is 802.1q instead of 802.1ad, the driver of PF enp175s0f1 does not support QinQ")))
+		})
+
+		It("Skips the verification when the kernel does not report the vlan proto", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetPFDriver", netconf.Master).Return("mlx5_core", nil)
+			mocked.On("LinkSetVfVlanQosProto", fakeLink, netconf.VFID, 100, 3, sriovtypes.VlanProtoInt[sriovtypes.Proto8021ad]).Return(nil)
+			fakeLink.Vfs = []netlink.VfInfo{{ID: 0, Vlan: 100, Qos: 3}}
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+		})
+
+		It("Does not check the PF driver for a QoS with 802.1q", func() {
+			vlanProto := sriovtypes.Proto8021q
+			netconf.VlanProto = &vlanProto
+			mocked.On("LinkSetVfVlanQosProto", fakeLink, netconf.VFID, 100, 3, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: &mocks.PciUtils{}}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+	Context("Checking SignalVFDown function", func() {
+		var (
+			netconf   *sriovtypes.NetConf
+			podifName string
+		)
+
+		BeforeEach(func() {
+			podifName = "net1"
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:          "enp175s0f1",
+				DeviceID:        "0000:af:06.0",
+				VFID:            0,
+				SignalDownOnDel: true,
+			}}
+		})
+
+		It("Sets the VF link down in the pod netns", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			podLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: podifName}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", podifName).Return(podLink, nil)
+			mocked.On("LinkSetDown", podLink).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			sm.SignalVFDown(netconf, podifName, targetNetNS)
+			mocked.AssertExpectations(t)
+		})
+
+		It("Does not touch the VF link when not configured", func() {
+			netconf.SignalDownOnDel = false
+			mocked := &mocks_utils.NetlinkManager{}
+			sm := sriovManager{nLink: mocked}
+			sm.SignalVFDown(netconf, podifName, nil)
+			mocked.AssertNotCalled(t, "LinkSetDown", mock.Anything)
+		})
+	})
+	Context("Checking SetupVF and ReleaseVF functions - VLAN egress QoS map", func() {
+		var (
+			netconf  *sriovtypes.NetConf
+			fakeLink *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:       "enp175s0f1",
+				DeviceID:     "0000:af:06.0",
+				VFID:         0,
+				EgressQoSMap: "0:1,2:3",
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+		})
+
+		It("Sets the egress QoS map on the pod interface", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mocked.On("LinkSetVlanEgressQoSMap", fakeLink, map[uint32]uint32{0: 1, 2: 3}).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, "net1", targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+
+		It("Resets the mapped priorities on release", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "net1").Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetVlanEgressQoSMap", fakeLink, map[uint32]uint32{0: 0, 2: 0}).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			err = sm.ReleaseVF(netconf, "net1", targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking SetupVF and ReleaseVF functions - DCB ingress QoS map", func() {
+		var (
+			netconf  *sriovtypes.NetConf
+			fakeLink *utils.FakeLink
+			apps     []utils.DCBApp
+		)
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:        "enp175s0f1",
+				DeviceID:      "0000:af:06.0",
+				VFID:          0,
+				IngressQoSMap: &sriovtypes.IngressQoSMap{DSCP: "46:5,10:1", PCP: "1:1,5:2"},
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+			apps = []utils.DCBApp{
+				{Selector: utils.DCBAppSelDSCP, Priority: 1, Protocol: 10},
+				{Selector: utils.DCBAppSelDSCP, Priority: 5, Protocol: 46},
+			}
+		})
+
+		setupMocks := func() (*mocks_utils.NetlinkManager, *mocks.PciUtils) {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+			return mocked, mockedPciUtils
+		}
+
+		It("Sets the DSCP and PCP mappings and records the original traffic classes", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mocked, mockedPciUtils := setupMocks()
+			mockedPciUtils.On("GetDCBETS", "net1").Return(&utils.DCBETS{ETSCap: 4}, nil)
+			mockedPciUtils.On("SetDCBETS", "net1", mock.MatchedBy(func(ets *utils.DCBETS) bool {
+				return ets.PrioTC == [utils.DCBMaxTCs]uint8{0, 1, 0, 0, 0, 2, 0, 0}
+			})).Return(nil)
+			mockedPciUtils.On("AddDCBApps", "net1", apps).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, "net1", targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.DCBPrioTC).To(Equal([]int{0, 0, 0, 0, 0, 0, 0, 0}))
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Rejects a traffic class the VF netdev does not have", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mocked, mockedPciUtils := setupMocks()
+			mockedPciUtils.On("GetDCBETS", "net1").Return(&utils.DCBETS{ETSCap: 2}, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, "net1", targetNetNS)
+			Expect(err).To(MatchError(ErrInvalidVFConfig))
+			Expect(err).To(MatchError(ContainSubstring("supports 2 traffic classes")))
+			Expect(netconf.OrigVfState.DCBPrioTC).To(BeNil())
+			mockedPciUtils.AssertNotCalled(t, "SetDCBETS", mock.Anything, mock.Anything)
+			mockedPciUtils.AssertNotCalled(t, "AddDCBApps", mock.Anything, mock.Anything)
+		})
+
+		It("Skips the mappings when the VF driver does not support DCB", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mocked, mockedPciUtils := setupMocks()
+			mockedPciUtils.On("GetDCBETS", "net1").Return(nil, utils.ErrNotSupported)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, "net1", targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.DCBPrioTC).To(BeNil())
+			mockedPciUtils.AssertNotCalled(t, "AddDCBApps", mock.Anything, mock.Anything)
+		})
+
+		It("Removes the DSCP mappings and restores the traffic classes on release", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			netconf.OrigVfState.DCBPrioTC = []int{0, 0, 0, 0, 0, 0, 0, 3}
+			mocked, mockedPciUtils := setupMocks()
+			mockedPciUtils.On("DelDCBApps", "enp175s6", apps).Return(nil)
+			mockedPciUtils.On("GetDCBETS", "enp175s6").Return(&utils.DCBETS{ETSCap: 4, PrioTC: [utils.DCBMaxTCs]uint8{0, 1, 0, 0, 0, 2, 0, 3}}, nil)
+			mockedPciUtils.On("SetDCBETS", "enp175s6", mock.MatchedBy(func(ets *utils.DCBETS) bool {
+				return ets.PrioTC == [utils.DCBMaxTCs]uint8{0, 0, 0, 0, 0, 0, 0, 3}
+			})).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.ReleaseVF(netconf, "net1", targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mockedPciUtils.AssertCalled(t, "DelDCBApps", "enp175s6", apps)
+			mockedPciUtils.AssertCalled(t, "SetDCBETS", "enp175s6", mock.Anything)
+		})
+
+		It("Leaves the DCB configuration alone on release when it was not set", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mocked, mockedPciUtils := setupMocks()
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.ReleaseVF(netconf, "net1", targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mockedPciUtils.AssertNotCalled(t, "DelDCBApps", mock.Anything, mock.Anything)
+			mockedPciUtils.AssertNotCalled(t, "SetDCBETS", mock.Anything, mock.Anything)
+		})
+	})
+	Context("Checking SetupVF and ReleaseVF functions - DCB ETS bandwidth allocation", func() {
+		var (
+			netconf  *sriovtypes.NetConf
+			fakeLink *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				ETS: []sriovtypes.ETSBandwidth{
+					{TC: 0, BandwidthPercent: 60},
+					{TC: 2, BandwidthPercent: 40},
+				},
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				}},
+			}
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+		})
+
+		setupMocks := func() (*mocks_utils.NetlinkManager, *mocks.PciUtils) {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+			return mocked, mockedPciUtils
+		}
+
+		It("Sets the bandwidth of the traffic classes and records the original allocation", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mocked, mockedPciUtils := setupMocks()
+			mockedPciUtils.On("GetDCBETS", "net1").Return(&utils.DCBETS{
+				ETSCap: 4,
+				TCTxBW: [utils.DCBMaxTCs]uint8{50, 50},
+				TCTSA:  [utils.DCBMaxTCs]uint8{utils.DCBTSAETS, utils.DCBTSAETS},
+			}, nil)
+			mockedPciUtils.On("SetDCBETS", "net1", mock.MatchedBy(func(ets *utils.DCBETS) bool {
+				return ets.TCTxBW == [utils.DCBMaxTCs]uint8{60, 0, 40} &&
+					ets.TCTSA == [utils.DCBMaxTCs]uint8{utils.DCBTSAETS, utils.DCBTSAETS, utils.DCBTSAETS}
+			})).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, "net1", targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.DCBTCBandwidth).To(Equal([]int{50, 50, 0, 0, 0, 0, 0, 0}))
+			Expect(netconf.OrigVfState.DCBTCTSA).To(Equal([]int{utils.DCBTSAETS, utils.DCBTSAETS, 0, 0, 0, 0, 0, 0}))
+			mockedPciUtils.AssertCalled(t, "SetDCBETS", "net1", mock.Anything)
+		})
+
+		It("Rejects a traffic class the VF netdev does not have", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mocked, mockedPciUtils := setupMocks()
+			mockedPciUtils.On("GetDCBETS", "net1").Return(&utils.DCBETS{ETSCap: 2}, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, "net1", targetNetNS)
+			Expect(err).To(MatchError(ErrInvalidVFConfig))
+			Expect(err).To(MatchError(ContainSubstring("invalid ets tc 2: net1 supports 2 traffic classes")))
+			mockedPciUtils.AssertNotCalled(t, "SetDCBETS", mock.Anything, mock.Anything)
+		})
+
+		It("Skips the allocation when the VF driver does not support DCB", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mocked, mockedPciUtils := setupMocks()
+			mockedPciUtils.On("GetDCBETS", "net1").Return(nil, utils.ErrNotSupported)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.SetupVF(netconf, "net1", targetNetNS)).To(Succeed())
+			Expect(netconf.OrigVfState.DCBTCBandwidth).To(BeNil())
+		})
+
+		It("Restores the original allocation on release", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			netconf.OrigVfState.DCBTCBandwidth = []int{50, 50, 0, 0, 0, 0, 0, 0}
+			netconf.OrigVfState.DCBTCTSA = []int{utils.DCBTSAETS, utils.DCBTSAETS, 0, 0, 0, 0, 0, 0}
+			mocked, mockedPciUtils := setupMocks()
+			mockedPciUtils.On("GetDCBETS", "enp175s6").Return(&utils.DCBETS{
+				ETSCap: 4,
+				TCTxBW: [utils.DCBMaxTCs]uint8{60, 0, 40},
+				TCTSA:  [utils.DCBMaxTCs]uint8{utils.DCBTSAETS, utils.DCBTSAETS, utils.DCBTSAETS},
+			}, nil)
+			mockedPciUtils.On("SetDCBETS", "enp175s6", mock.MatchedBy(func(ets *utils.DCBETS) bool {
+				return ets.TCTxBW == [utils.DCBMaxTCs]uint8{50, 50} &&
+					ets.TCTSA == [utils.DCBMaxTCs]uint8{utils.DCBTSAETS, utils.DCBTSAETS}
+			})).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ReleaseVF(netconf, "net1", targetNetNS)).To(Succeed())
+			mockedPciUtils.AssertCalled(t, "SetDCBETS", "enp175s6", mock.Anything)
+		})
+	})
+	Context("Checking setMicroburstProtection function", func() {
+		var netconf *sriovtypes.NetConf
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:               "enp175s0f1",
+				DeviceID:             "0000:af:06.0",
+				VFID:                 0,
+				MicroburstProtection: true,
+			}}
+		})
+
+		It("Sets the moderation private flags of drivers that have them", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetDrvInfo", "net1").Return(&utils.DrvInfo{Driver: "mlx5_core"}, nil)
+			mockedPciUtils.On("GetPrivFlags", "net1").Return(map[string]bool{"rx_cqe_moder": false, "tx_cqe_moder": false, "rx_cqe_compress": false}, nil)
+			mockedPciUtils.On("SetPrivFlags", "net1", map[string]bool{"rx_cqe_moder": true, "tx_cqe_moder": true}).Return(nil)
+			sm := sriovManager{utils: mockedPciUtils}
+			sm.setMicroburstProtection("net1", netconf)
+			mockedPciUtils.AssertExpectations(t)
+			Expect(netconf.OrigVfState.PrivFlags).To(Equal(map[string]bool{"rx_cqe_moder": false, "tx_cqe_moder": false}))
+		})
+
+		It("Leaves the private flags configured with privFlags", func() {
+			netconf.PrivFlags = map[string]bool{"tx_cqe_moder": false}
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetDrvInfo", "net1").Return(&utils.DrvInfo{Driver: "mlx5_core"}, nil)
+			mockedPciUtils.On("GetPrivFlags", "net1").Return(map[string]bool{"rx_cqe_moder": false, "tx_cqe_moder": false}, nil)
+			mockedPciUtils.On("SetPrivFlags", "net1", map[string]bool{"rx_cqe_moder": true}).Return(nil)
+			sm := sriovManager{utils: mockedPciUtils}
+			sm.setMicroburstProtection("net1", netconf)
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Enables adaptive interrupt coalescing for the other drivers", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetDrvInfo", "net1").Return(&utils.DrvInfo{Driver: "iavf"}, nil)
+			mockedPciUtils.On("GetAdaptiveCoalesce", "net1").Return(false, true, nil)
+			mockedPciUtils.On("SetAdaptiveCoalesce", "net1", true, true).Return(nil)
+			sm := sriovManager{utils: mockedPciUtils}
+			sm.setMicroburstProtection("net1", netconf)
+			mockedPciUtils.AssertExpectations(t)
+			Expect(netconf.OrigVfState.AdaptiveCoalesce).To(Equal(&sriovtypes.AdaptiveCoalesce{Rx: false, Tx: true}))
+		})
+
+		It("Only warns when the device does not support interrupt coalescing", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetDrvInfo", "net1").Return(&utils.DrvInfo{Driver: "iavf"}, nil)
+			mockedPciUtils.On("GetAdaptiveCoalesce", "net1").Return(false, false, unix.EOPNOTSUPP)
+			sm := sriovManager{utils: mockedPciUtils}
+			sm.setMicroburstProtection("net1", netconf)
+			mockedPciUtils.AssertNotCalled(t, "SetAdaptiveCoalesce", mock.Anything, mock.Anything, mock.Anything)
+			Expect(netconf.OrigVfState.AdaptiveCoalesce).To(BeNil())
+		})
+
+		It("Restores the original adaptive interrupt coalescing on the host name on release", func() {
+			netconf.OrigVfState = sriovtypes.VfState{
+				HostIFName:       "enp175s6",
+				AdaptiveCoalesce: &sriovtypes.AdaptiveCoalesce{Rx: false, Tx: true},
+			}
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "net1"}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "net1").Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, "enp175s6").Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("SetAdaptiveCoalesce", "enp175s6", false, true).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ReleaseVF(netconf, "net1", targetNetNS)).To(Succeed())
+			mockedPciUtils.AssertExpectations(t)
+		})
+	})
+	Context("Checking QuarantineRepresentor and ReclaimRepresentor functions", func() {
+		var (
+			netconf    *sriovtypes.NetConf
+			repLink    *utils.FakeLink
+			devlinkDev *netlink.DevlinkDevice
+		)
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:                 "enp175s0f1",
+				DeviceID:               "0000:af:06.0",
+				VFID:                   0,
+				QuarantineHostRepOnDel: true,
+			}}
+			repLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "enp175s0f1_0"}}
+			devlinkDev = &netlink.DevlinkDevice{BusName: "pci", DeviceName: "0000:af:00.1"}
+			devlinkDev.Attrs.Eswitch.Mode = "switchdev"
+		})
+
+		It("Sets the representor down and returns its name", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(devlinkDev, nil)
+			mockedPciUtils.On("GetVFRepresentor", netconf.Master, netconf.VFID).Return("enp175s0f1_0", nil)
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mocked.On("LinkSetDown", repLink).Return(nil)
+
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			repName, err := sm.QuarantineRepresentor(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repName).To(Equal("enp175s0f1_0"))
+			mocked.AssertExpectations(t)
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Fails when the PF e-switch is in legacy mode", func() {
+			devlinkDev.Attrs.Eswitch.Mode = "legacy"
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(devlinkDev, nil)
+
+			sm := sriovManager{nLink: mocked, utils: &mocks.PciUtils{}}
+			_, err := sm.QuarantineRepresentor(netconf)
+			Expect(err).To(HaveOccurred())
+			mocked.AssertNotCalled(t, "LinkSetDown", mock.Anything)
+		})
+
+		It("Sets a reclaimed representor up", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mocked.On("LinkSetUp", repLink).Return(nil)
+
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ReclaimRepresentor("enp175s0f1_0")).To(Succeed())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking setIRQAffinity function", func() {
+		It("Pins every VF IRQ to the CPUs of the local NUMA node", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetNUMANodeCPUMask", "temp_1000").Return("00000000,ffff0000", nil)
+			mockedPciUtils.On("GetVFIRQs", "temp_1000").Return([]int{120, 121}, nil)
+			mockedPciUtils.On("SetIRQAffinity", 120, "00000000,ffff0000").Return(nil)
+			mockedPciUtils.On("SetIRQAffinity", 121, "00000000,ffff0000").Return(nil)
+			sm := sriovManager{utils: mockedPciUtils}
+			Expect(sm.setIRQAffinity("temp_1000")).To(Succeed())
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Skips the affinity of a VF without local NUMA node", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetNUMANodeCPUMask", "temp_1000").Return("", fmt.Errorf("no NUMA node: %w", utils.ErrNotSupported))
+			sm := sriovManager{utils: mockedPciUtils}
+			Expect(sm.setIRQAffinity("temp_1000")).To(Succeed())
+			mockedPciUtils.AssertNotCalled(t, "GetVFIRQs", mock.Anything)
+		})
+
+		It("Logs and skips the affinity when not allowed to write it", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetNUMANodeCPUMask", "temp_1000").Return("00000000,ffff0000", nil)
+			mockedPciUtils.On("GetVFIRQs", "temp_1000").Return([]int{120, 121}, nil)
+			mockedPciUtils.On("SetIRQAffinity", 120, "00000000,ffff0000").Return(fmt.Errorf("failed to set the affinity: %w", os.ErrPermission))
+			sm := sriovManager{utils: mockedPciUtils}
+			Expect(sm.setIRQAffinity("temp_1000")).To(Succeed())
+			mockedPciUtils.AssertNotCalled(t, "SetIRQAffinity", 121, mock.Anything)
+		})
+
+		It("Fails when the IRQs cannot be listed", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetNUMANodeCPUMask", "temp_1000").Return("00000000,ffff0000", nil)
+			mockedPciUtils.On("GetVFIRQs", "temp_1000").Return(nil, fmt.Errorf("no msi_irqs"))
+			sm := sriovManager{utils: mockedPciUtils}
+			Expect(sm.setIRQAffinity("temp_1000")).NotTo(Succeed())
+		})
+	})
+	Context("Checking typed VF errors", func() {
+		var netconf *sriovtypes.NetConf
+
+		BeforeEach(func() {
+			vlan, vlanQoS, vlanProto := 100, 0, sriovtypes.Proto8021q
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:    "enp175s0f1",
+				DeviceID:  "0000:af:06.0",
+				VFID:      0,
+				Vlan:      &vlan,
+				VlanQoS:   &vlanQoS,
+				VlanProto: &vlanProto,
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				},
+			}}
+		})
+
+		It("SetupVF returns ErrVFNotFound for a missing VF netdev", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "enp175s6").Return(nil, netlink.LinkNotFoundError{})
+			sm := sriovManager{nLink: mocked}
+			err := sm.SetupVF(netconf, "net1", nil)
+			Expect(errors.Is(err, ErrVFNotFound)).To(BeTrue())
+			Expect(errors.Is(err, ErrVFBusy)).To(BeFalse())
+		})
+
+		It("SetupVF returns ErrVFBusy wrapping the netlink error", func() {
+			vfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s6"}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "enp175s6").Return(vfLink, nil)
+			mocked.On("LinkSetDown", vfLink).Return(unix.EBUSY)
+			sm := sriovManager{nLink: mocked}
+			err := sm.SetupVF(netconf, "net1", nil)
+			Expect(errors.Is(err, ErrVFBusy)).To(BeTrue())
+			Expect(errors.Is(err, unix.EBUSY)).To(BeTrue())
+			Expect(err.Error()).To(Equal(`failed to down vf device "enp175s6": device or resource busy`))
+		})
+
+		It("ApplyVFConfig returns ErrInvalidVFConfig for a vlan rejected by the driver", func() {
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1"}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mocked.On("LinkSetVfVlanQosProto", pfLink, 0, 100, 0, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]).Return(unix.EINVAL)
+			sm := sriovManager{nLink: mocked}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(errors.Is(err, ErrInvalidVFConfig)).To(BeTrue())
+			Expect(errors.Is(err, unix.EINVAL)).To(BeTrue())
+		})
+
+		It("ApplyVFConfig returns ErrInvalidVFConfig for a max_tx_rate above the ceiling", func() {
+			netconf.Vlan = nil
+			maxTxRate := 20000
+			netconf.MaxTxRate = &maxTxRate
+			netconf.EnforceRateCeiling = sriovtypes.RateCeilingReject
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1"}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mockedPciUtils.On("GetVFMaxTxRateCeiling", "enp175s0f1", 0).Return(10000, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(errors.Is(err, ErrInvalidVFConfig)).To(BeTrue())
+		})
+
+		It("ReleaseVF returns ErrVFNotFound for a VF missing from the pod netns", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "net1").Return(nil, netlink.LinkNotFoundError{})
+			sm := sriovManager{nLink: mocked}
+			err = sm.ReleaseVF(netconf, "net1", targetNetNS)
+			Expect(errors.Is(err, ErrVFNotFound)).To(BeTrue())
+		})
+
+		It("Leaves errors of an unknown cause unchanged", func() {
+			err := fmt.Errorf("failed: %w", unix.EPERM)
+			Expect(classifyVFError(err)).To(BeIdenticalTo(err))
+			Expect(classifyVFError(nil)).To(BeNil())
+		})
+	})
+	Context("Checking ApplyVFConfig function - vlan exclusivity", func() {
+		var netconf *sriovtypes.NetConf
+
+		BeforeEach(func() {
+			vlan, vlanQoS, vlanProto := 100, 0, sriovtypes.Proto8021q
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:                 "enp175s0f1",
+				DeviceID:               "0000:af:06.0",
+				VFID:                   0,
+				Vlan:                   &vlan,
+				VlanQoS:                &vlanQoS,
+				VlanProto:              &vlanProto,
+				EnforceVlanExclusivity: true,
+			}}
+		})
+
+		It("Rejects a vlan held by a sibling VF", func() {
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, Vlan: 0},
+				{ID: 1, Vlan: 100},
+			}}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).To(MatchError(ContainSubstring("vlan 100 of vf 0 is already set on vf 1")))
+			Expect(errors.Is(err, ErrInvalidVFConfig)).To(BeTrue())
+			mocked.AssertNotCalled(t, "LinkSetVfVlanQosProto", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("Sets a vlan no sibling VF holds", func() {
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, Vlan: 100},
+				{ID: 1, Vlan: 200},
+			}}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mocked.On("LinkSetVfVlanQosProto", pfLink, 0, 100, 0, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking the skipped MAC configuration", func() {
+		var (
+			netconf *sriovtypes.NetConf
+			hwMac   net.HardwareAddr
+		)
+
+		BeforeEach(func() {
+			skipMACConfig := true
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:          "enp175s0f1",
+				DeviceID:        "0000:af:06.0",
+				VFID:            0,
+				MAC:             "e4:11:22:33:44:55",
+				MacFromHostname: true,
+				SkipMACConfig:   &skipMACConfig,
+			}}
+			var err error
+			hwMac, err = net.ParseMAC("b4:96:91:00:00:01")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("ApplyVFConfig leaves the VF admin MAC untouched", func() {
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: hwMac},
+			}}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			Expect(netconf.MAC).To(BeEmpty())
+			mocked.AssertNotCalled(t, "LinkSetVfHardwareAddr", mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("CompareVFConfig does not compare the MAC", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: hwMac},
+			}}}
+			podLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "net1", HardwareAddr: hwMac}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkByName", "net1").Return(podLink, nil)
+			mockedPciUtils.On("GetDrvInfo", "net1").Return(&utils.DrvInfo{BusInfo: netconf.DeviceID}, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.CompareVFConfig(netconf, "net1", targetNetNS)).To(Succeed())
+		})
+	})
+	Context("Checking ApplyVFConfig function - min_tx_rate floor", func() {
+		var (
+			netconf *sriovtypes.NetConf
+			pfLink  *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			minTxRate := 30000
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:    "enp175s0f1",
+				DeviceID:  "0000:af:06.0",
+				VFID:      0,
+				MinTxRate: &minTxRate,
+			}}
+			pfLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1"}}
+		})
+
+		It("Rejects a min_tx_rate above the PF link speed before setting the rate", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mockedPciUtils.On("GetPFLinkSpeed", netconf.Master).Return(25000, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).To(MatchError("vf 0 min_tx_rate 30000 Mbps exceeds the PF enp175s0f1 link speed of 25000 Mbps"))
+			Expect(errors.Is(err, ErrInvalidVFConfig)).To(BeTrue())
+			mocked.AssertNotCalled(t, "LinkSetVfRate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("Rejects a min_tx_rate above the max_tx_rate clamped to the PF ceiling", func() {
+			minTxRate, maxTxRate := 15000, 20000
+			netconf.MinTxRate = &minTxRate
+			netconf.MaxTxRate = &maxTxRate
+			netconf.EnforceRateCeiling = sriovtypes.RateCeilingClamp
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mockedPciUtils.On("GetVFMaxTxRateCeiling", netconf.Master, 0).Return(10000, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).To(MatchError("vf 0 min_tx_rate 15000 Mbps exceeds its max_tx_rate 10000 Mbps"))
+			mocked.AssertNotCalled(t, "LinkSetVfRate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("Sets a min_tx_rate when the PF link speed is unknown", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfRate", pfLink, 0, 30000, 0).Return(nil)
+			mockedPciUtils.On("GetPFLinkSpeed", netconf.Master).Return(0, fmt.Errorf("link speed of PF enp175s0f1 is unknown"))
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking the FDB entry tagged with a VNI", func() {
+		var (
+			netconf *sriovtypes.NetConf
+			pfLink  *utils.FakeLink
+			vfMac   net.HardwareAddr
+		)
+
+		BeforeEach(func() {
+			vni := 10100
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				FdbVNI:   &vni,
+			}}
+			var err error
+			vfMac, err = net.ParseMAC("b4:96:91:00:00:01")
+			Expect(err).NotTo(HaveOccurred())
+			pfLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: vfMac},
+			}}}
+		})
+
+		It("ApplyVFConfig installs the FDB entry of the VF admin MAC with the VNI", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("NeighAppend", &netlink.Neigh{
+				LinkIndex:    1000,
+				Family:       unix.AF_BRIDGE,
+				Flags:        netlink.NTF_SELF,
+				State:        netlink.NUD_PERMANENT,
+				HardwareAddr: vfMac,
+				VNI:          10100,
+			}).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			Expect(netconf.AddedFdbMAC).To(Equal("b4:96:91:00:00:01"))
+			mocked.AssertExpectations(t)
+		})
+
+		It("ApplyVFConfig skips the FDB entry when the PF driver does not support it", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("NeighAppend", mock.Anything).Return(unix.EOPNOTSUPP)
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			Expect(netconf.AddedFdbMAC).To(BeEmpty())
+		})
+
+		It("ResetVFConfig removes the FDB entry", func() {
+			netconf.AddedFdbMAC = "b4:96:91:00:00:01"
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfVlanQosProto", pfLink, 0, 0, 0, mock.Anything).Return(nil).Maybe()
+			mocked.On("NeighDel", mock.MatchedBy(func(neigh *netlink.Neigh) bool {
+				return neigh.LinkIndex == 1000 && neigh.VNI == 10100 && neigh.HardwareAddr.String() == "b4:96:91:00:00:01"
+			})).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ResetVFConfig(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking ReleaseVF function - full reset", func() {
+		It("Resets every VF attribute to its default, regardless of the cached configuration", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			podifName := "net1"
+			fullReset := true
+			netconf := &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:    "enp175s0f1",
+				DeviceID:  "0000:af:06.0",
+				VFID:      0,
+				FullReset: &fullReset,
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				},
+			}}
+
+			vfMac, err := net.ParseMAC("b4:96:91:00:00:01")
+			Expect(err).NotTo(HaveOccurred())
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{{
+				ID:        0,
+				Mac:       vfMac,
+				Vlan:      100,
+				Qos:       3,
+				VlanProto: sriovtypes.VlanProtoInt[sriovtypes.Proto8021ad],
+				Spoofchk:  false,
+				Trust:     1,
+				LinkState: netlink.VF_LINK_STATE_ENABLE,
+				MinTxRate: 100,
+				MaxTxRate: 1000,
+			}}}}
+			vf := &pfLink.Vfs[0]
+			vfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: podifName}}
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", podifName).Return(vfLink, nil)
+			mocked.On("LinkSetDown", vfLink).Return(nil)
+			mocked.On("LinkSetName", vfLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetNsFd", vfLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfVlanQosProto", pfLink, 0, 0, 0, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]).
+				Run(func(args mock.Arguments) {
+					vf.Vlan, vf.Qos, vf.VlanProto = args.Int(2), args.Int(3), args.Int(4)
+				}).Return(nil)
+			mocked.On("LinkSetVfSpoofchk", pfLink, 0, true).
+				Run(func(args mock.Arguments) { vf.Spoofchk = args.Bool(2) }).Return(nil)
+			mocked.On("LinkSetVfTrust", pfLink, 0, false).
+				Run(func(_ mock.Arguments) { vf.Trust = 0 }).Return(nil)
+			mocked.On("LinkSetVfState", pfLink, 0, uint32(netlink.VF_LINK_STATE_AUTO)).
+				Run(func(args mock.Arguments) { vf.LinkState = args.Get(2).(uint32) }).Return(nil)
+			mocked.On("LinkSetVfRate", pfLink, 0, 0, 0).
+				Run(func(args mock.Arguments) {
+					vf.MinTxRate, vf.MaxTxRate = uint32(args.Int(2)), uint32(args.Int(3))
+				}).Return(nil)
+			mocked.On("LinkSetVfHardwareAddr", pfLink, 0, mock.Anything).
+				Run(func(args mock.Arguments) { vf.Mac = args.Get(2).(net.HardwareAddr) }).Return(nil)
+
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ReleaseVF(netconf, podifName, targetNetNS)).To(Succeed())
+			mocked.AssertExpectations(t)
+
+			state := sriovtypes.VfState{}
+			state.FillFromVfInfo(vf)
+			Expect(state).To(Equal(sriovtypes.VfState{
+				AdminMAC:  "00:00:00:00:00:00",
+				SpoofChk:  true,
+				VlanProto: sriovtypes.VlanProtoInt[sriovtypes.Proto8021q],
+				LinkState: netlink.VF_LINK_STATE_AUTO,
+			}))
+		})
+	})
+	Context("Checking ResetVFConfig function - parallel reset", func() {
+		var netconf *sriovtypes.NetConf
+
+		BeforeEach(func() {
+			vlan, maxTxRate := 100, 1000
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:        "enp175s0f1",
+				DeviceID:      "0000:af:06.0",
+				VFID:          0,
+				ParallelReset: true,
+				Vlan:          &vlan,
+				MAC:           "c6:c8:7f:1f:21:90",
+				MaxTxRate:     &maxTxRate,
+				SpoofChk:      "off",
+				Trust:         "on",
+				LinkState:     "enable",
+				OrigVfState: sriovtypes.VfState{
+					AdminMAC:  "6e:16:06:0e:b7:e9",
+					SpoofChk:  true,
+					LinkState: netlink.VF_LINK_STATE_AUTO,
+				},
+			}}
+		})
+
+		It("Runs every reset and restores trust after the MAC address", func() {
+			origMac, err := net.ParseMAC(netconf.OrigVfState.AdminMAC)
+			Expect(err).NotTo(HaveOccurred())
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: origMac},
+			}}}
+			macRestored, trustAfterMAC := false, false
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfVlanQosProto", pfLink, 0, 0, 0, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]).Return(nil)
+			mocked.On("LinkSetVfSpoofchk", pfLink, 0, true).Return(nil)
+			mocked.On("LinkSetVfHardwareAddr", pfLink, 0, origMac).
+				Run(func(_ mock.Arguments) { macRestored = true }).Return(nil)
+			mocked.On("LinkSetVfTrust", pfLink, 0, false).
+				Run(func(_ mock.Arguments) { trustAfterMAC = macRestored }).Return(nil)
+			mocked.On("LinkSetVfRate", pfLink, 0, 0, 0).Return(nil)
+			mocked.On("LinkSetVfState", pfLink, 0, uint32(netlink.VF_LINK_STATE_AUTO)).Return(nil)
+
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ResetVFConfig(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+			Expect(trustAfterMAC).To(BeTrue())
+		})
+
+		It("Aggregates the failures of the resets", func() {
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0},
+			}}}
+			netconf.MAC = ""
+			netconf.Trust = ""
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfVlanQosProto", pfLink, 0, 0, 0, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]).Return(unix.EBUSY)
+			mocked.On("LinkSetVfSpoofchk", pfLink, 0, true).Return(unix.EINVAL)
+
+			sm := sriovManager{nLink: mocked}
+			err := sm.ResetVFConfig(netconf)
+			Expect(err).To(MatchError(ContainSubstring("vlan configuration")))
+			Expect(err).To(MatchError(ContainSubstring("spoofchk")))
+			Expect(errors.Is(err, unix.EBUSY)).To(BeTrue())
+			Expect(errors.Is(err, unix.EINVAL)).To(BeTrue())
+			// the later stages are not run once a stage failed
+			mocked.AssertNotCalled(t, "LinkSetVfRate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("Stops at the first failure without parallelReset", func() {
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0},
+			}}}
+			netconf.ParallelReset = false
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfVlanQosProto", pfLink, 0, 0, 0, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]).Return(unix.EBUSY)
+
+			sm := sriovManager{nLink: mocked}
+			err := sm.ResetVFConfig(netconf)
+			Expect(err).To(MatchError(ContainSubstring("vlan configuration")))
+			mocked.AssertNotCalled(t, "LinkSetVfSpoofchk", mock.Anything, mock.Anything, mock.Anything)
+		})
+	})
+	Context("Checking SetupVF function - MAC address read back", func() {
+		var (
+			podifName   string
+			netconf     *sriovtypes.NetConf
+			targetNetNS ns.NetNS
+			mocked      *mocks_utils.NetlinkManager
+			sm          sriovManager
+			pfLink      *utils.FakeLink
+			net1Link    *utils.FakeLink
+			expMac      net.HardwareAddr
+		)
+
+		BeforeEach(func() {
+			var err error
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+
+			podifName = "net1"
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:    "enp175s0f1",
+				DeviceID:  "0000:af:06.0",
+				VFID:      0,
+				MAC:       "e4:11:22:33:44:55",
+				VerifyMAC: true,
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				},
+			}}
+			expMac, err = net.ParseMAC(netconf.MAC)
+			Expect(err).NotTo(HaveOccurred())
+			fakeMac, err := net.ParseMAC("6e:16:06:0e:b7:e9")
+			Expect(err).NotTo(HaveOccurred())
+
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", HardwareAddr: fakeMac}}
+			tempLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "temp_1000", HardwareAddr: fakeMac}}
+			net1Link = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "net1", HardwareAddr: expMac}}
+			pfLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: expMac},
+			}}}
+
+			mocked = &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "enp175s6").Return(fakeLink, nil)
+			mocked.On("LinkByName", "temp_1000").Return(tempLink, nil)
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", mock.Anything, mock.Anything).Return(nil)
+			mocked.On("LinkSetHardwareAddr", net1Link, expMac).Return(nil)
+			mocked.On("LinkSetNsFd", tempLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", tempLink).Return(nil)
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+			sm = sriovManager{nLink: mocked, utils: mockedPciUtils}
+		})
+
+		AfterEach(func() {
+			targetNetNS.Close()
+		})
+
+		It("Succeeds when both MAC addresses match the requested one", func() {
+			mocked.On("LinkByName", "net1").Return(net1Link, nil)
+			Expect(sm.SetupVF(netconf, podifName, targetNetNS)).To(Succeed())
+			mocked.AssertCalled(t, "LinkByName", "enp175s0f1")
+		})
+
+		It("Fails when the administrative MAC address read back diverges", func() {
+			otherMac, err := net.ParseMAC("e4:11:22:33:44:56")
+			Expect(err).NotTo(HaveOccurred())
+			pfLink.Vfs[0].Mac = otherMac
+			mocked.On("LinkByName", "net1").Return(net1Link, nil)
+			err = sm.SetupVF(netconf, podifName, targetNetNS)
+			Expect(err).To(MatchError(ContainSubstring("administrative MAC address e4:11:22:33:44:56 of vf 0 differs")))
+		})
+
+		It("Fails when the effective MAC address read back diverges", func() {
+			otherMac, err := net.ParseMAC("e4:11:22:33:44:56")
+			Expect(err).NotTo(HaveOccurred())
+			readBackLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "net1", HardwareAddr: otherMac}}
+			// the MAC address is read back right after being set by SetupVF, and once more after the VF is set up
+			mocked.On("LinkByName", "net1").Return(net1Link, nil).Twice()
+			mocked.On("LinkByName", "net1").Return(readBackLink, nil)
+			err = sm.SetupVF(netconf, podifName, targetNetNS)
+			Expect(err).To(MatchError(ContainSubstring("effective MAC address e4:11:22:33:44:56 of net1 differs")))
+		})
+		Context("with the legacy MAC ordering", func() {
+			BeforeEach(func() {
+				legacyMACOrder := true
+				netconf.LegacyMACOrder = &legacyMACOrder
+				// the administrative MAC address is set on the PF once the VF is moved to the pod netns
+				mocked.On("LinkSetVfHardwareAddr", pfLink, 0, expMac).Return(nil)
+			})
+
+			It("Succeeds when the administrative MAC address set after the move matches the requested one", func() {
+				mocked.On("LinkByName", "net1").Return(net1Link, nil)
+				Expect(sm.SetupVF(netconf, podifName, targetNetNS)).To(Succeed())
+				mocked.AssertCalled(t, "LinkSetVfHardwareAddr", pfLink, 0, expMac)
+			})
+		})
+	})
+	Context("Checking SetupVF and ReleaseVF functions - allmulticast", func() {
+		var (
+			podifName   string
+			netconf     *sriovtypes.NetConf
+			targetNetNS ns.NetNS
+		)
+
+		BeforeEach(func() {
+			var err error
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			podifName = "net1"
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				AllMulti: "on",
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				},
+			}}
+		})
+
+		AfterEach(func() {
+			targetNetNS.Close()
+		})
+
+		It("Sets allmulticast on and saves the original flag", func() {
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetAllmulticastOn", fakeLink).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.SetupVF(netconf, podifName, targetNetNS)).To(Succeed())
+			mocked.AssertExpectations(t)
+			Expect(netconf.OrigVfState.AllMulti).To(BeFalse())
+		})
+
+		It("Resets a VF left in allmulticast when the pod is deleted", func() {
+			// the pod turned allmulticast on, the VF had it off before the pod
+			netconf.OrigVfState.AllMulti = false
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: podifName, Allmulti: 1}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", podifName).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetAllmulticastOff", fakeLink).Run(func(_ mock.Arguments) {
+				fakeLink.Allmulti = 0
+			}).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ReleaseVF(netconf, podifName, targetNetNS)).To(Succeed())
+			mocked.AssertExpectations(t)
+			Expect(fakeLink.Allmulti).To(Equal(0))
+		})
+
+		It("Keeps allmulticast on when it was on before the pod", func() {
+			netconf.OrigVfState.AllMulti = true
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: podifName, Allmulti: 1}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", podifName).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetAllmulticastOn", fakeLink).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ReleaseVF(netconf, podifName, targetNetNS)).To(Succeed())
+			mocked.AssertExpectations(t)
+			mocked.AssertNotCalled(t, "LinkSetAllmulticastOff", fakeLink)
+		})
+	})
+	Context("Checking SetupVF and ReleaseVF functions - tx queue length", func() {
+		var (
+			podifName   string
+			netconf     *sriovtypes.NetConf
+			targetNetNS ns.NetNS
+		)
+
+		BeforeEach(func() {
+			var err error
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			podifName = "net1"
+			txQLen := 10000
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				TxQLen:   &txQLen,
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				},
+			}}
+		})
+
+		AfterEach(func() {
+			targetNetNS.Close()
+		})
+
+		It("Sets the tx queue length and saves the original one", func() {
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", TxQLen: 1000}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetTxQLen", fakeLink, 10000).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.SetupVF(netconf, podifName, targetNetNS)).To(Succeed())
+			mocked.AssertExpectations(t)
+			Expect(netconf.OrigVfState.TxQLen).To(Equal(1000))
+		})
+
+		It("Restores the original tx queue length when the pod is deleted", func() {
+			netconf.OrigVfState.TxQLen = 1000
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: podifName, TxQLen: 10000}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", podifName).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetTxQLen", fakeLink, 1000).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ReleaseVF(netconf, podifName, targetNetNS)).To(Succeed())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking SetupVF and ReleaseVF functions - keep in host netns", func() {
+		var (
+			podifName string
+			netconf   *sriovtypes.NetConf
+			hostNetNS ns.NetNS
+		)
+
+		BeforeEach(func() {
+			var err error
+			hostNetNS, err = ns.GetCurrentNS()
+			Expect(err).NotTo(HaveOccurred())
+			podifName = "sriov0"
+			keepInHostNetns := true
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:          "enp175s0f1",
+				DeviceID:        "0000:af:06.0",
+				VFID:            0,
+				IfNameTemplate:  "sriov%d",
+				KeepInHostNetns: &keepInHostNetns,
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				},
+			}}
+		})
+
+		AfterEach(func() {
+			hostNetNS.Close()
+		})
+
+		It("Renames the VF in the host netns without moving it", func() {
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, "temp_1000").Return(nil)
+			mocked.On("LinkSetName", fakeLink, podifName).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("EnableArpAndNdiscNotify", podifName).Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", podifName).Return(nil)
+
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.SetupVF(netconf, podifName, hostNetNS)).To(Succeed())
+			mocked.AssertExpectations(t)
+			mocked.AssertNotCalled(t, "LinkSetNsFd", mock.Anything, mock.Anything)
+		})
+
+		It("Renames the VF back in the host netns on release", func() {
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: podifName}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", podifName).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ReleaseVF(netconf, podifName, hostNetNS)).To(Succeed())
+			mocked.AssertExpectations(t)
+			mocked.AssertNotCalled(t, "LinkSetNsFd", mock.Anything, mock.Anything)
+		})
+	})
+	Context("Checking GetRepresentor function", func() {
+		netconf := &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{Master: "ens1", VFID: 1}}
+
+		It("Returns the representor of the VF", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetVFRepresentor", "ens1", 1).Return("ens1_1", nil)
+			sm := sriovManager{utils: mockedPciUtils}
+			repName, err := sm.GetRepresentor(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repName).To(Equal("ens1_1"))
+		})
+		It("Returns ErrLegacyMode for a PF in legacy mode", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetVFRepresentor", "ens1", 1).Return("", fmt.Errorf("no representor: %w", utils.ErrLegacyMode))
+			sm := sriovManager{utils: mockedPciUtils}
+			_, err := sm.GetRepresentor(netconf)
+			Expect(errors.Is(err, utils.ErrLegacyMode)).To(BeTrue())
+		})
+	})
+	Context("Checking the guest vlan tags configuration", func() {
+		var (
+			netconf *sriovtypes.NetConf
+			pfLink  *utils.FakeLink
+			mocked  *mocks_utils.NetlinkManager
+		)
+
+		BeforeEach(func() {
+			allow := true
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:         "enp175s0f1",
+				DeviceID:       "0000:af:06.0",
+				VFID:           0,
+				AllowGuestVlan: &allow,
+			}}
+			pfLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{{ID: 0}}}}
+			mocked = &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+		})
+
+		It("ApplyVFConfig turns the vlan anti-spoofing off to allow the guest vlan tags", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("SetVFVlanAntiSpoof", "enp175s0f1", 0, false).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("ApplyVFConfig turns the vlan anti-spoofing on to deny the guest vlan tags", func() {
+			*netconf.AllowGuestVlan = false
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("SetVFVlanAntiSpoof", "enp175s0f1", 0, true).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("ApplyVFConfig skips a PF driver without vlan anti-spoofing", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("SetVFVlanAntiSpoof", "enp175s0f1", 0, false).
+				Return(fmt.Errorf("no vlan_anti_spoof: %w", utils.ErrNotSupported))
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+		})
+
+		It("FillOriginalVfInfo saves and ResetVFConfig restores the vlan anti-spoofing", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetSriovNumVfs", "enp175s0f1").Return(2, nil)
+			mockedPciUtils.On("GetVFVlanAntiSpoof", "enp175s0f1", 0).Return(true, nil)
+			mockedPciUtils.On("SetVFVlanAntiSpoof", "enp175s0f1", 0, true).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.FillOriginalVfInfo(netconf)).To(Succeed())
+			Expect(netconf.OrigVfState.VlanAntiSpoof).To(BeTrue())
+			Expect(sm.ResetVFConfig(netconf)).To(Succeed())
+			mockedPciUtils.AssertExpectations(t)
+		})
+	})
+	Context("Checking ReconcileVFConfig function", func() {
+		var (
+			netconfs []*sriovtypes.NetConf
+			pfLink   *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			vlan, trust, spoofChk := 100, "on", "on"
+			netconfs = []*sriovtypes.NetConf{
+				{SriovNetConf: sriovtypes.SriovNetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.0", VFID: 0, Vlan: &vlan, Trust: trust}},
+				{SriovNetConf: sriovtypes.SriovNetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.1", VFID: 1, MAC: "b4:96:91:00:00:01", SpoofChk: spoofChk}},
+				{SriovNetConf: sriovtypes.SriovNetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.2", VFID: 2, Vlan: &vlan, LinkState: "enable"}},
+			}
+
+			vfMac, err := net.ParseMAC("b4:96:91:00:00:ff")
+			Expect(err).NotTo(HaveOccurred())
+			pfLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				// vlan and trust drifted
+				{ID: 0, Vlan: 200, Trust: 0, VlanProto: sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]},
+				// MAC address drifted
+				{ID: 1, Mac: vfMac, Spoofchk: true},
+				// in sync
+				{ID: 2, Vlan: 100, VlanProto: sriovtypes.VlanProtoInt[sriovtypes.Proto8021q], LinkState: netlink.VF_LINK_STATE_ENABLE},
+			}}}
+		})
+
+		It("Re-applies the drifted attributes of each attachment", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mocked.On("LinkSetVfVlanQosProto", pfLink, 0, 100, 0, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]).Return(nil)
+			mocked.On("LinkSetVfTrust", pfLink, 0, true).Return(nil)
+			mocked.On("LinkSetVfHardwareAddr", pfLink, 1, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				pfLink.Vfs[1].Mac = args.Get(2).(net.HardwareAddr)
+			})
+			sm := sriovManager{nLink: mocked}
+
+			drifts, err := sm.ReconcileVFConfig(netconfs[0], false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drifts).To(Equal([]string{
+				"vlan: expected 100 qos 0 proto 33024, found 200 qos 0 proto 33024",
+				"trust: expected on, found 0",
+			}))
+
+			drifts, err = sm.ReconcileVFConfig(netconfs[1], false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drifts).To(Equal([]string{"MAC address: expected b4:96:91:00:00:01, found b4:96:91:00:00:ff"}))
+
+			drifts, err = sm.ReconcileVFConfig(netconfs[2], false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drifts).To(BeEmpty())
+
+			mocked.AssertExpectations(t)
+			mocked.AssertNotCalled(t, "LinkSetVfVlanQosProto", pfLink, 2, mock.Anything, mock.Anything, mock.Anything)
+			mocked.AssertNotCalled(t, "LinkSetVfState", mock.Anything, mock.Anything, mock.Anything)
+			mocked.AssertNotCalled(t, "LinkSetVfSpoofchk", mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("Only reports the drifted attributes in dry-run mode", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			sm := sriovManager{nLink: mocked}
+
+			var allDrifts []string
+			for _, netconf := range netconfs {
+				drifts, err := sm.ReconcileVFConfig(netconf, true)
+				Expect(err).NotTo(HaveOccurred())
+				allDrifts = append(allDrifts, drifts...)
+			}
+			Expect(allDrifts).To(HaveLen(3))
+			mocked.AssertNotCalled(t, "LinkSetVfVlanQosProto", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			mocked.AssertNotCalled(t, "LinkSetVfTrust", mock.Anything, mock.Anything, mock.Anything)
+			mocked.AssertNotCalled(t, "LinkSetVfHardwareAddr", mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("Stops at the first attribute that fails to be re-applied", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mocked.On("LinkSetVfVlanQosProto", pfLink, 0, 100, 0, sriovtypes.VlanProtoInt[sriovtypes.Proto8021q]).Return(fmt.Errorf("operation not permitted"))
+			sm := sriovManager{nLink: mocked}
+
+			drifts, err := sm.ReconcileVFConfig(netconfs[0], false)
+			Expect(err).To(MatchError(ContainSubstring("failed to re-apply vf 0 vlan")))
+			Expect(drifts).To(HaveLen(1))
+			mocked.AssertNotCalled(t, "LinkSetVfTrust", mock.Anything, mock.Anything, mock.Anything)
+		})
+	})
+	Context("Checking the managed representor admin state", func() {
+		var (
+			netconf    *sriovtypes.NetConf
+			pfLink     *utils.FakeLink
+			repLink    *utils.FakeLink
+			devlinkDev *netlink.DevlinkDevice
+		)
+
+		BeforeEach(func() {
+			manageRepresentor := true
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:            "enp175s0f1",
+				DeviceID:          "0000:af:06.0",
+				VFID:              0,
+				LinkState:         "enable",
+				ManageRepresentor: &manageRepresentor,
+			}}
+			pfLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, LinkState: netlink.VF_LINK_STATE_ENABLE},
+			}}}
+			repLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "enp175s0f1_0"}}
+			devlinkDev = &netlink.DevlinkDevice{BusName: "pci", DeviceName: "0000:af:00.1"}
+			devlinkDev.Attrs.Eswitch.Mode = "switchdev"
+		})
+
+		It("ApplyVFConfig sets the representor up", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfState", pfLink, 0, uint32(netlink.VF_LINK_STATE_ENABLE)).Return(nil)
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mocked.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(devlinkDev, nil)
+			mockedPciUtils.On("GetVFRepresentor", netconf.Master, netconf.VFID).Return("enp175s0f1_0", nil)
+			mocked.On("LinkSetUp", repLink).Return(nil)
+
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+		})
+
+		It("ApplyVFConfig leaves the representor untouched when it is not managed", func() {
+			netconf.ManageRepresentor = nil
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfState", pfLink, 0, uint32(netlink.VF_LINK_STATE_ENABLE)).Return(nil)
+
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			mocked.AssertNotCalled(t, "LinkSetUp", mock.Anything)
+			mockedPciUtils.AssertNotCalled(t, "GetVFRepresentor", mock.Anything, mock.Anything)
+		})
+
+		It("ResetVFConfig sets the representor down", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfState", pfLink, 0, uint32(netlink.VF_LINK_STATE_AUTO)).Return(nil)
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mocked.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(devlinkDev, nil)
+			mockedPciUtils.On("GetVFRepresentor", netconf.Master, netconf.VFID).Return("enp175s0f1_0", nil)
+			mocked.On("LinkSetDown", repLink).Return(nil)
+
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ResetVFConfig(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking the netlink timeout", func() {
+		It("Aborts a netlink operation that does not complete within the timeout", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1"}}
+			mocked.On("LinkByName", "enp175s0f1").After(2*time.Second).Return(pfLink, nil)
+			nLink := &timeoutNetlink{nLink: mocked, timeout: 100 * time.Millisecond}
+
+			start := time.Now()
+			_, err := nLink.LinkByName("enp175s0f1")
+			Expect(err).To(MatchError(ContainSubstring("netlink LinkByName did not complete within 100ms")))
+			Expect(errors.Is(err, ErrNetlinkTimeout)).To(BeTrue())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+
+		It("Returns the result of a netlink operation completing within the timeout", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1"}}
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mocked.On("LinkSetUp", pfLink).Return(unix.EBUSY)
+			nLink := &timeoutNetlink{nLink: mocked, timeout: time.Second}
+
+			link, err := nLink.LinkByName("enp175s0f1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal(pfLink))
+			Expect(nLink.LinkSetUp(pfLink)).To(MatchError(unix.EBUSY))
+		})
+
+		It("Runs the netlink operation in the netns of the caller", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			var opNS unix.Stat_t
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkSetDown", mock.Anything).Return(nil).Run(func(_ mock.Arguments) {
+				curNS, err := ns.GetCurrentNS()
+				if err == nil {
+					_ = unix.Stat(curNS.Path(), &opNS)
+					curNS.Close()
+				}
+			})
+			nLink := &timeoutNetlink{nLink: mocked, timeout: time.Second}
+
+			err = targetNetNS.Do(func(ns.NetNS) error {
+				return nLink.LinkSetDown(&utils.FakeLink{})
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var targetNS unix.Stat_t
+			Expect(unix.Stat(targetNetNS.Path(), &targetNS)).To(Succeed())
+			Expect(opNS.Ino).To(Equal(targetNS.Ino))
+		})
+
+		It("Classifies a timed out operation as a netlink timeout", func() {
+			err := classifyVFError(newVFError(ErrNetlinkTimeout, fmt.Errorf("netlink LinkSetVfTrust did not complete within 10s")))
+			Expect(errors.Is(err, ErrNetlinkTimeout)).To(BeTrue())
+			Expect(errors.Is(err, ErrVFBusy)).To(BeFalse())
+		})
+	})
+	Context("Checking the VF static routes", func() {
+		var (
+			targetNetNS ns.NetNS
+			netconf     *sriovtypes.NetConf
+			result      *current.Result
+			vfLink      *utils.FakeLink
+			routes      []string
+			mocked      *mocks_utils.NetlinkManager
+		)
+
+		BeforeEach(func() {
+			var err error
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(targetNetNS.Close)
+
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				Routes: []sriovtypes.Route{
+					{Dst: "10.0.0.0/8", GW: "192.168.1.254"},
+					{Dst: "172.16.0.0/12"},
+					{Dst: "fd01::/64", GW: "fe80::1"},
+				},
+			}}
+
+			ipv4, ipv4Net, err := net.ParseCIDR("192.168.1.10/24")
+			Expect(err).NotTo(HaveOccurred())
+			ipv4Net.IP = ipv4
+			result = &current.Result{IPs: []*current.IPConfig{{Address: *ipv4Net}}}
+			vfLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "net1"}}
+
+			routes = nil
+			mocked = &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "net1").Return(vfLink, nil)
+		})
+
+		recordRoute := func(args mock.Arguments) {
+			route := args.Get(0).(*netlink.Route)
+			routes = append(routes, fmt.Sprintf("%s via %s scope %s link %d table %d", route.Dst, route.Gw, route.Scope, route.LinkIndex, route.Table))
+		}
+
+		It("addStaticRoutes adds the routes to the VF", func() {
+			mocked.On("RouteAdd", mock.Anything).Return(nil).Run(recordRoute)
+			sm := sriovManager{nLink: mocked}
+
+			Expect(sm.addStaticRoutes(netconf, "net1", targetNetNS, result)).To(Succeed())
+			Expect(routes).To(Equal([]string{
+				"10.0.0.0/8 via 192.168.1.254 scope universe link 1001 table 0",
+				"172.16.0.0/12 via <nil> scope link link 1001 table 0",
+				"fd01::/64 via fe80::1 scope universe link 1001 table 0",
+			}))
+		})
+
+		It("addStaticRoutes adds the routes to the route table of the VF", func() {
+			table := 100
+			netconf.RouteTable = &table
+			netconf.Routes = netconf.Routes[:1]
+			mocked.On("RouteAdd", mock.Anything).Return(nil).Run(recordRoute)
+			sm := sriovManager{nLink: mocked}
+
+			Expect(sm.addStaticRoutes(netconf, "net1", targetNetNS, result)).To(Succeed())
+			Expect(routes).To(Equal([]string{"10.0.0.0/8 via 192.168.1.254 scope universe link 1001 table 100"}))
+		})
+
+		It("addStaticRoutes leaves an existing route as is", func() {
+			mocked.On("RouteAdd", mock.Anything).Return(unix.EEXIST).Once()
+			mocked.On("RouteAdd", mock.Anything).Return(nil).Run(recordRoute)
+			sm := sriovManager{nLink: mocked}
+
+			Expect(sm.addStaticRoutes(netconf, "net1", targetNetNS, result)).To(Succeed())
+			Expect(routes).To(HaveLen(2))
+		})
+
+		It("addStaticRoutes fails for a gateway outside of the VF subnets", func() {
+			netconf.Routes = []sriovtypes.Route{{Dst: "10.0.0.0/8", GW: "192.168.2.1"}}
+			sm := sriovManager{nLink: mocked}
+
+			err := sm.addStaticRoutes(netconf, "net1", targetNetNS, result)
+			Expect(err).To(MatchError(ErrInvalidVFConfig))
+			Expect(err).To(MatchError(ContainSubstring("gateway 192.168.2.1 of route 10.0.0.0/8 is not reachable")))
+			mocked.AssertNotCalled(t, "RouteAdd", mock.Anything)
+		})
+
+		It("addStaticRoutes does nothing without routes", func() {
+			netconf.Routes = nil
+			sm := sriovManager{nLink: mocked}
+
+			Expect(sm.addStaticRoutes(netconf, "net1", targetNetNS, result)).To(Succeed())
+			mocked.AssertNotCalled(t, "LinkByName", mock.Anything)
+		})
+	})
+	Context("Checking the VF route table", func() {
+		var (
+			targetNetNS ns.NetNS
+			netconf     *sriovtypes.NetConf
+			result      *current.Result
+			vfLink      *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			var err error
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(targetNetNS.Close)
+
+			table := 100
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:     "enp175s0f1",
+				DeviceID:   "0000:af:06.0",
+				VFID:       0,
+				RouteTable: &table,
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				},
+			}}
+
+			ipv4, ipv4Net, err := net.ParseCIDR("192.168.1.10/24")
+			Expect(err).NotTo(HaveOccurred())
+			ipv4Net.IP = ipv4
+			ipv6, ipv6Net, err := net.ParseCIDR("fd00::10/64")
+			Expect(err).NotTo(HaveOccurred())
+			ipv6Net.IP = ipv6
+			_, dst, err := net.ParseCIDR("10.0.0.0/8")
+			Expect(err).NotTo(HaveOccurred())
+			_, defaultDst, err := net.ParseCIDR("0.0.0.0/0")
+			Expect(err).NotTo(HaveOccurred())
+			result = &current.Result{
+				IPs: []*current.IPConfig{
+					{Address: *ipv4Net, Gateway: net.ParseIP("192.168.1.1")},
+					{Address: *ipv6Net},
+				},
+				Routes: []*cnitypes.Route{
+					{Dst: *defaultDst},
+					{Dst: *dst, GW: net.ParseIP("192.168.1.254")},
+				},
+			}
+			vfLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "net1"}}
+		})
+
+		It("SetupRouteTable installs the routes and the source rules in the table", func() {
+			var routes, rules []string
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "net1").Return(vfLink, nil)
+			mocked.On("RouteAdd", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				route := args.Get(0).(*netlink.Route)
+				routes = append(routes, fmt.Sprintf("%s via %s src %s link %d table %d", route.Dst, route.Gw, route.Src, route.LinkIndex, route.Table))
+			})
+			mocked.On("RuleAdd", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				rule := args.Get(0).(*netlink.Rule)
+				rules = append(rules, fmt.Sprintf("from %s lookup %d", rule.Src, rule.Table))
+			})
+			sm := sriovManager{nLink: mocked}
+
+			Expect(sm.SetupRouteTable(netconf, "net1", targetNetNS, result)).To(Succeed())
+			Expect(routes).To(Equal([]string{
+				"192.168.1.0/24 via <nil> src 192.168.1.10 link 1001 table 100",
+				"fd00::/64 via <nil> src fd00::10 link 1001 table 100",
+				"0.0.0.0/0 via 192.168.1.1 src <nil> link 1001 table 100",
+				"10.0.0.0/8 via 192.168.1.254 src <nil> link 1001 table 100",
+			}))
+			Expect(rules).To(Equal([]string{
+				"from 192.168.1.10/32 lookup 100",
+				"from fd00::10/128 lookup 100",
+			}))
+		})
+
+		It("SetupRouteTable does nothing without a route table", func() {
+			netconf.RouteTable = nil
+			mocked := &mocks_utils.NetlinkManager{}
+			sm := sriovManager{nLink: mocked}
+
+			Expect(sm.SetupRouteTable(netconf, "net1", targetNetNS, result)).To(Succeed())
+			mocked.AssertNotCalled(t, "RouteAdd", mock.Anything)
+			mocked.AssertNotCalled(t, "RuleAdd", mock.Anything)
+		})
+
+		It("ReleaseVF removes the source rules", func() {
+			netconf.AddResult = result
+			var rules []string
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "net1").Return(vfLink, nil)
+			mocked.On("LinkSetDown", vfLink).Return(nil)
+			mocked.On("LinkSetName", vfLink, "enp175s6").Return(nil)
+			mocked.On("RuleDel", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				rule := args.Get(0).(*netlink.Rule)
+				rules = append(rules, fmt.Sprintf("from %s lookup %d", rule.Src, rule.Table))
+			}).Once()
+			mocked.On("RuleDel", mock.Anything).Return(unix.ENOENT).Once()
+			mocked.On("LinkSetNsFd", vfLink, mock.AnythingOfType("int")).Return(nil)
+			sm := sriovManager{nLink: mocked}
+
+			Expect(sm.ReleaseVF(netconf, "net1", targetNetNS)).To(Succeed())
+			Expect(rules).To(Equal([]string{"from 192.168.1.10/32 lookup 100"}))
+			mocked.AssertNumberOfCalls(t, "RuleDel", 2)
+		})
+	})
+	Context("Checking ApplyVFConfig function - PF scheduler capacity", func() {
+		var (
+			netconf *sriovtypes.NetConf
+			pfLink  *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			minTxRate := 5000
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:                   "enp175s0f1",
+				DeviceID:                 "0000:af:06.0",
+				VFID:                     0,
+				MinTxRate:                &minTxRate,
+				EnforceSchedulerCapacity: sriovtypes.SchedulerCapacityReject,
+				// vf 2 is cached with a rate its driver does not report
+				SiblingMinTxRates: map[int]int{1: 10000, 2: 8000},
+			}}
+			pfLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0},
+				{ID: 1, MinTxRate: 10000},
+				{ID: 2},
+				// vf 3 is not configured by the plugin
+				{ID: 3, MinTxRate: 1000},
+			}}}
+		})
+
+		It("Sets a min_tx_rate that the PF can guarantee together with the other VFs", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfRate", pfLink, 0, 5000, 0).Return(nil)
+			mockedPciUtils.On("GetPFLinkSpeed", netconf.Master).Return(25000, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+		})
+
+		It("Rejects a min_tx_rate exceeding the PF link speed together with the other VFs", func() {
+			minTxRate := 7000
+			netconf.MinTxRate = &minTxRate
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mockedPciUtils.On("GetPFLinkSpeed", netconf.Master).Return(25000, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.ApplyVFConfig(netconf)
+			Expect(err).To(MatchError("vf 0 min_tx_rate 7000 Mbps brings the min_tx_rate of the VFs of PF enp175s0f1 to 26000 Mbps, above its link speed of 25000 Mbps"))
+			Expect(errors.Is(err, ErrInvalidVFConfig)).To(BeTrue())
+			mocked.AssertNotCalled(t, "LinkSetVfRate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("Only warns about an exceeded PF link speed in warn mode", func() {
+			minTxRate := 7000
+			netconf.MinTxRate = &minTxRate
+			netconf.EnforceSchedulerCapacity = sriovtypes.SchedulerCapacityWarn
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mocked.On("LinkByName", netconf.Master).Return(pfLink, nil)
+			mocked.On("LinkSetVfRate", pfLink, 0, 7000, 0).Return(nil)
+			mockedPciUtils.On("GetPFLinkSpeed", netconf.Master).Return(25000, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ApplyVFConfig(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking ConfigureIPAMResult function - IPv6 only", func() {
+		It("Applies an IPv6 only result with a link-local gateway", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			// a veth stands for the VF moved to the pod netns
+			err = targetNetNS.Do(func(ns.NetNS) error {
+				veth := &netlink.Veth{LinkAttrs: netlink.LinkAttrs{Name: "net1"}, PeerName: "peer1"}
+				if err := netlink.LinkAdd(veth); err != nil {
+					return err
+				}
+				// skip duplicate address detection, the address is usable at once
+				return os.WriteFile("/proc/sys/net/ipv6/conf/net1/accept_dad", []byte("0"), 0644)
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			address, err := netlink.ParseIPNet("fd00::10/64")
+			Expect(err).NotTo(HaveOccurred())
+			_, defaultDst, err := net.ParseCIDR("::/0")
+			Expect(err).NotTo(HaveOccurred())
+			result := &current.Result{
+				Interfaces: []*current.Interface{{Name: "net1", Sandbox: targetNetNS.Path()}},
+				IPs:        []*current.IPConfig{{Interface: current.Int(0), Address: *address, Gateway: net.ParseIP("fe80::1")}},
+				Routes:     []*cnitypes.Route{{Dst: *defaultDst}},
+			}
+			netconf := &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{Master: "enp175s0f1", VFID: 0}}
+
+			sm := sriovManager{nLink: &mocks_utils.NetlinkManager{}}
+			Expect(sm.ConfigureIPAMResult(netconf, "net1", targetNetNS, result)).To(Succeed())
+
+			err = targetNetNS.Do(func(ns.NetNS) error {
+				link, err := netlink.LinkByName("net1")
+				Expect(err).NotTo(HaveOccurred())
+
+				addrs, err := netlink.AddrList(link, netlink.FAMILY_V6)
+				Expect(err).NotTo(HaveOccurred())
+				var addrStrings []string
+				for _, addr := range addrs {
+					addrStrings = append(addrStrings, addr.IPNet.String())
+				}
+				Expect(addrStrings).To(ContainElement("fd00::10/64"))
+
+				v4Addrs, err := netlink.AddrList(link, netlink.FAMILY_V4)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(v4Addrs).To(BeEmpty())
+
+				routes, err := netlink.RouteList(link, netlink.FAMILY_V6)
+				Expect(err).NotTo(HaveOccurred())
+				var gateways []string
+				for _, route := range routes {
+					if route.Dst == nil || route.Dst.String() == "::/0" {
+						gateways = append(gateways, route.Gw.String())
+					}
+				}
+				Expect(gateways).To(Equal([]string{"fe80::1"}))
+				return nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+	Context("Checking SetupVF function - interface sysctls", func() {
+		var (
+			netconf     *sriovtypes.NetConf
+			targetNetNS ns.NetNS
+			mocked      *mocks_utils.NetlinkManager
+			fakeLink    *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			var err error
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(targetNetNS.Close)
+
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				Sysctls: map[string]string{
+					"net.ipv6.conf.IFNAME.accept_ra":    "2",
+					"net.ipv4.conf.IFNAME.rp_filter":    "0",
+					"net.ipv6.conf.IFNAME.disable_ipv6": "0",
+				},
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				},
+			}}
+
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+			mocked = &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+		})
+
+		It("Applies the sysctls to the renamed interface after it is up", func() {
+			var applied []string
+			linkUp := false
+			mocked.On("LinkSetUp", fakeLink).Unset()
+			mocked.On("LinkSetUp", fakeLink).Return(nil).Run(func(_ mock.Arguments) { linkUp = true })
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("EnableArpAndNdiscNotify", "net1").Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", "net1").Return(nil)
+			mockedPciUtils.On("SetInterfaceSysctl", "net1", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				if linkUp {
+					applied = append(applied, args.String(1)+"="+args.String(2))
+				}
+			})
+
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.SetupVF(netconf, "net1", targetNetNS)).To(Succeed())
+			Expect(applied).To(Equal([]string{
+				"net.ipv4.conf.IFNAME.rp_filter=0",
+				"net.ipv6.conf.IFNAME.accept_ra=2",
+				"net.ipv6.conf.IFNAME.disable_ipv6=0",
+			}))
+		})
+
+		It("Fails when a sysctl cannot be written", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("EnableArpAndNdiscNotify", "net1").Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", "net1").Return(nil)
+			mockedPciUtils.On("SetInterfaceSysctl", "net1", "net.ipv4.conf.IFNAME.rp_filter", "0").
+				Return(fmt.Errorf("failed to write net.ipv4.conf.IFNAME.rp_filter=0 for interface net1: permission denied"))
+
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.SetupVF(netconf, "net1", targetNetNS)
+			Expect(err).To(MatchError(ContainSubstring("rp_filter=0 for interface net1")))
+			mockedPciUtils.AssertNumberOfCalls(t, "SetInterfaceSysctl", 1)
+		})
+
+		It("Applies the neighbor table thresholds of both address families", func() {
+			netconf.Sysctls = nil
+			netconf.Neigh = &sriovtypes.Neigh{GcThresh1: 1024, GcThresh3: 8192}
+			var applied []string
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("EnableArpAndNdiscNotify", "net1").Return(nil)
+			mockedPciUtils.On("EnableOptimisticDad", "net1").Return(nil)
+			mockedPciUtils.On("SetInterfaceSysctl", "net1", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				applied = append(applied, args.String(1)+"="+args.String(2))
+			})
+
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.SetupVF(netconf, "net1", targetNetNS)).To(Succeed())
+			Expect(applied).To(Equal([]string{
+				"net.ipv4.neigh.default.gc_thresh1=1024",
+				"net.ipv4.neigh.default.gc_thresh3=8192",
+				"net.ipv6.neigh.default.gc_thresh1=1024",
+				"net.ipv6.neigh.default.gc_thresh3=8192",
+			}))
+		})
+	})
+	Context("Checking SetupVFs function", func() {
+		var (
+			targetNetNS ns.NetNS
+			vfs         []BatchVF
+			pfLink      *utils.FakeLink
+			mocked      *mocks_utils.NetlinkManager
+			pciUtils    *mocks.PciUtils
+		)
+
+		// mockVF mocks the netlink calls configuring the VF of hostIFName as podIfName and releasing it
+		mockVF := func(hostIFName, podIfName string, index int) {
+			mac, _ := net.ParseMAC(fmt.Sprintf("6e:16:06:0e:b7:%02x", index%256))
+			hostLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: index, Name: hostIFName, HardwareAddr: mac}}
+			tempName := fmt.Sprintf("temp_%d", index)
+			tempLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: index, Name: tempName, HardwareAddr: mac}}
+			podLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: index, Name: podIfName, HardwareAddr: mac}}
+			mocked.On("LinkByName", hostIFName).Return(hostLink, nil)
+			mocked.On("LinkByName", tempName).Return(tempLink, nil)
+			mocked.On("LinkByName", podIfName).Return(podLink, nil)
+			mocked.On("LinkSetDown", mock.Anything).Return(nil)
+			mocked.On("LinkSetName", mock.Anything, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", mock.Anything, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", mock.Anything).Return(nil)
+		}
+
+		BeforeEach(func() {
+			var err error
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+
+			maxTxRate := 1000
+			vfs = []BatchVF{
+				{Conf: &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.0", VFID: 0, MaxTxRate: &maxTxRate,
+					OrigVfState: sriovtypes.VfState{HostIFName: "enp175s6"}}}, PodIfName: "net1"},
+				{Conf: &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.1", VFID: 1, MaxTxRate: &maxTxRate,
+					OrigVfState: sriovtypes.VfState{HostIFName: "enp175s7"}}}, PodIfName: "net2"},
+			}
+			pfLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1, Name: "enp175s0f1", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: net.HardwareAddr{0, 0, 0, 0, 0, 0}},
+				{ID: 1, Mac: net.HardwareAddr{0, 0, 0, 0, 0, 0}},
+			}}}
+
+			mocked = &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mocked.On("LinkSetVfRate", pfLink, mock.AnythingOfType("int"), mock.AnythingOfType("int"), mock.AnythingOfType("int")).Return(nil)
+			pciUtils = &mocks.PciUtils{}
+			pciUtils.On("GetSriovNumVfs", "enp175s0f1").Return(2, nil)
+			pciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			pciUtils.On("EnableOptimisticDad", mock.AnythingOfType("string")).Return(nil)
+		})
+
+		AfterEach(func() {
+			Expect(targetNetNS.Close()).To(Succeed())
+		})
+
+		It("Configures every VF", func() {
+			mockVF("enp175s6", "net1", 1000)
+			mockVF("enp175s7", "net2", 1001)
+
+			sm := sriovManager{nLink: mocked, utils: pciUtils}
+			Expect(sm.SetupVFs(vfs, targetNetNS, 1)).To(Succeed())
+			mocked.AssertCalled(t, "LinkSetVfRate", pfLink, 0, 0, 1000)
+			mocked.AssertCalled(t, "LinkSetVfRate", pfLink, 1, 0, 1000)
+			mocked.AssertNotCalled(t, "LinkSetVfRate", pfLink, mock.AnythingOfType("int"), 0, 0)
+			for _, vf := range vfs {
+				Expect(vf.Conf.OrigVfState.PFNumVFs).To(Equal(2))
+				Expect(vf.Conf.OrigVfState.EffectiveMAC).NotTo(BeEmpty())
+			}
+			mocked.AssertCalled(t, "LinkByName", "temp_1000")
+			mocked.AssertCalled(t, "LinkByName", "temp_1001")
+		})
+
+		It("Rolls back every VF when one of them fails", func() {
+			mockVF("enp175s6", "net1", 1000)
+			mocked.On("LinkByName", "enp175s7").Return(nil, fmt.Errorf("link not found"))
+			mocked.On("LinkByName", "net2").Return(nil, fmt.Errorf("link not found"))
+
+			sm := sriovManager{nLink: mocked, utils: pciUtils}
+			err := sm.SetupVFs(vfs, targetNetNS, 0)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to set up VF 0000:af:06.1 as net2"))
+			Expect(err.Error()).NotTo(ContainSubstring("0000:af:06.0"))
+
+			// the configured VF is returned to the host under its original name
+			mocked.AssertCalled(t, "LinkSetName", mock.Anything, "enp175s6")
+			// both VFs are configured, then reset
+			mocked.AssertCalled(t, "LinkSetVfRate", pfLink, 0, 0, 1000)
+			mocked.AssertCalled(t, "LinkSetVfRate", pfLink, 1, 0, 1000)
+			mocked.AssertCalled(t, "LinkSetVfRate", pfLink, 0, 0, 0)
+			mocked.AssertCalled(t, "LinkSetVfRate", pfLink, 1, 0, 0)
+			Expect(vfs[0].Conf.ResetScope).To(Equal(sriovtypes.ResetScopeAll))
+		})
+	})
+	Context("Checking SetupBond function", func() {
+		var (
+			targetNetNS ns.NetNS
+			netconf     *sriovtypes.NetConf
+			mocked      *mocks_utils.NetlinkManager
+			members     []*utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			var err error
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Bond: &sriovtypes.Bond{Mode: sriovtypes.BondModeActiveBackup, Members: []string{"0000:af:06.0", "0000:af:06.1"}},
+			}}
+			members = []*utils.FakeLink{
+				{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "net1_0"}},
+				{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "net1_1"}},
+			}
+			mocked = &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", "net1_0").Return(members[0], nil)
+			mocked.On("LinkByName", "net1_1").Return(members[1], nil)
+			mocked.On("LinkSetDown", mock.Anything).Return(nil)
+		})
+
+		AfterEach(func() {
+			Expect(targetNetNS.Close()).To(Succeed())
+		})
+
+		It("Creates the bond and enslaves the members", func() {
+			isBond := mock.MatchedBy(func(link netlink.Link) bool {
+				bond, ok := link.(*netlink.Bond)
+				return ok && bond.Name == "net1" && bond.Mode == netlink.BOND_MODE_ACTIVE_BACKUP && bond.Miimon == 100
+			})
+			mocked.On("LinkAdd", isBond).Return(nil)
+			mocked.On("LinkSetMaster", mock.Anything, isBond).Return(nil)
+			mocked.On("LinkSetUp", isBond).Return(nil)
+
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.SetupBond(netconf, "net1", []string{"net1_0", "net1_1"}, targetNetNS)).To(Succeed())
+			mocked.AssertCalled(t, "LinkSetDown", members[0])
+			mocked.AssertCalled(t, "LinkSetMaster", members[0], isBond)
+			mocked.AssertCalled(t, "LinkSetMaster", members[1], isBond)
+			mocked.AssertNotCalled(t, "LinkDel", mock.Anything)
+		})
+
+		It("Deletes the bond when a member cannot be enslaved", func() {
+			mocked.On("LinkAdd", mock.Anything).Return(nil)
+			mocked.On("LinkSetMaster", members[0], mock.Anything).Return(nil)
+			mocked.On("LinkSetMaster", members[1], mock.Anything).Return(fmt.Errorf("operation not permitted"))
+			mocked.On("LinkDel", mock.Anything).Return(nil)
+
+			sm := sriovManager{nLink: mocked}
+			err := sm.SetupBond(netconf, "net1", []string{"net1_0", "net1_1"}, targetNetNS)
+			Expect(err).To(MatchError(ContainSubstring("failed to enslave net1_1 to bond net1")))
+			mocked.AssertCalled(t, "LinkDel", mock.Anything)
+			mocked.AssertNotCalled(t, "LinkSetUp", mock.Anything)
+		})
+
+		It("Deletes the bond on ReleaseBond", func() {
+			bond := netlink.NewLinkBond(netlink.LinkAttrs{Name: "net1"})
+			mocked.On("LinkByName", "net1").Return(bond, nil)
+			mocked.On("LinkDel", bond).Return(nil)
+
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ReleaseBond("net1", targetNetNS)).To(Succeed())
+			mocked.AssertCalled(t, "LinkDel", bond)
+		})
+
+		It("Ignores a bond already deleted on ReleaseBond", func() {
+			mocked.On("LinkByName", "net1").Return(nil, fmt.Errorf("link not found"))
+
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ReleaseBond("net1", targetNetNS)).To(Succeed())
+			mocked.AssertNotCalled(t, "LinkDel", mock.Anything)
+		})
+	})
+	Context("Checking LocatePort function", func() {
+		It("Identifies the PF of a VF for the given duration", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("IdentifyPort", "enp175s0f1", 30).Return(nil)
+
+			sm := sriovManager{utils: mockedPciUtils}
+			pfName, err := sm.LocatePort("0000:af:06.0", 30)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pfName).To(Equal("enp175s0f1"))
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Identifies a PF given its own pci address", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("IdentifyPort", "enp175s0f1", 5).Return(nil)
+
+			sm := sriovManager{utils: mockedPciUtils}
+			pfName, err := sm.LocatePort("0000:af:00.1", 5)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pfName).To(Equal("enp175s0f1"))
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Fails for an unknown pci address", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+
+			sm := sriovManager{utils: mockedPciUtils}
+			_, err := sm.LocatePort("0000:ff:00.0", 5)
+			Expect(err).To(MatchError(utils.ErrDeviceNotFound))
+			mockedPciUtils.AssertNotCalled(t, "IdentifyPort", mock.Anything, mock.Anything)
+		})
+
+		It("Returns the identify error", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("IdentifyPort", "enp175s0f1", 5).Return(fmt.Errorf("operation not supported"))
+
+			sm := sriovManager{utils: mockedPciUtils}
+			_, err := sm.LocatePort("0000:af:06.0", 5)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking SelfTestVF function", func() {
+		It("Runs the self-test with the loopback feature enabled and restores it", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetLoopback", "enp175s6").Return(false, nil)
+			mockedPciUtils.On("SetLoopback", "enp175s6", true).Return(nil).Once()
+			mockedPciUtils.On("RunSelfTest", "enp175s6").Return(&utils.SelfTestResult{
+				Passed:  true,
+				Results: map[string]uint64{"Register test": 0, "Loopback test": 0},
+			}, nil)
+			mockedPciUtils.On("SetLoopback", "enp175s6", false).Return(nil).Once()
+
+			sm := sriovManager{utils: mockedPciUtils}
+			result, err := sm.SelfTestVF("0000:af:06.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Passed).To(BeTrue())
+			Expect(result.Results).To(HaveLen(2))
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Reports a failed self-test", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetLoopback", "enp175s6").Return(false, nil)
+			mockedPciUtils.On("SetLoopback", "enp175s6", true).Return(nil).Once()
+			mockedPciUtils.On("RunSelfTest", "enp175s6").Return(&utils.SelfTestResult{
+				Passed:  false,
+				Results: map[string]uint64{"Loopback test": 1},
+			}, nil)
+			mockedPciUtils.On("SetLoopback", "enp175s6", false).Return(nil).Once()
+
+			sm := sriovManager{utils: mockedPciUtils}
+			result, err := sm.SelfTestVF("0000:af:06.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Passed).To(BeFalse())
+			Expect(result.Results).To(HaveKeyWithValue("Loopback test", uint64(1)))
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Leaves an already enabled loopback feature enabled", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetLoopback", "enp175s6").Return(true, nil)
+			mockedPciUtils.On("RunSelfTest", "enp175s6").Return(&utils.SelfTestResult{Passed: true}, nil)
+
+			sm := sriovManager{utils: mockedPciUtils}
+			_, err := sm.SelfTestVF("0000:af:06.0")
+			Expect(err).NotTo(HaveOccurred())
+			mockedPciUtils.AssertNotCalled(t, "SetLoopback", mock.Anything, mock.Anything)
+		})
+
+		It("Restores the loopback feature when the self-test fails to run", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetLoopback", "enp175s6").Return(false, nil)
+			mockedPciUtils.On("SetLoopback", "enp175s6", true).Return(nil).Once()
+			mockedPciUtils.On("RunSelfTest", "enp175s6").Return(nil, fmt.Errorf("device has no self-test: %w", utils.ErrNotSupported))
+			mockedPciUtils.On("SetLoopback", "enp175s6", false).Return(nil).Once()
+
+			sm := sriovManager{utils: mockedPciUtils}
+			_, err := sm.SelfTestVF("0000:af:06.0")
+			Expect(err).To(MatchError(utils.ErrNotSupported))
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Fails when the VF has no loopback feature", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetLoopback", "enp175s6").Return(false, fmt.Errorf("no loopback feature: %w", utils.ErrNotSupported))
+
+			sm := sriovManager{utils: mockedPciUtils}
+			_, err := sm.SelfTestVF("0000:af:06.0")
+			Expect(err).To(MatchError(utils.ErrNotSupported))
+			mockedPciUtils.AssertNotCalled(t, "RunSelfTest", mock.Anything)
+		})
+	})
+	Context("Checking CaptureOnFailure function", func() {
+		var netconf *sriovtypes.NetConf
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:           "enp175s0f1",
+				DeviceID:         "0000:af:06.0",
+				VFID:             0,
+				CaptureOnFailure: true,
+				CaptureDir:       "/var/lib/cni/sriov/captures",
+			}}
+		})
+
+		It("Captures the VF packets when ADD failed", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("CapturePackets", "net1", mock.MatchedBy(func(path string) bool {
+				return strings.HasPrefix(path, "/var/lib/cni/sriov/captures/0000:af:06.0-") && strings.HasSuffix(path, ".pcap")
+			}), time.Second).Return(3, nil)
+			sm := sriovManager{utils: mockedPciUtils}
+			sm.CaptureOnFailure(netconf, "net1", targetNetNS, errors.New("IPAM failed"))
+			mockedPciUtils.AssertExpectations(t)
+		})
+
+		It("Does not capture when ADD succeeded", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mockedPciUtils := &mocks.PciUtils{}
+			sm := sriovManager{utils: mockedPciUtils}
+			sm.CaptureOnFailure(netconf, "net1", targetNetNS, nil)
+			mockedPciUtils.AssertNotCalled(t, "CapturePackets", mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("Does not capture when captureOnFailure is not set", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			netconf.CaptureOnFailure = false
+			mockedPciUtils := &mocks.PciUtils{}
+			sm := sriovManager{utils: mockedPciUtils}
+			sm.CaptureOnFailure(netconf, "net1", targetNetNS, errors.New("IPAM failed"))
+			mockedPciUtils.AssertNotCalled(t, "CapturePackets", mock.Anything, mock.Anything, mock.Anything)
+		})
+
+		It("Ignores a failed capture", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("CapturePackets", "net1", mock.Anything, time.Second).Return(0, errors.New("permission denied"))
+			sm := sriovManager{utils: mockedPciUtils}
+			sm.CaptureOnFailure(netconf, "net1", targetNetNS, errors.New("IPAM failed"))
+			mockedPciUtils.AssertExpectations(t)
+		})
+	})
+	Context("Checking CheckVFDevice function", func() {
+		var netconf *sriovtypes.NetConf
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				OrigVfState: sriovtypes.VfState{
+					HostIFName: "enp175s6",
+				},
+			}}
+		})
+
+		It("Succeeds when the sysfs devices of the VF and the PF exist", func() {
+			sm := sriovManager{}
+			Expect(sm.CheckVFDevice(netconf)).To(Succeed())
+		})
+
+		It("Returns ErrVFNotFound when the sysfs device of the VF is missing", func() {
+			netconf.DeviceID = "0000:af:07.7"
+			sm := sriovManager{}
+			err := sm.CheckVFDevice(netconf)
+			Expect(err).To(MatchError(ErrVFNotFound))
+			Expect(err).To(MatchError(ContainSubstring("the PF driver may be unloaded")))
+		})
+
+		It("Returns ErrVFNotFound when the PF netdev is missing", func() {
+			netconf.Master = "enp175s0f9"
+			sm := sriovManager{}
+			Expect(sm.CheckVFDevice(netconf)).To(MatchError(ErrVFNotFound))
+		})
+
+		It("Releases a VF whose sysfs device is missing without touching it", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			netconf.DeviceID = "0000:af:07.7"
+			mocked := &mocks_utils.NetlinkManager{}
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ReleaseVF(netconf, "net1", targetNetNS)).To(Succeed())
+			mocked.AssertNotCalled(t, "LinkByName", mock.Anything)
+		})
+	})
+	Context("Checking CheckPFNumVFs function", func() {
+		var netconf *sriovtypes.NetConf
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{SriovNetConf: sriovtypes.SriovNetConf{
+				Master:      "enp175s0f1",
+				DeviceID:    "0000:af:06.3",
+				VFID:        3,
+				OrigVfState: sriovtypes.VfState{PFNumVFs: 8},
+			}}
+		})
+
+		It("Succeeds when the number of VFs is unchanged", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetSriovNumVfs", "enp175s0f1").Return(8, nil)
+
+			sm := sriovManager{utils: mockedPciUtils}
+			Expect(sm.CheckPFNumVFs(netconf)).To(Succeed())
+		})
+
+		It("Succeeds when the number of VFs changed but the PF still has the VF", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetSriovNumVfs", "enp175s0f1").Return(4, nil)
+
+			sm := sriovManager{utils: mockedPciUtils}
+			Expect(sm.CheckPFNumVFs(netconf)).To(Succeed())
+		})
+
+		It("Returns ErrVFNotFound when the number of VFs shrank below the VF index", func() {
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetSriovNumVfs", "enp175s0f1").Return(2, nil)
+			mockedPciUtils.On("GetPFTotalVFs", "enp175s0f1").Return(64, nil)
+
+			sm := sriovManager{utils: mockedPciUtils}
+			err := sm.CheckPFNumVFs(netconf)
+			Expect(err).To(MatchError(ErrVFNotFound))
+			Expect(err).To(MatchError(ContainSubstring("changed from 8 to 2 out of 64 supported VFs")))
+		})
+
+		It("Returns ErrVFNotFound for a configuration cached without the number of VFs", func() {
+			netconf.OrigVfState.PFNumVFs = 0
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetSriovNumVfs", "enp175s0f1").Return(0, nil)
+			mockedPciUtils.On("GetPFTotalVFs", "enp175s0f1").Return(0, fmt.Errorf("no sriov_totalvfs"))
+
+			sm := sriovManager{utils: mockedPciUtils}
+			Expect(sm.CheckPFNumVFs(netconf)).To(MatchError(ErrVFNotFound))
+		})
+	})
+})
+
+// setFakeVfTrust applies the trust flag set by LinkSetVfTrust to the VF info of the fake PF link, like the driver does
+func setFakeVfTrust(args mock.Arguments) {
+	pfLink := args.Get(0).(*utils.FakeLink)
+	vfID, trust := args.Int(1), uint32(0)
+	if args.Bool(2) {
+		trust = 1
+	}
+	for i := range pfLink.Vfs {
+		if pfLink.Vfs[i].ID == vfID {
+			pfLink.Vfs[i].Trust = trust
+			return
+		}
+	}
+	pfLink.Vfs = append(pfLink.Vfs, netlink.VfInfo{ID: vfID, Trust: trust})
+}
@@ -3,8 +3,13 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+
 	"github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/vishvananda/netlink"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
 )
 
 const (
@@ -14,19 +19,75 @@ const (
 
 var VlanProtoInt = map[string]int{Proto8021q: 33024, Proto8021ad: 34984}
 
+// Policies applied to a max tx rate above the per-VF ceiling of the PF
+const (
+	RateCeilingReject = "reject"
+	RateCeilingClamp  = "clamp"
+)
+
+// Policies applied to min tx rates of the VFs of a PF exceeding its link speed
+const (
+	SchedulerCapacityWarn   = "warn"
+	SchedulerCapacityReject = "reject"
+)
+
+// MaxDrainDelay is the longest drain delay in milliseconds, it is kept well below the kubelet runtime request
+// timeout so that cmdDel does not time out.
+const MaxDrainDelay = 30000
+
+// MaxVNI is the largest VXLAN network identifier, VNIs are 24 bits long
+const MaxVNI = 1<<24 - 1
+
+// MaxRouteTable is the largest routing table id, table ids are 32 bits long
+const MaxRouteTable = 1<<32 - 1
+
+// MACAuto is the mac value deriving the VF MAC address from its pci address
+const MACAuto = "auto"
+
+// ModeMacvlanHost is the mode of VFs hosting MACVLAN interfaces, which requires spoofchk off and trust on
+const ModeMacvlanHost = "macvlan-host"
+
+// Scopes of the configuration reverted on cmdDel
+const (
+	ResetScopeAll    = "all"
+	ResetScopeL3Only = "l3only"
+	ResetScopeL2Only = "l2only"
+)
+
 // VfState represents the state of the VF
 type VfState struct {
-	HostIFName   string
-	SpoofChk     bool
-	Trust        bool
-	AdminMAC     string
-	EffectiveMAC string
-	Vlan         int
-	VlanQoS      int
-	VlanProto    int
-	MinTxRate    int
-	MaxTxRate    int
-	LinkState    uint32
+	HostIFName    string
+	SpoofChk      bool
+	Trust         bool
+	AdminMAC      string
+	EffectiveMAC  string
+	Vlan          int
+	VlanQoS       int
+	VlanProto     int
+	MinTxRate     int
+	MaxTxRate     int
+	LinkState     uint32
+	Driver        string
+	KernelDriver  string // kernel driver of the VF, known even when it was left bound to a userspace driver
+	NodeGUID      string // node GUID of an InfiniBand VF
+	PortGUID      string // port GUID of an InfiniBand VF
+	MaxMacChanges int
+	VlanAntiSpoof bool
+	AllMulti      bool            // allmulticast flag of the VF netdev
+	TxQLen        int             // transmit queue length of the VF netdev
+	PFNumVFs      int             // sriov_numvfs of the PF, the VF indices are only valid while it is unchanged
+	PrivFlags     map[string]bool // private flags of the VF netdev changed during cmdAdd, with their original values
+	// adaptive interrupt coalescing of the VF netdev changed during cmdAdd, with its original state
+	AdaptiveCoalesce *AdaptiveCoalesce
+	DCBPrioTC        []int // DCB priority to traffic class table of the VF netdev changed during cmdAdd, when supported
+	DCBTCBandwidth   []int // DCB ETS bandwidth of the traffic classes of the VF netdev changed during cmdAdd, when supported
+	DCBTCTSA         []int // DCB transmission selection algorithm of the traffic classes changed with DCBTCBandwidth
+}
+
+// AdaptiveCoalesce holds the adaptive interrupt coalescing state of a netdev
+type AdaptiveCoalesce struct {
+	Rx bool
+	Tx bool
 }
 
 // FillFromVfInfo - Fill attributes according to the provided netlink.VfInfo struct
@@ -42,6 +103,81 @@ func (vs *VfState) FillFromVfInfo(info *netlink.VfInfo) {
 	vs.Trust = info.Trust != 0
 }
 
+// MaxGcThresh is the largest neighbor table garbage collection threshold, the thresholds are ints
+const MaxGcThresh = 1<<31 - 1
+
+// Neigh holds the neighbor table garbage collection thresholds of the pod netns, applied to both the IPv4 ARP and
+// the IPv6 ND tables. Unset thresholds are left unchanged.
+type Neigh struct {
+	GcThresh1 int `json:"gcThresh1,omitempty"` // number of entries below which the garbage collector does not run
+	GcThresh2 int `json:"gcThresh2,omitempty"` // number of entries above which older entries are collected after 5s
+	GcThresh3 int `json:"gcThresh3,omitempty"` // maximum number of entries
+}
+
+// Sysctls returns the sysctls of the set thresholds, by name
+func (n *Neigh) Sysctls() map[string]string {
+	sysctls := map[string]string{}
+	for i, thresh := range []int{n.GcThresh1, n.GcThresh2, n.GcThresh3} {
+		if thresh == 0 {
+			continue
+		}
+		for _, family := range []string{"ipv4", "ipv6"} {
+			sysctls[fmt.Sprintf("net.%s.neigh.default.gc_thresh%d", family, i+1)] = strconv.Itoa(thresh)
+		}
+	}
+	return sysctls
+}
+
+// Route is a static route of the VF in the pod netns
+type Route struct {
+	Dst string `json:"dst"`          // destination CIDR
+	GW  string `json:"gw,omitempty"` // gateway, the route is a direct one without
+}
+
+// QueueRate holds the maximum transmit rate of a single VF tx queue
+type QueueRate struct {
+	Queue   int `json:"queue"`
+	MaxRate int `json:"maxRate"` // Mbps, 0 = disable rate limiting
+}
+
+// IngressPolice holds the policing applied to the traffic received by the VF netdev
+type IngressPolice struct {
+	Rate  int `json:"rate"`  // Mbps
+	Burst int `json:"burst"` // bytes
+}
+
+// IngressQoSMap holds the DCB mappings selecting the traffic class of the packets received by the VF netdev: the
+// DSCP of a packet selects its priority, the priority, also the PCP of a tagged packet, selects its traffic class
+type IngressQoSMap struct {
+	DSCP string `json:"dscp,omitempty"` // DSCP to priority mappings, e.g. "10:1,46:5"
+	PCP  string `json:"pcp,omitempty"`  // PCP to traffic class mappings, e.g. "1:1,5:2"
+}
+
+// ETSBandwidth holds the share of the transmit bandwidth allocated to a traffic class with DCB ETS
+type ETSBandwidth struct {
+	TC               int `json:"tc"`
+	BandwidthPercent int `json:"bandwidthPercent"`
+}
+
+// Bond modes of a bond created over VFs in the pod netns
+const (
+	BondModeActiveBackup = "active-backup"
+	BondMode8023AD       = "802.3ad"
+)
+
+// Bond holds the bond created in the pod netns over several VFs, for NIC redundancy
+type Bond struct {
+	Mode    string   `json:"mode"`             // active-backup|802.3ad
+	Members []string `json:"members"`          // pci addresses of the member VFs
+	IfName  string   `json:"ifName,omitempty"` // name of the bond interface, defaults to the CNI interface name
+}
+
+// RSS holds the receive side scaling configuration of the VF netdev
+type RSS struct {
+	HashKey    string `json:"hashKey,omitempty"`    // hex encoded RSS hash key
+	IndirTable []int  `json:"indirTable,omitempty"` // rx queue of each indirection table entry
+}
+
 type NetConf struct {
 	types.NetConf
 	SriovNetConf
@@ -49,26 +185,142 @@ type NetConf struct {
 
 // NetConf extends types.NetConf for sriov-cni
 type SriovNetConf struct {
-	OrigVfState   VfState // Stores the original VF state as it was prior to any operations done during cmdAdd flow
-	DPDKMode      bool    `json:"-"`
-	Master        string
-	MAC           string
-	MTU           *int    // interface MTU
-	Vlan          *int    `json:"vlan"`
-	VlanQoS       *int    `json:"vlanQoS"`
-	VlanProto     *string `json:"vlanProto"` // 802.1ad|802.1q
-	DeviceID      string  `json:"deviceID"`  // PCI address of a VF in valid sysfs format
-	VFID          int
-	MinTxRate     *int   `json:"min_tx_rate"`          // Mbps, 0 = disable rate limiting
-	MaxTxRate     *int   `json:"max_tx_rate"`          // Mbps, 0 = disable rate limiting
-	SpoofChk      string `json:"spoofchk,omitempty"`   // on|off
-	Trust         string `json:"trust,omitempty"`      // on|off
-	LinkState     string `json:"link_state,omitempty"` // auto|enable|disable
-	RuntimeConfig struct {
-		Mac string `json:"mac,omitempty"`
+	OrigVfState       VfState         // Stores the original VF state as it was prior to any operations done during cmdAdd flow
+	DPDKMode          bool            `json:"-"`
+	AddedAltMACs      []string        // Secondary MAC addresses added to the VF during cmdAdd, removed on cmdDel
+	AddedFdbMAC       string          // MAC address of the FDB entry added on the PF during cmdAdd, removed on cmdDel
+	AddResult         *current.Result // Result of the cmdAdd that configured the VF, returned to a retried cmdAdd
+	SchemaVersion     int             // Version of the cache format, set when the NetConf is cached
+	SiblingMinTxRates map[int]int     `json:"-"` // min_tx_rate of the other VFs of the PF in the cached NetConfs, by VF id
+	Master            string
+	MAC               string
+	MTU               *int    // interface MTU
+	Vlan              *int    `json:"vlan"`
+	VlanQoS           *int    `json:"vlanQoS"`
+	VlanProto         *string `json:"vlanProto"` // 802.1ad|802.1q
+	DeviceID          string  `json:"deviceID"`  // PCI address of a VF in valid sysfs format
+	VFID              int
+	MinTxRate         *int           `json:"min_tx_rate"`          // Mbps, 0 = disable rate limiting
+	MaxTxRate         *int           `json:"max_tx_rate"`          // Mbps, 0 = disable rate limiting
+	SpoofChk          string         `json:"spoofchk,omitempty"`   // on|off
+	Trust             string         `json:"trust,omitempty"`      // on|off
+	LinkState         string         `json:"link_state,omitempty"` // auto|enable|disable
+	QueueRates        []QueueRate    `json:"queueRates,omitempty"`
+	AltMACs           []string       `json:"altMACs,omitempty"`  // secondary unicast MAC addresses of the VF netdev
+	AllMulti          string         `json:"allMulti,omitempty"` // on|off
+	TxQLen            *int           `json:"txQLen,omitempty"`   // transmit queue length of the VF netdev in the pod netns
+	IngressPolice     *IngressPolice `json:"ingressPolice,omitempty"`
+	RuntimeConfig     struct {
+		Mac  string `json:"mac,omitempty"`
+		Vlan *int   `json:"vlan,omitempty"` // overrides vlan
 	} `json:"runtimeConfig,omitempty"`
-	LogLevel string `json:"logLevel,omitempty"`
-	LogFile  string `json:"logFile,omitempty"`
+	IfNameTemplate           string              `json:"ifNameTemplate,omitempty"` // name of the VF netdevice in the pod netns, %d is replaced by the VF index
+	LogLevel                 string              `json:"logLevel,omitempty"`
+	LogFile                  string              `json:"logFile,omitempty"`
+	LogFormat                string              `json:"logFormat,omitempty"`                // text or json
+	LogCaller                bool                `json:"logCaller,omitempty"`                // add the file:line of the log call to the debug records
+	LogToStderr              *bool               `json:"logToStderr,omitempty"`              // log to stderr in addition to logFile
+	LevelFiles               map[string]string   `json:"levelFiles,omitempty"`               // log level to the file its lines are also logged to
+	LogOptions               *logging.LogOptions `json:"logOptions,omitempty"`               // rotation settings of the log files
+	CheckUplinkVlan          bool                `json:"checkUplinkVlan,omitempty"`          // warn if the vlan is not carried by the PF uplink
+	AllowGuestVlan           *bool               `json:"allowGuestVlan,omitempty"`           // allow the guest to send frames with its own vlan tags, where supported
+	EnforceVlanExclusivity   bool                `json:"enforceVlanExclusivity,omitempty"`   // reject a vlan already set on another VF of the PF
+	DriverOverride           string              `json:"driverOverride,omitempty"`           // userspace driver to bind the VF to, e.g. vfio-pci
+	RebindOnDel              *bool               `json:"rebindOnDel,omitempty"`              // rebind the VF to its kernel driver on DEL, defaults to true
+	MacFromHostname          bool                `json:"macFromHostname,omitempty"`          // derive the MAC from the node hostname, the PF and the VF index
+	VerifyMAC                bool                `json:"verifyMAC,omitempty"`                // read back the admin and effective MAC of the VF on ADD and fail when they differ from the requested one
+	MACRegistry              bool                `json:"macRegistry,omitempty"`              // fail ADD when the MAC address is in use by another VF of the node
+	GlobalSerialize          bool                `json:"globalSerialize,omitempty"`          // serialize the VF operations of all the invocations on the node
+	CaptureOnFailure         bool                `json:"captureOnFailure,omitempty"`         // capture the VF packets to a pcap file when ADD fails with the VF in the pod
+	CaptureDir               string              `json:"captureDir,omitempty"`               // directory of the pcap files, defaults to <cacheDir>/captures
+	SkipMACConfig            *bool               `json:"skipMACConfig,omitempty"`            // leave the admin and effective MAC of the VF untouched
+	LegacyMACOrder           *bool               `json:"legacyMACOrder,omitempty"`           // set the admin MAC of the VF on the PF after the VF is moved to the pod netns
+	RSSHashKey               string              `json:"rssHashKey,omitempty"`               // hex encoded RSS hash key
+	RSS                      *RSS                `json:"rss,omitempty"`                      // RSS hash key and indirection table
+	ParallelReset            bool                `json:"parallelReset,omitempty"`            // restore the independent VF attributes concurrently on DEL
+	FullReset                *bool               `json:"fullReset,omitempty"`                // reset every VF attribute to its default on DEL, regardless of the cached configuration
+	ResetScope               string              `json:"resetScope,omitempty"`               // all|l3only|l2only, defaults to all
+	GUID                     string              `json:"guid,omitempty"`                     // node and port GUID of InfiniBand VFs
+	MaxMacChanges            *int                `json:"maxMacChanges,omitempty"`            // MAC changes allowed to a trusted VF, where supported
+	MetricsFile              string              `json:"metricsFile,omitempty"`              // OpenMetrics text file recording the ADD/DEL operations
+	FixLinkStateOnCheck      bool                `json:"fixLinkStateOnCheck,omitempty"`      // re-apply a drifted link state on CHECK
+	EnforceRateCeiling       string              `json:"enforceRateCeiling,omitempty"`       // reject|clamp a max_tx_rate above the PF per-VF ceiling
+	EnforceSchedulerCapacity string              `json:"enforceSchedulerCapacity,omitempty"` // warn|reject min_tx_rate guarantees of the VFs of the PF above its link speed
+	DrainDelay               int                 `json:"drainDelay,omitempty"`               // milliseconds the VF is kept configured on DEL before it is reset
+	SignalDownOnDel          bool                `json:"signalDownOnDel,omitempty"`          // set the VF link down at the start of cmdDel, before it is drained and reset
+	VerifyAllocation         bool                `json:"verifyAllocation,omitempty"`         // reject a deviceID the device plugin did not allocate to the pod
+	EgressQoSMap             string              `json:"egressQoSMap,omitempty"`             // skb priority to VLAN PCP mappings of the VF netdev, e.g. "0:1,2:3"
+	IngressQoSMap            *IngressQoSMap      `json:"ingressQoSMap,omitempty"`            // DCB DSCP and PCP to traffic class mappings of the received packets
+	ETS                      []ETSBandwidth      `json:"ets,omitempty"`                      // DCB ETS bandwidth allocation of the traffic classes of the VF netdev
+	MicroburstProtection     bool                `json:"microburstProtection,omitempty"`     // smooth rx/tx bursts with driver moderation features, where supported
+	IRQAffinity              *bool               `json:"irqAffinity,omitempty"`              // pin the VF MSI-X vectors to the CPUs of its local NUMA node
+	CacheDir                 string              `json:"cacheDir,omitempty"`                 // directory of the cached NetConf and PCI allocations, defaults to /var/lib/cni/sriov
+	BinaryCache              bool                `json:"binaryCache,omitempty"`              // also cache the NetConf as a gob state file, preferred over the JSON file on DEL
+	IPAMDataDir              string              `json:"ipamDataDir,omitempty"`              // data dir passed to the IPAM plugin when its ipam config sets none
+	WaitForLinkUp            *bool               `json:"waitForLinkUp,omitempty"`            // wait for the VF link to be up before returning from ADD
+	NetlinkTimeout           *int                `json:"netlinkTimeout,omitempty"`           // seconds a netlink operation may take before it is aborted
+	LinkUpTimeout            *int                `json:"linkUpTimeout,omitempty"`            // seconds to wait for the VF link to be up
+	Mode                     string              `json:"mode,omitempty"`                     // macvlan-host sets spoofchk off and trust on
+	SpoofChkFollowsTrust     *bool               `json:"spoofChkFollowsTrust,omitempty"`     // unset spoofchk is off when trust is on and on when trust is off
+	Sysctls                  map[string]string   `json:"sysctls,omitempty"`                  // interface sysctls applied to the VF netdev in the pod netns, e.g. net.ipv6.conf.IFNAME.accept_ra
+	Neigh                    *Neigh              `json:"neigh,omitempty"`                    // neighbor table garbage collection thresholds of the pod netns
+	PrivFlags                map[string]bool     `json:"privFlags,omitempty"`                // ethtool private flags of the VF netdev, by name
+	RepresentorVlan          *int                `json:"representorVlan,omitempty"`          // vlan set as untagged pvid on the bridge port of the VF representor, in switchdev mode
+	FdbVNI                   *int                `json:"fdbVni,omitempty"`                   // VNI tag of an FDB entry of the VF MAC programmed on the PF, for EVPN setups
+	RouteTable               *int                `json:"routeTable,omitempty"`               // routing table of the VF routes and of the source rules of its IPs, in the pod netns
+	Routes                   []Route             `json:"routes,omitempty"`                   // static routes of the VF added after the IPAM routes
+	ManageRepresentor        *bool               `json:"manageRepresentor,omitempty"`        // set the VF representor up on ADD and down on DEL, with link_state enable
+	QuarantineHostRepOnDel   bool                `json:"quarantineHostRepOnDel,omitempty"`   // leave the VF representor down on DEL until it is reclaimed
+	Passthrough              *bool               `json:"passthrough,omitempty"`              // move and rename the VF without configuring its attributes
+	Bond                     *Bond               `json:"bond,omitempty"`                     // bond the member VFs in the pod netns instead of configuring deviceID
+	KeepInHostNetns          *bool               `json:"keepInHostNetns,omitempty"`          // configure the VF in the host netns under its ifNameTemplate name instead of moving it
+}
+
+// RebindsOnDel returns true if the VF bound to driverOverride is rebound to its kernel driver on cmdDel
+func (n *SriovNetConf) RebindsOnDel() bool {
+	return n.RebindOnDel == nil || *n.RebindOnDel
+}
+
+// SkipsMACConfig returns true if the admin and effective MAC of the VF are left untouched
+func (n *SriovNetConf) SkipsMACConfig() bool {
+	return (n.SkipMACConfig != nil && *n.SkipMACConfig) || n.IsPassthrough()
+}
+
+// UsesLegacyMACOrder returns true if the admin MAC of the VF is set on the PF once the VF is moved to the pod netns,
+// instead of before the move
+func (n *SriovNetConf) UsesLegacyMACOrder() bool {
+	return n.LegacyMACOrder != nil && *n.LegacyMACOrder
+}
+
+// KeepsInHostNetns returns true if the VF is renamed and configured in the host netns instead of being moved to the
+// pod netns
+func (n *SriovNetConf) KeepsInHostNetns() bool {
+	return n.KeepInHostNetns != nil && *n.KeepInHostNetns
+}
+
+// IsPassthrough returns true if the VF is moved to the pod netns and renamed without configuring its attributes
+func (n *SriovNetConf) IsPassthrough() bool {
+	return n.Passthrough != nil && *n.Passthrough
+}
+
+// FullResets returns true if the VF is reset to its factory defaults on cmdDel
+func (n *SriovNetConf) FullResets() bool {
+	return n.FullReset != nil && *n.FullReset
+}
+
+// ManagesRepresentor returns true if the admin state of the VF representor follows the VF
+func (n *SriovNetConf) ManagesRepresentor() bool {
+	return n.ManageRepresentor != nil && *n.ManageRepresentor
+}
+
+// ResetsL2 returns true if the VF L2 attributes are reverted on cmdDel
+func (n *SriovNetConf) ResetsL2() bool {
+	return n.ResetScope != ResetScopeL3Only
+}
+
+// ResetsL3 returns true if the VF IP configuration is reverted on cmdDel
+func (n *SriovNetConf) ResetsL3() bool {
+	return n.ResetScope != ResetScopeL2Only
 }
 
 func (n *NetConf) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// pcapSnapLen is the largest number of bytes of a packet recorded in a capture
+	pcapSnapLen = 65535
+	// pcapLinkTypeEthernet is the pcap link type of the Ethernet frames
+	pcapLinkTypeEthernet = 1
+	// maxCapturePackets is the largest number of packets recorded in a capture, to keep it brief on a busy VF
+	maxCapturePackets = 1000
+)
+
+// writePcapHeader writes the pcap global header of a capture of Ethernet frames
+func writePcapHeader(w io.Writer) error {
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:4], 0xa1b2c3d4) // magic of the microsecond timestamps
+	binary.LittleEndian.PutUint16(hdr[4:6], 2)          // version 2.4
+	binary.LittleEndian.PutUint16(hdr[6:8], 4)
+	binary.LittleEndian.PutUint32(hdr[16:20], pcapSnapLen)
+	binary.LittleEndian.PutUint32(hdr[20:24], pcapLinkTypeEthernet)
+	_, err := w.Write(hdr)
+	return err
+}
+
+// writePcapRecord writes a pcap record of the packet data received at ts, origLen is the length of the packet before
+// it was truncated to data
+func writePcapRecord(w io.Writer, ts time.Time, data []byte, origLen int) error {
+	hdr := make([]byte, 16)
+	binary.LittleEndian.PutUint32(hdr[0:4], uint32(ts.Unix()))
+	binary.LittleEndian.PutUint32(hdr[4:8], uint32(ts.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(hdr[8:12], uint32(len(data)))
+	binary.LittleEndian.PutUint32(hdr[12:16], uint32(origLen))
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// CapturePackets records the packets sent and received by the netdev of the current netns for the duration, or
+// until maxCapturePackets are recorded, to a pcap file at path. It returns the number of recorded packets.
+func CapturePackets(ifName, path string, duration time.Duration) (int, error) {
+	iface, err := net.InterfaceByName(ifName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find netdev %s: %v", ifName, err)
+	}
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, int(htons(unix.ETH_P_ALL)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create AF_PACKET raw socket: %v", err)
+	}
+	defer unix.Close(fd)
+	if err := unix.Bind(fd, &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ALL), Ifindex: iface.Index}); err != nil {
+		return 0, fmt.Errorf("failed to bind AF_PACKET socket to %s: %v", ifName, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return 0, fmt.Errorf("failed to create the capture directory(%q): %v", filepath.Dir(path), err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create the capture file %s: %v", path, err)
+	}
+	defer f.Close()
+	if err := writePcapHeader(f); err != nil {
+		return 0, fmt.Errorf("failed to write the capture file %s: %v", path, err)
+	}
+
+	buf := make([]byte, pcapSnapLen)
+	deadline := time.Now().Add(duration)
+	count := 0
+	for count < maxCapturePackets {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		tv := unix.NsecToTimeval(remaining.Nanoseconds())
+		if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+			return count, fmt.Errorf("failed to set the AF_PACKET socket timeout: %v", err)
+		}
+		// MSG_TRUNC returns the length of the packet even when it is larger than buf
+		n, _, err := unix.Recvfrom(fd, buf, unix.MSG_TRUNC)
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return count, fmt.Errorf("failed to receive from %s: %v", ifName, err)
+		}
+		if err := writePcapRecord(f, time.Now(), buf[:min(n, len(buf))], n); err != nil {
+			return count, fmt.Errorf("failed to write the capture file %s: %v", path, err)
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Capture", func() {
+	It("Writes a pcap header and records", func() {
+		var buf bytes.Buffer
+		Expect(writePcapHeader(&buf)).To(Succeed())
+		ts := time.Unix(1700000000, 123456000)
+		Expect(writePcapRecord(&buf, ts, []byte{0xde, 0xad}, 60)).To(Succeed())
+
+		b := buf.Bytes()
+		Expect(b).To(HaveLen(24 + 16 + 2))
+		Expect(binary.LittleEndian.Uint32(b[0:4])).To(Equal(uint32(0xa1b2c3d4)))
+		Expect(binary.LittleEndian.Uint16(b[4:6])).To(Equal(uint16(2)))
+		Expect(binary.LittleEndian.Uint16(b[6:8])).To(Equal(uint16(4)))
+		Expect(binary.LittleEndian.Uint32(b[16:20])).To(Equal(uint32(pcapSnapLen)))
+		Expect(binary.LittleEndian.Uint32(b[20:24])).To(Equal(uint32(pcapLinkTypeEthernet)))
+
+		record := b[24:]
+		Expect(binary.LittleEndian.Uint32(record[0:4])).To(Equal(uint32(1700000000)))
+		Expect(binary.LittleEndian.Uint32(record[4:8])).To(Equal(uint32(123456)))
+		Expect(binary.LittleEndian.Uint32(record[8:12])).To(Equal(uint32(2)))
+		Expect(binary.LittleEndian.Uint32(record[12:16])).To(Equal(uint32(60)))
+		Expect(record[16:]).To(Equal([]byte{0xde, 0xad}))
+	})
+
+	It("Fails to capture on a missing netdev", func() {
+		_, err := CapturePackets("missing0", "/tmp/missing0.pcap", time.Millisecond)
+		Expect(err).To(MatchError(ContainSubstring("failed to find netdev missing0")))
+	})
+})
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// KubeletCheckpointFile is the checkpoint of the kubelet device manager, holding the devices allocated to each pod
+var KubeletCheckpointFile = "/var/lib/kubelet/device-plugins/kubelet_internal_checkpoint"
+
+// podDevicesEntry is a device allocation of a pod container in the kubelet checkpoint
+type podDevicesEntry struct {
+	PodUID        string
+	ContainerName string
+	ResourceName  string
+	// DeviceIDs is a list of device IDs, or since Kubernetes 1.20 a map of device IDs by NUMA node
+	DeviceIDs json.RawMessage
+}
+
+type kubeletCheckpoint struct {
+	Data struct {
+		PodDeviceEntries []podDevicesEntry
+	}
+}
+
+// deviceIDs returns the device IDs of an allocation in either of the checkpoint formats
+func (e *podDevicesEntry) deviceIDs() ([]string, error) {
+	var ids []string
+	if err := json.Unmarshal(e.DeviceIDs, &ids); err == nil {
+		return ids, nil
+	}
+
+	byNUMA := map[string][]string{}
+	if err := json.Unmarshal(e.DeviceIDs, &byNUMA); err != nil {
+		return nil, fmt.Errorf("failed to parse the devices of resource %s: %v", e.ResourceName, err)
+	}
+	for _, numaIDs := range byNUMA {
+		ids = append(ids, numaIDs...)
+	}
+	return ids, nil
+}
+
+// IsDeviceAllocatedToPod returns true if the device plugins allocated the device to a container of the pod,
+// according to the kubelet checkpoint file
+func IsDeviceAllocatedToPod(checkpointFile, podUID, deviceID string) (bool, error) {
+	data, err := os.ReadFile(checkpointFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the kubelet checkpoint %s: %v", checkpointFile, err)
+	}
+
+	checkpoint := &kubeletCheckpoint{}
+	if err = json.Unmarshal(data, checkpoint); err != nil {
+		return false, fmt.Errorf("failed to parse the kubelet checkpoint %s: %v", checkpointFile, err)
+	}
+
+	for i := range checkpoint.Data.PodDeviceEntries {
+		entry := &checkpoint.Data.PodDeviceEntries[i]
+		if entry.PodUID != podUID {
+			continue
+		}
+		ids, err := entry.deviceIDs()
+		if err != nil {
+			return false, err
+		}
+		for _, id := range ids {
+			if id == deviceID {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checkpoint", func() {
+	var checkpointFile string
+
+	BeforeEach(func() {
+		checkpointFile = filepath.Join(ts.dirRoot, "kubelet_internal_checkpoint")
+	})
+
+	AfterEach(func() {
+		_ = os.Remove(checkpointFile)
+	})
+
+	Context("IsDeviceAllocatedToPod", func() {
+		It("Assuming the device is allocated to the pod, by NUMA node", func() {
+			Expect(os.WriteFile(checkpointFile, []byte(`{"Data":{"PodDeviceEntries":[
+				{"PodUID":"pod-a","ContainerName":"app","ResourceName":"intel.com/sriov","DeviceIDs":{"0":["0000:af:06.0"],"1":["0000:af:06.1"]}}
+			],"RegisteredDevices":{}},"Checksum":1}`), 0600)).To(Succeed())
+
+			allocated, err := IsDeviceAllocatedToPod(checkpointFile, "pod-a", "0000:af:06.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allocated).To(BeTrue())
+		})
+
+		It("Assuming the device is allocated to the pod, as a list", func() {
+			Expect(os.WriteFile(checkpointFile, []byte(`{"Data":{"PodDeviceEntries":[
+				{"PodUID":"pod-a","ContainerName":"app","ResourceName":"intel.com/sriov","DeviceIDs":["0000:af:06.0"]}
+			]}}`), 0600)).To(Succeed())
+
+			allocated, err := IsDeviceAllocatedToPod(checkpointFile, "pod-a", "0000:af:06.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allocated).To(BeTrue())
+		})
+
+		It("Assuming the device is allocated to another pod", func() {
+			Expect(os.WriteFile(checkpointFile, []byte(`{"Data":{"PodDeviceEntries":[
+				{"PodUID":"pod-a","ContainerName":"app","ResourceName":"intel.com/sriov","DeviceIDs":{"0":["0000:af:06.0"]}},
+				{"PodUID":"pod-b","ContainerName":"app","ResourceName":"intel.com/sriov","DeviceIDs":{"0":["0000:af:06.1"]}}
+			]}}`), 0600)).To(Succeed())
+
+			allocated, err := IsDeviceAllocatedToPod(checkpointFile, "pod-a", "0000:af:06.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allocated).To(BeFalse())
+		})
+
+		It("Assuming the checkpoint does not exist", func() {
+			_, err := IsDeviceAllocatedToPod(checkpointFile, "pod-a", "0000:af:06.0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
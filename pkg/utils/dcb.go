@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// DCB netlink (dcbnl) definitions of linux/dcbnl.h, which the netlink library does not implement
+const (
+	dcbCmdIEEESet = 20
+	dcbCmdIEEEGet = 21
+	dcbCmdIEEEDel = 27
+
+	dcbAttrIfname = 1
+	dcbAttrIEEE   = 13
+
+	dcbAttrIEEEETS      = 1
+	dcbAttrIEEEAppTable = 3
+	dcbAttrIEEEApp      = 1
+
+	// DCBAppSelDSCP is the selector of the DCB APP entries mapping a DSCP to a priority
+	DCBAppSelDSCP = 5
+	// DCBMaxTCs is the largest number of traffic classes of a DCB capable netdev
+	DCBMaxTCs = 8
+	// DCBTSAETS is the transmission selection algorithm of the traffic classes sharing the bandwidth with ETS
+	DCBTSAETS = 2
+
+	dcbETSLen = 3 + 7*DCBMaxTCs
+	dcbAppLen = 4
+)
+
+// DCBETS is the IEEE 802.1Qaz ETS configuration of a netdev, the struct ieee_ets of linux/dcbnl.h
+type DCBETS struct {
+	Willing    uint8
+	ETSCap     uint8 // number of traffic classes supported by the netdev
+	CBS        uint8
+	TCTxBW     [DCBMaxTCs]uint8
+	TCRxBW     [DCBMaxTCs]uint8
+	TCTSA      [DCBMaxTCs]uint8
+	PrioTC     [DCBMaxTCs]uint8 // traffic class of each priority
+	TCRecoBW   [DCBMaxTCs]uint8
+	TCRecoTSA  [DCBMaxTCs]uint8
+	RecoPrioTC [DCBMaxTCs]uint8
+}
+
+func (e *DCBETS) serialize() []byte {
+	b := make([]byte, 0, dcbETSLen)
+	b = append(b, e.Willing, e.ETSCap, e.CBS)
+	for _, a := range [][DCBMaxTCs]uint8{e.TCTxBW, e.TCRxBW, e.TCTSA, e.PrioTC, e.TCRecoBW, e.TCRecoTSA, e.RecoPrioTC} {
+		b = append(b, a[:]...)
+	}
+	return b
+}
+
+func parseDCBETS(b []byte) (*DCBETS, error) {
+	if len(b) < dcbETSLen {
+		return nil, fmt.Errorf("short DCB ETS attribute of %d bytes", len(b))
+	}
+	e := &DCBETS{Willing: b[0], ETSCap: b[1], CBS: b[2]}
+	for i, a := range []*[DCBMaxTCs]uint8{&e.TCTxBW, &e.TCRxBW, &e.TCTSA, &e.PrioTC, &e.TCRecoBW, &e.TCRecoTSA, &e.RecoPrioTC} {
+		copy(a[:], b[3+i*DCBMaxTCs:])
+	}
+	return e, nil
+}
+
+// DCBApp is a DCB APP table entry of a netdev, the struct dcb_app of linux/dcbnl.h
+type DCBApp struct {
+	Selector uint8
+	Priority uint8
+	Protocol uint16 // e.g. the DSCP for the DCBAppSelDSCP selector
+}
+
+// dcbMsg is the struct dcbmsg header of the DCB netlink messages
+type dcbMsg struct {
+	family uint8
+	cmd    uint8
+}
+
+func (m *dcbMsg) Len() int {
+	return 4
+}
+
+func (m *dcbMsg) Serialize() []byte {
+	return []byte{m.family, m.cmd, 0, 0}
+}
+
+// dcbRequest sends a DCB IEEE command for the netdev and returns the attributes of the DCB_ATTR_IEEE
+// attribute of the reply. The get command fails with ErrNotSupported when the netdev has no DCB support.
+func dcbRequest(ifName string, cmd uint8, ieee *nl.RtAttr) (map[uint16][]byte, error) {
+	req := nl.NewNetlinkRequest(unix.RTM_GETDCB, 0)
+	if cmd != dcbCmdIEEEGet {
+		req = nl.NewNetlinkRequest(unix.RTM_SETDCB, 0)
+	}
+	req.AddData(&dcbMsg{family: unix.AF_UNSPEC, cmd: cmd})
+	req.AddData(nl.NewRtAttr(dcbAttrIfname, nl.ZeroTerminated(ifName)))
+	if ieee != nil {
+		req.AddData(ieee)
+	}
+
+	msgs, err := req.Execute(unix.NETLINK_ROUTE, 0)
+	if err != nil {
+		if errors.Is(err, unix.EOPNOTSUPP) {
+			return nil, fmt.Errorf("failed to run DCB command %d on %s: %w", cmd, ifName, ErrNotSupported)
+		}
+		return nil, fmt.Errorf("failed to run DCB command %d on %s: %v", cmd, ifName, err)
+	}
+	if len(msgs) == 0 || len(msgs[0]) < 4 {
+		return nil, fmt.Errorf("no reply to DCB command %d on %s", cmd, ifName)
+	}
+
+	attrs, err := nl.ParseRouteAttr(msgs[0][4:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the reply to DCB command %d on %s: %v", cmd, ifName, err)
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK != dcbAttrIEEE {
+			continue
+		}
+		if cmd != dcbCmdIEEEGet {
+			// the set and del commands report the status as a negative errno byte
+			if len(attr.Value) > 0 && attr.Value[0] != 0 {
+				errno := syscall.Errno(-int8(attr.Value[0]))
+				if errno == unix.EOPNOTSUPP {
+					return nil, fmt.Errorf("failed to run DCB command %d on %s: %w", cmd, ifName, ErrNotSupported)
+				}
+				return nil, fmt.Errorf("failed to run DCB command %d on %s: %v", cmd, ifName, errno)
+			}
+			return nil, nil
+		}
+		nested, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the DCB attributes of %s: %v", ifName, err)
+		}
+		ieeeAttrs := map[uint16][]byte{}
+		for _, a := range nested {
+			ieeeAttrs[a.Attr.Type&nl.NLA_TYPE_MASK] = a.Value
+		}
+		return ieeeAttrs, nil
+	}
+	return nil, fmt.Errorf("no DCB attributes in the reply to DCB command %d on %s", cmd, ifName)
+}
+
+// GetDCBETS returns the ETS configuration of the netdev
+func GetDCBETS(ifName string) (*DCBETS, error) {
+	attrs, err := dcbRequest(ifName, dcbCmdIEEEGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	ets, ok := attrs[dcbAttrIEEEETS]
+	if !ok {
+		return nil, fmt.Errorf("failed to get the DCB ETS configuration of %s: %w", ifName, ErrNotSupported)
+	}
+	return parseDCBETS(ets)
+}
+
+// SetDCBETS sets the ETS configuration of the netdev
+func SetDCBETS(ifName string, ets *DCBETS) error {
+	ieee := nl.NewRtAttr(unix.NLA_F_NESTED|dcbAttrIEEE, nil)
+	ieee.AddRtAttr(dcbAttrIEEEETS, ets.serialize())
+	_, err := dcbRequest(ifName, dcbCmdIEEESet, ieee)
+	return err
+}
+
+func dcbAppTable(apps []DCBApp) *nl.RtAttr {
+	ieee := nl.NewRtAttr(unix.NLA_F_NESTED|dcbAttrIEEE, nil)
+	table := ieee.AddRtAttr(unix.NLA_F_NESTED|dcbAttrIEEEAppTable, nil)
+	for _, app := range apps {
+		b := make([]byte, dcbAppLen)
+		b[0] = app.Selector
+		b[1] = app.Priority
+		nl.NativeEndian().PutUint16(b[2:], app.Protocol)
+		table.AddRtAttr(dcbAttrIEEEApp, b)
+	}
+	return ieee
+}
+
+// AddDCBApps adds the entries to the APP table of the netdev
+func AddDCBApps(ifName string, apps []DCBApp) error {
+	_, err := dcbRequest(ifName, dcbCmdIEEESet, dcbAppTable(apps))
+	return err
+}
+
+// DelDCBApps removes the entries from the APP table of the netdev
+func DelDCBApps(ifName string, apps []DCBApp) error {
+	_, err := dcbRequest(ifName, dcbCmdIEEEDel, dcbAppTable(apps))
+	return err
+}
@@ -0,0 +1,440 @@
+package utils
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// ethtoolRxfhHdrLen is the size of struct ethtool_rxfh without the trailing rss_config array
+	ethtoolRxfhHdrLen = 24
+	// ethtoolRxfhIndirNoChange tells ETHTOOL_SRSSH to leave the indirection table unchanged
+	ethtoolRxfhIndirNoChange = 0xffffffff
+	// ethtoolGStringLen is the length of a string of an ethtool string set
+	ethtoolGStringLen = 32
+	// ethtoolSSTest is the ETH_SS_TEST string set of the self-test names
+	ethtoolSSTest = 0
+	// ethtoolSSPrivFlags is the ETH_SS_PRIV_FLAGS string set of the private flag names
+	ethtoolSSPrivFlags = 2
+	// ethtoolSSFeatures is the ETH_SS_FEATURES string set of the feature names
+	ethtoolSSFeatures = 4
+	// ethtoolFeatureLoopback is the name of the loopback feature, ethtool -K loopback
+	ethtoolFeatureLoopback = "loopback"
+	// ethtoolTestFlOffline and ethtoolTestFlFailed are the ETH_TEST_FL_OFFLINE and ETH_TEST_FL_FAILED flags of
+	// struct ethtool_test
+	ethtoolTestFlOffline = 1 << 0
+	ethtoolTestFlFailed  = 1 << 1
+	// ethtoolCoalesceLen is the size of struct ethtool_coalesce, 23 __u32 members
+	ethtoolCoalesceLen = 92
+	// ethtoolCoalesceAdaptiveRx and ethtoolCoalesceAdaptiveTx are the offsets of use_adaptive_rx_coalesce and
+	// use_adaptive_tx_coalesce in struct ethtool_coalesce
+	ethtoolCoalesceAdaptiveRx = 40
+	ethtoolCoalesceAdaptiveTx = 44
+)
+
+// ethtoolIfreq is struct ifreq with the ifr_data member used by SIOCETHTOOL
+type ethtoolIfreq struct {
+	name [unix.IFNAMSIZ]byte
+	data uintptr
+}
+
+// ethtoolIoctl issues a SIOCETHTOOL ioctl for netdev with data as the ethtool command buffer.
+// The socket is created in the current network namespace.
+func ethtoolIoctl(ifName string, data []byte) error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to create ethtool socket: %v", err)
+	}
+	defer unix.Close(fd)
+
+	ifr := ethtoolIfreq{data: uintptr(unsafe.Pointer(&data[0]))}
+	copy(ifr.name[:unix.IFNAMSIZ-1], ifName)
+
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&ifr)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// getRxfh reads the RSS indirection table size and hash key size of netdev
+func getRxfh(ifName string) (indirSize, keySize uint32, err error) {
+	buf := make([]byte, ethtoolRxfhHdrLen)
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_GRSSH)
+	if err := ethtoolIoctl(ifName, buf); err != nil {
+		return 0, 0, fmt.Errorf("failed to get RSS configuration of device %q: %v", ifName, err)
+	}
+	return binary.NativeEndian.Uint32(buf[8:]), binary.NativeEndian.Uint32(buf[12:]), nil
+}
+
+// ParseRSSHashKey parses an RSS hash key given as a hex string, optionally colon separated as printed by ethtool
+func ParseRSSHashKey(key string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.ReplaceAll(key, ":", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RSS hash key %q: %v", key, err)
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("invalid RSS hash key %q: key is empty", key)
+	}
+	return b, nil
+}
+
+// GetRSSHashKeySize returns the RSS hash key size, in bytes, of netdev
+func GetRSSHashKeySize(ifName string) (int, error) {
+	_, keySize, err := getRxfh(ifName)
+	if err != nil {
+		return 0, err
+	}
+	return int(keySize), nil
+}
+
+// SetRSSHashKey sets the RSS hash key of netdev leaving its indirection table unchanged
+func SetRSSHashKey(ifName string, key []byte) error {
+	buf := make([]byte, ethtoolRxfhHdrLen+len(key))
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_SRSSH)
+	binary.NativeEndian.PutUint32(buf[8:], ethtoolRxfhIndirNoChange)
+	binary.NativeEndian.PutUint32(buf[12:], uint32(len(key)))
+	copy(buf[ethtoolRxfhHdrLen:], key)
+	if err := ethtoolIoctl(ifName, buf); err != nil {
+		return fmt.Errorf("failed to set RSS hash key of device %q: %v", ifName, err)
+	}
+	return nil
+}
+
+// GetRSSIndirSize returns the RSS indirection table size of netdev
+func GetRSSIndirSize(ifName string) (int, error) {
+	indirSize, _, err := getRxfh(ifName)
+	if err != nil {
+		return 0, err
+	}
+	return int(indirSize), nil
+}
+
+// SetRSSIndirTable sets the RSS indirection table of netdev leaving its hash key unchanged.
+// The table must have the indirection table size of the driver.
+func SetRSSIndirTable(ifName string, table []uint32) error {
+	buf := make([]byte, ethtoolRxfhHdrLen+4*len(table))
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_SRSSH)
+	binary.NativeEndian.PutUint32(buf[8:], uint32(len(table)))
+	for i, queue := range table {
+		binary.NativeEndian.PutUint32(buf[ethtoolRxfhHdrLen+4*i:], queue)
+	}
+	if err := ethtoolIoctl(ifName, buf); err != nil {
+		return fmt.Errorf("failed to set RSS indirection table of device %q: %v", ifName, err)
+	}
+	return nil
+}
+
+// DrvInfo holds the driver information of a netdev as reported by ethtool -i
+type DrvInfo struct {
+	Driver    string
+	Version   string
+	FwVersion string
+	BusInfo   string
+}
+
+// GetDrvInfo returns the driver information of netdev. The socket is created in the current network namespace.
+func GetDrvInfo(ifName string) (*DrvInfo, error) {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ethtool socket: %v", err)
+	}
+	defer unix.Close(fd)
+
+	info, err := unix.IoctlGetEthtoolDrvinfo(fd, ifName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get driver information of device %q: %v", ifName, err)
+	}
+	return drvInfoFromEthtool(info), nil
+}
+
+// drvInfoFromEthtool converts the NUL terminated fields of struct ethtool_drvinfo
+func drvInfoFromEthtool(info *unix.EthtoolDrvinfo) *DrvInfo {
+	return &DrvInfo{
+		Driver:    unix.ByteSliceToString(info.Driver[:]),
+		Version:   unix.ByteSliceToString(info.Version[:]),
+		FwVersion: unix.ByteSliceToString(info.Fw_version[:]),
+		BusInfo:   unix.ByteSliceToString(info.Bus_info[:]),
+	}
+}
+
+// getStringSet returns the strings of the ethtool string set of netdev, in the bit order of the driver. desc names
+// the string set in the errors.
+func getStringSet(ifName string, set uint32, desc string) ([]string, error) {
+	// struct ethtool_sset_info with room for a single count in its data array
+	buf := make([]byte, 20)
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_GSSET_INFO)
+	binary.NativeEndian.PutUint64(buf[8:], 1<<set)
+	if err := ethtoolIoctl(ifName, buf); err != nil {
+		return nil, fmt.Errorf("failed to get %s count of device %q: %v", desc, ifName, err)
+	}
+	if binary.NativeEndian.Uint64(buf[8:])&(1<<set) == 0 {
+		return nil, nil
+	}
+	count := binary.NativeEndian.Uint32(buf[16:])
+	if count == 0 {
+		return nil, nil
+	}
+
+	// struct ethtool_gstrings
+	buf = make([]byte, 12+count*ethtoolGStringLen)
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_GSTRINGS)
+	binary.NativeEndian.PutUint32(buf[4:], set)
+	binary.NativeEndian.PutUint32(buf[8:], count)
+	if err := ethtoolIoctl(ifName, buf); err != nil {
+		return nil, fmt.Errorf("failed to get %s names of device %q: %v", desc, ifName, err)
+	}
+	names := make([]string, count)
+	for i := range names {
+		off := 12 + i*ethtoolGStringLen
+		names[i] = unix.ByteSliceToString(buf[off : off+ethtoolGStringLen])
+	}
+	return names, nil
+}
+
+// getPrivFlagNames returns the private flag names of netdev, in the bit order of the driver
+func getPrivFlagNames(ifName string) ([]string, error) {
+	return getStringSet(ifName, ethtoolSSPrivFlags, "private flag")
+}
+
+// getPrivFlagsMask returns the private flags bitmask of netdev
+func getPrivFlagsMask(ifName string) (uint32, error) {
+	// struct ethtool_value
+	buf := make([]byte, 8)
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_GPFLAGS)
+	if err := ethtoolIoctl(ifName, buf); err != nil {
+		return 0, fmt.Errorf("failed to get private flags of device %q: %v", ifName, err)
+	}
+	return binary.NativeEndian.Uint32(buf[4:]), nil
+}
+
+// privFlagsFromMask maps a private flags bitmask to the flag names
+func privFlagsFromMask(names []string, mask uint32) map[string]bool {
+	flags := make(map[string]bool, len(names))
+	for i, name := range names {
+		flags[name] = mask&(1<<i) != 0
+	}
+	return flags
+}
+
+// privFlagsMask updates mask with the flags set by name, it fails on a name the driver does not support
+func privFlagsMask(ifName string, names []string, mask uint32, flags map[string]bool) (uint32, error) {
+	requested := make([]string, 0, len(flags))
+	for name := range flags {
+		requested = append(requested, name)
+	}
+	sort.Strings(requested)
+
+	for _, name := range requested {
+		bit := -1
+		for i := range names {
+			if names[i] == name {
+				bit = i
+				break
+			}
+		}
+		if bit < 0 {
+			return 0, fmt.Errorf("private flag %q is not supported by device %q, available flags: [%s]",
+				name, ifName, strings.Join(names, ", "))
+		}
+		if flags[name] {
+			mask |= 1 << bit
+		} else {
+			mask &^= 1 << bit
+		}
+	}
+	return mask, nil
+}
+
+// GetPrivFlags returns the private flags of netdev by name
+func GetPrivFlags(ifName string) (map[string]bool, error) {
+	names, err := getPrivFlagNames(ifName)
+	if err != nil {
+		return nil, err
+	}
+	mask, err := getPrivFlagsMask(ifName)
+	if err != nil {
+		return nil, err
+	}
+	return privFlagsFromMask(names, mask), nil
+}
+
+// SetPrivFlags sets the given private flags of netdev by name, leaving the other flags unchanged.
+// It fails, listing the available flags, if a flag is not supported by the driver.
+func SetPrivFlags(ifName string, flags map[string]bool) error {
+	names, err := getPrivFlagNames(ifName)
+	if err != nil {
+		return err
+	}
+	mask, err := getPrivFlagsMask(ifName)
+	if err != nil {
+		return err
+	}
+	mask, err = privFlagsMask(ifName, names, mask, flags)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 8)
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_SPFLAGS)
+	binary.NativeEndian.PutUint32(buf[4:], mask)
+	if err := ethtoolIoctl(ifName, buf); err != nil {
+		return fmt.Errorf("failed to set private flags of device %q: %v", ifName, err)
+	}
+	return nil
+}
+
+// getCoalesce reads the struct ethtool_coalesce of netdev
+func getCoalesce(ifName string) ([]byte, error) {
+	buf := make([]byte, ethtoolCoalesceLen)
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_GCOALESCE)
+	if err := ethtoolIoctl(ifName, buf); err != nil {
+		return nil, fmt.Errorf("failed to get interrupt coalescing of device %q: %v", ifName, err)
+	}
+	return buf, nil
+}
+
+// GetAdaptiveCoalesce returns whether the adaptive rx and tx interrupt coalescing of netdev are enabled
+func GetAdaptiveCoalesce(ifName string) (rx, tx bool, err error) {
+	buf, err := getCoalesce(ifName)
+	if err != nil {
+		return false, false, err
+	}
+	return binary.NativeEndian.Uint32(buf[ethtoolCoalesceAdaptiveRx:]) != 0,
+		binary.NativeEndian.Uint32(buf[ethtoolCoalesceAdaptiveTx:]) != 0, nil
+}
+
+// SetAdaptiveCoalesce enables or disables the adaptive rx and tx interrupt coalescing of netdev, leaving the
+// other coalescing parameters unchanged
+func SetAdaptiveCoalesce(ifName string, rx, tx bool) error {
+	buf, err := getCoalesce(ifName)
+	if err != nil {
+		return err
+	}
+
+	boolToUint32 := func(b bool) uint32 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_SCOALESCE)
+	binary.NativeEndian.PutUint32(buf[ethtoolCoalesceAdaptiveRx:], boolToUint32(rx))
+	binary.NativeEndian.PutUint32(buf[ethtoolCoalesceAdaptiveTx:], boolToUint32(tx))
+	if err := ethtoolIoctl(ifName, buf); err != nil {
+		return fmt.Errorf("failed to set adaptive interrupt coalescing of device %q: %v", ifName, err)
+	}
+	return nil
+}
+
+// IdentifyPort blinks the port identification LED of netdev for the given number of seconds, like
+// ethtool --identify. The call blocks until the identification ends.
+func IdentifyPort(ifName string, seconds int) error {
+	// struct ethtool_value, the data member is the duration in seconds
+	buf := make([]byte, 8)
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_PHYS_ID)
+	binary.NativeEndian.PutUint32(buf[4:], uint32(seconds))
+	if err := ethtoolIoctl(ifName, buf); err != nil {
+		return fmt.Errorf("failed to identify port of device %q: %v", ifName, err)
+	}
+	return nil
+}
+
+// loopbackFeature returns the bit of the loopback feature of netdev and the number of 32 bit blocks of its
+// features. It fails with ErrNotSupported when netdev has no loopback feature.
+func loopbackFeature(ifName string) (bit, blocks int, err error) {
+	names, err := getStringSet(ifName, ethtoolSSFeatures, "feature")
+	if err != nil {
+		return 0, 0, err
+	}
+	for i, name := range names {
+		if name == ethtoolFeatureLoopback {
+			return i, (len(names) + 31) / 32, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("device %q has no %s feature: %w", ifName, ethtoolFeatureLoopback, ErrNotSupported)
+}
+
+// GetLoopback returns whether the loopback feature of netdev, which loops its tx packets back to its rx, is enabled
+func GetLoopback(ifName string) (bool, error) {
+	bit, blocks, err := loopbackFeature(ifName)
+	if err != nil {
+		return false, err
+	}
+
+	// struct ethtool_gfeatures, each block is a struct ethtool_get_features_block of available, requested, active
+	// and never_changed bitmaps
+	buf := make([]byte, 8+16*blocks)
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_GFEATURES)
+	binary.NativeEndian.PutUint32(buf[4:], uint32(blocks))
+	if err := ethtoolIoctl(ifName, buf); err != nil {
+		return false, fmt.Errorf("failed to get features of device %q: %v", ifName, err)
+	}
+	active := binary.NativeEndian.Uint32(buf[8+16*(bit/32)+8:])
+	return active&(1<<(bit%32)) != 0, nil
+}
+
+// SetLoopback enables or disables the loopback feature of netdev, leaving its other features unchanged
+func SetLoopback(ifName string, enable bool) error {
+	bit, blocks, err := loopbackFeature(ifName)
+	if err != nil {
+		return err
+	}
+
+	// struct ethtool_sfeatures, each block is a struct ethtool_set_features_block of valid and requested bitmaps
+	buf := make([]byte, 8+8*blocks)
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_SFEATURES)
+	binary.NativeEndian.PutUint32(buf[4:], uint32(blocks))
+	off := 8 + 8*(bit/32)
+	binary.NativeEndian.PutUint32(buf[off:], 1<<(bit%32))
+	if enable {
+		binary.NativeEndian.PutUint32(buf[off+4:], 1<<(bit%32))
+	}
+	if err := ethtoolIoctl(ifName, buf); err != nil {
+		return fmt.Errorf("failed to set the %s feature of device %q: %v", ethtoolFeatureLoopback, ifName, err)
+	}
+	return nil
+}
+
+// SelfTestResult is the result of the ethtool self-test of a netdev
+type SelfTestResult struct {
+	Passed  bool
+	Results map[string]uint64 // result of each test by name, 0 for a passed test
+}
+
+// RunSelfTest runs the offline self-test of netdev, like ethtool -t offline, which includes the loopback tests of
+// the drivers that have them. The netdev traffic is interrupted while the test runs. It fails with
+// ErrNotSupported when the driver has no self-test.
+func RunSelfTest(ifName string) (*SelfTestResult, error) {
+	names, err := getStringSet(ifName, ethtoolSSTest, "self-test")
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("device %q has no self-test: %w", ifName, ErrNotSupported)
+	}
+
+	// struct ethtool_test
+	buf := make([]byte, 16+8*len(names))
+	binary.NativeEndian.PutUint32(buf[0:], unix.ETHTOOL_TEST)
+	binary.NativeEndian.PutUint32(buf[4:], ethtoolTestFlOffline)
+	binary.NativeEndian.PutUint32(buf[12:], uint32(len(names)))
+	if err := ethtoolIoctl(ifName, buf); err != nil {
+		return nil, fmt.Errorf("failed to run the self-test of device %q: %v", ifName, err)
+	}
+
+	result := &SelfTestResult{
+		Passed:  binary.NativeEndian.Uint32(buf[4:])&ethtoolTestFlFailed == 0,
+		Results: make(map[string]uint64, len(names)),
+	}
+	for i, name := range names {
+		result.Results[name] = binary.NativeEndian.Uint64(buf[16+8*i:])
+	}
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ProcIrq is the procfs directory of the IRQ settings
+	ProcIrq = "/proc/irq"
+	// SysNodeDirectory is the sysfs directory of the NUMA nodes
+	SysNodeDirectory = "/sys/devices/system/node"
+)
+
+// GetVFIRQs returns the MSI-X vectors of the netdev, from its msi_irqs sysfs directory
+func GetVFIRQs(ifName string) ([]int, error) {
+	msiIrqsDir := filepath.Join(NetDirectory, ifName, "device", "msi_irqs")
+	entries, err := os.ReadDir(msiIrqsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the IRQs of %s: %v", ifName, err)
+	}
+
+	irqs := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		irq, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		irqs = append(irqs, irq)
+	}
+	sort.Ints(irqs)
+	return irqs, nil
+}
+
+// GetNUMANodeCPUMask returns the mask of the CPUs of the NUMA node local to the netdev device, in the format of
+// smp_affinity. ErrNotSupported is returned if the device has no NUMA affinity.
+func GetNUMANodeCPUMask(ifName string) (string, error) {
+	numaNodePath := filepath.Join(NetDirectory, ifName, "device", "numa_node")
+	data, err := os.ReadFile(numaNodePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the NUMA node of %s: %v", ifName, err)
+	}
+	node, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the NUMA node of %s: %v", ifName, err)
+	}
+	if node < 0 {
+		return "", fmt.Errorf("device of %s has no NUMA node: %w", ifName, ErrNotSupported)
+	}
+
+	cpuMapPath := filepath.Join(SysNodeDirectory, fmt.Sprintf("node%d", node), "cpumap")
+	data, err = os.ReadFile(cpuMapPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the CPUs of NUMA node %d: %v", node, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SetIRQAffinity writes the CPU mask to the smp_affinity of the IRQ
+func SetIRQAffinity(irq int, mask string) error {
+	smpAffinityPath := filepath.Join(ProcIrq, strconv.Itoa(irq), "smp_affinity")
+	if err := os.WriteFile(smpAffinityPath, []byte(mask), 0600); err != nil {
+		return fmt.Errorf("failed to set the affinity of IRQ %d to %s: %w", irq, mask, err)
+	}
+	return nil
+}
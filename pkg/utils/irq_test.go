@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IRQ affinity", func() {
+	Context("Checking GetVFIRQs function", func() {
+		It("Returns the MSI-X vectors of the netdev", func() {
+			irqs, err := GetVFIRQs("enp175s6")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(irqs).To(Equal([]int{120, 121}))
+		})
+		It("Fails for a netdev without msi_irqs", func() {
+			_, err := GetVFIRQs("enp175s7")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking GetNUMANodeCPUMask function", func() {
+		It("Returns the CPU mask of the local NUMA node", func() {
+			mask, err := GetNUMANodeCPUMask("enp175s6")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mask).To(Equal("00000000,ffff0000"))
+		})
+		It("Returns ErrNotSupported for a device without NUMA node", func() {
+			numaNodePath := filepath.Join(NetDirectory, "enp175s6", "device", "numa_node")
+			Expect(os.WriteFile(numaNodePath, []byte("-1\n"), 0600)).To(Succeed())
+			defer func() {
+				Expect(os.WriteFile(numaNodePath, []byte("1\n"), 0600)).To(Succeed())
+			}()
+			_, err := GetNUMANodeCPUMask("enp175s6")
+			Expect(errors.Is(err, ErrNotSupported)).To(BeTrue())
+		})
+	})
+	Context("Checking SetIRQAffinity function", func() {
+		It("Writes the mask to smp_affinity", func() {
+			Expect(SetIRQAffinity(120, "00000000,ffff0000")).To(Succeed())
+			data, err := os.ReadFile(filepath.Join(ProcIrq, "120", "smp_affinity"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("00000000,ffff0000"))
+		})
+		It("Fails for an unknown IRQ", func() {
+			Expect(SetIRQAffinity(200, "00000000,ffff0000")).NotTo(Succeed())
+		})
+	})
+})
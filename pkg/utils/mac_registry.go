@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrMACConflict is returned when a MAC address is already in use by another live VF of the node
+var ErrMACConflict = errors.New("MAC address already in use")
+
+// MACRegistry records the MAC addresses statically assigned to the VFs of the node, by VF pci address, so that
+// two VFs are not given the same MAC address. The registry is a single file locked while it is accessed, for the
+// concurrent plugin invocations.
+type MACRegistry struct {
+	path      string
+	allocator *PCIAllocator
+}
+
+// NewMACRegistry returns a new MAC registry
+// it will use the <dataDir>/registry/macs file to store the MAC addresses and the PCI allocations of <dataDir> to
+// tell whether the VF of a MAC address is still in use. The registry is kept out of <dataDir>, where every file is
+// a cached NetConf.
+func NewMACRegistry(dataDir string) *MACRegistry {
+	return &MACRegistry{path: filepath.Join(dataDir, "registry", "macs"), allocator: NewPCIAllocator(dataDir)}
+}
+
+// Reserve records the MAC address as the one of the VF. It returns ErrMACConflict if the MAC address is recorded
+// for another VF that is still allocated to a running pod, the stale entries of released VFs are replaced.
+func (r *MACRegistry) Reserve(mac, pciAddress string) error {
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return fmt.Errorf("failed to parse MAC address %s: %v", mac, err)
+	}
+	mac = hwAddr.String()
+
+	return r.update(func(macs map[string]string) error {
+		owner, ok := macs[mac]
+		if ok && owner != pciAddress {
+			allocated, err := r.allocator.IsAllocated(owner)
+			if err != nil {
+				return err
+			}
+			if allocated {
+				return fmt.Errorf("MAC address %s of VF %s is the one of VF %s: %w", mac, pciAddress, owner, ErrMACConflict)
+			}
+		}
+		macs[mac] = pciAddress
+		return nil
+	})
+}
+
+// Release removes the MAC address from the registry if it is recorded for the VF
+func (r *MACRegistry) Release(mac, pciAddress string) error {
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return fmt.Errorf("failed to parse MAC address %s: %v", mac, err)
+	}
+	mac = hwAddr.String()
+
+	return r.update(func(macs map[string]string) error {
+		if macs[mac] == pciAddress {
+			delete(macs, mac)
+		}
+		return nil
+	})
+}
+
+// update locks the registry file, passes its MAC addresses to fn and writes them back if fn succeeds
+func (r *MACRegistry) update(fn func(macs map[string]string) error) error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return fmt.Errorf("failed to create the MAC registry directory(%q): %v", filepath.Dir(r.path), err)
+	}
+	f, err := os.OpenFile(r.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the MAC registry %s: %v", r.path, err)
+	}
+	defer f.Close()
+
+	// the lock is released when the file is closed
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock the MAC registry %s: %v", r.path, err)
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("failed to read the MAC registry %s: %v", r.path, err)
+	}
+	macs := map[string]string{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &macs); err != nil {
+			return fmt.Errorf("failed to parse the MAC registry %s: %v", r.path, err)
+		}
+	}
+
+	if err := fn(macs); err != nil {
+		return err
+	}
+
+	data, err = json.Marshal(macs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the MAC registry: %v", err)
+	}
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate the MAC registry %s: %v", r.path, err)
+	}
+	if _, err := f.WriteAt(data, 0); err != nil {
+		return fmt.Errorf("failed to write the MAC registry %s: %v", r.path, err)
+	}
+	return nil
+}
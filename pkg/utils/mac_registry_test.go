@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MACRegistry", func() {
+	var (
+		dataDir  string
+		registry *MACRegistry
+	)
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = os.MkdirTemp("", "sriov-mac-registry-")
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dataDir)
+		registry = NewMACRegistry(dataDir)
+	})
+
+	It("Fails to reserve a MAC address of another allocated VF", func() {
+		Expect(NewPCIAllocator(dataDir).SaveAllocatedPCI("0000:af:06.0", "/proc/self/ns/net")).To(Succeed())
+		Expect(registry.Reserve("0A:00:00:00:00:01", "0000:af:06.0")).To(Succeed())
+
+		err := registry.Reserve("0a:00:00:00:00:01", "0000:af:06.1")
+		Expect(err).To(MatchError(ErrMACConflict))
+		Expect(err).To(MatchError(ContainSubstring("is the one of VF 0000:af:06.0")))
+	})
+
+	It("Reserves again the MAC address of the same VF", func() {
+		Expect(registry.Reserve("0a:00:00:00:00:01", "0000:af:06.0")).To(Succeed())
+		Expect(registry.Reserve("0a:00:00:00:00:01", "0000:af:06.0")).To(Succeed())
+	})
+
+	It("Replaces the MAC address of a VF that is no longer allocated", func() {
+		Expect(registry.Reserve("0a:00:00:00:00:01", "0000:af:06.0")).To(Succeed())
+		Expect(registry.Reserve("0a:00:00:00:00:01", "0000:af:06.1")).To(Succeed())
+	})
+
+	It("Releases the MAC address of the VF only", func() {
+		Expect(NewPCIAllocator(dataDir).SaveAllocatedPCI("0000:af:06.0", "/proc/self/ns/net")).To(Succeed())
+		Expect(registry.Reserve("0a:00:00:00:00:01", "0000:af:06.0")).To(Succeed())
+
+		Expect(registry.Release("0a:00:00:00:00:01", "0000:af:06.1")).To(Succeed())
+		Expect(registry.Reserve("0a:00:00:00:00:01", "0000:af:06.1")).To(MatchError(ErrMACConflict))
+
+		Expect(registry.Release("0a:00:00:00:00:01", "0000:af:06.0")).To(Succeed())
+		Expect(registry.Reserve("0a:00:00:00:00:01", "0000:af:06.1")).To(Succeed())
+	})
+
+	It("Keeps the registry consistent under concurrent reservations", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				mac := []byte("0a:00:00:00:00:00")
+				mac[len(mac)-1] = "0123456789abcdef"[i]
+				Expect(registry.Reserve(string(mac), "0000:af:06.0")).To(Succeed())
+			}(i)
+		}
+		wg.Wait()
+
+		data, err := os.ReadFile(filepath.Join(dataDir, "registry", "macs"))
+		Expect(err).ToNot(HaveOccurred())
+		for _, c := range "0123456789abcdef" {
+			Expect(string(data)).To(ContainSubstring(`"0a:00:00:00:00:0` + string(c) + `"`))
+		}
+	})
+})
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	metricName   = "sriov_cni_operation_duration_seconds"
+	metricHeader = "# HELP " + metricName + " Duration of the last SR-IOV CNI operation.\n" +
+		"# TYPE " + metricName + " gauge\n"
+	metricEOF = "# EOF\n"
+)
+
+// OperationMetric describes the outcome of a CNI command on a VF
+type OperationMetric struct {
+	Command  string // ADD|DEL|CHECK
+	DeviceID string // PCI address of the VF
+	VFID     string // VF index, empty if unknown
+	Outcome  string // success|failure
+	Duration time.Duration
+}
+
+func (m *OperationMetric) labels() string {
+	return fmt.Sprintf(`command=%q,device_id=%q,vf=%q,outcome=%q`, m.Command, m.DeviceID, m.VFID, m.Outcome)
+}
+
+// WriteOperationMetric records the metric in an OpenMetrics text file that can be scraped by the node-exporter
+// textfile collector. The sample replaces the previous one with the same labels so the file stays valid.
+// The file is written to a temporary file first and renamed so readers never see a partial file.
+func WriteOperationMetric(path string, m *OperationMetric) error {
+	labels := m.labels()
+	sample := fmt.Sprintf("%s{%s} %g\n", metricName, labels, m.Duration.Seconds())
+
+	var b strings.Builder
+	b.WriteString(metricHeader)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read metrics file %s: %v", path, err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, metricName+"{") || strings.HasPrefix(line, metricName+"{"+labels+"}") {
+			continue
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(sample)
+	b.WriteString(metricEOF)
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary metrics file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err = tmpFile.WriteString(b.String()); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write temporary metrics file %s: %v", tmpFile.Name(), err)
+	}
+	if err = tmpFile.Chmod(0644); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to set permissions of temporary metrics file %s: %v", tmpFile.Name(), err)
+	}
+	if err = tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary metrics file %s: %v", tmpFile.Name(), err)
+	}
+
+	if err = os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename temporary metrics file to %s: %v", path, err)
+	}
+
+	return nil
+}
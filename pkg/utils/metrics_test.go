@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics", func() {
+	var (
+		tmpDir      string
+		metricsFile string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("/tmp", "sriovplugin-metrics-test-")
+		Expect(err).NotTo(HaveOccurred())
+		metricsFile = filepath.Join(tmpDir, "sriov_cni.prom")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	Context("Checking WriteOperationMetric function", func() {
+		It("Records a sample per command, device and outcome", func() {
+			add := &OperationMetric{Command: "ADD", DeviceID: "0000:af:06.0", VFID: "0", Outcome: "success", Duration: 1500 * time.Millisecond}
+			del := &OperationMetric{Command: "DEL", DeviceID: "0000:af:06.0", VFID: "0", Outcome: "failure", Duration: 250 * time.Millisecond}
+			Expect(WriteOperationMetric(metricsFile, add)).To(Succeed())
+			Expect(WriteOperationMetric(metricsFile, del)).To(Succeed())
+
+			data, err := os.ReadFile(metricsFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal(`# HELP sriov_cni_operation_duration_seconds Duration of the last SR-IOV CNI operation.
+# TYPE sriov_cni_operation_duration_seconds gauge
+sriov_cni_operation_duration_seconds{command="ADD",device_id="0000:af:06.0",vf="0",outcome="success"} 1.5
+sriov_cni_operation_duration_seconds{command="DEL",device_id="0000:af:06.0",vf="0",outcome="failure"} 0.25
+# EOF
+`))
+		})
+
+		It("Replaces the previous sample with the same labels", func() {
+			m := &OperationMetric{Command: "ADD", DeviceID: "0000:af:06.0", VFID: "0", Outcome: "success", Duration: time.Second}
+			Expect(WriteOperationMetric(metricsFile, m)).To(Succeed())
+			m.Duration = 2 * time.Second
+			Expect(WriteOperationMetric(metricsFile, m)).To(Succeed())
+
+			data, err := os.ReadFile(metricsFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strings.Count(string(data), "command=\"ADD\"")).To(Equal(1))
+			Expect(string(data)).To(ContainSubstring(`outcome="success"} 2`))
+
+			entries, err := os.ReadDir(tmpDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+		})
+
+		It("Fails when the metrics directory does not exist", func() {
+			m := &OperationMetric{Command: "ADD", DeviceID: "0000:af:06.0", Outcome: "success", Duration: time.Second}
+			Expect(WriteOperationMetric(filepath.Join(tmpDir, "missing", "sriov_cni.prom"), m)).NotTo(Succeed())
+		})
+	})
+})
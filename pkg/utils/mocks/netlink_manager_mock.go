@@ -8,6 +8,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	netlink "github.com/vishvananda/netlink"
+
+	nl "github.com/vishvananda/netlink/nl"
 )
 
 // NetlinkManager is an autogenerated mock type for the NetlinkManager type
@@ -15,6 +17,114 @@ type NetlinkManager struct {
 	mock.Mock
 }
 
+// BridgeVlanAdd provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4, _a5
+func (_m *NetlinkManager) BridgeVlanAdd(_a0 netlink.Link, _a1 uint16, _a2 bool, _a3 bool, _a4 bool, _a5 bool) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4, _a5)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, uint16, bool, bool, bool, bool) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4, _a5)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// BridgeVlanDel provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4, _a5
+func (_m *NetlinkManager) BridgeVlanDel(_a0 netlink.Link, _a1 uint16, _a2 bool, _a3 bool, _a4 bool, _a5 bool) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4, _a5)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, uint16, bool, bool, bool, bool) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4, _a5)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// BridgeVlanList provides a mock function with given fields:
+func (_m *NetlinkManager) BridgeVlanList() (map[int32][]*nl.BridgeVlanInfo, error) {
+	ret := _m.Called()
+
+	var r0 map[int32][]*nl.BridgeVlanInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (map[int32][]*nl.BridgeVlanInfo, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() map[int32][]*nl.BridgeVlanInfo); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[int32][]*nl.BridgeVlanInfo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DevLinkGetDeviceByName provides a mock function with given fields: _a0, _a1
+func (_m *NetlinkManager) DevLinkGetDeviceByName(_a0 string, _a1 string) (*netlink.DevlinkDevice, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *netlink.DevlinkDevice
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) (*netlink.DevlinkDevice, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) *netlink.DevlinkDevice); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*netlink.DevlinkDevice)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FilterAdd provides a mock function with given fields: _a0
+func (_m *NetlinkManager) FilterAdd(_a0 netlink.Filter) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Filter) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkAdd provides a mock function with given fields: _a0
+func (_m *NetlinkManager) LinkAdd(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkByName provides a mock function with given fields: _a0
 func (_m *NetlinkManager) LinkByName(_a0 string) (netlink.Link, error) {
 	ret := _m.Called(_a0)
@@ -41,6 +151,20 @@ func (_m *NetlinkManager) LinkByName(_a0 string) (netlink.Link, error) {
 	return r0, r1
 }
 
+// LinkDel provides a mock function with given fields: _a0
+func (_m *NetlinkManager) LinkDel(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkDelAltName provides a mock function with given fields: _a0, _a1
 func (_m *NetlinkManager) LinkDelAltName(_a0 netlink.Link, _a1 string) error {
 	ret := _m.Called(_a0, _a1)
@@ -55,6 +179,86 @@ func (_m *NetlinkManager) LinkDelAltName(_a0 netlink.Link, _a1 string) error {
 	return r0
 }
 
+// LinkGetVfNodeGUID provides a mock function with given fields: _a0, _a1
+func (_m *NetlinkManager) LinkGetVfNodeGUID(_a0 netlink.Link, _a1 int) (net.HardwareAddr, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 net.HardwareAddr
+	var r1 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) (net.HardwareAddr, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) net.HardwareAddr); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(net.HardwareAddr)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(netlink.Link, int) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkGetVfPortGUID provides a mock function with given fields: _a0, _a1
+func (_m *NetlinkManager) LinkGetVfPortGUID(_a0 netlink.Link, _a1 int) (net.HardwareAddr, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 net.HardwareAddr
+	var r1 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) (net.HardwareAddr, error)); ok {
+		return rf(_a0, _a1)
+	}
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) net.HardwareAddr); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(net.HardwareAddr)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(netlink.Link, int) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkSetAllmulticastOff provides a mock function with given fields: _a0
+func (_m *NetlinkManager) LinkSetAllmulticastOff(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetAllmulticastOn provides a mock function with given fields: _a0
+func (_m *NetlinkManager) LinkSetAllmulticastOn(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetDown provides a mock function with given fields: _a0
 func (_m *NetlinkManager) LinkSetDown(_a0 netlink.Link) error {
 	ret := _m.Called(_a0)
@@ -83,6 +287,20 @@ func (_m *NetlinkManager) LinkSetHardwareAddr(_a0 netlink.Link, _a1 net.Hardware
 	return r0
 }
 
+// LinkSetMaster provides a mock function with given fields: _a0, _a1
+func (_m *NetlinkManager) LinkSetMaster(_a0 netlink.Link, _a1 netlink.Link) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, netlink.Link) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetName provides a mock function with given fields: _a0, _a1
 func (_m *NetlinkManager) LinkSetName(_a0 netlink.Link, _a1 string) error {
 	ret := _m.Called(_a0, _a1)
@@ -111,6 +329,20 @@ func (_m *NetlinkManager) LinkSetNsFd(_a0 netlink.Link, _a1 int) error {
 	return r0
 }
 
+// LinkSetTxQLen provides a mock function with given fields: _a0, _a1
+func (_m *NetlinkManager) LinkSetTxQLen(_a0 netlink.Link, _a1 int) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetUp provides a mock function with given fields: _a0
 func (_m *NetlinkManager) LinkSetUp(_a0 netlink.Link) error {
 	ret := _m.Called(_a0)
@@ -139,6 +371,34 @@ func (_m *NetlinkManager) LinkSetVfHardwareAddr(_a0 netlink.Link, _a1 int, _a2 n
 	return r0
 }
 
+// LinkSetVfNodeGUID provides a mock function with given fields: _a0, _a1, _a2
+func (_m *NetlinkManager) LinkSetVfNodeGUID(_a0 netlink.Link, _a1 int, _a2 net.HardwareAddr) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, net.HardwareAddr) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetVfPortGUID provides a mock function with given fields: _a0, _a1, _a2
+func (_m *NetlinkManager) LinkSetVfPortGUID(_a0 netlink.Link, _a1 int, _a2 net.HardwareAddr) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, net.HardwareAddr) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetVfRate provides a mock function with given fields: _a0, _a1, _a2, _a3
 func (_m *NetlinkManager) LinkSetVfRate(_a0 netlink.Link, _a1 int, _a2 int, _a3 int) error {
 	ret := _m.Called(_a0, _a1, _a2, _a3)
@@ -209,6 +469,118 @@ func (_m *NetlinkManager) LinkSetVfVlanQosProto(_a0 netlink.Link, _a1 int, _a2 i
 	return r0
 }
 
+// LinkSetVlanEgressQoSMap provides a mock function with given fields: _a0, _a1
+func (_m *NetlinkManager) LinkSetVlanEgressQoSMap(_a0 netlink.Link, _a1 map[uint32]uint32) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, map[uint32]uint32) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NeighAppend provides a mock function with given fields: _a0
+func (_m *NetlinkManager) NeighAppend(_a0 *netlink.Neigh) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.Neigh) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NeighDel provides a mock function with given fields: _a0
+func (_m *NetlinkManager) NeighDel(_a0 *netlink.Neigh) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.Neigh) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// QdiscAdd provides a mock function with given fields: _a0
+func (_m *NetlinkManager) QdiscAdd(_a0 netlink.Qdisc) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Qdisc) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// QdiscDel provides a mock function with given fields: _a0
+func (_m *NetlinkManager) QdiscDel(_a0 netlink.Qdisc) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Qdisc) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RouteAdd provides a mock function with given fields: _a0
+func (_m *NetlinkManager) RouteAdd(_a0 *netlink.Route) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.Route) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RuleAdd provides a mock function with given fields: _a0
+func (_m *NetlinkManager) RuleAdd(_a0 *netlink.Rule) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.Rule) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RuleDel provides a mock function with given fields: _a0
+func (_m *NetlinkManager) RuleDel(_a0 *netlink.Rule) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.Rule) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewNetlinkManager creates a new instance of NetlinkManager. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewNetlinkManager(t interface {
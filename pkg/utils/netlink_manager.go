@@ -1,9 +1,13 @@
 package utils
 
 import (
+	"encoding/binary"
+	"fmt"
 	"net"
 
 	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
 )
 
 // Mocked netlink interface, this is required for unit tests
@@ -22,7 +26,30 @@ type NetlinkManager interface {
 	LinkSetVfSpoofchk(netlink.Link, int, bool) error
 	LinkSetVfTrust(netlink.Link, int, bool) error
 	LinkSetVfState(netlink.Link, int, uint32) error
+	LinkSetVfNodeGUID(netlink.Link, int, net.HardwareAddr) error
+	LinkSetVfPortGUID(netlink.Link, int, net.HardwareAddr) error
+	LinkGetVfNodeGUID(netlink.Link, int) (net.HardwareAddr, error)
+	LinkGetVfPortGUID(netlink.Link, int) (net.HardwareAddr, error)
 	LinkDelAltName(netlink.Link, string) error
+	BridgeVlanList() (map[int32][]*nl.BridgeVlanInfo, error)
+	DevLinkGetDeviceByName(string, string) (*netlink.DevlinkDevice, error)
+	NeighAppend(*netlink.Neigh) error
+	NeighDel(*netlink.Neigh) error
+	QdiscAdd(netlink.Qdisc) error
+	QdiscDel(netlink.Qdisc) error
+	FilterAdd(netlink.Filter) error
+	BridgeVlanAdd(netlink.Link, uint16, bool, bool, bool, bool) error
+	BridgeVlanDel(netlink.Link, uint16, bool, bool, bool, bool) error
+	LinkSetVlanEgressQoSMap(netlink.Link, map[uint32]uint32) error
+	LinkSetAllmulticastOn(netlink.Link) error
+	LinkSetAllmulticastOff(netlink.Link) error
+	LinkSetTxQLen(netlink.Link, int) error
+	RouteAdd(*netlink.Route) error
+	RuleAdd(*netlink.Rule) error
+	RuleDel(*netlink.Rule) error
+	LinkAdd(netlink.Link) error
+	LinkDel(netlink.Link) error
+	LinkSetMaster(netlink.Link, netlink.Link) error
 }
 
 // MyNetlink NetlinkManager
@@ -92,7 +119,195 @@ func (n *MyNetlink) LinkSetVfState(link netlink.Link, vf int, state uint32) erro
 	return netlink.LinkSetVfState(link, vf, state)
 }
 
+// LinkSetVfNodeGUID using NetlinkManager
+func (n *MyNetlink) LinkSetVfNodeGUID(link netlink.Link, vf int, nodeguid net.HardwareAddr) error {
+	return netlink.LinkSetVfNodeGUID(link, vf, nodeguid)
+}
+
+// LinkSetVfPortGUID using NetlinkManager
+func (n *MyNetlink) LinkSetVfPortGUID(link netlink.Link, vf int, portguid net.HardwareAddr) error {
+	return netlink.LinkSetVfPortGUID(link, vf, portguid)
+}
+
+// LinkGetVfNodeGUID returns the node GUID of a vf of the link
+func (n *MyNetlink) LinkGetVfNodeGUID(link netlink.Link, vf int) (net.HardwareAddr, error) {
+	return linkGetVfGUID(link, vf, nl.IFLA_VF_IB_NODE_GUID)
+}
+
+// LinkGetVfPortGUID returns the port GUID of a vf of the link
+func (n *MyNetlink) LinkGetVfPortGUID(link netlink.Link, vf int) (net.HardwareAddr, error) {
+	return linkGetVfGUID(link, vf, nl.IFLA_VF_IB_PORT_GUID)
+}
+
 // LinkDelAltName using NetlinkManager
 func (n *MyNetlink) LinkDelAltName(link netlink.Link, altName string) error {
 	return netlink.LinkDelAltName(link, altName)
 }
+
+// BridgeVlanList using NetlinkManager
+func (n *MyNetlink) BridgeVlanList() (map[int32][]*nl.BridgeVlanInfo, error) {
+	return netlink.BridgeVlanList()
+}
+
+// DevLinkGetDeviceByName using NetlinkManager
+func (n *MyNetlink) DevLinkGetDeviceByName(bus, device string) (*netlink.DevlinkDevice, error) {
+	return netlink.DevLinkGetDeviceByName(bus, device)
+}
+
+// NeighAppend using NetlinkManager
+func (n *MyNetlink) NeighAppend(neigh *netlink.Neigh) error {
+	return netlink.NeighAppend(neigh)
+}
+
+// NeighDel using NetlinkManager
+func (n *MyNetlink) NeighDel(neigh *netlink.Neigh) error {
+	return netlink.NeighDel(neigh)
+}
+
+// QdiscAdd using NetlinkManager
+func (n *MyNetlink) QdiscAdd(qdisc netlink.Qdisc) error {
+	return netlink.QdiscAdd(qdisc)
+}
+
+// QdiscDel using NetlinkManager
+func (n *MyNetlink) QdiscDel(qdisc netlink.Qdisc) error {
+	return netlink.QdiscDel(qdisc)
+}
+
+// FilterAdd using NetlinkManager
+func (n *MyNetlink) FilterAdd(filter netlink.Filter) error {
+	return netlink.FilterAdd(filter)
+}
+
+// BridgeVlanAdd using NetlinkManager
+func (n *MyNetlink) BridgeVlanAdd(link netlink.Link, vid uint16, pvid, untagged, self, master bool) error {
+	return netlink.BridgeVlanAdd(link, vid, pvid, untagged, self, master)
+}
+
+// BridgeVlanDel using NetlinkManager
+func (n *MyNetlink) BridgeVlanDel(link netlink.Link, vid uint16, pvid, untagged, self, master bool) error {
+	return netlink.BridgeVlanDel(link, vid, pvid, untagged, self, master)
+}
+
+// LinkSetAllmulticastOn using NetlinkManager
+func (n *MyNetlink) LinkSetAllmulticastOn(link netlink.Link) error {
+	return netlink.LinkSetAllmulticastOn(link)
+}
+
+// LinkSetAllmulticastOff using NetlinkManager
+func (n *MyNetlink) LinkSetAllmulticastOff(link netlink.Link) error {
+	return netlink.LinkSetAllmulticastOff(link)
+}
+
+// LinkSetTxQLen using NetlinkManager
+func (n *MyNetlink) LinkSetTxQLen(link netlink.Link, qlen int) error {
+	return netlink.LinkSetTxQLen(link, qlen)
+}
+
+// RouteAdd using NetlinkManager
+func (n *MyNetlink) RouteAdd(route *netlink.Route) error {
+	return netlink.RouteAdd(route)
+}
+
+// RuleAdd using NetlinkManager
+func (n *MyNetlink) RuleAdd(rule *netlink.Rule) error {
+	return netlink.RuleAdd(rule)
+}
+
+// RuleDel using NetlinkManager
+func (n *MyNetlink) RuleDel(rule *netlink.Rule) error {
+	return netlink.RuleDel(rule)
+}
+
+// LinkAdd using NetlinkManager
+func (n *MyNetlink) LinkAdd(link netlink.Link) error {
+	return netlink.LinkAdd(link)
+}
+
+// LinkDel using NetlinkManager
+func (n *MyNetlink) LinkDel(link netlink.Link) error {
+	return netlink.LinkDel(link)
+}
+
+// LinkSetMaster using NetlinkManager
+func (n *MyNetlink) LinkSetMaster(link, master netlink.Link) error {
+	return netlink.LinkSetMaster(link, master)
+}
+
+// LinkSetVlanEgressQoSMap sets the skb priority to VLAN PCP mappings of the tags inserted by the link, which
+// the netlink library only sets when it creates a VLAN link
+func (n *MyNetlink) LinkSetVlanEgressQoSMap(link netlink.Link, qosMap map[uint32]uint32) error {
+	req := nl.NewNetlinkRequest(unix.RTM_NEWLINK, unix.NLM_F_ACK)
+	msg := nl.NewIfInfomsg(unix.AF_UNSPEC)
+	msg.Index = int32(link.Attrs().Index)
+	req.AddData(msg)
+
+	linkInfo := nl.NewRtAttr(unix.IFLA_LINKINFO, nil)
+	linkInfo.AddRtAttr(nl.IFLA_INFO_KIND, nl.NonZeroTerminated("vlan"))
+	data := linkInfo.AddRtAttr(nl.IFLA_INFO_DATA, nil)
+	egress := data.AddRtAttr(nl.IFLA_VLAN_EGRESS_QOS, nil)
+	for from, to := range qosMap {
+		// struct ifla_vlan_qos_mapping
+		mapping := make([]byte, 8)
+		nl.NativeEndian().PutUint32(mapping[0:4], from)
+		nl.NativeEndian().PutUint32(mapping[4:8], to)
+		egress.AddRtAttr(unix.IFLA_VLAN_QOS_MAPPING, mapping)
+	}
+	req.AddData(linkInfo)
+
+	_, err := req.Execute(unix.NETLINK_ROUTE, 0)
+	return err
+}
+
+// linkGetVfGUID returns the node or port GUID of a vf from the VF info of the link, which the netlink library
+// does not parse. The GUIDs are only reported by the drivers of InfiniBand VFs.
+func linkGetVfGUID(link netlink.Link, vf, guidType int) (net.HardwareAddr, error) {
+	req := nl.NewNetlinkRequest(unix.RTM_GETLINK, unix.NLM_F_ACK)
+	msg := nl.NewIfInfomsg(unix.AF_UNSPEC)
+	msg.Index = int32(link.Attrs().Index)
+	req.AddData(msg)
+	req.AddData(nl.NewRtAttr(unix.IFLA_EXT_MASK, nl.Uint32Attr(nl.RTEXT_FILTER_VF)))
+
+	msgs, err := req.Execute(unix.NETLINK_ROUTE, unix.RTM_NEWLINK)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) != 1 || len(msgs[0]) < unix.SizeofIfInfomsg {
+		return nil, fmt.Errorf("unexpected netlink reply for link %s", link.Attrs().Name)
+	}
+
+	attrs, err := nl.ParseRouteAttr(msgs[0][unix.SizeofIfInfomsg:])
+	if err != nil {
+		return nil, err
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK != unix.IFLA_VFINFO_LIST {
+			continue
+		}
+		vfInfos, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return nil, err
+		}
+		for _, vfInfo := range vfInfos {
+			vfAttrs, err := nl.ParseRouteAttr(vfInfo.Value)
+			if err != nil {
+				return nil, err
+			}
+			for _, vfAttr := range vfAttrs {
+				if int(vfAttr.Attr.Type) != guidType || len(vfAttr.Value) < nl.SizeofVfGUID {
+					continue
+				}
+				// struct ifla_vf_guid, the GUID is set from its big endian representation
+				vfGUID := nl.DeserializeVfGUID(vfAttr.Value)
+				if int(vfGUID.Vf) != vf {
+					continue
+				}
+				guid := make(net.HardwareAddr, 8)
+				binary.BigEndian.PutUint64(guid, vfGUID.GUID)
+				return guid, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no GUID reported for vf %d of link %s", vf, link.Attrs().Name)
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// NodeLock is a node-wide lock serializing the VF operations of the concurrent plugin invocations, for the drivers
+// whose reconfiguration races are not limited to the VFs of a PF
+type NodeLock struct {
+	path string
+	file *os.File
+}
+
+// NewNodeLock returns a new node lock
+// it will use the <dataDir>/lock/node.lock file, which the invocations sharing dataDir lock. The lock is kept out
+// of <dataDir>, where every file is a cached NetConf.
+func NewNodeLock(dataDir string) *NodeLock {
+	return &NodeLock{path: filepath.Join(dataDir, "lock", "node.lock")}
+}
+
+// Lock blocks until the lock is acquired
+func (l *NodeLock) Lock() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create the node lock directory(%q): %v", filepath.Dir(l.path), err)
+	}
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the node lock %s: %v", l.path, err)
+	}
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to lock the node lock %s: %v", l.path, err)
+	}
+	l.file = f
+	return nil
+}
+
+// Unlock releases the lock, the kernel also releases it when the process exits
+func (l *NodeLock) Unlock() error {
+	if l.file == nil {
+		return nil
+	}
+	// the lock is released when the file is closed
+	err := l.file.Close()
+	l.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to unlock the node lock %s: %v", l.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NodeLock", func() {
+	var dataDir string
+
+	BeforeEach(func() {
+		var err error
+		dataDir, err = os.MkdirTemp("", "sriov-node-lock-")
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dataDir)
+	})
+
+	It("Serializes the holders of the lock", func() {
+		var (
+			wg      sync.WaitGroup
+			holders int32
+			overlap int32
+		)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				lock := NewNodeLock(dataDir)
+				Expect(lock.Lock()).To(Succeed())
+				if atomic.AddInt32(&holders, 1) > 1 {
+					atomic.StoreInt32(&overlap, 1)
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&holders, -1)
+				Expect(lock.Unlock()).To(Succeed())
+			}()
+		}
+		wg.Wait()
+		Expect(atomic.LoadInt32(&overlap)).To(BeZero())
+	})
+
+	It("Blocks until the lock is released", func() {
+		first := NewNodeLock(dataDir)
+		Expect(first.Lock()).To(Succeed())
+
+		acquired := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			second := NewNodeLock(dataDir)
+			Expect(second.Lock()).To(Succeed())
+			close(acquired)
+			Expect(second.Unlock()).To(Succeed())
+		}()
+
+		Consistently(acquired, 100*time.Millisecond).ShouldNot(BeClosed())
+		Expect(first.Unlock()).To(Succeed())
+		Eventually(acquired).Should(BeClosed())
+	})
+
+	It("Ignores an unlock of a lock that is not held", func() {
+		Expect(NewNodeLock(dataDir).Unlock()).To(Succeed())
+	})
+})
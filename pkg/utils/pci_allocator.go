@@ -90,3 +90,31 @@ func (p *PCIAllocator) IsAllocated(pciAddress string) (bool, error) {
 	networkNamespace.Close()
 	return true, nil
 }
+
+// CountFreeVFs returns the number of VFs enabled on the PF and the number of those that are not allocated to a
+// running pod, according to the PCI allocations of dataDir, as a hint for external schedulers. Allocations of pods
+// whose network namespace is gone are released.
+func CountFreeVFs(dataDir, pf string) (total, free int, err error) {
+	total, err = GetSriovNumVfs(pf)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	allocator := NewPCIAllocator(dataDir)
+	for vf := 0; vf < total; vf++ {
+		pciAddr, err := GetPciAddress(pf, vf)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		allocated, err := allocator.IsAllocated(pciAddr)
+		if err != nil {
+			return 0, 0, err
+		}
+		if !allocated {
+			free++
+		}
+	}
+
+	return total, free, nil
+}
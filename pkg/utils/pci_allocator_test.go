@@ -57,4 +57,46 @@ var _ = Describe("PCIAllocator", func() {
 			Expect(isAllocated).To(BeFalse())
 		})
 	})
+
+	Context("CountFreeVFs", func() {
+		It("Assuming no VF is allocated", func() {
+			total, free, err := CountFreeVFs(ts.dirRoot, "enp175s0f1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(total).To(Equal(2))
+			Expect(free).To(Equal(2))
+		})
+
+		It("Assuming a VF is allocated to a running pod", func() {
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+
+			allocator := NewPCIAllocator(ts.dirRoot)
+			err = allocator.SaveAllocatedPCI("0000:af:06.0", targetNetNS.Path())
+			Expect(err).ToNot(HaveOccurred())
+			defer func() {
+				_ = allocator.DeleteAllocatedPCI("0000:af:06.0")
+			}()
+
+			total, free, err := CountFreeVFs(ts.dirRoot, "enp175s0f1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(total).To(Equal(2))
+			Expect(free).To(Equal(1))
+		})
+
+		It("Assuming a VF is allocated to a pod whose namespace doesn't exist", func() {
+			allocator := NewPCIAllocator(ts.dirRoot)
+			err = allocator.SaveAllocatedPCI("0000:af:06.1", "/var/run/netns/not-existing")
+			Expect(err).ToNot(HaveOccurred())
+
+			total, free, err := CountFreeVFs(ts.dirRoot, "enp175s0f1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(total).To(Equal(2))
+			Expect(free).To(Equal(2))
+		})
+
+		It("Assuming the device is not a PF", func() {
+			_, _, err := CountFreeVFs(ts.dirRoot, "enp175s6")
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
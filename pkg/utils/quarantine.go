@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RepresentorQuarantine records the VF representors that cmdDel left down, until an operator reclaims them
+type RepresentorQuarantine struct {
+	dataDir string
+}
+
+// NewRepresentorQuarantine returns a new representor quarantine
+// it will use the <dataDir>/quarantine folder to store the quarantined representors
+func NewRepresentorQuarantine(dataDir string) *RepresentorQuarantine {
+	return &RepresentorQuarantine{dataDir: filepath.Join(dataDir, "quarantine")}
+}
+
+// Record creates a file with the representor name as a name and the VF pci address as the content
+func (q *RepresentorQuarantine) Record(repName, pciAddress string) error {
+	if err := os.MkdirAll(q.dataDir, 0700); err != nil {
+		return fmt.Errorf("failed to create the sriov quarantine directory(%q): %v", q.dataDir, err)
+	}
+
+	path := filepath.Join(q.dataDir, repName)
+	if err := os.WriteFile(path, []byte(pciAddress), 0600); err != nil {
+		return fmt.Errorf("failed to write quarantined representor file in the path(%q): %v", path, err)
+	}
+	return nil
+}
+
+// Remove removes the file of a quarantined representor
+// return error if the representor is not quarantined
+func (q *RepresentorQuarantine) Remove(repName string) error {
+	path := filepath.Join(q.dataDir, repName)
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("error removing quarantined representor file %s: %v", path, err)
+	}
+	return nil
+}
+
+// List returns the quarantined representors with the pci address of their VF
+func (q *RepresentorQuarantine) List() (map[string]string, error) {
+	entries, err := os.ReadDir(q.dataDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("failed to list quarantined representors in %s: %v", q.dataDir, err)
+	}
+
+	reps := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		dat, err := os.ReadFile(filepath.Join(q.dataDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read quarantined representor file %s: %v", entry.Name(), err)
+		}
+		reps[entry.Name()] = string(dat)
+	}
+	return reps, nil
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RepresentorQuarantine", func() {
+	It("Records, lists and removes quarantined representors", func() {
+		quarantine := NewRepresentorQuarantine(ts.dirRoot)
+
+		reps, err := quarantine.List()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reps).To(BeEmpty())
+
+		Expect(quarantine.Record("enp175s0f1_0", "0000:af:06.0")).To(Succeed())
+		Expect(quarantine.Record("enp175s0f1_1", "0000:af:06.1")).To(Succeed())
+
+		reps, err = quarantine.List()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reps).To(Equal(map[string]string{"enp175s0f1_0": "0000:af:06.0", "enp175s0f1_1": "0000:af:06.1"}))
+
+		Expect(quarantine.Remove("enp175s0f1_0")).To(Succeed())
+		Expect(quarantine.Remove("enp175s0f1_0")).ToNot(Succeed())
+
+		reps, err = quarantine.List()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reps).To(HaveKey("enp175s0f1_1"))
+		Expect(reps).To(HaveLen(1))
+	})
+})
@@ -28,15 +28,56 @@ var ts = tmpSysFs{
 	dirList: []string{
 		"sys/class/net",
 		"sys/bus/pci/devices",
+		"sys/bus/pci/drivers/i40e",
+		"sys/bus/pci/drivers/iavf",
+		"sys/bus/pci/drivers/vfio-pci",
+		"sys/module/iavf",
+		"sys/devices/system/node/node1",
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0/msi_irqs",
+		"proc/irq/120",
+		"proc/irq/121",
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/net/enp175s0f1",
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/sriov/0",
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0/net/enp175s6",
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0/net/enp175s6/queues/tx-0",
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0/net/enp175s6/queues/tx-1",
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0/net/enp175s6/queues/rx-0",
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.1/net/enp175s7",
 		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1",
 		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1d1",
+		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1d1/compat/devlink",
+		"sys/devices/virtual/net/ens1_0",
+		"sys/devices/virtual/net/ens1_1",
 	},
 	fileList: map[string][]byte{
-		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/sriov_numvfs": []byte("2"),
-		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/sriov_numvfs": []byte("0"),
+		"sys/module/iavf/version":                                                              []byte("4.8.2\n"),
+		"sys/bus/pci/drivers/iavf/bind":                                                        []byte(""),
+		"sys/bus/pci/drivers/iavf/unbind":                                                      []byte(""),
+		"sys/bus/pci/drivers/vfio-pci/bind":                                                    []byte(""),
+		"sys/bus/pci/drivers/vfio-pci/unbind":                                                  []byte(""),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0/driver_override":                     []byte(""),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0/numa_node":                           []byte("1\n"),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0/msi_irqs/120":                        []byte("msix"),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0/msi_irqs/121":                        []byte("msix"),
+		"sys/devices/system/node/node1/cpumap":                                                 []byte("00000000,ffff0000\n"),
+		"proc/irq/120/smp_affinity":                                                            []byte("ffffffff,ffffffff"),
+		"proc/irq/121/smp_affinity":                                                            []byte("ffffffff,ffffffff"),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/net/enp175s0f1/speed":                []byte("25000\n"),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/sriov_numvfs":                        []byte("2"),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/sriov_totalvfs":                      []byte("64\n"),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/sriov/0/vlan_anti_spoof":             []byte("on\n"),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/sriov/0/max_mac_changes":             []byte("0"),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/sriov/0/max_tx_rate":                 []byte("10000\n"),
+		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/sriov_numvfs":                        []byte("0"),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0/net/enp175s6/queues/tx-0/tx_maxrate": []byte("0"),
+		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1/phys_switch_id":             []byte("b8cef603000a1b2c\n"),
+		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1/phys_port_name":             []byte("p0\n"),
+		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1d1/phys_switch_id":           []byte("b8cef603000a1b2d\n"),
+		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1d1/compat/devlink/mode":      []byte("legacy\n"),
+		"sys/devices/virtual/net/ens1_0/phys_switch_id":                                        []byte("b8cef603000a1b2c\n"),
+		"sys/devices/virtual/net/ens1_0/phys_port_name":                                        []byte("pf0vf0\n"),
+		"sys/devices/virtual/net/ens1_1/phys_switch_id":                                        []byte("b8cef603000a1b2c\n"),
+		"sys/devices/virtual/net/ens1_1/phys_port_name":                                        []byte("pf0vf1\n"),
 	},
 	netSymlinks: map[string]string{
 		"sys/class/net/enp175s0f1": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/net/enp175s0f1",
@@ -44,6 +85,8 @@ var ts = tmpSysFs{
 		"sys/class/net/enp175s7":   "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.1/net/enp175s7",
 		"sys/class/net/ens1":       "sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1",
 		"sys/class/net/ens1d1":     "sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1d1",
+		"sys/class/net/ens1_0":     "sys/devices/virtual/net/ens1_0",
+		"sys/class/net/ens1_1":     "sys/devices/virtual/net/ens1_1",
 	},
 	devSymlinks: map[string]string{
 		"sys/class/net/enp175s0f1/device": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1",
@@ -56,6 +99,9 @@ var ts = tmpSysFs{
 		"sys/bus/pci/devices/0000:af:06.0": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0",
 		"sys/bus/pci/devices/0000:af:06.1": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.1",
 		"sys/bus/pci/devices/0000:05:00.0": "sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0",
+
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/driver": "sys/bus/pci/drivers/i40e",
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0/driver": "sys/bus/pci/drivers/iavf",
 	},
 	vfSymlinks: map[string]string{
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/virtfn0": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0",
@@ -110,7 +156,11 @@ func CreateTmpSysFs() error {
 	}
 
 	SysBusPci = filepath.Join(ts.dirRoot, SysBusPci)
+	SysBusPciDrivers = filepath.Join(ts.dirRoot, SysBusPciDrivers)
 	NetDirectory = filepath.Join(ts.dirRoot, NetDirectory)
+	SysModule = filepath.Join(ts.dirRoot, SysModule)
+	SysNodeDirectory = filepath.Join(ts.dirRoot, SysNodeDirectory)
+	ProcIrq = filepath.Join(ts.dirRoot, ProcIrq)
 	return nil
 }
 
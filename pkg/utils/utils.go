@@ -2,30 +2,53 @@ package utils
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/containernetworking/plugins/pkg/ns"
+	"golang.org/x/sys/unix"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
 	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 )
 
 var (
 	sriovConfigured = "/sriov_numvfs"
+	sriovTotalVFs   = "sriov_totalvfs"
 	// NetDirectory sysfs net directory
 	NetDirectory = "/sys/class/net"
 	// SysBusPci is sysfs pci device directory
 	SysBusPci = "/sys/bus/pci/devices"
+	// SysBusPciDrivers is sysfs pci driver directory
+	SysBusPciDrivers = "/sys/bus/pci/drivers"
+	// SysModule is the sysfs directory of the loaded kernel modules
+	SysModule = "/sys/module"
 	// SysV4ArpNotify is the sysfs IPv4 ARP Notify directory
 	SysV4ArpNotify = "/proc/sys/net/ipv4/conf/"
 	// SysV6NdiscNotify is the sysfs IPv6 Neighbor Discovery Notify directory
 	SysV6NdiscNotify = "/proc/sys/net/ipv6/conf/"
+	// SysProcSys is the procfs sysctl directory
+	SysProcSys = "/proc/sys"
 	// UserspaceDrivers is a list of driver names that don't have netlink representation for their devices
 	UserspaceDrivers = []string{"vfio-pci", "uio_pci_generic", "igb_uio"}
+	// ErrNotSupported is returned when the device or its driver does not support the requested operation
+	ErrNotSupported = errors.New("operation not supported by device")
+	// ErrLegacyMode is returned when the e-switch of the PF is in legacy mode, its VFs have no representor
+	ErrLegacyMode = errors.New("PF e-switch is in legacy mode")
+	// ErrDeviceNotFound is returned when there is no PCI device with the given address
+	ErrDeviceNotFound = errors.New("pci device not found")
+	// ErrNotVF is returned when the PCI device is not an SR-IOV VF
+	ErrNotVF = errors.New("pci device is not an SR-IOV VF")
 )
 
 // EnableArpAndNdiscNotify enables IPv4 arp_notify and IPv6 ndisc_notify for netdev
@@ -38,15 +61,35 @@ func EnableArpAndNdiscNotify(ifName string) error {
 	 * an interface or if the MAC address configuration is changed. The kernel is responsible
 	 * for sending of these packets when the conditions are met.
 	 */
+	// Each address family is set independently, so that an IPv6 only pod still gets ndisc_notify
+	var errs []error
 	v4ArpNotifyPath := filepath.Join(SysV4ArpNotify, ifName, "arp_notify")
-	err := os.WriteFile(v4ArpNotifyPath, []byte("1"), os.ModeAppend)
-	if err != nil {
-		return fmt.Errorf("failed to write arp_notify=1 for interface %s: %v", ifName, err)
+	if err := os.WriteFile(v4ArpNotifyPath, []byte("1"), os.ModeAppend); err != nil {
+		errs = append(errs, fmt.Errorf("failed to write arp_notify=1 for interface %s: %v", ifName, err))
 	}
 	v6NdiscNotifyPath := filepath.Join(SysV6NdiscNotify, ifName, "ndisc_notify")
-	err = os.WriteFile(v6NdiscNotifyPath, []byte("1"), os.ModeAppend)
-	if err != nil {
-		return fmt.Errorf("failed to write ndisc_notify=1 for interface %s: %v", ifName, err)
+	if err := os.WriteFile(v6NdiscNotifyPath, []byte("1"), os.ModeAppend); err != nil {
+		errs = append(errs, fmt.Errorf("failed to write ndisc_notify=1 for interface %s: %v", ifName, err))
+	}
+	return errors.Join(errs...)
+}
+
+// SysctlIfName is the placeholder of the interface name in the name of an interface sysctl
+const SysctlIfName = "IFNAME"
+
+// SetInterfaceSysctl sets an interface sysctl named with SysctlIfName in place of the interface name,
+// e.g. net.ipv6.conf.IFNAME.accept_ra
+func SetInterfaceSysctl(ifName, name, value string) error {
+	// the interface name may contain dots, it is substituted after the name is split
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part == SysctlIfName {
+			parts[i] = ifName
+		}
+	}
+	path := filepath.Join(append([]string{SysProcSys}, parts...)...)
+	if err := os.WriteFile(path, []byte(value), os.ModeAppend); err != nil {
+		return fmt.Errorf("failed to write %s=%s for interface %s: %v", name, value, ifName, err)
 	}
 	return nil
 }
@@ -61,6 +104,162 @@ func EnableOptimisticDad(ifName string) error {
 	return nil
 }
 
+// GetTxQueueCount returns the number of tx queues exposed in sysfs for netdev
+func GetTxQueueCount(ifName string) (int, error) {
+	return getQueueCount(ifName, "tx-")
+}
+
+// GetRxQueueCount returns the number of rx queues exposed in sysfs for netdev
+func GetRxQueueCount(ifName string) (int, error) {
+	return getQueueCount(ifName, "rx-")
+}
+
+func getQueueCount(ifName, prefix string) (int, error) {
+	queuesDir := filepath.Join(NetDirectory, ifName, "queues")
+	fInfos, err := os.ReadDir(queuesDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the queues dir of the device %q: %v", ifName, err)
+	}
+
+	count := 0
+	for _, f := range fInfos {
+		if strings.HasPrefix(f.Name(), prefix) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// SetTxQueueMaxRate sets the maximum transmit rate, in Mbps, of a tx queue of netdev. 0 disables rate limiting.
+// ErrNotSupported is returned if the driver does not expose or implement per-queue rate limiting.
+func SetTxQueueMaxRate(ifName string, queue, rate int) error {
+	path := filepath.Join(NetDirectory, ifName, "queues", fmt.Sprintf("tx-%d", queue), "tx_maxrate")
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("tx queue %d of device %q has no tx_maxrate: %w", queue, ifName, ErrNotSupported)
+		}
+		return fmt.Errorf("failed to stat tx_maxrate of tx queue %d of device %q: %v", queue, ifName, err)
+	}
+
+	if err := os.WriteFile(path, []byte(strconv.Itoa(rate)), os.ModeAppend); err != nil {
+		if errors.Is(err, syscall.EOPNOTSUPP) {
+			return fmt.Errorf("failed to write tx_maxrate=%d for tx queue %d of device %q: %w", rate, queue, ifName, ErrNotSupported)
+		}
+		return fmt.Errorf("failed to write tx_maxrate=%d for tx queue %d of device %q: %v", rate, queue, ifName, err)
+	}
+	return nil
+}
+
+// vfSriovAttrPath returns the path of a per-VF attribute exposed by the PF driver under device/sriov/<vf>
+func vfSriovAttrPath(pfName string, vfID int, attr string) string {
+	return filepath.Join(NetDirectory, pfName, "device", "sriov", strconv.Itoa(vfID), attr)
+}
+
+// GetVFMaxTxRateCeiling returns the maximum transmit rate, in Mbps, the PF allows to configure on a VF.
+// ErrNotSupported is returned if the PF driver does not expose a per-VF ceiling.
+func GetVFMaxTxRateCeiling(pfName string, vfID int) (int, error) {
+	path := vfSriovAttrPath(pfName, vfID, "max_tx_rate")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, fmt.Errorf("vf %d of device %q has no max_tx_rate: %w", vfID, pfName, ErrNotSupported)
+		}
+		return 0, fmt.Errorf("failed to read max_tx_rate of vf %d of device %q: %v", vfID, pfName, err)
+	}
+
+	ceiling, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse max_tx_rate of vf %d of device %q: %v", vfID, pfName, err)
+	}
+	return ceiling, nil
+}
+
+// GetVFMaxMacChanges returns the maximum number of MAC address changes allowed to a VF.
+// ErrNotSupported is returned if the PF driver does not expose the limit.
+func GetVFMaxMacChanges(pfName string, vfID int) (int, error) {
+	path := vfSriovAttrPath(pfName, vfID, "max_mac_changes")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, fmt.Errorf("vf %d of device %q has no max_mac_changes: %w", vfID, pfName, ErrNotSupported)
+		}
+		return 0, fmt.Errorf("failed to read max_mac_changes of vf %d of device %q: %v", vfID, pfName, err)
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse max_mac_changes of vf %d of device %q: %v", vfID, pfName, err)
+	}
+	return limit, nil
+}
+
+// SetVFMaxMacChanges limits the number of MAC address changes allowed to a VF.
+// ErrNotSupported is returned if the PF driver does not expose the limit.
+func SetVFMaxMacChanges(pfName string, vfID, limit int) error {
+	path := vfSriovAttrPath(pfName, vfID, "max_mac_changes")
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("vf %d of device %q has no max_mac_changes: %w", vfID, pfName, ErrNotSupported)
+		}
+		return fmt.Errorf("failed to stat max_mac_changes of vf %d of device %q: %v", vfID, pfName, err)
+	}
+
+	if err := os.WriteFile(path, []byte(strconv.Itoa(limit)), os.ModeAppend); err != nil {
+		if errors.Is(err, syscall.EOPNOTSUPP) {
+			return fmt.Errorf("failed to write max_mac_changes=%d for vf %d of device %q: %w", limit, vfID, pfName, ErrNotSupported)
+		}
+		return fmt.Errorf("failed to write max_mac_changes=%d for vf %d of device %q: %v", limit, vfID, pfName, err)
+	}
+	return nil
+}
+
+// GetVFVlanAntiSpoof returns true if the PF drops the frames of the VF carrying a vlan tag the VF is not assigned.
+// ErrNotSupported is returned if the PF driver does not expose the vlan anti-spoofing of its VFs.
+func GetVFVlanAntiSpoof(pfName string, vfID int) (bool, error) {
+	path := vfSriovAttrPath(pfName, vfID, "vlan_anti_spoof")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, fmt.Errorf("vf %d of device %q has no vlan_anti_spoof: %w", vfID, pfName, ErrNotSupported)
+		}
+		return false, fmt.Errorf("failed to read vlan_anti_spoof of vf %d of device %q: %v", vfID, pfName, err)
+	}
+
+	switch state := strings.TrimSpace(string(data)); state {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	default:
+		return false, fmt.Errorf("failed to parse vlan_anti_spoof %q of vf %d of device %q", state, vfID, pfName)
+	}
+}
+
+// SetVFVlanAntiSpoof turns on or off the dropping of the frames of the VF carrying a vlan tag the VF is not assigned.
+// ErrNotSupported is returned if the PF driver does not expose the vlan anti-spoofing of its VFs.
+func SetVFVlanAntiSpoof(pfName string, vfID int, on bool) error {
+	path := vfSriovAttrPath(pfName, vfID, "vlan_anti_spoof")
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("vf %d of device %q has no vlan_anti_spoof: %w", vfID, pfName, ErrNotSupported)
+		}
+		return fmt.Errorf("failed to stat vlan_anti_spoof of vf %d of device %q: %v", vfID, pfName, err)
+	}
+
+	state := "off"
+	if on {
+		state = "on"
+	}
+	if err := os.WriteFile(path, []byte(state), os.ModeAppend); err != nil {
+		if errors.Is(err, syscall.EOPNOTSUPP) {
+			return fmt.Errorf("failed to write vlan_anti_spoof=%s for vf %d of device %q: %w", state, vfID, pfName, ErrNotSupported)
+		}
+		return fmt.Errorf("failed to write vlan_anti_spoof=%s for vf %d of device %q: %v", state, vfID, pfName, err)
+	}
+	return nil
+}
+
 // GetSriovNumVfs takes in a PF name(ifName) as string and returns number of VF configured as int
 func GetSriovNumVfs(ifName string) (int, error) {
 	var vfTotal int
@@ -113,10 +312,50 @@ func GetVfid(addr string, pfName string) (int, error) {
 	return id, fmt.Errorf("unable to get VF ID with PF: %s and VF pci address %v", pfName, addr)
 }
 
+// VFDeviceExists returns false when the sysfs device of the VF or the one of its PF no longer exists, e.g. when the
+// PF driver was unbound, which removes the PF netdev and destroys its VFs
+func VFDeviceExists(pfName, vfPci string) (bool, error) {
+	for _, path := range []string{filepath.Join(SysBusPci, vfPci), filepath.Join(NetDirectory, pfName, "device")} {
+		if _, err := os.Lstat(path); err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// physFnPath returns the sysfs physfn symlink of a VF pci address.
+// ErrDeviceNotFound or ErrNotVF is returned if the address is not the one of a VF.
+func physFnPath(vfPci string) (string, error) {
+	if _, err := os.Lstat(filepath.Join(SysBusPci, vfPci)); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%s: %w", vfPci, ErrDeviceNotFound)
+		}
+		return "", err
+	}
+
+	physFn := filepath.Join(SysBusPci, vfPci, "physfn")
+	if _, err := os.Lstat(physFn); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%s: %w", vfPci, ErrNotVF)
+		}
+		return "", err
+	}
+
+	return physFn, nil
+}
+
 // GetPfName returns PF net device name of a given VF pci address
-func GetPfName(vf string) (string, error) {
-	pfSymLink := filepath.Join(SysBusPci, vf, "physfn", "net")
-	_, err := os.Lstat(pfSymLink)
+func GetPfName(vfPci string) (string, error) {
+	physFn, err := physFnPath(vfPci)
+	if err != nil {
+		return "", err
+	}
+
+	pfSymLink := filepath.Join(physFn, "net")
+	_, err = os.Lstat(pfSymLink)
 	if err != nil {
 		return "", err
 	}
@@ -133,6 +372,45 @@ func GetPfName(vf string) (string, error) {
 	return strings.TrimSpace(files[0].Name()), nil
 }
 
+// GetPfPciFromVfPci returns the PF pci address of a given VF pci address
+func GetPfPciFromVfPci(vfPci string) (string, error) {
+	physFn, err := physFnPath(vfPci)
+	if err != nil {
+		return "", err
+	}
+
+	pfPath, err := os.Readlink(physFn)
+	if err != nil {
+		return "", fmt.Errorf("can't read the physfn symbolic link of the device %q: %v", vfPci, err)
+	}
+
+	return filepath.Base(pfPath), nil
+}
+
+// GetVfIndexByPci returns the VF index of a given VF pci address, matching the virtfn symlinks of its PF
+func GetVfIndexByPci(vfPci string) (int, error) {
+	physFn, err := physFnPath(vfPci)
+	if err != nil {
+		return -1, err
+	}
+
+	virtFns, err := filepath.Glob(filepath.Join(physFn, "virtfn*"))
+	if err != nil {
+		return -1, err
+	}
+	for _, virtFn := range virtFns {
+		pciinfo, err := os.Readlink(virtFn)
+		if err != nil {
+			continue
+		}
+		if filepath.Base(pciinfo) == vfPci {
+			return strconv.Atoi(strings.TrimPrefix(filepath.Base(virtFn), "virtfn"))
+		}
+	}
+
+	return -1, fmt.Errorf("unable to find VF index of the device %q in the virtfn symbolic links of its PF", vfPci)
+}
+
 // GetPciAddress takes in a interface(ifName) and VF id and returns its pci addr as string
 func GetPciAddress(ifName string, vf int) (string, error) {
 	var pciaddr string
@@ -228,6 +506,74 @@ func GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, error) {
 	return names, nil
 }
 
+var (
+	// pfPortNameRe matches the phys_port_name of a PF uplink representor, e.g. p0
+	pfPortNameRe = regexp.MustCompile(`^p(\d+)$`)
+	// vfPortNameRe matches the phys_port_name of a VF representor, e.g. pf0vf3 or c1pf0vf3
+	vfPortNameRe = regexp.MustCompile(`^(?:c\d+)?pf(\d+)vf(\d+)$`)
+	// legacyVFPortNameRe matches the phys_port_name of a VF representor of older drivers, i.e. the VF index
+	legacyVFPortNameRe = regexp.MustCompile(`^(\d+)$`)
+)
+
+// readNetAttr reads a sysfs attribute of netdev, returning an empty string if it is not readable
+func readNetAttr(ifName, attr string) string {
+	data, err := os.ReadFile(filepath.Join(NetDirectory, ifName, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// IsSwitchdevMode returns true if the e-switch of the PF is in switchdev mode, as reported by the devlink compat
+// mode of the drivers that have it, or else by the phys_switch_id the PF has in switchdev mode
+func IsSwitchdevMode(pfName string) bool {
+	if mode := readNetAttr(pfName, "compat/devlink/mode"); mode != "" {
+		return mode == "switchdev"
+	}
+	return readNetAttr(pfName, "phys_switch_id") != ""
+}
+
+// GetVFRepresentor returns the name of the representor netdev of a VF of a PF in switchdev mode. The representor
+// shares the phys_switch_id of the PF and its phys_port_name identifies the VF index. The error wraps
+// ErrLegacyMode when the PF is in legacy mode.
+func GetVFRepresentor(pfName string, vfIndex int) (string, error) {
+	if !IsSwitchdevMode(pfName) {
+		return "", fmt.Errorf("PF %s is not in switchdev mode, its VFs have no representor: %w", pfName, ErrLegacyMode)
+	}
+	switchID := readNetAttr(pfName, "phys_switch_id")
+	if switchID == "" {
+		return "", fmt.Errorf("PF %s has no switch id, its VFs have no representor", pfName)
+	}
+	pfIndex := ""
+	if m := pfPortNameRe.FindStringSubmatch(readNetAttr(pfName, "phys_port_name")); m != nil {
+		pfIndex = m[1]
+	}
+
+	fInfos, err := os.ReadDir(NetDirectory)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the net directory: %v", err)
+	}
+	vf := strconv.Itoa(vfIndex)
+	for _, f := range fInfos {
+		ifName := f.Name()
+		if ifName == pfName || readNetAttr(ifName, "phys_switch_id") != switchID {
+			continue
+		}
+		portName := readNetAttr(ifName, "phys_port_name")
+		if m := vfPortNameRe.FindStringSubmatch(portName); m != nil {
+			if m[2] == vf && (pfIndex == "" || m[1] == pfIndex) {
+				return ifName, nil
+			}
+			continue
+		}
+		if m := legacyVFPortNameRe.FindStringSubmatch(portName); m != nil && m[1] == vf {
+			return ifName, nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to find the representor of vf %d of PF %s", vfIndex, pfName)
+}
+
 // HasDpdkDriver checks if a device is attached to dpdk supported driver
 func HasDpdkDriver(pciAddr string) (bool, error) {
 	driverLink := filepath.Join(SysBusPci, pciAddr, "driver")
@@ -239,13 +585,165 @@ func HasDpdkDriver(pciAddr string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	driverName := driverStat.Name()
+	return IsUserspaceDriver(driverStat.Name()), nil
+}
+
+// IsUserspaceDriver checks if driver is one of the UserspaceDrivers
+func IsUserspaceDriver(driver string) bool {
 	for _, drv := range UserspaceDrivers {
-		if driverName == drv {
-			return true, nil
+		if driver == drv {
+			return true
+		}
+	}
+	return false
+}
+
+// GetVFDriver returns the name of the driver a PCI device is bound to, or the empty string if it is not bound
+func GetVFDriver(pciAddr string) (string, error) {
+	driverLink := filepath.Join(SysBusPci, pciAddr, "driver")
+	driverPath, err := os.Readlink(driverLink)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read the driver link of device %s: %v", pciAddr, err)
+	}
+	return filepath.Base(driverPath), nil
+}
+
+// GetPFDriver returns the name of the driver the PF netdev is bound to
+func GetPFDriver(pfName string) (string, error) {
+	driverLink := filepath.Join(NetDirectory, pfName, "device", "driver")
+	driverPath, err := os.Readlink(driverLink)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the driver link of PF %s: %v", pfName, err)
+	}
+	return filepath.Base(driverPath), nil
+}
+
+// GetPFLinkSpeed returns the link speed of the PF netdev in Mbps. The speed of a PF whose link is down, or whose
+// driver does not report it, is unknown and returned as an error.
+func GetPFLinkSpeed(pfName string) (int, error) {
+	speedFile := filepath.Join(NetDirectory, pfName, "speed")
+	data, err := os.ReadFile(speedFile)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the link speed of PF %s: %v", pfName, err)
+	}
+	speed, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse the link speed of PF %s: %v", pfName, err)
+	}
+	if speed <= 0 {
+		return 0, fmt.Errorf("link speed of PF %s is unknown", pfName)
+	}
+	return speed, nil
+}
+
+// GetPFTotalVFs returns the maximum number of VFs the PF supports, from its sriov_totalvfs sysfs node
+func GetPFTotalVFs(pfName string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(NetDirectory, pfName, "device", sriovTotalVFs))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the sriov_totalvfs of PF %s: %v", pfName, err)
+	}
+	totalVFs, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse the sriov_totalvfs of PF %s: %v", pfName, err)
+	}
+	return totalVFs, nil
+}
+
+// GetKernelRelease returns the release of the running kernel, as printed by uname -r
+func GetKernelRelease() (string, error) {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return "", fmt.Errorf("failed to get the kernel release: %v", err)
+	}
+	return unix.ByteSliceToString(uts.Release[:]), nil
+}
+
+// GetDriverVersion returns the version of a driver module, or the empty string if the module has none, e.g.
+// in-tree drivers versioned with the kernel
+func GetDriverVersion(driver string) string {
+	data, err := os.ReadFile(filepath.Join(SysModule, driver, "version"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// LogEnvironment logs the kernel release and the driver of the VF and its version at debug level, for
+// support triage
+func LogEnvironment(pciAddr string) {
+	kernel, err := GetKernelRelease()
+	if err != nil {
+		kernel = err.Error()
+	}
+	driver, err := GetVFDriver(pciAddr)
+	if err != nil {
+		driver = err.Error()
+	}
+	logging.Debug("environment",
+		"kernel", kernel,
+		"driver", driver,
+		"driver_version", GetDriverVersion(driver))
+}
+
+// BindDriver unbinds a PCI device from its current driver and binds it to driver using driver_override
+func BindDriver(pciAddr, driver string) error {
+	return bindDriver(pciAddr, driver, driver)
+}
+
+// RestoreDriver unbinds a PCI device from its current driver, clears driver_override and binds it to driver
+func RestoreDriver(pciAddr, driver string) error {
+	return bindDriver(pciAddr, driver, "\n")
+}
+
+func bindDriver(pciAddr, driver, override string) error {
+	unbindPath := filepath.Join(SysBusPci, pciAddr, "driver", "unbind")
+	if _, err := os.Stat(unbindPath); err == nil {
+		if err := os.WriteFile(unbindPath, []byte(pciAddr), os.ModeAppend); err != nil {
+			return fmt.Errorf("failed to unbind device %s from its driver: %v", pciAddr, err)
 		}
 	}
-	return false, nil
+
+	overridePath := filepath.Join(SysBusPci, pciAddr, "driver_override")
+	if err := os.WriteFile(overridePath, []byte(override), os.ModeAppend); err != nil {
+		return fmt.Errorf("failed to write driver_override for device %s: %v", pciAddr, err)
+	}
+
+	bindPath := filepath.Join(SysBusPciDrivers, driver, "bind")
+	if err := os.WriteFile(bindPath, []byte(pciAddr), os.ModeAppend); err != nil {
+		return fmt.Errorf("failed to bind device %s to driver %s: %v", pciAddr, driver, err)
+	}
+	return nil
+}
+
+// SaveVFKernelDriver records the kernel driver of a VF in dataDir, so that it is still known once the VF was
+// left bound to a userspace driver
+func SaveVFKernelDriver(dataDir, pciAddr, driver string) error {
+	driversDir := filepath.Join(dataDir, "drivers")
+	if err := os.MkdirAll(driversDir, 0700); err != nil {
+		return fmt.Errorf("failed to create the drivers directory(%q): %v", driversDir, err)
+	}
+
+	path := filepath.Join(driversDir, pciAddr)
+	if err := os.WriteFile(path, []byte(driver), 0600); err != nil {
+		return fmt.Errorf("failed to write the kernel driver of %s in the path(%q): %v", pciAddr, path, err)
+	}
+	return nil
+}
+
+// GetVFKernelDriver returns the kernel driver of a VF recorded by SaveVFKernelDriver, or an empty string if none was
+func GetVFKernelDriver(dataDir, pciAddr string) (string, error) {
+	path := filepath.Join(dataDir, "drivers", pciAddr)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read the kernel driver of %s in the path(%q): %v", pciAddr, path, err)
+	}
+	return strings.TrimSpace(string(data)), nil
 }
 
 // SaveNetConf takes in container ID, data dir and Pod interface name as string and a json encoded struct Conf
@@ -392,6 +890,88 @@ func IsValidMACAddress(addr net.HardwareAddr) bool {
 	return valid
 }
 
+// ParseGUID parses an InfiniBand GUID given as 8 colon separated bytes, e.g. 00:11:22:33:44:55:66:77
+func ParseGUID(guid string) (net.HardwareAddr, error) {
+	addr, err := net.ParseMAC(guid)
+	if err != nil || len(addr) != 8 || strings.Count(guid, ":") != 7 {
+		return nil, fmt.Errorf("invalid GUID %q: value must be 8 colon separated bytes", guid)
+	}
+	return addr, nil
+}
+
+// ParseEgressQoSMap parses a VLAN egress QoS map of comma separated skb priority to PCP mappings, e.g. "0:1,2:3"
+func ParseEgressQoSMap(qosMap string) (map[uint32]uint32, error) {
+	mappings := map[uint32]uint32{}
+	for _, entry := range strings.Split(qosMap, ",") {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid egress qos mapping %q: expected <priority>:<pcp>", entry)
+		}
+		from, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil || from > 7 {
+			return nil, fmt.Errorf("invalid egress qos mapping %q: priority must be in the range 0-7", entry)
+		}
+		to, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil || to > 7 {
+			return nil, fmt.Errorf("invalid egress qos mapping %q: pcp must be in the range 0-7", entry)
+		}
+		if _, ok := mappings[uint32(from)]; ok {
+			return nil, fmt.Errorf("invalid egress qos mapping %q: priority %d is mapped more than once", entry, from)
+		}
+		mappings[uint32(from)] = uint32(to)
+	}
+	return mappings, nil
+}
+
+// ParseIngressQoSMap parses an ingress QoS map of comma separated mappings of a packet field value in the range
+// 0-maxFrom to a value in the range 0-7, e.g. "10:1,46:5". The field and the target are named in the errors.
+func ParseIngressQoSMap(qosMap, field, target string, maxFrom uint8) (map[uint8]uint8, error) {
+	mappings := map[uint8]uint8{}
+	for _, entry := range strings.Split(qosMap, ",") {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid ingress qos mapping %q: expected <%s>:<%s>", entry, field, target)
+		}
+		from, err := strconv.ParseUint(fields[0], 10, 8)
+		if err != nil || from > uint64(maxFrom) {
+			return nil, fmt.Errorf("invalid ingress qos mapping %q: %s must be in the range 0-%d", entry, field, maxFrom)
+		}
+		to, err := strconv.ParseUint(fields[1], 10, 8)
+		if err != nil || to > 7 {
+			return nil, fmt.Errorf("invalid ingress qos mapping %q: %s must be in the range 0-7", entry, target)
+		}
+		if _, ok := mappings[uint8(from)]; ok {
+			return nil, fmt.Errorf("invalid ingress qos mapping %q: %s %d is mapped more than once", entry, field, from)
+		}
+		mappings[uint8(from)] = uint8(to)
+	}
+	return mappings, nil
+}
+
+// MACFromPCI derives a deterministic MAC address from a VF pci address, so that a VF always gets the same MAC
+// without an external allocator. The address is the first 6 bytes of the SHA-256 digest of the pci address string,
+// with the locally administered bit (0x02) of the first byte set and the multicast bit (0x01) cleared, so it is a
+// unicast address that does not collide with vendor assigned MACs.
+func MACFromPCI(pciAddr string) net.HardwareAddr {
+	sum := sha256.Sum256([]byte(pciAddr))
+	mac := net.HardwareAddr(sum[:6])
+	mac[0] = (mac[0] | 0x02) &^ 0x01
+	return mac
+}
+
+// MACFromHostname derives a stable MAC address for a VF from the node hostname, its PF name and its VF index. The first
+// 4 bytes are the first bytes of the SHA-256 digest of "<hostname>/<pfName>", with the locally administered bit set and
+// the multicast bit cleared, and the last 2 bytes are the VF index, so VFs of a PF on a node never share a MAC.
+func MACFromHostname(hostname, pfName string, vfID int) net.HardwareAddr {
+	sum := sha256.Sum256([]byte(hostname + "/" + pfName))
+	mac := make(net.HardwareAddr, 6)
+	copy(mac, sum[:4])
+	mac[0] = (mac[0] | 0x02) &^ 0x01
+	mac[4] = byte(vfID >> 8)
+	mac[5] = byte(vfID)
+	return mac
+}
+
 // IsIPv4 checks if a net.IP is an IPv4 address.
 func IsIPv4(ip net.IP) bool {
 	return ip.To4() != nil
@@ -414,3 +994,32 @@ func Retry(retries int, sleep time.Duration, f func() error) error {
 	}
 	return err
 }
+
+var (
+	// NetNSOpenRetries is the number of attempts made to open a netns which is briefly unavailable
+	NetNSOpenRetries = 10
+	// NetNSOpenRetryInterval is the time waited between two attempts to open a netns
+	NetNSOpenRetryInterval = 100 * time.Millisecond
+)
+
+// GetNSWithRetry opens the netns at nspath, retrying while the path does not exist yet or is not yet
+// a network namespace, as happens while the pod sandbox is being created. Other errors are returned immediately.
+func GetNSWithRetry(nspath string) (ns.NetNS, error) {
+	var err error
+	for attempt := 1; attempt <= NetNSOpenRetries; attempt++ {
+		var netns ns.NetNS
+		netns, err = ns.GetNS(nspath)
+		if err == nil {
+			return netns, nil
+		}
+		switch err.(type) {
+		case ns.NSPathNotExistErr, ns.NSPathNotNSErr:
+		default:
+			return nil, err
+		}
+		if attempt < NetNSOpenRetries {
+			time.Sleep(NetNSOpenRetryInterval)
+		}
+	}
+	return nil, fmt.Errorf("netns %q still unavailable after %d attempts: %v", nspath, NetNSOpenRetries, err)
+}
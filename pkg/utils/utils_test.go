@@ -3,6 +3,7 @@ package utils
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
@@ -12,9 +13,11 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 
 	cnitypes "github.com/containernetworking/cni/pkg/types"
 
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
 	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 	mocks_utils "github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils/mocks"
 )
@@ -53,6 +56,40 @@ var _ = Describe("Utils", func() {
 			result, err := GetPfName("0000:af:07.0")
 			Expect(result).To(Equal(""))
 			Expect(err).To(HaveOccurred(), "Not existing VF should return an error")
+			Expect(errors.Is(err, ErrDeviceNotFound)).To(BeTrue())
+		})
+		It("Assuming device is not a vf", func() {
+			_, err := GetPfName("0000:af:00.1")
+			Expect(errors.Is(err, ErrNotVF)).To(BeTrue(), "PF device should return ErrNotVF")
+		})
+	})
+	Context("Checking GetPfPciFromVfPci function", func() {
+		It("Assuming existing vf", func() {
+			result, err := GetPfPciFromVfPci("0000:af:06.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("0000:af:00.1"))
+		})
+		It("Assuming not existing device", func() {
+			_, err := GetPfPciFromVfPci("0000:af:07.0")
+			Expect(errors.Is(err, ErrDeviceNotFound)).To(BeTrue())
+		})
+		It("Assuming device is not a vf", func() {
+			_, err := GetPfPciFromVfPci("0000:af:00.1")
+			Expect(errors.Is(err, ErrNotVF)).To(BeTrue())
+		})
+	})
+	Context("Checking GetVfIndexByPci function", func() {
+		It("Assuming existing vfs", func() {
+			Expect(GetVfIndexByPci("0000:af:06.0")).To(Equal(0))
+			Expect(GetVfIndexByPci("0000:af:06.1")).To(Equal(1))
+		})
+		It("Assuming not existing device", func() {
+			_, err := GetVfIndexByPci("0000:af:07.0")
+			Expect(errors.Is(err, ErrDeviceNotFound)).To(BeTrue())
+		})
+		It("Assuming device is not a vf", func() {
+			_, err := GetVfIndexByPci("0000:05:00.0")
+			Expect(errors.Is(err, ErrNotVF)).To(BeTrue())
 		})
 	})
 	Context("Checking GetPciAddress function", func() {
@@ -233,4 +270,423 @@ var _ = Describe("Utils", func() {
 			Expect(netconf.DNS.Domain).To(Equal(newNetConf.DNS.Domain))
 		})
 	})
+	Context("Checking GetTxQueueCount function", func() {
+		It("Assuming existing interface", func() {
+			result, err := GetTxQueueCount("enp175s6")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(2), "Only tx queues should be counted")
+		})
+		It("Assuming not existing interface", func() {
+			_, err := GetTxQueueCount("enp175s9")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking SaveVFKernelDriver and GetVFKernelDriver functions", func() {
+		It("Assuming the kernel driver was recorded", func() {
+			dataDir := GinkgoT().TempDir()
+			Expect(SaveVFKernelDriver(dataDir, "0000:af:06.0", "iavf")).To(Succeed())
+			driver, err := GetVFKernelDriver(dataDir, "0000:af:06.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(driver).To(Equal("iavf"))
+		})
+		It("Assuming no kernel driver was recorded", func() {
+			driver, err := GetVFKernelDriver(GinkgoT().TempDir(), "0000:af:06.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(driver).To(BeEmpty())
+		})
+	})
+	Context("Checking GetRxQueueCount function", func() {
+		It("Assuming existing interface", func() {
+			result, err := GetRxQueueCount("enp175s6")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(1), "Only rx queues should be counted")
+		})
+	})
+	Context("Checking SetTxQueueMaxRate function", func() {
+		It("Assuming queue supports rate limiting", func() {
+			err := SetTxQueueMaxRate("enp175s6", 0, 1000)
+			Expect(err).NotTo(HaveOccurred())
+			data, err := os.ReadFile(filepath.Join(NetDirectory, "enp175s6", "queues", "tx-0", "tx_maxrate"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("1000"))
+		})
+		It("Assuming queue does not support rate limiting", func() {
+			err := SetTxQueueMaxRate("enp175s6", 1, 1000)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, ErrNotSupported)).To(BeTrue())
+		})
+	})
+	Context("Checking GetVFDriver function", func() {
+		It("Assuming device bound to a driver", func() {
+			result, err := GetVFDriver("0000:af:06.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("iavf"))
+		})
+		It("Assuming device not bound to a driver", func() {
+			result, err := GetVFDriver("0000:af:06.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(""))
+		})
+	})
+	Context("Checking ParseEgressQoSMap function", func() {
+		It("Parses the mappings", func() {
+			qosMap, err := ParseEgressQoSMap("0:1, 2:3,7:7")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(qosMap).To(Equal(map[uint32]uint32{0: 1, 2: 3, 7: 7}))
+		})
+		DescribeTable("Rejects invalid mappings",
+			func(qosMap string) {
+				_, err := ParseEgressQoSMap(qosMap)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("empty", ""),
+			Entry("missing pcp", "0:1,2"),
+			Entry("priority out of range", "8:1"),
+			Entry("pcp out of range", "0:8"),
+			Entry("negative value", "-1:1"),
+			Entry("duplicate priority", "0:1,0:2"),
+		)
+	})
+	Context("Checking GetPFDriver function", func() {
+		It("Assuming existing PF", func() {
+			result, err := GetPFDriver("enp175s0f1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal("i40e"))
+		})
+		It("Assuming not existing PF", func() {
+			_, err := GetPFDriver("enp175s0f9")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking LogEnvironment function", func() {
+		var logFile string
+
+		BeforeEach(func() {
+			logFile = filepath.Join(ts.dirRoot, "environment.log")
+		})
+
+		AfterEach(func() {
+			logging.Init("", "", "", "", "")
+			_ = os.Remove(logFile)
+		})
+
+		It("Logs the kernel, the VF driver and its version at debug level", func() {
+			logging.Init("debug", logFile, "", "", "")
+			LogEnvironment("0000:af:06.0")
+
+			kernel, err := GetKernelRelease()
+			Expect(err).NotTo(HaveOccurred())
+			data, err := os.ReadFile(logFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring(`msg="environment"`))
+			Expect(string(data)).To(ContainSubstring(fmt.Sprintf(`kernel=%q`, kernel)))
+			Expect(string(data)).To(ContainSubstring(`driver="iavf"`))
+			Expect(string(data)).To(ContainSubstring(`driver_version="4.8.2"`))
+		})
+
+		It("Does not log the environment at info level", func() {
+			logging.Init("info", logFile, "", "", "")
+			LogEnvironment("0000:af:06.0")
+
+			data, _ := os.ReadFile(logFile)
+			Expect(string(data)).NotTo(ContainSubstring("environment"))
+		})
+	})
+	Context("Checking BindDriver and RestoreDriver functions", func() {
+		It("Binds the device using driver_override", func() {
+			err := BindDriver("0000:af:06.0", "vfio-pci")
+			Expect(err).NotTo(HaveOccurred())
+
+			data, err := os.ReadFile(filepath.Join(SysBusPciDrivers, "iavf", "unbind"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("0000:af:06.0"))
+			data, err = os.ReadFile(filepath.Join(SysBusPci, "0000:af:06.0", "driver_override"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("vfio-pci"))
+			data, err = os.ReadFile(filepath.Join(SysBusPciDrivers, "vfio-pci", "bind"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("0000:af:06.0"))
+		})
+		It("Restores the original driver and clears driver_override", func() {
+			err := RestoreDriver("0000:af:06.0", "iavf")
+			Expect(err).NotTo(HaveOccurred())
+
+			data, err := os.ReadFile(filepath.Join(SysBusPci, "0000:af:06.0", "driver_override"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("\n"))
+			data, err = os.ReadFile(filepath.Join(SysBusPciDrivers, "iavf", "bind"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("0000:af:06.0"))
+		})
+		It("Assuming not existing driver", func() {
+			err := BindDriver("0000:af:06.0", "not-a-driver")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking GetVFMaxMacChanges and SetVFMaxMacChanges functions", func() {
+		It("Assuming PF driver exposes the MAC change limit", func() {
+			err := SetVFMaxMacChanges("enp175s0f1", 0, 3)
+			Expect(err).NotTo(HaveOccurred())
+			limit, err := GetVFMaxMacChanges("enp175s0f1", 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(limit).To(Equal(3))
+		})
+		It("Assuming PF driver does not expose the MAC change limit", func() {
+			err := SetVFMaxMacChanges("enp175s0f1", 1, 3)
+			Expect(errors.Is(err, ErrNotSupported)).To(BeTrue())
+			_, err = GetVFMaxMacChanges("enp175s0f1", 1)
+			Expect(errors.Is(err, ErrNotSupported)).To(BeTrue())
+		})
+	})
+	Context("Checking GetVFVlanAntiSpoof and SetVFVlanAntiSpoof functions", func() {
+		It("Assuming PF driver exposes the vlan anti-spoofing", func() {
+			on, err := GetVFVlanAntiSpoof("enp175s0f1", 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(on).To(BeTrue())
+
+			Expect(SetVFVlanAntiSpoof("enp175s0f1", 0, false)).To(Succeed())
+			defer func() { Expect(SetVFVlanAntiSpoof("enp175s0f1", 0, true)).To(Succeed()) }()
+			on, err = GetVFVlanAntiSpoof("enp175s0f1", 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(on).To(BeFalse())
+		})
+		It("Assuming PF driver does not expose the vlan anti-spoofing", func() {
+			err := SetVFVlanAntiSpoof("enp175s0f1", 1, false)
+			Expect(errors.Is(err, ErrNotSupported)).To(BeTrue())
+			_, err = GetVFVlanAntiSpoof("enp175s0f1", 1)
+			Expect(errors.Is(err, ErrNotSupported)).To(BeTrue())
+		})
+	})
+	Context("Checking GetVFMaxTxRateCeiling function", func() {
+		It("Assuming PF driver exposes a per-VF ceiling", func() {
+			ceiling, err := GetVFMaxTxRateCeiling("enp175s0f1", 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ceiling).To(Equal(10000))
+		})
+		It("Assuming PF driver does not expose a per-VF ceiling", func() {
+			_, err := GetVFMaxTxRateCeiling("enp175s0f1", 1)
+			Expect(errors.Is(err, ErrNotSupported)).To(BeTrue())
+		})
+	})
+	Context("Checking MACFromHostname function", func() {
+		It("Assuming the MAC is stable for a fixed hostname", func() {
+			mac := MACFromHostname("worker-0.example.com", "enp175s0f1", 3)
+			Expect(MACFromHostname("worker-0.example.com", "enp175s0f1", 3)).To(Equal(mac))
+			Expect(mac).To(HaveLen(6))
+			Expect(mac[0] & 0x02).To(Equal(byte(0x02)))
+			Expect(mac[0] & 0x01).To(Equal(byte(0x00)))
+		})
+		It("Assuming VFs of a PF get different MACs", func() {
+			seen := map[string]bool{}
+			for vfID := 0; vfID < 512; vfID++ {
+				mac := MACFromHostname("worker-0.example.com", "enp175s0f1", vfID).String()
+				Expect(seen).NotTo(HaveKey(mac))
+				seen[mac] = true
+			}
+		})
+		It("Assuming different hosts and PFs get different MACs", func() {
+			mac := MACFromHostname("worker-0.example.com", "enp175s0f1", 0)
+			Expect(MACFromHostname("worker-1.example.com", "enp175s0f1", 0)).NotTo(Equal(mac))
+			Expect(MACFromHostname("worker-0.example.com", "enp175s0f0", 0)).NotTo(Equal(mac))
+		})
+	})
+	Context("Checking MACFromPCI function", func() {
+		It("Assuming the MAC is stable across calls", func() {
+			mac := MACFromPCI("0000:af:06.0")
+			Expect(MACFromPCI("0000:af:06.0")).To(Equal(mac))
+			Expect(mac).To(HaveLen(6))
+		})
+		It("Assuming the MAC is a locally administered unicast address", func() {
+			for _, pciAddr := range []string{"0000:af:06.0", "0000:af:06.1", "0000:3b:02.0", "0000:05:00.0"} {
+				mac := MACFromPCI(pciAddr)
+				Expect(mac[0] & 0x02).To(Equal(byte(0x02)))
+				Expect(mac[0] & 0x01).To(Equal(byte(0x00)))
+			}
+		})
+		It("Assuming different VFs get different MACs", func() {
+			Expect(MACFromPCI("0000:af:06.0")).NotTo(Equal(MACFromPCI("0000:af:06.1")))
+		})
+	})
+	Context("Checking GetNSWithRetry function", func() {
+		var (
+			nsPath          string
+			origRetries     int
+			origRetryPeriod time.Duration
+		)
+
+		BeforeEach(func() {
+			nsPath = filepath.Join(GinkgoT().TempDir(), "netns")
+			origRetries, origRetryPeriod = NetNSOpenRetries, NetNSOpenRetryInterval
+			NetNSOpenRetries, NetNSOpenRetryInterval = 10, 20*time.Millisecond
+		})
+
+		AfterEach(func() {
+			NetNSOpenRetries, NetNSOpenRetryInterval = origRetries, origRetryPeriod
+		})
+
+		It("Assuming the netns becomes available after a short delay", func() {
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(50 * time.Millisecond)
+				Expect(os.Symlink("/proc/self/ns/net", nsPath)).To(Succeed())
+			}()
+			netns, err := GetNSWithRetry(nsPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netns.Close()).To(Succeed())
+		})
+		It("Assuming the netns never becomes available", func() {
+			_, err := GetNSWithRetry(nsPath)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("still unavailable after 10 attempts"))
+		})
+		It("Assuming the path is not a netns", func() {
+			Expect(os.WriteFile(nsPath, []byte{}, 0600)).To(Succeed())
+			start := time.Now()
+			_, err := GetNSWithRetry(nsPath)
+			Expect(err).To(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically(">=", 9*20*time.Millisecond))
+		})
+	})
+	Context("Checking drvInfoFromEthtool function", func() {
+		It("Assuming the driver reports its firmware and bus info", func() {
+			info := &unix.EthtoolDrvinfo{Cmd: unix.ETHTOOL_GDRVINFO}
+			copy(info.Driver[:], "iavf")
+			copy(info.Version[:], "6.8.0")
+			copy(info.Fw_version[:], "4.40 0x8001c967 1.3534.0")
+			copy(info.Bus_info[:], "0000:af:06.0")
+			Expect(drvInfoFromEthtool(info)).To(Equal(&DrvInfo{
+				Driver:    "iavf",
+				Version:   "6.8.0",
+				FwVersion: "4.40 0x8001c967 1.3534.0",
+				BusInfo:   "0000:af:06.0",
+			}))
+		})
+		It("Assuming the driver reports no firmware", func() {
+			info := &unix.EthtoolDrvinfo{Cmd: unix.ETHTOOL_GDRVINFO}
+			copy(info.Driver[:], "mlx5_core")
+			drvInfo := drvInfoFromEthtool(info)
+			Expect(drvInfo.Driver).To(Equal("mlx5_core"))
+			Expect(drvInfo.FwVersion).To(BeEmpty())
+			Expect(drvInfo.BusInfo).To(BeEmpty())
+		})
+		It("Assuming the device does not exist", func() {
+			_, err := GetDrvInfo("nonexistent0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking private flags masks", func() {
+		names := []string{"link-down-on-close", "legacy-rx", "vf-true-promisc-support"}
+
+		It("Assuming the mask maps to the flag names in bit order", func() {
+			Expect(privFlagsFromMask(names, 0x5)).To(Equal(map[string]bool{
+				"link-down-on-close":      true,
+				"legacy-rx":               false,
+				"vf-true-promisc-support": true,
+			}))
+		})
+		It("Assuming only the requested flags are changed", func() {
+			mask, err := privFlagsMask("net1", names, 0x1, map[string]bool{"vf-true-promisc-support": true, "link-down-on-close": false})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mask).To(Equal(uint32(0x4)))
+		})
+		It("Assuming a flag is not supported by the driver", func() {
+			_, err := privFlagsMask("net1", names, 0x1, map[string]bool{"no-such-flag": true})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("available flags: [link-down-on-close, legacy-rx, vf-true-promisc-support]"))
+		})
+	})
+	Context("Checking GetVFRepresentor function", func() {
+		It("Assuming the PF is in switchdev mode", func() {
+			rep, err := GetVFRepresentor("ens1", 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rep).To(Equal("ens1_1"))
+		})
+		It("Assuming the VF has no representor", func() {
+			_, err := GetVFRepresentor("ens1", 5)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Assuming the PF is in legacy mode", func() {
+			_, err := GetVFRepresentor("enp175s0f1", 0)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, ErrLegacyMode)).To(BeTrue())
+		})
+		It("Assuming the devlink compat mode of the PF is legacy", func() {
+			Expect(IsSwitchdevMode("ens1d1")).To(BeFalse())
+			_, err := GetVFRepresentor("ens1d1", 0)
+			Expect(errors.Is(err, ErrLegacyMode)).To(BeTrue())
+		})
+		It("Assuming the PF without devlink compat mode has a switch id", func() {
+			Expect(IsSwitchdevMode("ens1")).To(BeTrue())
+		})
+	})
+	Context("Checking GetPFLinkSpeed function", func() {
+		It("Returns the PF link speed", func() {
+			speed, err := GetPFLinkSpeed("enp175s0f1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(speed).To(Equal(25000))
+		})
+		It("Fails for a PF whose link speed is unknown", func() {
+			speedFile := filepath.Join(NetDirectory, "enp175s0f1", "speed")
+			Expect(os.WriteFile(speedFile, []byte("-1\n"), 0600)).To(Succeed())
+			defer func() {
+				Expect(os.WriteFile(speedFile, []byte("25000\n"), 0600)).To(Succeed())
+			}()
+			_, err := GetPFLinkSpeed("enp175s0f1")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking GetPFTotalVFs function", func() {
+		It("Returns the number of VFs the PF supports", func() {
+			totalVFs, err := GetPFTotalVFs("enp175s0f1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(totalVFs).To(Equal(64))
+		})
+		It("Fails for a device without SR-IOV", func() {
+			_, err := GetPFTotalVFs("enp175s0f2")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking EnableArpAndNdiscNotify function", func() {
+		It("Enables ndisc_notify even when arp_notify cannot be set", func() {
+			tmpdir, err := os.MkdirTemp("/tmp", "sriovplugin-notify-test-")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmpdir)
+
+			origV4, origV6 := SysV4ArpNotify, SysV6NdiscNotify
+			defer func() {
+				SysV4ArpNotify, SysV6NdiscNotify = origV4, origV6
+			}()
+			// the IPv4 conf directory of the interface is missing
+			SysV4ArpNotify = filepath.Join(tmpdir, "ipv4")
+			SysV6NdiscNotify = filepath.Join(tmpdir, "ipv6")
+			Expect(os.MkdirAll(filepath.Join(SysV6NdiscNotify, "net1"), 0755)).To(Succeed())
+
+			err = EnableArpAndNdiscNotify("net1")
+			Expect(err).To(MatchError(ContainSubstring("failed to write arp_notify=1 for interface net1")))
+			Expect(err).NotTo(MatchError(ContainSubstring("ndisc_notify")))
+			ndiscNotify, err := os.ReadFile(filepath.Join(SysV6NdiscNotify, "net1", "ndisc_notify"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(ndiscNotify)).To(Equal("1"))
+		})
+	})
+	Context("Checking SetInterfaceSysctl function", func() {
+		It("Writes the sysctl of the interface, whose name may contain dots", func() {
+			tmpdir, err := os.MkdirTemp("/tmp", "sriovplugin-sysctl-test-")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmpdir)
+
+			origProcSys := SysProcSys
+			defer func() { SysProcSys = origProcSys }()
+			SysProcSys = tmpdir
+			Expect(os.MkdirAll(filepath.Join(tmpdir, "net", "ipv6", "conf", "net1.100"), 0755)).To(Succeed())
+
+			Expect(SetInterfaceSysctl("net1.100", "net.ipv6.conf.IFNAME.accept_ra", "2")).To(Succeed())
+			acceptRA, err := os.ReadFile(filepath.Join(tmpdir, "net", "ipv6", "conf", "net1.100", "accept_ra"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(acceptRA)).To(Equal("2"))
+
+			err = SetInterfaceSysctl("net1", "net.ipv6.conf.IFNAME.accept_ra", "2")
+			Expect(err).To(MatchError(ContainSubstring("failed to write net.ipv6.conf.IFNAME.accept_ra=2 for interface net1")))
+		})
+	})
 })